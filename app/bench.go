@@ -0,0 +1,221 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+func NewBenchCommand(f Factory) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "bench",
+		Short: "Measure how fast a running mirror answers",
+		Long: `
+DESCRIPTION:
+  Send requests to the "kubemrr watch" process from many clients at once and report
+  the distribution of latencies and errors.
+
+  Requests mix filters the way completion does: objects of all servers, of one server,
+  and of one namespace of one server. Servers and namespaces are taken from the mirror.
+
+EXAMPLE:
+  kubemrr -a 10.5.1.6 -p 33033 bench --clients 50 --qps 200 --kind po
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := RunCommon(cmd); err != nil {
+				return err
+			}
+			return RunBench(f, cmd, args)
+		},
+	}
+
+	AddCommonFlags(cmd)
+	AddMirrorTLSFlags(cmd)
+	AddTokenFlag(cmd)
+	cmd.Flags().Int("clients", 10, "Number of concurrent clients")
+	cmd.Flags().Float64("qps", 100, "Total number of requests per second, 0 to send as fast as possible")
+	cmd.Flags().String("kind", "pod", "Kind of the requested objects")
+	cmd.Flags().Duration("duration", 10*time.Second, "How long to send requests")
+	return cmd
+}
+
+func RunBench(f Factory, cmd *cobra.Command, args []string) error {
+	clients, err := cmd.Flags().GetInt("clients")
+	if err != nil || clients < 1 {
+		return errors.New("--clients must be a positive number")
+	}
+
+	qps, err := cmd.Flags().GetFloat64("qps")
+	if err != nil || qps < 0 {
+		return errors.New("--qps must not be negative")
+	}
+
+	duration, err := cmd.Flags().GetDuration("duration")
+	if err != nil || duration <= 0 {
+		return errors.New("--duration must be positive")
+	}
+
+	resource, err := cmd.Flags().GetString("kind")
+	if err != nil {
+		return errors.New("could not parse value of --kind")
+	}
+	kind, err := resourceKind(resource)
+	if err != nil {
+		return err
+	}
+
+	bind, err := GetBind(cmd)
+	if err != nil {
+		return fmt.Errorf("unexpected error: %s", err)
+	}
+
+	opts, err := GetMrrClientOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	mrrClients := make([]MrrClient, clients)
+	for i := range mrrClients {
+		mrrClients[i], err = f.MrrClient(bind, opts)
+		if err != nil {
+			return fmt.Errorf("could not create client to kubemrr: %s", err)
+		}
+	}
+
+	filters, err := benchFilters(mrrClients[0], kind)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(f.StdOut(), "Sending %s requests of %d filters from %d clients for %s\n", kind, len(filters), clients, duration)
+	r := runBench(mrrClients, filters, qps, duration)
+	r.report(f.StdOut())
+	return nil
+}
+
+//benchFilters returns filters of objects of the kind: of all servers, of each server
+//and of each namespace where the mirror has objects of the kind
+func benchFilters(c MrrClient, kind string) ([]MrrFilter, error) {
+	status, err := c.Status(MrrFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get status of kubemrr: %s", err)
+	}
+
+	filters := []MrrFilter{{Kind: kind}}
+	for _, s := range status.Servers {
+		f := MrrFilter{Server: s.Server, Kind: kind}
+		filters = append(filters, f)
+
+		objects, err := c.Objects(f)
+		if err != nil {
+			return nil, fmt.Errorf("could not get objects of %s: %s", s.Server, err)
+		}
+		seen := map[string]bool{}
+		for _, o := range objects {
+			if o.Namespace != "" && !seen[o.Namespace] {
+				seen[o.Namespace] = true
+				filters = append(filters, MrrFilter{Server: s.Server, Namespace: o.Namespace, Kind: kind})
+			}
+		}
+	}
+	return filters, nil
+}
+
+//benchResult holds outcome of all requests of a benchmark
+type benchResult struct {
+	elapsed   time.Duration
+	latencies []time.Duration
+	errors    map[string]int
+	objects   int
+}
+
+//runBench sends requests with random filters from all clients at the given total rate
+func runBench(clients []MrrClient, filters []MrrFilter, qps float64, duration time.Duration) benchResult {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	r := benchResult{errors: map[string]int{}}
+
+	start := time.Now()
+	deadline := start.Add(duration)
+	for i, c := range clients {
+		wg.Add(1)
+		go func(c MrrClient, seed int64) {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(seed))
+			var interval time.Duration
+			if qps > 0 {
+				interval = time.Duration(float64(len(clients)) / qps * float64(time.Second))
+			}
+
+			next := time.Now()
+			for next.Before(deadline) {
+				if d := time.Until(next); d > 0 {
+					time.Sleep(d)
+				}
+				next = next.Add(interval)
+
+				f := filters[random.Intn(len(filters))]
+				sent := time.Now()
+				objects, err := c.Objects(f)
+				latency := time.Since(sent)
+
+				mu.Lock()
+				r.latencies = append(r.latencies, latency)
+				if err != nil {
+					r.errors[err.Error()]++
+				}
+				r.objects += len(objects)
+				mu.Unlock()
+			}
+		}(c, start.UnixNano()+int64(i))
+	}
+	wg.Wait()
+	r.elapsed = time.Since(start)
+	return r
+}
+
+func (r benchResult) report(out io.Writer) {
+	total := len(r.latencies)
+	failed := 0
+	for _, n := range r.errors {
+		failed += n
+	}
+
+	fmt.Fprintf(out, "Requests:  %d in %s (%.1f/s)\n", total, r.elapsed, float64(total)/r.elapsed.Seconds())
+	fmt.Fprintf(out, "Errors:    %d\n", failed)
+	if total == 0 {
+		return
+	}
+	fmt.Fprintf(out, "Objects:   %.1f per request\n", float64(r.objects)/float64(total))
+
+	sorted := append([]time.Duration{}, r.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	fmt.Fprintf(out, "Latency:   p50 %s, p90 %s, p99 %s, max %s\n",
+		percentile(sorted, 50), percentile(sorted, 90), percentile(sorted, 99), sorted[len(sorted)-1])
+
+	messages := []string{}
+	for m := range r.errors {
+		messages = append(messages, m)
+	}
+	sort.Slice(messages, func(i, j int) bool { return r.errors[messages[i]] > r.errors[messages[j]] })
+	for _, m := range messages {
+		fmt.Fprintf(out, "  %6d  %s\n", r.errors[m], m)
+	}
+}
+
+//percentile returns the latency below which the given percent of sorted latencies fall
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
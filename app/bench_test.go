@@ -0,0 +1,57 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBenchFilters(t *testing.T) {
+	tc := &TestMirrorClient{
+		status: MrrStatus{Servers: []ServerStatus{{Server: "https://foo.com"}}},
+		objects: []KubeObject{
+			{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "ns1"}},
+			{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "b", Namespace: "ns1"}},
+			{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "c", Namespace: "ns2"}},
+		},
+	}
+
+	filters, err := benchFilters(tc, "pod")
+	assert.NoError(t, err)
+	assert.Equal(t, []MrrFilter{
+		{Kind: "pod"},
+		{Server: "https://foo.com", Kind: "pod"},
+		{Server: "https://foo.com", Namespace: "ns1", Kind: "pod"},
+		{Server: "https://foo.com", Namespace: "ns2", Kind: "pod"},
+	}, filters)
+}
+
+func TestRunBench(t *testing.T) {
+	clients := []MrrClient{
+		&TestMirrorClient{objects: []KubeObject{{}, {}}},
+		&TestMirrorClient{err: errors.New("connection refused")},
+	}
+
+	r := runBench(clients, []MrrFilter{{Kind: "pod"}}, 200, 100*time.Millisecond)
+	if len(r.latencies) < 10 || len(r.latencies) > 30 {
+		t.Errorf("Expected about 20 requests at 200 qps, sent %d", len(r.latencies))
+	}
+	assert.Equal(t, len(r.latencies)/2, r.errors["connection refused"], "half of the clients fail")
+
+	out := &bytes.Buffer{}
+	r.report(out)
+	assert.Contains(t, out.String(), "Latency:   p50")
+	assert.Contains(t, out.String(), "connection refused")
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	assert.Equal(t, time.Duration(50), percentile(sorted, 50))
+	assert.Equal(t, time.Duration(99), percentile(sorted, 99))
+	assert.Equal(t, time.Duration(1), percentile(sorted[:1], 99))
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+//customColumnsPrefix starts the output format that prints columns given by the user, like in kubectl
+const customColumnsPrefix = "custom-columns="
+
+//customColumn is a column of the custom-columns output, such as NAME:.metadata.name
+type customColumn struct {
+	header string
+	path   []string
+}
+
+//parseCustomColumns parses the spec of columns given as HEADER:.field.path separated by commas.
+//Paths name fields of objects as printed by -o json, and may be wrapped in braces like {.metadata.name}
+func parseCustomColumns(spec string) ([]customColumn, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("custom-columns format requires columns, such as %sNAME:.metadata.name", customColumnsPrefix)
+	}
+
+	columns := []customColumn{}
+	for _, raw := range strings.Split(spec, ",") {
+		parts := strings.SplitN(raw, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid custom column %q, must be HEADER:.field.path", raw)
+		}
+		path := strings.TrimSuffix(strings.TrimPrefix(parts[1], "{"), "}")
+		if !strings.HasPrefix(path, ".") || path == "." {
+			return nil, fmt.Errorf("invalid field path %q of custom column %s, must start with a dot", parts[1], parts[0])
+		}
+		column := customColumn{header: parts[0], path: strings.Split(path[1:], ".")}
+		for _, field := range column.path {
+			if field == "" {
+				return nil, fmt.Errorf("invalid field path %q of custom column %s", parts[1], parts[0])
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+//value returns the field of the object at the path of the column, empty if the object does not have it
+func (c *customColumn) value(o interface{}) string {
+	for _, field := range c.path {
+		m, ok := o.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		o = m[field]
+	}
+
+	switch v := o.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(raw)
+	}
+}
+
+//outputCustomColumns prints a table of the given columns of the objects
+func outputCustomColumns(objects []KubeObject, columns []customColumn, out io.Writer) error {
+	raw, err := json.Marshal(objects)
+	if err != nil {
+		return err
+	}
+	var generic []interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	values := make([]string, len(columns))
+	for i := range columns {
+		values[i] = columns[i].header
+	}
+	fmt.Fprintln(w, strings.Join(values, "\t"))
+	for _, o := range generic {
+		for i := range columns {
+			values[i] = valueOrNone(columns[i].value(o))
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	return w.Flush()
+}
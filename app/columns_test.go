@@ -0,0 +1,43 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseCustomColumns(t *testing.T) {
+	columns, err := parseCustomColumns("NAME:.metadata.name,APP:{.metadata.labels.app}")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(columns) != 2 || columns[0].header != "NAME" || strings.Join(columns[1].path, "/") != "metadata/labels/app" {
+		t.Errorf("Unexpected columns %+v", columns)
+	}
+
+	for _, spec := range []string{"", "NAME", ":.metadata.name", "NAME:metadata.name", "NAME:.", "NAME:.metadata..name"} {
+		if _, err := parseCustomColumns(spec); err == nil {
+			t.Errorf("Spec %q: expected error", spec)
+		}
+	}
+}
+
+func TestOutputCustomColumns(t *testing.T) {
+	objects := []KubeObject{
+		{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "web", Namespace: "prod", Labels: map[string]string{"app": "checkout"}}, Server: "https://s1"},
+		{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "db", Namespace: "prod"}, Server: "https://s1"},
+	}
+	columns, _ := parseCustomColumns("NAME:.metadata.name,APP:.metadata.labels.app,SERVER:.server,LABELS:.metadata.labels")
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := outputCustomColumns(objects, columns, buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "" +
+		"NAME  APP       SERVER      LABELS\n" +
+		"web   checkout  https://s1  {\"app\":\"checkout\"}\n" +
+		"db    <none>    https://s1  <none>\n"
+	if buf.String() != expected {
+		t.Errorf("Expected output\n%s\ngot\n%s", expected, buf)
+	}
+}
@@ -0,0 +1,313 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func NewCompleteCommand(f Factory) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "complete",
+		Short: "Print candidates for the word under the cursor of a kubectl command line",
+		Long: `
+DESCRIPTION:
+  Parses the kubectl command line up to the cursor: the verb, the resource type, names and flags.
+  Then prints candidates for the word under the cursor, one per line: resource types, names of
+  objects, namespaces, containers, labels, contexts, clusters or users. Candidates are asked from
+  the mirror the same way "kubemrr get" asks for them. Nodes of "kubectl drain" and "kubectl cordon"
+  are only the ready ones, that are not cordoned yet.
+
+  When a path to a file is completed, such as after -f, ":files" is printed instead.
+  Completion functions of any shell only have to pass the line and the cursor.
+
+EXAMPLE:
+  kubemrr complete --line "kubectl logs -n prod web-"
+  kubemrr complete --line "kubectl delete deployment web -n " --point 30
+  kubemrr complete --line "kubectl logs web-1 -c "
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := RunCommon(cmd); err != nil {
+				return err
+			}
+			if quiet, err := GetQuiet(cmd); err != nil {
+				return err
+			} else if quiet {
+				silence(cmd)
+			}
+			return RunComplete(f, cmd, args)
+		},
+	}
+
+	AddCommonFlags(cmd)
+	AddLogLevelFlag(cmd)
+	AddFallbackFlag(cmd)
+	AddMirrorTLSFlags(cmd)
+	AddTokenFlag(cmd)
+	AddProfileFlag(cmd)
+	cmd.Flags().String("line", "", "The kubectl command line being completed")
+	cmd.Flags().Int("point", -1, "Position of the cursor in --line, by default its end")
+	cmd.Flags().Bool("quiet", false, "Print no errors and warnings, only exit with non-zero code, by default taken from $"+quietEnv)
+	cmd.Flags().Duration("timeout", defaultGetTimeout, "How long to wait for the mirror to connect and to answer")
+	return cmd
+}
+
+//completion describes candidates for the word under the cursor
+type completion struct {
+	//get are arguments of "kubemrr get" that prints the candidates, nil when candidates are not asked from the mirror
+	get []string
+	//phase limits candidates to pods in the phase
+	phase string
+	//readyOnly limits candidates to ready nodes
+	readyOnly bool
+	//types is set when the resource type is completed
+	types bool
+	//files is set when a path to a file is completed
+	files bool
+
+	//prefix is the typed part of the candidates, and word is what the shell replaces with them,
+	//such as "--namespace=" followed by a namespace
+	prefix string
+	word   string
+
+	//line is the command line before the word under the cursor
+	line string
+}
+
+//kubectlPodVerbs are commands of kubectl that are followed by the name of a pod
+var kubectlPodVerbs = map[string]bool{"logs": true, "exec": true, "attach": true, "port-forward": true}
+
+//flagCompletions maps flags of kubectl to arguments of "kubemrr get" that complete their values
+var flagCompletions = map[string][]string{
+	"-n":          {"namespaces"},
+	"--namespace": {"namespaces"},
+	"--context":   {"contexts"},
+	"--cluster":   {"clusters"},
+	"--user":      {"users"},
+}
+
+//parseCompletion tells what is completed at the point of the kubectl command line.
+//Negative point, or a point past the end, means the end of the line
+func parseCompletion(line string, point int) completion {
+	runes := []rune(line)
+	if point >= 0 && point < len(runes) {
+		runes = runes[:point]
+	}
+	line = string(runes)
+
+	words := splitKubectlLine(line)
+	cur := ""
+	if len(runes) > 0 && !strings.ContainsRune(" \t\n", runes[len(runes)-1]) && len(words) > 0 {
+		cur = words[len(words)-1]
+		words = words[:len(words)-1]
+		line = line[:strings.LastIndexAny(line, " \t\n")+1]
+	}
+	prev := ""
+	if len(words) > 0 {
+		prev = words[len(words)-1]
+	}
+
+	verb, positional := commandVerb(words)
+	c := completion{prefix: cur, line: line}
+
+	//values of flags, either glued to them or in the next word
+	flag := ""
+	if strings.HasPrefix(cur, "-") {
+		i := strings.Index(cur, "=")
+		if i < 0 {
+			return c
+		}
+		flag, c.prefix = cur[:i], cur[i+1:]
+		c.word = cur[:i+1]
+	} else if strings.HasPrefix(prev, "-") && !strings.Contains(prev, "=") &&
+		(kubectlFlagsWithValue[prev] || kubectlValueFlags[prev] != nil) {
+		flag = prev
+	}
+	if flag != "" {
+		switch {
+		case flagCompletions[flag] != nil:
+			c.get = flagCompletions[flag]
+		case flag == "-f" || flag == "--filename":
+			c.files = true
+		case flag == "-l" || flag == "--selector":
+			resource := "all"
+			if !kubectlPodVerbs[verb] && len(positional) > 0 {
+				resource = typeOf(positional[0])
+			} else if kubectlPodVerbs[verb] {
+				resource = "pod"
+			}
+			c.get = []string{"labels", resource}
+		case (flag == "-c" || flag == "--container") && kubectlPodVerbs[verb] && len(positional) > 0:
+			c.get = []string{"containers", nameOf(positional[0])}
+		}
+		return c
+	}
+
+	_, nodeVerb := kubectlNodeVerbs[verb]
+	switch {
+	case nodeVerb && len(positional) == 0:
+		c.get = []string{"node"}
+		c.readyOnly = kubectlNodeVerbs[verb]
+	case nodeVerb:
+	case verb == "port-forward" && len(positional) == 0 && strings.Contains(cur, "/"):
+		c.get = []string{typeOf(cur)}
+		c.prefix = nameOf(cur)
+		c.word = typeOf(cur) + "/"
+	case verb == "port-forward" && len(positional) == 1:
+		c.get = []string{"ports", positional[0]}
+		//remote ports follow local ones, as in 8080:80
+		if i := strings.Index(cur, ":"); i >= 0 {
+			c.prefix = cur[i+1:]
+			c.word = cur[:i+1]
+		}
+	case kubectlPodVerbs[verb] && len(positional) == 0:
+		c.get = []string{"pod"}
+		if verb != "logs" {
+			c.phase = "Running"
+		}
+	case verb == "logs" && len(positional) == 1:
+		c.get = []string{"containers", nameOf(positional[0])}
+	case verb != "" && !kubectlPodVerbs[verb] && len(positional) == 0:
+		if i := strings.Index(cur, "/"); i >= 0 {
+			c.get = []string{cur[:i]}
+			c.prefix = cur[i+1:]
+			c.word = cur[:i+1]
+		} else {
+			c.types = true
+		}
+	case verb != "" && !kubectlPodVerbs[verb]:
+		c.get = []string{typeOf(positional[0])}
+	}
+	return c
+}
+
+//commandVerb returns the verb of the kubectl command and positional arguments that follow it,
+//without subcommands of the verb. Empty verb is returned when the command has no known verb
+func commandVerb(words []string) (string, []string) {
+	positional := []string{}
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if strings.HasPrefix(w, "-") {
+			if !strings.Contains(w, "=") && (kubectlFlagsWithValue[w] || kubectlValueFlags[w] != nil) {
+				i++
+			}
+			continue
+		}
+		positional = append(positional, w)
+	}
+
+	for i, w := range positional {
+		if _, ok := kubectlNodeVerbs[w]; ok || kubectlPodVerbs[w] {
+			return w, positional[i+1:]
+		}
+		if subcommands, ok := kubectlResourceVerbs[w]; ok {
+			if i+subcommands+1 > len(positional) {
+				return w, []string{}
+			}
+			return w, positional[i+subcommands+1:]
+		}
+	}
+	return "", nil
+}
+
+//typeOf returns the resource type of "TYPE/NAME" or of "TYPE"
+func typeOf(resource string) string {
+	if i := strings.Index(resource, "/"); i >= 0 {
+		return resource[:i]
+	}
+	return resource
+}
+
+//nameOf returns the name of "TYPE/NAME" or "NAME"
+func nameOf(resource string) string {
+	if i := strings.Index(resource, "/"); i >= 0 {
+		return resource[i+1:]
+	}
+	return resource
+}
+
+func RunComplete(f Factory, cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return errors.New("no arguments are expected, the command line is given by --line")
+	}
+	line, err := cmd.Flags().GetString("line")
+	if err != nil {
+		return errors.New("could not parse value of --line")
+	}
+	point, err := cmd.Flags().GetInt("point")
+	if err != nil {
+		return errors.New("could not parse value of --point")
+	}
+
+	c := parseCompletion(line, point)
+	candidates := []string{}
+	switch {
+	case c.files:
+		_, err := fmt.Fprintln(f.StdOut(), filesDirective)
+		return err
+	case c.types:
+		c.get = []string{"kinds"}
+		fallthrough
+	case c.get != nil:
+		if candidates, err = completeWithGet(f, cmd, c); err != nil {
+			return err
+		}
+	}
+
+	for _, candidate := range candidates {
+		if _, err := fmt.Fprintln(f.StdOut(), c.word+candidate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//completeWithGet runs "kubemrr get" with the flags of the complete command that it has,
+//and returns the names that it prints
+func completeWithGet(f Factory, cmd *cobra.Command, c completion) ([]string, error) {
+	out := &bytes.Buffer{}
+	getFactory := &outputFactory{Factory: f, out: out}
+	get := NewGetCommand(getFactory)
+	var err error
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if get.Flags().Lookup(flag.Name) == nil || err != nil {
+			return
+		}
+		value := flag.Value.String()
+		if flag.Value.Type() == "stringSlice" {
+			//slices are printed in brackets, but set from comma-separated values
+			values, _ := cmd.Flags().GetStringSlice(flag.Name)
+			value = strings.Join(values, ",")
+		}
+		err = get.Flags().Set(flag.Name, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{"kubectl-flags": c.line, "prefix": c.prefix, "phase": c.phase, "ready-only": strconv.FormatBool(c.readyOnly)}
+	for name, value := range values {
+		if err := get.Flags().Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := RunGet(getFactory, get, c.get); err != nil {
+		return nil, err
+	}
+	return strings.Fields(out.String()), nil
+}
+
+//outputFactory is the factory whose output is captured
+type outputFactory struct {
+	Factory
+	out io.Writer
+}
+
+func (f *outputFactory) StdOut() io.Writer {
+	return f.out
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseCompletion(t *testing.T) {
+	tests := []struct {
+		line     string
+		point    int
+		expected completion
+	}{
+		{line: "kubectl get ", point: -1, expected: completion{types: true, line: "kubectl get "}},
+		{line: "kubectl get po", point: -1, expected: completion{types: true, prefix: "po", line: "kubectl get "}},
+		{line: "kubectl get po we", point: -1, expected: completion{get: []string{"po"}, prefix: "we", line: "kubectl get po "}},
+		{line: "kubectl -n prod delete deploy/we", point: -1, expected: completion{get: []string{"deploy"}, prefix: "we", word: "deploy/", line: "kubectl -n prod delete "}},
+		{line: "kubectl rollout restart deployment ", point: -1, expected: completion{get: []string{"deployment"}, line: "kubectl rollout restart deployment "}},
+		{line: "kubectl exec ", point: -1, expected: completion{get: []string{"pod"}, phase: "Running", line: "kubectl exec "}},
+		{line: "kubectl logs web", point: -1, expected: completion{get: []string{"pod"}, prefix: "web", line: "kubectl logs "}},
+		{line: "kubectl logs web-1 ", point: -1, expected: completion{get: []string{"containers", "web-1"}, line: "kubectl logs web-1 "}},
+		{line: "kubectl exec pod/web-1 -c ", point: -1, expected: completion{get: []string{"containers", "web-1"}, line: "kubectl exec pod/web-1 -c "}},
+		{line: "kubectl get po -n ku", point: -1, expected: completion{get: []string{"namespaces"}, prefix: "ku", line: "kubectl get po -n "}},
+		{line: "kubectl get po --namespace=ku", point: -1, expected: completion{get: []string{"namespaces"}, prefix: "ku", word: "--namespace=", line: "kubectl get po "}},
+		{line: "kubectl get svc -l app=", point: -1, expected: completion{get: []string{"labels", "svc"}, prefix: "app=", line: "kubectl get svc -l "}},
+		{line: "kubectl --context ", point: -1, expected: completion{get: []string{"contexts"}, line: "kubectl --context "}},
+		{line: "kubectl apply -f ", point: -1, expected: completion{files: true, line: "kubectl apply -f "}},
+		{line: "kubectl get po --all", point: -1, expected: completion{prefix: "--all", line: "kubectl get po "}},
+		{line: "kubectl version ", point: -1, expected: completion{line: "kubectl version "}},
+		{line: "kubectl port-forward svc/w", point: -1, expected: completion{get: []string{"svc"}, prefix: "w", word: "svc/", line: "kubectl port-forward "}},
+		{line: "kubectl port-forward w", point: -1, expected: completion{get: []string{"pod"}, phase: "Running", prefix: "w", line: "kubectl port-forward "}},
+		{line: "kubectl port-forward svc/web ", point: -1, expected: completion{get: []string{"ports", "svc/web"}, line: "kubectl port-forward svc/web "}},
+		{line: "kubectl port-forward svc/web 8080:8", point: -1, expected: completion{get: []string{"ports", "svc/web"}, prefix: "8", word: "8080:", line: "kubectl port-forward svc/web "}},
+		{line: "kubectl drain n", point: -1, expected: completion{get: []string{"node"}, readyOnly: true, prefix: "n", line: "kubectl drain "}},
+		{line: "kubectl uncordon ", point: -1, expected: completion{get: []string{"node"}, line: "kubectl uncordon "}},
+		{line: "kubectl drain node-1 ", point: -1, expected: completion{line: "kubectl drain node-1 "}},
+		{line: "kubectl taint nodes ", point: -1, expected: completion{get: []string{"nodes"}, line: "kubectl taint nodes "}},
+		{line: "kubectl get po web -n prod", point: 18, expected: completion{get: []string{"po"}, prefix: "web", line: "kubectl get po "}},
+	}
+
+	for i, test := range tests {
+		if actual := parseCompletion(test.line, test.point); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
+
+func TestRunComplete(t *testing.T) {
+	tc := &TestMirrorClient{objects: []KubeObject{
+		{ObjectMeta: ObjectMeta{Name: "web-1"}},
+		{ObjectMeta: ObjectMeta{Name: "api-1"}},
+	}, resources: []APIResource{{Name: "virtualservices", Kind: "VirtualService"}}}
+	conf := Config{
+		CurrentContext: "prod",
+		Contexts:       []ContextWrap{{Name: "prod"}, {Name: "dev"}},
+	}
+
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{line: "kubectl get po w", expected: "web-1\n"},
+		{line: "kubectl delete pod/", expected: "pod/web-1\npod/api-1\n"},
+		{line: "kubectl get po --context=d", expected: "--context=dev\n"},
+		{line: "kubectl get de", expected: "deployments\n"},
+		{line: "kubectl get vi", expected: "virtualservices\n"},
+		{line: "kubectl apply -f ", expected: ":files\n"},
+		{line: "kubectl version ", expected: ""},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer([]byte{})
+		cmd := NewCompleteCommand(&TestFactory{mrrClient: tc, kubeconfig: conf, stdOut: buf})
+		cmd.Flags().Set("line", test.line)
+		if err := cmd.RunE(cmd, []string{}); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if buf.String() != test.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, test.expected, buf.String())
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewCompleteCommand(&TestFactory{mrrClient: tc, kubeconfig: conf, stdOut: buf})
+	cmd.Flags().Set("line", "kubectl exec ")
+	if err := cmd.RunE(cmd, []string{}); err != nil || tc.lastFilter.Phase != "Running" {
+		t.Errorf("Expected only running pods to be asked for, got %+v, %v", tc.lastFilter, err)
+	}
+}
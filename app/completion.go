@@ -12,14 +12,31 @@ func NewCompletionCommand(f Factory) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "completion",
 		Short: "Create completion script for kubectl (or alias)",
+		Long: `
+DESCRIPTION:
+  Prints the completion script of kubectl for bash or zsh, in which names of objects and values
+  of flags are completed by "kubemrr get" instead of kubectl. The script asks the mirror at the
+  given address and port, or the unix socket given by --bind, so it does not have to be edited.
+
+  Load it in the current shell, or save it into /etc/bash_completion.d to load it in every shell.
+
+EXAMPLE:
+  source <(kubemrr completion bash)
+  kubemrr completion bash -a 10.5.1.6 -p 33033 --kubectl-alias=kus > /etc/bash_completion.d/kus
+  kubemrr completion zsh --bind=unix:///tmp/kubemrr.sock > ~/.kubemrr-completion.zsh
+`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return RunAlias(f, cmd, args)
 		},
 	}
 
 	AddCommonFlags(cmd)
+	AddFallbackFlag(cmd)
+	AddMirrorTLSFlags(cmd)
+	AddProfileFlag(cmd)
 	cmd.Flags().String("kubectl-alias", "kubectl", "Alias of your kubectl command")
 	cmd.Flags().String("kubemrr-path", "kubemrr", "Path to the kubemrr command, if it is outside $PATH variable")
+	cmd.Flags().Bool("quiet", true, "Make \"kubemrr get\" print no errors into the prompt. Disable to debug the script")
 
 	return cmd
 }
@@ -58,18 +75,56 @@ func RunAlias(f Factory, cmd *cobra.Command, args []string) error {
 	if c.kubemrrAddress, err = cmd.Flags().GetString("address"); err != nil {
 		return err
 	}
+	if c.kubemrrBind, err = cmd.Flags().GetString("bind"); err != nil {
+		return err
+	}
+	if c.kubemrrFallback, err = cmd.Flags().GetStringSlice("fallback"); err != nil {
+		return err
+	}
+	if c.kubemrrTLS, err = cmd.Flags().GetBool("tls"); err != nil {
+		return err
+	}
+	if c.kubemrrTLSCA, err = cmd.Flags().GetString("tls-ca"); err != nil {
+		return err
+	}
+	if c.kubemrrProfile, err = cmd.Flags().GetString("profile"); err != nil {
+		return err
+	}
 	if c.kubectlAlias, err = cmd.Flags().GetString("kubectl-alias"); err != nil {
 		return err
 	}
 	if c.kubemrrPath, err = cmd.Flags().GetString("kubemrr-path"); err != nil {
 		return err
 	}
+	if c.kubemrrQuiet, err = cmd.Flags().GetBool("quiet"); err != nil {
+		return err
+	}
 
 	in = fmt.Sprintf("# Below is your completion script for %s with %+v \n", shell, c) + in
+	if c.kubemrrBind != "" {
+		in = strings.Replace(in, "-a [[kubemrr_address]] -p [[kubemrr_port]]", "--bind="+c.kubemrrBind, -1)
+	}
 	in = strings.Replace(in, "[[kubectl_alias]]", c.kubectlAlias, -1)
 	in = strings.Replace(in, "[[kubemrr_path]]", c.kubemrrPath, -1)
 	in = strings.Replace(in, "[[kubemrr_address]]", c.kubemrrAddress, -1)
 	in = strings.Replace(in, "[[kubemrr_port]]", strconv.Itoa(c.kubemrrPort), -1)
+	flags := ""
+	if len(c.kubemrrFallback) > 0 {
+		flags += " --fallback=" + strings.Join(c.kubemrrFallback, ",")
+	}
+	if c.kubemrrTLS {
+		flags += " --tls"
+	}
+	if c.kubemrrTLSCA != "" {
+		flags += " --tls-ca=" + c.kubemrrTLSCA
+	}
+	if c.kubemrrProfile != "" {
+		flags += " --profile=" + c.kubemrrProfile
+	}
+	if c.kubemrrQuiet {
+		flags += " --quiet"
+	}
+	in = strings.Replace(in, "[[kubemrr_flags]]", flags, -1)
 	in = in + fmt.Sprintf("# Above is your completion script for %s with %+v \n", shell, c)
 
 	fmt.Fprint(f.StdOut(), in)
@@ -77,8 +132,54 @@ func RunAlias(f Factory, cmd *cobra.Command, args []string) error {
 }
 
 type replacement struct {
-	kubectlAlias   string
-	kubemrrPort    int
-	kubemrrAddress string
-	kubemrrPath    string
+	kubectlAlias    string
+	kubemrrPort     int
+	kubemrrAddress  string
+	kubemrrBind     string
+	kubemrrFallback []string
+	kubemrrTLS      bool
+	kubemrrTLSCA    string
+	kubemrrProfile  string
+	kubemrrPath     string
+	kubemrrQuiet    bool
+}
+
+//filesDirective is printed by the completion engine instead of names when the word being
+//completed is a path. Completion scripts then complete files and do not query the mirror
+const filesDirective = ":files"
+
+//pathFlags are kubectl flags that take paths to manifests or kustomization directories
+var pathFlags = []string{"-f", "--filename", "-k", "--kustomize"}
+
+//completesPath tells if the word being completed, given as prefix, is the value of a path flag
+//in the kubectl command line. In "kubectl logs" -f is --follow, which takes no value
+func completesPath(kubectlLine string, prefix string) bool {
+	for _, flag := range pathFlags {
+		if strings.HasPrefix(prefix, flag+"=") {
+			return true
+		}
+	}
+
+	words := strings.Fields(kubectlLine)
+	if prefix != "" && len(words) > 0 && words[len(words)-1] == prefix {
+		words = words[:len(words)-1]
+	}
+	if len(words) == 0 {
+		return false
+	}
+
+	prev := words[len(words)-1]
+	if prev == "-f" {
+		for _, w := range words {
+			if w == "logs" || w == "log" {
+				return false
+			}
+		}
+	}
+	for _, flag := range pathFlags {
+		if prev == flag {
+			return true
+		}
+	}
+	return false
 }
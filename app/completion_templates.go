@@ -265,11 +265,44 @@ __kubectl_get_namespaces()
 {
     local template kubectl_out
     template="{{ range .items  }}{{ .metadata.name }} {{ end }}"
-    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]] --kubectl-flags="$kubectl_line" get namespace); then
+    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]][[kubemrr_flags]] --kubectl-flags="$kubectl_line" get namespace); then
         COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
     fi
 }
 
+__kubectl_get_labels()
+{
+    local kubectl_line
+    __unalias "$COMP_LINE"
+    local kubectl_out
+    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]][[kubemrr_flags]] --kubectl-flags="$kubectl_line" --prefix="$cur" get labels "${nouns[0]:-all}" 2>/dev/null); then
+        COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
+    fi
+}
+
+__kubectl_get_kubeconfig()
+{
+    local kubectl_out
+    if kubectl_out=$([[kubemrr_path]] get "$1"); then
+        COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
+    fi
+}
+
+__kubectl_get_contexts()
+{
+    __kubectl_get_kubeconfig contexts
+}
+
+__kubectl_get_clusters()
+{
+    __kubectl_get_kubeconfig clusters
+}
+
+__kubectl_get_users()
+{
+    __kubectl_get_kubeconfig users
+}
+
 __unalias()
 {
     __debug "${FUNCNAME[0]}: $1"
@@ -288,17 +321,29 @@ __kubectl_parse_get()
     local template
     template="{{ range .items  }}{{ .metadata.name }} {{ end }}"
     local kubectl_out
-    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]] --kubectl-flags="$kubectl_line" get "$1" 2>>"$bash_comp_err_file"); then
+    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]][[kubemrr_flags]] --kubectl-flags="$kubectl_line" --prefix="$cur" get "$@" 2>>"$bash_comp_err_file"); then
+        # the completion engine decides that a path to a file is completed, e.g. after -f
+        if [[ ${kubectl_out} == ":files" ]]; then
+            _filedir
+            return
+        fi
         COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
+        # a compressed prefix of many names is completed without space, to drill down on the next [TAB]
+        if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == *[-.] && $(type -t compopt) = "builtin" ]]; then
+            compopt -o nospace
+        fi
     fi
 }
 
 __kubectl_get_resource()
 {
+    # types missing from the static list, such as custom resources, are asked from the mirror
     if [[ ${#nouns[@]} -eq 0 ]]; then
-        return 1
+        __kubectl_parse_get kinds
+        return
     fi
-    __kubectl_parse_get "${nouns[${#nouns[@]} -1]}"
+    # the resource type is taken from the command, names already given are not types
+    __kubectl_parse_get --for-command
 }
 
 __kubectl_get_resource_pod()
@@ -311,11 +356,32 @@ __kubectl_get_resource_rc()
     __kubectl_parse_get "rc"
 }
 
+# candidates of "kubemrr complete" for the whole line, such as svc/NAME and ports of port-forward
+__kubectl_complete_line()
+{
+    local kubectl_line
+    __unalias "$COMP_LINE"
+    local kubectl_out
+    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]][[kubemrr_flags]] complete --line="$kubectl_line" 2>/dev/null); then
+        COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
+    fi
+}
+
+# pods that can be executed in and attached to, not completed or evicted ones
+__kubectl_get_resource_running_pod()
+{
+    __kubectl_parse_get "pod" --phase=Running
+}
+
+# nodes that can be drained and cordoned, not cordoned or not ready ones
+__kubectl_get_resource_ready_node()
+{
+    __kubectl_parse_get "node" --ready-only
+}
+
 # $1 is the name of the pod we want to get the list of containers inside
 __kubectl_get_containers()
 {
-    local template
-    template="{{ range .spec.containers  }}{{ .name }} {{ end }}"
     __debug "${FUNCNAME} nouns are ${nouns[*]}"
 
     local len="${#nouns[@]}"
@@ -323,8 +389,10 @@ __kubectl_get_containers()
         return
     fi
     local last=${nouns[${len} -1]}
+    local kubectl_line
+    __unalias "$COMP_LINE"
     local kubectl_out
-    if kubectl_out=$(kubectl get $(__kubectl_namespace_flag) -o template --template="${template}" pods "${last}" 2>/dev/null); then
+    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]][[kubemrr_flags]] --kubectl-flags="$kubectl_line" --prefix="$cur" get containers "${last}" 2>/dev/null); then
         COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
     fi
 }
@@ -351,8 +419,20 @@ __custom_func() {
             __kubectl_require_pod_and_container
             return
             ;;
-        kubectl_exec)
-            __kubectl_get_resource_pod
+        kubectl_exec | kubectl_attach)
+            __kubectl_get_resource_running_pod
+            return
+            ;;
+        kubectl_port-forward | kubectl_taint)
+            __kubectl_complete_line
+            return
+            ;;
+        kubectl_drain | kubectl_cordon)
+            __kubectl_get_resource_ready_node
+            return
+            ;;
+        kubectl_uncordon)
+            __kubectl_parse_get "node"
             return
             ;;
         kubectl_rolling-update)
@@ -393,6 +473,10 @@ _kubectl_get()
     flags+=("-R")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-all")
     flags+=("-a")
     flags+=("--show-labels")
@@ -410,7 +494,11 @@ _kubectl_get()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -427,6 +515,8 @@ _kubectl_get()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -544,6 +634,10 @@ _kubectl_set_image()
     flags+=("-R")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-all")
     flags+=("-a")
     flags+=("--show-labels")
@@ -558,7 +652,11 @@ _kubectl_set_image()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -575,6 +673,8 @@ _kubectl_set_image()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -602,7 +702,11 @@ _kubectl_set()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -619,6 +723,8 @@ _kubectl_set()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -649,6 +755,10 @@ _kubectl_describe()
     flags+=("-R")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-events")
     flags+=("--alsologtostderr")
     flags+=("--api-version=")
@@ -657,7 +767,11 @@ _kubectl_describe()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -674,6 +788,8 @@ _kubectl_describe()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -779,7 +895,11 @@ _kubectl_create_namespace()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -796,6 +916,8 @@ _kubectl_create_namespace()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -845,7 +967,11 @@ _kubectl_create_secret_docker-registry()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -862,6 +988,8 @@ _kubectl_create_secret_docker-registry()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -911,7 +1039,11 @@ _kubectl_create_secret_tls()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -928,6 +1060,8 @@ _kubectl_create_secret_tls()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -975,7 +1109,11 @@ _kubectl_create_secret_generic()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -992,6 +1130,8 @@ _kubectl_create_secret_generic()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1021,7 +1161,11 @@ _kubectl_create_secret()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1038,6 +1182,8 @@ _kubectl_create_secret()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1084,7 +1230,11 @@ _kubectl_create_configmap()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1101,6 +1251,8 @@ _kubectl_create_configmap()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1146,7 +1298,11 @@ _kubectl_create_serviceaccount()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1163,6 +1319,8 @@ _kubectl_create_serviceaccount()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1210,7 +1368,11 @@ _kubectl_create()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1227,6 +1389,8 @@ _kubectl_create()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1276,7 +1440,11 @@ _kubectl_replace()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1293,6 +1461,8 @@ _kubectl_replace()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1336,7 +1506,11 @@ _kubectl_patch()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1353,6 +1527,8 @@ _kubectl_patch()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1473,6 +1649,10 @@ _kubectl_delete()
     flags+=("-R")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--timeout=")
     flags+=("--alsologtostderr")
     flags+=("--api-version=")
@@ -1481,7 +1661,11 @@ _kubectl_delete()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1498,6 +1682,8 @@ _kubectl_delete()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1620,7 +1806,11 @@ _kubectl_edit()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1637,6 +1827,8 @@ _kubectl_edit()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1760,7 +1952,11 @@ _kubectl_apply()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1777,6 +1973,8 @@ _kubectl_apply()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1805,7 +2003,11 @@ _kubectl_namespace()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1822,6 +2024,8 @@ _kubectl_namespace()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1843,6 +2047,10 @@ _kubectl_logs()
 
     flags+=("--container=")
     two_word_flags+=("-c")
+    flags_with_completion+=("--container")
+    flags_completion+=("__kubectl_get_containers")
+    flags_with_completion+=("-c")
+    flags_completion+=("__kubectl_get_containers")
     flags+=("--follow")
     flags+=("-f")
     flags+=("--include-extended-apis")
@@ -1861,7 +2069,11 @@ _kubectl_logs()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1878,6 +2090,8 @@ _kubectl_logs()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1935,7 +2149,11 @@ _kubectl_rolling-update()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -1952,6 +2170,8 @@ _kubectl_rolling-update()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -1997,7 +2217,11 @@ _kubectl_scale()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2014,6 +2238,8 @@ _kubectl_scale()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2047,7 +2273,11 @@ _kubectl_cordon()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2064,6 +2294,8 @@ _kubectl_cordon()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2094,7 +2326,11 @@ _kubectl_drain()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2111,6 +2347,8 @@ _kubectl_drain()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2137,7 +2375,11 @@ _kubectl_uncordon()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2154,6 +2396,8 @@ _kubectl_uncordon()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2175,6 +2419,10 @@ _kubectl_attach()
 
     flags+=("--container=")
     two_word_flags+=("-c")
+    flags_with_completion+=("--container")
+    flags_completion+=("__kubectl_get_containers")
+    flags_with_completion+=("-c")
+    flags_completion+=("__kubectl_get_containers")
     flags+=("--stdin")
     flags+=("-i")
     flags+=("--tty")
@@ -2186,7 +2434,11 @@ _kubectl_attach()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2203,6 +2455,8 @@ _kubectl_attach()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2224,6 +2478,10 @@ _kubectl_exec()
 
     flags+=("--container=")
     two_word_flags+=("-c")
+    flags_with_completion+=("--container")
+    flags_completion+=("__kubectl_get_containers")
+    flags_with_completion+=("-c")
+    flags_completion+=("__kubectl_get_containers")
     flags+=("--pod=")
     two_word_flags+=("-p")
     flags+=("--stdin")
@@ -2237,7 +2495,11 @@ _kubectl_exec()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2254,6 +2516,8 @@ _kubectl_exec()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2282,7 +2546,11 @@ _kubectl_port-forward()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2299,6 +2567,8 @@ _kubectl_port-forward()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2340,7 +2610,11 @@ _kubectl_proxy()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2357,6 +2631,8 @@ _kubectl_proxy()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2422,7 +2698,11 @@ _kubectl_run()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2439,6 +2719,8 @@ _kubectl_run()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2503,7 +2785,11 @@ _kubectl_expose()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2520,6 +2806,8 @@ _kubectl_expose()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2588,7 +2876,11 @@ _kubectl_autoscale()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2605,6 +2897,8 @@ _kubectl_autoscale()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2641,7 +2935,11 @@ _kubectl_rollout_history()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2658,6 +2956,8 @@ _kubectl_rollout_history()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2692,7 +2992,11 @@ _kubectl_rollout_pause()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2709,6 +3013,8 @@ _kubectl_rollout_pause()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2743,7 +3049,11 @@ _kubectl_rollout_resume()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2760,6 +3070,8 @@ _kubectl_rollout_resume()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2795,7 +3107,11 @@ _kubectl_rollout_undo()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2812,6 +3128,8 @@ _kubectl_rollout_undo()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2846,7 +3164,11 @@ _kubectl_rollout_status()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2863,6 +3185,8 @@ _kubectl_rollout_status()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2894,7 +3218,11 @@ _kubectl_rollout()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2911,6 +3239,8 @@ _kubectl_rollout()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -2950,6 +3280,10 @@ _kubectl_label()
     flags+=("--resource-version=")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-all")
     flags+=("-a")
     flags+=("--show-labels")
@@ -2964,7 +3298,11 @@ _kubectl_label()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -2981,6 +3319,8 @@ _kubectl_label()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3100,6 +3440,10 @@ _kubectl_annotate()
     flags+=("--resource-version=")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-all")
     flags+=("-a")
     flags+=("--show-labels")
@@ -3114,7 +3458,11 @@ _kubectl_annotate()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3131,6 +3479,8 @@ _kubectl_annotate()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3197,6 +3547,10 @@ _kubectl_taint()
     flags_completion+=("_filedir")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-all")
     flags+=("-a")
     flags+=("--show-labels")
@@ -3212,7 +3566,11 @@ _kubectl_taint()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3229,6 +3587,8 @@ _kubectl_taint()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3302,7 +3662,11 @@ _kubectl_config_view()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3319,6 +3683,8 @@ _kubectl_config_view()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3350,7 +3716,11 @@ _kubectl_config_set-cluster()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
     flags+=("--log-dir=")
@@ -3364,6 +3734,8 @@ _kubectl_config_set-cluster()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3398,7 +3770,11 @@ _kubectl_config_set-credentials()
     flags+=("--as=")
     flags+=("--certificate-authority=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3413,6 +3789,8 @@ _kubectl_config_set-credentials()
     two_word_flags+=("-s")
     flags+=("--stderrthreshold=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--v=")
     flags+=("--vmodule=")
 
@@ -3432,8 +3810,12 @@ _kubectl_config_set-context()
     flags_completion=()
 
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--namespace=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--alsologtostderr")
     flags+=("--api-version=")
     flags+=("--as=")
@@ -3441,6 +3823,8 @@ _kubectl_config_set-context()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3480,7 +3864,11 @@ _kubectl_config_set()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3497,6 +3885,8 @@ _kubectl_config_set()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3523,7 +3913,11 @@ _kubectl_config_unset()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3540,6 +3934,8 @@ _kubectl_config_unset()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3566,7 +3962,11 @@ _kubectl_config_current-context()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3583,6 +3983,8 @@ _kubectl_config_current-context()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3609,7 +4011,11 @@ _kubectl_config_use-context()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3626,6 +4032,8 @@ _kubectl_config_use-context()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3661,7 +4069,11 @@ _kubectl_config()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--log-backtrace-at=")
     flags+=("--log-dir=")
@@ -3677,6 +4089,8 @@ _kubectl_config()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3706,7 +4120,11 @@ _kubectl_cluster-info_dump()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3723,6 +4141,8 @@ _kubectl_cluster-info_dump()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3751,7 +4171,11 @@ _kubectl_cluster-info()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3768,6 +4192,8 @@ _kubectl_cluster-info()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3794,7 +4220,11 @@ _kubectl_api-versions()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3811,6 +4241,8 @@ _kubectl_api-versions()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3839,7 +4271,11 @@ _kubectl_version()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3856,6 +4292,8 @@ _kubectl_version()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3884,7 +4322,11 @@ _kubectl_explain()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3901,6 +4343,8 @@ _kubectl_explain()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3952,7 +4396,11 @@ _kubectl_convert()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -3969,6 +4417,8 @@ _kubectl_convert()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -3997,7 +4447,11 @@ _kubectl_completion()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -4014,6 +4468,8 @@ _kubectl_completion()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -4076,7 +4532,11 @@ _[[kubectl_alias]]()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -4093,6 +4553,8 @@ _[[kubectl_alias]]()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -4562,11 +5024,44 @@ __kubectl_get_namespaces()
 {
     local template kubectl_out
     template="{{ range .items  }}{{ .metadata.name }} {{ end }}"
-    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]] --kubectl-flags="$kubectl_line" get namespace); then
+    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]][[kubemrr_flags]] --kubectl-flags="$kubectl_line" get namespace); then
+        COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
+    fi
+}
+
+__kubectl_get_labels()
+{
+    local kubectl_line=$COMP_LINE
+    local resource=${nouns[@]:0:1}
+    local kubectl_out
+    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]][[kubemrr_flags]] --kubectl-flags="$kubectl_line" --prefix="$cur" get labels "${resource:-all}" 2>/dev/null); then
+        COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
+    fi
+}
+
+__kubectl_get_kubeconfig()
+{
+    local kubectl_out
+    if kubectl_out=$([[kubemrr_path]] get "$1"); then
         COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
     fi
 }
 
+__kubectl_get_contexts()
+{
+    __kubectl_get_kubeconfig contexts
+}
+
+__kubectl_get_clusters()
+{
+    __kubectl_get_kubeconfig clusters
+}
+
+__kubectl_get_users()
+{
+    __kubectl_get_kubeconfig users
+}
+
 __kubectl_parse_get()
 {
 		local kubectl_line=$COMP_LINE
@@ -4576,17 +5071,29 @@ __kubectl_parse_get()
     local template
     template="{{ range .items  }}{{ .metadata.name }} {{ end }}"
     local kubectl_out
-    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]] --kubectl-flags="$kubectl_line" get "$1" 2>>"$bash_comp_err_file"); then
+    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]][[kubemrr_flags]] --kubectl-flags="$kubectl_line" --prefix="$cur" get "$@" 2>>"$bash_comp_err_file"); then
+        # the completion engine decides that a path to a file is completed, e.g. after -f
+        if [[ ${kubectl_out} == ":files" ]]; then
+            _filedir
+            return
+        fi
         COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
+        # a compressed prefix of many names is completed without space, to drill down on the next [TAB]
+        if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == *[-.] && $(type -t compopt) = "builtin" ]]; then
+            compopt -o nospace
+        fi
     fi
 }
 
 __kubectl_get_resource()
 {
+    # types missing from the static list, such as custom resources, are asked from the mirror
     if [[ ${#nouns[@]} -eq 0 ]]; then
-        return 1
+        __kubectl_parse_get kinds
+        return
     fi
-    __kubectl_parse_get "${nouns[${#nouns[@]} -1]}"
+    # the resource type is taken from the command, names already given are not types
+    __kubectl_parse_get --for-command
 }
 
 __kubectl_get_resource_pod()
@@ -4599,20 +5106,42 @@ __kubectl_get_resource_rc()
     __kubectl_parse_get "rc"
 }
 
+# candidates of "kubemrr complete" for the whole line, such as svc/NAME and ports of port-forward
+__kubectl_complete_line()
+{
+    local kubectl_line
+    __unalias "$COMP_LINE"
+    local kubectl_out
+    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]][[kubemrr_flags]] complete --line="$kubectl_line" 2>/dev/null); then
+        COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
+    fi
+}
+
+# pods that can be executed in and attached to, not completed or evicted ones
+__kubectl_get_resource_running_pod()
+{
+    __kubectl_parse_get "pod" --phase=Running
+}
+
+# nodes that can be drained and cordoned, not cordoned or not ready ones
+__kubectl_get_resource_ready_node()
+{
+    __kubectl_parse_get "node" --ready-only
+}
+
 # $1 is the name of the pod we want to get the list of containers inside
 __kubectl_get_containers()
 {
-    local template
-    template="{{ range .spec.containers  }}{{ .name }} {{ end }}"
     __debug "${FUNCNAME} nouns are ${nouns[*]}"
 
     local len="${#nouns[@]}"
     if [[ ${len} -ne 1 ]]; then
         return
     fi
-    local last=${nouns[${len} -1]}
+    local last=${nouns[@]: -1:1}
+    local kubectl_line=$COMP_LINE
     local kubectl_out
-    if kubectl_out=$(kubectl get $(__kubectl_namespace_flag) -o template --template="${template}" pods "${last}" 2>/dev/null); then
+    if kubectl_out=$([[kubemrr_path]] -a [[kubemrr_address]] -p [[kubemrr_port]][[kubemrr_flags]] --kubectl-flags="$kubectl_line" --prefix="$cur" get containers "${last}" 2>/dev/null); then
         COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
     fi
 }
@@ -4639,8 +5168,20 @@ __custom_func() {
             __kubectl_require_pod_and_container
             return
             ;;
-        kubectl_exec)
-            __kubectl_get_resource_pod
+        kubectl_exec | kubectl_attach)
+            __kubectl_get_resource_running_pod
+            return
+            ;;
+        kubectl_port-forward | kubectl_taint)
+            __kubectl_complete_line
+            return
+            ;;
+        kubectl_drain | kubectl_cordon)
+            __kubectl_get_resource_ready_node
+            return
+            ;;
+        kubectl_uncordon)
+            __kubectl_parse_get "node"
             return
             ;;
         kubectl_rolling-update)
@@ -4681,6 +5222,10 @@ _kubectl_get()
     flags+=("-R")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-all")
     flags+=("-a")
     flags+=("--show-labels")
@@ -4698,7 +5243,11 @@ _kubectl_get()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -4715,6 +5264,8 @@ _kubectl_get()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -4832,6 +5383,10 @@ _kubectl_set_image()
     flags+=("-R")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-all")
     flags+=("-a")
     flags+=("--show-labels")
@@ -4846,7 +5401,11 @@ _kubectl_set_image()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -4863,6 +5422,8 @@ _kubectl_set_image()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -4890,7 +5451,11 @@ _kubectl_set()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -4907,6 +5472,8 @@ _kubectl_set()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -4937,6 +5504,10 @@ _kubectl_describe()
     flags+=("-R")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-events")
     flags+=("--alsologtostderr")
     flags+=("--api-version=")
@@ -4945,7 +5516,11 @@ _kubectl_describe()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -4962,6 +5537,8 @@ _kubectl_describe()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5067,7 +5644,11 @@ _kubectl_create_namespace()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5084,6 +5665,8 @@ _kubectl_create_namespace()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5133,7 +5716,11 @@ _kubectl_create_secret_docker-registry()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5150,6 +5737,8 @@ _kubectl_create_secret_docker-registry()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5199,7 +5788,11 @@ _kubectl_create_secret_tls()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5216,6 +5809,8 @@ _kubectl_create_secret_tls()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5263,7 +5858,11 @@ _kubectl_create_secret_generic()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5280,6 +5879,8 @@ _kubectl_create_secret_generic()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5309,7 +5910,11 @@ _kubectl_create_secret()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5326,6 +5931,8 @@ _kubectl_create_secret()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5372,7 +5979,11 @@ _kubectl_create_configmap()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5389,6 +6000,8 @@ _kubectl_create_configmap()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5434,7 +6047,11 @@ _kubectl_create_serviceaccount()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5451,6 +6068,8 @@ _kubectl_create_serviceaccount()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5498,7 +6117,11 @@ _kubectl_create()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5515,6 +6138,8 @@ _kubectl_create()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5564,7 +6189,11 @@ _kubectl_replace()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5581,6 +6210,8 @@ _kubectl_replace()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5624,7 +6255,11 @@ _kubectl_patch()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5641,6 +6276,8 @@ _kubectl_patch()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5761,6 +6398,10 @@ _kubectl_delete()
     flags+=("-R")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--timeout=")
     flags+=("--alsologtostderr")
     flags+=("--api-version=")
@@ -5769,7 +6410,11 @@ _kubectl_delete()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5786,6 +6431,8 @@ _kubectl_delete()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -5908,7 +6555,11 @@ _kubectl_edit()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -5925,6 +6576,8 @@ _kubectl_edit()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6048,7 +6701,11 @@ _kubectl_apply()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6065,6 +6722,8 @@ _kubectl_apply()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6093,7 +6752,11 @@ _kubectl_namespace()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6110,6 +6773,8 @@ _kubectl_namespace()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6131,6 +6796,10 @@ _kubectl_logs()
 
     flags+=("--container=")
     two_word_flags+=("-c")
+    flags_with_completion+=("--container")
+    flags_completion+=("__kubectl_get_containers")
+    flags_with_completion+=("-c")
+    flags_completion+=("__kubectl_get_containers")
     flags+=("--follow")
     flags+=("-f")
     flags+=("--include-extended-apis")
@@ -6149,7 +6818,11 @@ _kubectl_logs()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6166,6 +6839,8 @@ _kubectl_logs()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6223,7 +6898,11 @@ _kubectl_rolling-update()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6240,6 +6919,8 @@ _kubectl_rolling-update()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6285,7 +6966,11 @@ _kubectl_scale()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6302,6 +6987,8 @@ _kubectl_scale()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6335,7 +7022,11 @@ _kubectl_cordon()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6352,6 +7043,8 @@ _kubectl_cordon()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6382,7 +7075,11 @@ _kubectl_drain()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6399,6 +7096,8 @@ _kubectl_drain()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6425,7 +7124,11 @@ _kubectl_uncordon()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6442,6 +7145,8 @@ _kubectl_uncordon()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6463,6 +7168,10 @@ _kubectl_attach()
 
     flags+=("--container=")
     two_word_flags+=("-c")
+    flags_with_completion+=("--container")
+    flags_completion+=("__kubectl_get_containers")
+    flags_with_completion+=("-c")
+    flags_completion+=("__kubectl_get_containers")
     flags+=("--stdin")
     flags+=("-i")
     flags+=("--tty")
@@ -6474,7 +7183,11 @@ _kubectl_attach()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6491,6 +7204,8 @@ _kubectl_attach()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6512,6 +7227,10 @@ _kubectl_exec()
 
     flags+=("--container=")
     two_word_flags+=("-c")
+    flags_with_completion+=("--container")
+    flags_completion+=("__kubectl_get_containers")
+    flags_with_completion+=("-c")
+    flags_completion+=("__kubectl_get_containers")
     flags+=("--pod=")
     two_word_flags+=("-p")
     flags+=("--stdin")
@@ -6525,7 +7244,11 @@ _kubectl_exec()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6542,6 +7265,8 @@ _kubectl_exec()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6570,7 +7295,11 @@ _kubectl_port-forward()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6587,6 +7316,8 @@ _kubectl_port-forward()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6628,7 +7359,11 @@ _kubectl_proxy()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6645,6 +7380,8 @@ _kubectl_proxy()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6710,7 +7447,11 @@ _kubectl_run()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6727,6 +7468,8 @@ _kubectl_run()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6791,7 +7534,11 @@ _kubectl_expose()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6808,6 +7555,8 @@ _kubectl_expose()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6876,7 +7625,11 @@ _kubectl_autoscale()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6893,6 +7646,8 @@ _kubectl_autoscale()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6929,7 +7684,11 @@ _kubectl_rollout_history()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6946,6 +7705,8 @@ _kubectl_rollout_history()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -6980,7 +7741,11 @@ _kubectl_rollout_pause()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -6997,6 +7762,8 @@ _kubectl_rollout_pause()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7031,7 +7798,11 @@ _kubectl_rollout_resume()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7048,6 +7819,8 @@ _kubectl_rollout_resume()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7083,7 +7856,11 @@ _kubectl_rollout_undo()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7100,6 +7877,8 @@ _kubectl_rollout_undo()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7134,7 +7913,11 @@ _kubectl_rollout_status()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7151,6 +7934,8 @@ _kubectl_rollout_status()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7182,7 +7967,11 @@ _kubectl_rollout()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7199,6 +7988,8 @@ _kubectl_rollout()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7238,6 +8029,10 @@ _kubectl_label()
     flags+=("--resource-version=")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-all")
     flags+=("-a")
     flags+=("--show-labels")
@@ -7252,7 +8047,11 @@ _kubectl_label()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7269,6 +8068,8 @@ _kubectl_label()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7388,6 +8189,10 @@ _kubectl_annotate()
     flags+=("--resource-version=")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-all")
     flags+=("-a")
     flags+=("--show-labels")
@@ -7402,7 +8207,11 @@ _kubectl_annotate()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7419,6 +8228,8 @@ _kubectl_annotate()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7485,6 +8296,10 @@ _kubectl_taint()
     flags_completion+=("_filedir")
     flags+=("--selector=")
     two_word_flags+=("-l")
+    flags_with_completion+=("--selector")
+    flags_completion+=("__kubectl_get_labels")
+    flags_with_completion+=("-l")
+    flags_completion+=("__kubectl_get_labels")
     flags+=("--show-all")
     flags+=("-a")
     flags+=("--show-labels")
@@ -7500,7 +8315,11 @@ _kubectl_taint()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7517,6 +8336,8 @@ _kubectl_taint()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7590,7 +8411,11 @@ _kubectl_config_view()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7607,6 +8432,8 @@ _kubectl_config_view()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7638,7 +8465,11 @@ _kubectl_config_set-cluster()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
     flags+=("--log-dir=")
@@ -7652,6 +8483,8 @@ _kubectl_config_set-cluster()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7686,7 +8519,11 @@ _kubectl_config_set-credentials()
     flags+=("--as=")
     flags+=("--certificate-authority=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7701,6 +8538,8 @@ _kubectl_config_set-credentials()
     two_word_flags+=("-s")
     flags+=("--stderrthreshold=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--v=")
     flags+=("--vmodule=")
 
@@ -7720,8 +8559,12 @@ _kubectl_config_set-context()
     flags_completion=()
 
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--namespace=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--alsologtostderr")
     flags+=("--api-version=")
     flags+=("--as=")
@@ -7729,6 +8572,8 @@ _kubectl_config_set-context()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7768,7 +8613,11 @@ _kubectl_config_set()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7785,6 +8634,8 @@ _kubectl_config_set()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7811,7 +8662,11 @@ _kubectl_config_unset()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7828,6 +8683,8 @@ _kubectl_config_unset()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7854,7 +8711,11 @@ _kubectl_config_current-context()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7871,6 +8732,8 @@ _kubectl_config_current-context()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7897,7 +8760,11 @@ _kubectl_config_use-context()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -7914,6 +8781,8 @@ _kubectl_config_use-context()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7949,7 +8818,11 @@ _kubectl_config()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--log-backtrace-at=")
     flags+=("--log-dir=")
@@ -7965,6 +8838,8 @@ _kubectl_config()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -7994,7 +8869,11 @@ _kubectl_cluster-info_dump()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -8011,6 +8890,8 @@ _kubectl_cluster-info_dump()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -8039,7 +8920,11 @@ _kubectl_cluster-info()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -8056,6 +8941,8 @@ _kubectl_cluster-info()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -8082,7 +8969,11 @@ _kubectl_api-versions()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -8099,6 +8990,8 @@ _kubectl_api-versions()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -8127,7 +9020,11 @@ _kubectl_version()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -8144,6 +9041,8 @@ _kubectl_version()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -8172,7 +9071,11 @@ _kubectl_explain()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -8189,6 +9092,8 @@ _kubectl_explain()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -8240,7 +9145,11 @@ _kubectl_convert()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -8257,6 +9166,8 @@ _kubectl_convert()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -8285,7 +9196,11 @@ _kubectl_completion()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -8302,6 +9217,8 @@ _kubectl_completion()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -8364,7 +9281,11 @@ _kubectl()
     flags+=("--client-certificate=")
     flags+=("--client-key=")
     flags+=("--cluster=")
+    flags_with_completion+=("--cluster")
+    flags_completion+=("__kubectl_get_clusters")
     flags+=("--context=")
+    flags_with_completion+=("--context")
+    flags_completion+=("__kubectl_get_contexts")
     flags+=("--insecure-skip-tls-verify")
     flags+=("--kubeconfig=")
     flags+=("--log-backtrace-at=")
@@ -8381,6 +9302,8 @@ _kubectl()
     flags+=("--stderrthreshold=")
     flags+=("--token=")
     flags+=("--user=")
+    flags_with_completion+=("--user")
+    flags_completion+=("__kubectl_get_users")
     flags+=("--username=")
     flags+=("--v=")
     flags+=("--vmodule=")
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunAlias(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh"} {
+		buf := bytes.NewBuffer([]byte{})
+		cmd := NewCompletionCommand(&TestFactory{stdOut: buf})
+		cmd.Flags().Set("address", "10.5.1.6")
+		cmd.Flags().Set("port", "4000")
+		cmd.Flags().Set("kubectl-alias", "kus")
+		if err := cmd.RunE(cmd, []string{shell}); err != nil {
+			t.Fatalf("%s: unexpected error %v", shell, err)
+		}
+
+		script := buf.String()
+		if strings.Contains(script, "[[kube") {
+			t.Errorf("%s: expected all parameters to be replaced", shell)
+		}
+		if !strings.Contains(script, "kubemrr -a 10.5.1.6 -p 4000 --quiet --kubectl-flags=") {
+			t.Errorf("%s: expected the mirror to be asked at 10.5.1.6:4000", shell)
+		}
+		if !strings.Contains(script, "kus") {
+			t.Errorf("%s: expected completion for kus", shell)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewCompletionCommand(&TestFactory{stdOut: buf})
+	cmd.Flags().Set("bind", "unix:///tmp/kubemrr.sock")
+	if err := cmd.RunE(cmd, []string{"bash"}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if strings.Contains(buf.String(), " -a ") || !strings.Contains(buf.String(), "kubemrr --bind=unix:///tmp/kubemrr.sock") {
+		t.Errorf("Expected the mirror to be asked on the unix socket")
+	}
+
+	buf.Reset()
+	cmd = NewCompletionCommand(&TestFactory{stdOut: buf})
+	cmd.Flags().Set("quiet", "false")
+	if err := cmd.RunE(cmd, []string{"bash"}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if strings.Contains(buf.String(), "--quiet") {
+		t.Errorf("Expected errors of get to be printed when --quiet=false")
+	}
+
+	cmd = NewCompletionCommand(&TestFactory{stdOut: buf})
+	if err := cmd.RunE(cmd, []string{"fish"}); err == nil {
+		t.Errorf("Expected error for unsupported shell")
+	}
+}
+
+func TestZshTemplateIndexesArraysPortably(t *testing.T) {
+	//arrays of zsh start at 1, while completion functions of bash index them from 0
+	for _, bad := range []string{"${nouns[0]", "${nouns[${"} {
+		if strings.Contains(zsh_template, bad) {
+			t.Errorf("Expected zsh script to slice arrays instead of %s...}", bad)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+//defaultPidfile is where "kubemrr watch --daemon" writes its process id and where "kubemrr stop" finds it
+const defaultPidfile = "~/.kubemrr/pid"
+
+//daemonStartTimeout is how long "kubemrr watch --daemon" waits for the mirror to reach API servers
+const daemonStartTimeout = 30 * time.Second
+
+//readPidfile returns the process id written to the file
+func readPidfile(path string) (int, error) {
+	path, err := substituteUserHome(path)
+	if err != nil {
+		return 0, err
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return 0, fmt.Errorf("%s does not contain a process id", path)
+	}
+	return pid, nil
+}
+
+//writePidfile writes the id of this process to the file and locks it for as long as the process runs,
+//unless a running mirror holds the lock. The returned function removes the file
+func writePidfile(path string) (func(), error) {
+	path, err := substituteUserHome(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	//the lock is taken at once, so two mirrors started together cannot both pass
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if pid, err := readPidfile(path); err == nil {
+			return nil, fmt.Errorf("kubemrr is already running with pid %d, see %s", pid, path)
+		}
+		return nil, fmt.Errorf("kubemrr is already running, see %s", path)
+	}
+
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.WriteString(strconv.Itoa(os.Getpid()) + "\n"); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		os.Remove(path)
+		f.Close()
+	}, nil
+}
+
+//lockedPidfile returns the process id of the mirror that holds the lock of the pidfile.
+//errStalePidfile is returned when no process holds it, such as after a crash
+func lockedPidfile(path string) (int, error) {
+	path, err := substituteUserHome(path)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err == nil {
+		return 0, errStalePidfile
+	}
+	return readPidfile(path)
+}
+
+//daemonOutFile returns the file that output of the daemon other than logs goes to
+func daemonOutFile(logFile string) string {
+	return logFile + ".out"
+}
+
+//errStalePidfile tells that the process that wrote the pidfile is gone
+var errStalePidfile = errors.New("no process holds the pidfile")
+
+//daemonize starts the same command again in background, detached from the terminal, with --daemon=false.
+//The started process writes its logs to the log file, which it rotates. Its other output, such as panics,
+//goes to FILE.out next to it, which is never rotated. It waits until the process writes the pidfile
+func daemonize(pidfile string, logFile string, stdOut io.Writer) error {
+	pidfile, err := substituteUserHome(pidfile)
+	if err != nil {
+		return err
+	}
+	logFile, err = substituteUserHome(logFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(logFile), 0700); err != nil {
+		return err
+	}
+	outFile := daemonOutFile(logFile)
+	out, err := os.OpenFile(outFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open output file: %s", err)
+	}
+	defer out.Close()
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := append(os.Args[1:], "--daemon=false", "--pidfile="+pidfile, "--log-file="+logFile)
+	child := exec.Command(self, args...)
+	child.Stdout = out
+	child.Stderr = out
+	child.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("could not start kubemrr in background: %s", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		child.Wait()
+		close(exited)
+	}()
+
+	deadline := time.After(daemonStartTimeout)
+	for {
+		if pid, err := readPidfile(pidfile); err == nil && pid == child.Process.Pid {
+			fmt.Fprintf(stdOut, "Started kubemrr in background with pid %d, logs are in %s\n", pid, logFile)
+			return nil
+		}
+		select {
+		case <-exited:
+			return fmt.Errorf("kubemrr has stopped, see %s and %s", logFile, outFile)
+		case <-deadline:
+			return fmt.Errorf("kubemrr did not start in time, see %s and %s", logFile, outFile)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestWritePidfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pidfile := filepath.Join(dir, "run", "pid")
+
+	remove, err := writePidfile(pidfile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pid, err := lockedPidfile(pidfile)
+	if err != nil || pid != os.Getpid() {
+		t.Errorf("Expected pid %d, got %d, %v", os.Getpid(), pid, err)
+	}
+	if info, err := os.Stat(pidfile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected pidfile with mode 0600, got %v, %v", info, err)
+	}
+	if _, err := writePidfile(pidfile); err == nil {
+		t.Errorf("Expected error when another mirror holds the pidfile")
+	}
+	remove()
+	if _, err := os.Stat(pidfile); !os.IsNotExist(err) {
+		t.Errorf("Expected pidfile to be removed, got %v", err)
+	}
+
+	//the id of a running process that does not lock the file is stale too
+	ioutil.WriteFile(pidfile, []byte(strconv.Itoa(os.Getppid())), 0644)
+	if _, err := lockedPidfile(pidfile); err != errStalePidfile {
+		t.Errorf("Expected stale pidfile, got %v", err)
+	}
+	remove, err = writePidfile(pidfile)
+	if err != nil {
+		t.Fatalf("Expected stale pidfile to be replaced, got %v", err)
+	}
+	defer remove()
+	if info, err := os.Stat(pidfile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected pidfile with mode 0600, got %v, %v", info, err)
+	}
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"net/url"
+	"strings"
+)
+
+//normalizeServerURL brings URLs of the same API server to one form: lower case scheme and host,
+//no default port and no trailing slash. URLs that cannot be parsed are returned as they are
+func normalizeServerURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.TrimSuffix(raw, "/")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "https" && u.Port() == "443") || (u.Scheme == "http" && u.Port() == "80") {
+		u.Host = u.Hostname()
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
+
+//setClusterID remembers the UID of the cluster that the server belongs to. Servers of
+//the same cluster, reached by different URLs or watched for several contexts, are merged in answers
+func (c *MrrCache) setClusterID(server KubeServer, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clusters[server] = id
+	c.results.invalidate(server, "")
+}
+
+//clusterOf identifies the cluster of the server by its UID, or by its URL when the UID is not known.
+//Caller must hold at least the read lock
+func (c *MrrCache) clusterOf(server KubeServer) string {
+	if id, ok := c.clusters[server]; ok {
+		return "uid:" + id
+	}
+	return "url:" + normalizeServerURL(server.URL)
+}
+
+//sharedClusters checks whether some of the servers belong to the same cluster.
+//Caller must hold at least the read lock
+func (c *MrrCache) sharedClusters(servers KubeServers) bool {
+	seen := map[string]bool{}
+	for _, s := range servers {
+		id := c.clusterOf(s)
+		if seen[id] {
+			return true
+		}
+		seen[id] = true
+	}
+	return false
+}
+
+//dedupKey identifies an object within its cluster
+type dedupKey struct {
+	cluster   string
+	kind      string
+	namespace string
+	name      string
+}
+
+//dedupObjects leaves out objects already added for another server of the same cluster.
+//Caller must hold at least the read lock
+func (c *MrrCache) dedupObjects(server KubeServer, objects []KubeObject, seen map[dedupKey]bool) []KubeObject {
+	cluster := c.clusterOf(server)
+	res := []KubeObject{}
+	for _, o := range objects {
+		k := dedupKey{cluster, strings.ToLower(o.Kind), o.Namespace, o.Name}
+		if !seen[k] {
+			seen[k] = true
+			res = append(res, o)
+		}
+	}
+	return res
+}
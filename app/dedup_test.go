@@ -0,0 +1,58 @@
+package app
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeServerURL(t *testing.T) {
+	tests := map[string]string{
+		"https://kube.example.com":        "https://kube.example.com",
+		"https://Kube.Example.com:443/":   "https://kube.example.com",
+		"HTTPS://kube.example.com:6443":   "https://kube.example.com:6443",
+		"http://kube.example.com:80/k8s/": "http://kube.example.com/k8s",
+		"kube.example.com/":               "kube.example.com",
+	}
+	for in, expected := range tests {
+		assert.Equal(t, expected, normalizeServerURL(in), in)
+	}
+}
+
+func TestObjectsSharedCluster(t *testing.T) {
+	c := NewMrrCache()
+	s1 := KubeServer{URL: "https://kube.example.com"}
+	s2 := KubeServer{URL: "https://kube.example.com:443/"}
+	s3 := KubeServer{URL: "https://10.0.0.1"}
+	s4 := KubeServer{URL: "https://other.example.com"}
+	pod := func(name string) KubeObject {
+		return KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: name, Namespace: "prod"}}
+	}
+	c.updateKubeObject(s1, pod("a"))
+	c.updateKubeObject(s2, pod("a"))
+	c.updateKubeObject(s2, pod("b"))
+	c.updateKubeObject(s3, pod("a"))
+	c.updateKubeObject(s4, pod("a"))
+
+	names := func() []string {
+		var os []KubeObject
+		assert.NoError(t, c.Objects(&MrrFilter{Kind: "pod"}, &os))
+		res := []string{}
+		for _, o := range os {
+			res = append(res, o.Name)
+		}
+		return res
+	}
+
+	//the same url is merged, the ip and the other cluster are not known to be the same
+	assert.Equal(t, []string{"a", "a", "b", "a"}, names())
+
+	c.setClusterID(s1, "uid-1")
+	c.setClusterID(s2, "uid-1")
+	c.setClusterID(s3, "uid-1")
+	c.setClusterID(s4, "uid-2")
+	assert.Equal(t, []string{"a", "b", "a"}, names())
+
+	c.deleteServer(s4)
+	c.updateKubeObject(s4, pod("a"))
+	assert.Equal(t, []string{"a", "b", "a"}, names())
+}
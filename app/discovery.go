@@ -0,0 +1,262 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+//APIResource is a kind of objects served by a Kubernetes API server
+type APIResource struct {
+	Name         string   `json:"name"`
+	SingularName string   `json:"singularName,omitempty"`
+	Kind         string   `json:"kind"`
+	Namespaced   bool     `json:"namespaced"`
+	ShortNames   []string `json:"shortNames,omitempty"`
+	GroupVersion string   `json:"groupVersion"`
+}
+
+type apiResourceList struct {
+	GroupVersion string        `json:"groupVersion"`
+	Resources    []APIResource `json:"resources"`
+}
+
+type apiGroupVersion struct {
+	GroupVersion string `json:"groupVersion"`
+}
+
+type apiGroupList struct {
+	Groups []struct {
+		Name             string            `json:"name"`
+		Versions         []apiGroupVersion `json:"versions"`
+		PreferredVersion apiGroupVersion   `json:"preferredVersion"`
+	} `json:"groups"`
+}
+
+//path returns the API path of objects of the resource in the namespace, relative to the URL of the server.
+//Objects of all namespaces are returned if the namespace is empty
+func (r APIResource) path(namespace string) string {
+	prefix := "/apis/" + r.GroupVersion + "/"
+	if r.GroupVersion == "v1" {
+		prefix = "api/v1/"
+	}
+	if r.Namespaced && namespace != "" {
+		prefix += "namespaces/" + namespace + "/"
+	}
+	return prefix + r.Name
+}
+
+//discover asks the server for resources of the core group and of the preferred version of other groups
+func (kc *DefaultKubeClient) discover() ([]APIResource, error) {
+	groupVersions := []string{"v1"}
+
+	req, err := kc.newRequest("GET", "/apis", nil)
+	if err != nil {
+		return nil, err
+	}
+	var groups apiGroupList
+	if err := kc.do(req, &groups); err != nil {
+		return nil, fmt.Errorf("could not discover API groups: %s", err)
+	}
+	for _, g := range groups.Groups {
+		if g.PreferredVersion.GroupVersion != "" {
+			groupVersions = append(groupVersions, g.PreferredVersion.GroupVersion)
+		}
+	}
+
+	res := []APIResource{}
+	for _, gv := range groupVersions {
+		path := "/apis/" + gv
+		if gv == "v1" {
+			path = "/api/v1"
+		}
+		req, err := kc.newRequest("GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		var list apiResourceList
+		if err := kc.do(req, &list); err != nil {
+			if gv == "v1" {
+				return nil, fmt.Errorf("could not discover resources of %s: %s", gv, err)
+			}
+			//aggregated APIs, such as metrics, are often unavailable and must not hide other groups
+			log.WithFields(log.Fields{"server": kc.baseURL.String(), "group": gv, "error": err}).Warn("could not discover resources of group")
+			continue
+		}
+		for _, r := range list.Resources {
+			//subresources, such as pods/log, are not lists of objects
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			r.GroupVersion = gv
+			res = append(res, r)
+		}
+	}
+
+	return res, nil
+}
+
+//deprecatedGroups serve copies of kinds that moved to other groups, for example
+//deployments are served by both extensions/v1beta1 and apps/v1 on some versions of Kubernetes
+var deprecatedGroups = map[string]bool{
+	"extensions": true,
+}
+
+//groupRank orders groups serving the same kind: the core group, then current groups, then deprecated ones
+func groupRank(groupVersion string) int {
+	if !strings.Contains(groupVersion, "/") {
+		return 0
+	}
+	if deprecatedGroups[strings.SplitN(groupVersion, "/", 2)[0]] {
+		return 2
+	}
+	return 1
+}
+
+//kindResources maps lower-cased kinds to their resources. When several groups serve the same kind,
+//the group with the lowest rank wins, and among groups of the same rank the first discovered one
+func kindResources(resources []APIResource) map[string]APIResource {
+	res := make(map[string]APIResource)
+	for _, r := range resources {
+		kind := strings.ToLower(r.Kind)
+		if prev, ok := res[kind]; !ok || groupRank(r.GroupVersion) < groupRank(prev.GroupVersion) {
+			res[kind] = r
+		}
+	}
+	return res
+}
+
+//resourceNames maps plural, singular and short names of the resources to their lower-cased kinds,
+//such as "pvc" to "persistentvolumeclaim", the way kubectl resolves them
+func resourceNames(resources []APIResource) map[string]string {
+	res := map[string]string{}
+	for _, r := range resources {
+		kind := strings.ToLower(r.Kind)
+		names := append([]string{r.Name, r.SingularName, kind}, r.ShortNames...)
+		for _, name := range names {
+			if name != "" {
+				res[strings.ToLower(name)] = kind
+			}
+		}
+	}
+	return res
+}
+
+//discoverResources asks the server for its resources in background and keeps them in the cache,
+//so that clients resolve short names of resources the way kubectl does
+func discoverResources(c *MrrCache, w *clusterWatcher) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		server := w.kc.Server()
+		resources, err := w.kc.Resources()
+		if w.isStopped() {
+			return
+		}
+		if err != nil {
+			log.WithField("server", server.URL).WithField("error", err).Info("could not discover short names of resources")
+			return
+		}
+		c.setResources(server, resources)
+	}()
+}
+
+func (c *MrrCache) setResources(server KubeServer, resources []APIResource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resources[server] = resources
+}
+
+//Resources returns resources discovered on the servers that match the filter. A kind served by
+//several servers is returned once
+func (c *MrrCache) Resources(f *MrrFilter, rs *[]APIResource) error {
+	defer c.metrics.observeRequest("Resources", time.Now())
+	c.requests.RLock()
+	defer c.requests.RUnlock()
+	if f == nil {
+		return errors.New("Cannot find resources with nil filter")
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := KubeServers{}
+	for k := range c.resources {
+		if matchesServer(f, k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Sort(keys)
+
+	res := []APIResource{}
+	kinds := map[string]bool{}
+	for _, k := range keys {
+		for _, r := range c.resources[k] {
+			if !kinds[r.Kind] {
+				kinds[r.Kind] = true
+				res = append(res, r)
+			}
+		}
+	}
+	*rs = res
+	return nil
+}
+
+//discoveryCache keeps discovered resources on disk, one file per server
+type discoveryCache struct {
+	dir string
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9.\-]+`)
+
+func (c discoveryCache) filename(server KubeServer) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(server.URL, "https://"), "http://")
+	return filepath.Join(c.dir, unsafeFileChars.ReplaceAllString(name, "_")+".json")
+}
+
+func (c discoveryCache) load(server KubeServer) ([]APIResource, error) {
+	raw, err := ioutil.ReadFile(c.filename(server))
+	if err != nil {
+		return nil, err
+	}
+
+	var res []APIResource
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, fmt.Errorf("could not parse discovery cache: %s", err)
+	}
+	return res, nil
+}
+
+func (c discoveryCache) save(server KubeServer, resources []APIResource) error {
+	raw, err := json.Marshal(resources)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	//write to a temporary file first, so that concurrent readers never see partial content
+	tmp, err := ioutil.TempFile(c.dir, "discovery")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.filename(server))
+}
@@ -0,0 +1,238 @@
+package app
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func handleDiscovery() {
+	handleGroups(`{"groups": [
+		{"name": "apps", "versions": [{"groupVersion": "apps/v1"}], "preferredVersion": {"groupVersion": "apps/v1"}}
+	]}`)
+}
+
+func handleGroups(groups string) {
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"groupVersion": "v1", "resources": [
+			{"name": "pods", "namespaced": true, "kind": "Pod", "shortNames": ["po"]},
+			{"name": "pods/log", "namespaced": true, "kind": "Pod"},
+			{"name": "nodes", "namespaced": false, "kind": "Node", "shortNames": ["no"]}
+		]}`)
+	})
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, groups)
+	})
+	mux.HandleFunc("/apis/apps/v1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"groupVersion": "apps/v1", "resources": [
+			{"name": "deployments", "namespaced": true, "kind": "Deployment", "shortNames": ["deploy"]}
+		]}`)
+	})
+}
+
+func TestDiscover(t *testing.T) {
+	setup()
+	defer teardown()
+	handleDiscovery()
+
+	res, err := client.(*DefaultKubeClient).discover()
+	assert.NoError(t, err)
+
+	expected := []APIResource{
+		{Name: "pods", Kind: "Pod", Namespaced: true, ShortNames: []string{"po"}, GroupVersion: "v1"},
+		{Name: "nodes", Kind: "Node", ShortNames: []string{"no"}, GroupVersion: "v1"},
+		{Name: "deployments", Kind: "Deployment", Namespaced: true, ShortNames: []string{"deploy"}, GroupVersion: "apps/v1"},
+	}
+	assert.Equal(t, expected, res)
+}
+
+func TestDiscoverSkipsUnavailableGroups(t *testing.T) {
+	setup()
+	defer teardown()
+	handleGroups(`{"groups": [
+		{"name": "metrics.k8s.io", "versions": [{"groupVersion": "metrics.k8s.io/v1beta1"}], "preferredVersion": {"groupVersion": "metrics.k8s.io/v1beta1"}},
+		{"name": "apps", "versions": [{"groupVersion": "apps/v1"}], "preferredVersion": {"groupVersion": "apps/v1"}}
+	]}`)
+	mux.HandleFunc("/apis/metrics.k8s.io/v1beta1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	res, err := client.(*DefaultKubeClient).discover()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(res))
+	assert.Equal(t, "apps/v1", res[2].GroupVersion)
+}
+
+func TestKindResources(t *testing.T) {
+	resources := []APIResource{
+		{Name: "deployments", Kind: "Deployment", GroupVersion: "extensions/v1beta1"},
+		{Name: "ingresses", Kind: "Ingress", GroupVersion: "extensions/v1beta1"},
+		{Name: "deployments", Kind: "Deployment", GroupVersion: "apps/v1"},
+		{Name: "cronjobs", Kind: "CronJob", GroupVersion: "batch/v1"},
+		{Name: "cronjobs", Kind: "CronJob", GroupVersion: "batch/v2alpha1"},
+		{Name: "events", Kind: "Event", GroupVersion: "events.k8s.io/v1"},
+		{Name: "events", Kind: "Event", GroupVersion: "v1"},
+	}
+
+	res := kindResources(resources)
+	assert.Equal(t, "apps/v1", res["deployment"].GroupVersion, "deprecated group loses")
+	assert.Equal(t, "extensions/v1beta1", res["ingress"].GroupVersion, "deprecated group is used when it is the only one")
+	assert.Equal(t, "batch/v1", res["cronjob"].GroupVersion, "first discovered group wins among equal ones")
+	assert.Equal(t, "v1", res["event"].GroupVersion, "core group wins")
+}
+
+func TestResources(t *testing.T) {
+	setup()
+	defer teardown()
+	handleDiscovery()
+
+	res, err := client.Resources()
+	assert.NoError(t, err)
+	kinds := []string{}
+	for _, r := range res {
+		kinds = append(kinds, r.Kind)
+	}
+	assert.Equal(t, []string{"Deployment", "Node", "Pod"}, kinds)
+}
+
+func TestResourceNames(t *testing.T) {
+	names := resourceNames([]APIResource{
+		{Name: "persistentvolumeclaims", SingularName: "persistentvolumeclaim", Kind: "PersistentVolumeClaim", ShortNames: []string{"pvc"}},
+		{Name: "networkpolicies", Kind: "NetworkPolicy", ShortNames: []string{"netpol"}},
+	})
+	expected := map[string]string{
+		"persistentvolumeclaims": "persistentvolumeclaim",
+		"persistentvolumeclaim":  "persistentvolumeclaim",
+		"pvc":                    "persistentvolumeclaim",
+		"networkpolicies":        "networkpolicy",
+		"networkpolicy":          "networkpolicy",
+		"netpol":                 "networkpolicy",
+	}
+	assert.Equal(t, expected, names)
+}
+
+func TestCacheResources(t *testing.T) {
+	c := NewMrrCache()
+	s1, s2 := KubeServer{URL: "https://s1"}, KubeServer{URL: "https://s2"}
+	c.setResources(s1, []APIResource{{Name: "pods", Kind: "Pod"}})
+	c.setResources(s2, []APIResource{{Name: "pods", Kind: "Pod"}, {Name: "virtualservices", Kind: "VirtualService", ShortNames: []string{"vs"}}})
+
+	var res []APIResource
+	assert.NoError(t, c.Resources(&MrrFilter{}, &res))
+	assert.Equal(t, 2, len(res), "kinds of several servers are returned once")
+
+	assert.NoError(t, c.Resources(&MrrFilter{Server: "https://s1"}, &res))
+	assert.Equal(t, []APIResource{{Name: "pods", Kind: "Pod"}}, res)
+
+	c.mu.Lock()
+	c.deleteServerLocked(s2)
+	c.mu.Unlock()
+	assert.NoError(t, c.Resources(&MrrFilter{}, &res))
+	assert.Equal(t, 1, len(res), "resources of deleted servers are forgotten")
+}
+
+func TestKindURLUsesDiscovery(t *testing.T) {
+	setup()
+	defer teardown()
+	handleDiscovery()
+
+	u, err := client.(*DefaultKubeClient).kindURL("deployment", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "/apis/apps/v1/deployments", u.String())
+
+	u, err = client.(*DefaultKubeClient).kindURL("configmap", "")
+	assert.NoError(t, err, "must fall back to default paths")
+	assert.Equal(t, "api/v1/configmaps", u.String())
+}
+
+func TestKindURLNamespace(t *testing.T) {
+	setup()
+	defer teardown()
+	handleDiscovery()
+
+	kc := client.(*DefaultKubeClient)
+	tests := []struct {
+		kind     string
+		expected string
+	}{
+		{"deployment", "/apis/apps/v1/namespaces/red/deployments"},
+		{"pod", "api/v1/namespaces/red/pods"},
+		{"configmap", "api/v1/namespaces/red/configmaps"},
+		{"node", "api/v1/nodes"},
+	}
+	for _, test := range tests {
+		u, err := kc.kindURL(test.kind, "red")
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, u.String(), "kind %s", test.kind)
+	}
+}
+
+func TestDiscoveryCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := discoveryCache{dir}
+	s := KubeServer{URL: "https://foo.com:8443/k8s"}
+	_, err = c.load(s)
+	assert.Error(t, err)
+
+	resources := []APIResource{{Name: "pods", Kind: "Pod", Namespaced: true, GroupVersion: "v1"}}
+	assert.NoError(t, c.save(s, resources))
+	actual, err := c.load(s)
+	assert.NoError(t, err)
+	assert.Equal(t, resources, actual)
+	assert.Equal(t, dir+"/foo.com_8443_k8s.json", c.filename(s))
+}
+
+func TestKindURLUsesDiscoveryCache(t *testing.T) {
+	setup()
+	defer teardown()
+
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg, _ := NewConfigFromURL(server.URL)
+	kc := NewKubeClient(cfg, KubeClientOptions{DiscoveryCacheDir: dir}).(*DefaultKubeClient)
+	cached := []APIResource{{Name: "deployments", Kind: "Deployment", Namespaced: true, GroupVersion: "extensions/v1beta1"}}
+	kc.discoveryCache.save(kc.Server(), cached)
+
+	discovered := make(chan struct{})
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, r *http.Request) {
+		<-discovered
+		fmt.Fprint(w, `{"groups": [
+			{"name": "apps", "versions": [{"groupVersion": "apps/v1"}], "preferredVersion": {"groupVersion": "apps/v1"}}
+		]}`)
+	})
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"groupVersion": "v1", "resources": []}`)
+	})
+	mux.HandleFunc("/apis/apps/v1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"groupVersion": "apps/v1", "resources": [
+			{"name": "deployments", "namespaced": true, "kind": "Deployment"}
+		]}`)
+	})
+
+	u, err := kc.kindURL("deployment", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "/apis/extensions/v1beta1/deployments", u.String(), "must use cache while discovery is in progress")
+
+	close(discovered)
+	time.Sleep(50 * time.Millisecond)
+	u, err = kc.kindURL("deployment", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "/apis/apps/v1/deployments", u.String(), "must use result of discovery")
+
+	saved, err := kc.discoveryCache.load(kc.Server())
+	assert.NoError(t, err)
+	assert.Equal(t, "apps/v1", saved[0].GroupVersion, "must update the cache")
+}
@@ -0,0 +1,186 @@
+package app
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"sort"
+	"strings"
+	"time"
+)
+
+//limitCheckInterval is how often the number of cached objects is checked against --max-objects
+const limitCheckInterval = 10 * time.Second
+
+func keyOf(o KubeObject) objectKey {
+	return objectKey{strings.ToLower(o.Kind), o.Namespace, o.Name}
+}
+
+//seenLocked records that the server has just confirmed the object. Caller must hold the write lock
+func (c *MrrCache) seenLocked(server KubeServer, o KubeObject) {
+	if c.seen[server] == nil {
+		c.seen[server] = make(map[objectKey]time.Time)
+	}
+	c.seen[server][keyOf(o)] = time.Now()
+}
+
+//evictStale removes objects that servers have not confirmed for longer than ttl and returns their number.
+//Objects that were never confirmed, like objects loaded from a snapshot, are taken as confirmed now
+func (c *MrrCache) evictStale(ttl time.Duration) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	evicted := 0
+	for server, objects := range c.objects {
+		seen := c.seen[server]
+		if seen == nil {
+			seen = make(map[objectKey]time.Time)
+			c.seen[server] = seen
+		}
+
+		cached := map[objectKey]bool{}
+		kept := []KubeObject{}
+		for _, o := range objects {
+			k := keyOf(o)
+			cached[k] = true
+			if _, ok := seen[k]; !ok {
+				seen[k] = now
+			}
+			if now.Sub(seen[k]) > ttl {
+				c.recordLocked(server, Deleted, o)
+				c.metrics.evicted(server, o.Kind)
+				delete(seen, k)
+				evicted++
+				continue
+			}
+			kept = append(kept, o)
+		}
+		c.objects[server] = kept
+		c.reindexLocked(server)
+
+		//objects deleted by events are forgotten here rather than on every delete
+		for k := range seen {
+			if !cached[k] {
+				delete(seen, k)
+			}
+		}
+	}
+
+	for server := range c.seen {
+		if _, ok := c.objects[server]; !ok {
+			delete(c.seen, server)
+		}
+	}
+	return evicted
+}
+
+//evictStaleObjects evicts objects not confirmed within ttl regularly, until stop is closed
+func (c *MrrCache) evictStaleObjects(ttl time.Duration, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(ttl / 4):
+		}
+		if evicted := c.evictStale(ttl); evicted > 0 {
+			log.WithField("objects", evicted).WithField("ttl", ttl.String()).Warn("evicted objects that servers have not confirmed")
+		}
+	}
+}
+
+//access records that clients have just asked for objects of the kind in the namespace of the servers
+func (c *MrrCache) access(servers []KubeServer, kind string, namespace string) {
+	if !isNamespaced(strings.ToLower(kind)) {
+		namespace = ""
+	}
+	k := objectKey{kind: strings.ToLower(kind), namespace: namespace}
+
+	c.accessMu.Lock()
+	defer c.accessMu.Unlock()
+	for _, server := range servers {
+		if c.accessed[server] == nil {
+			c.accessed[server] = make(map[objectKey]time.Time)
+		}
+		c.accessed[server][k] = time.Now()
+	}
+}
+
+//lastUsedLocked returns when the object was last confirmed by the server or asked for by clients.
+//Caller must hold the write lock and accessMu
+func (c *MrrCache) lastUsedLocked(server KubeServer, o KubeObject) time.Time {
+	k := keyOf(o)
+	used := c.seen[server][k]
+	for _, scope := range []objectKey{{kind: k.kind}, {kind: k.kind, namespace: k.namespace}} {
+		if t := c.accessed[server][scope]; t.After(used) {
+			used = t
+		}
+	}
+	return used
+}
+
+//evictLeastUsed removes least recently used objects until there are at most max objects in the cache,
+//and returns the number of removed objects
+func (c *MrrCache) evictLeastUsed(max int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total := 0
+	for _, objects := range c.objects {
+		total += len(objects)
+	}
+	if total <= max {
+		return 0
+	}
+
+	type candidate struct {
+		server KubeServer
+		key    objectKey
+		used   time.Time
+	}
+	c.accessMu.Lock()
+	candidates := make([]candidate, 0, total)
+	for server, objects := range c.objects {
+		for _, o := range objects {
+			candidates = append(candidates, candidate{server, keyOf(o), c.lastUsedLocked(server, o)})
+		}
+	}
+	c.accessMu.Unlock()
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].used.Before(candidates[j].used) })
+
+	evict := map[KubeServer]map[objectKey]bool{}
+	for _, cand := range candidates[:total-max] {
+		if evict[cand.server] == nil {
+			evict[cand.server] = make(map[objectKey]bool)
+		}
+		evict[cand.server][cand.key] = true
+	}
+
+	for server, keys := range evict {
+		kept := []KubeObject{}
+		for _, o := range c.objects[server] {
+			if !keys[keyOf(o)] {
+				kept = append(kept, o)
+				continue
+			}
+			c.recordLocked(server, Deleted, o)
+			c.metrics.evicted(server, o.Kind)
+			delete(c.seen[server], keyOf(o))
+		}
+		c.objects[server] = kept
+		c.reindexLocked(server)
+	}
+	return total - max
+}
+
+//limitObjects keeps at most max objects in the cache, checking it regularly until stop is closed
+func (c *MrrCache) limitObjects(max int, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(limitCheckInterval):
+		}
+		if evicted := c.evictLeastUsed(max); evicted > 0 {
+			log.WithField("objects", evicted).WithField("max", max).Warn("evicted least recently used objects")
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEvictStale(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "https://foo.com"}
+	ghost := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "ghost"}}
+	alive := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "alive"}}
+	c.updateKubeObject(s, ghost)
+	c.updateKubeObject(s, alive)
+
+	assert.Equal(t, 0, c.evictStale(time.Hour), "must keep confirmed objects")
+
+	c.seen[s][keyOf(ghost)] = time.Now().Add(-2 * time.Hour)
+	assert.Equal(t, 1, c.evictStale(time.Hour))
+	assert.Equal(t, []KubeObject{alive}, c.objects[s])
+	assert.Equal(t, Deleted, c.events[len(c.events)-1].Type, "eviction must be recorded as deletion")
+	_, ok := c.seen[s][keyOf(ghost)]
+	assert.False(t, ok, "must forget evicted objects")
+
+	c.replaceKubeObjects(s, "pod", "", []KubeObject{alive})
+	c.seen[s][keyOf(alive)] = time.Now().Add(-2 * time.Hour)
+	c.replaceKubeObjects(s, "pod", "", []KubeObject{alive})
+	assert.Equal(t, 0, c.evictStale(time.Hour), "lists must confirm objects")
+
+	loaded := KubeServer{URL: "https://bar.com"}
+	c.objects[loaded] = []KubeObject{ghost}
+	assert.Equal(t, 0, c.evictStale(time.Hour), "objects that were never confirmed are taken as confirmed now")
+
+	c.deleteServer(loaded)
+	c.evictStale(time.Hour)
+	_, ok = c.seen[loaded]
+	assert.False(t, ok, "must forget deleted servers")
+}
+
+func TestEvictLeastUsed(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "https://foo.com"}
+	old := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "old", Namespace: "dev"}}
+	queried := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "queried", Namespace: "prod"}}
+	recent := KubeObject{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "recent", Namespace: "dev"}}
+	c.updateKubeObject(s, old)
+	c.updateKubeObject(s, queried)
+	c.updateKubeObject(s, recent)
+	c.seen[s][keyOf(old)] = time.Now().Add(-3 * time.Hour)
+	c.seen[s][keyOf(queried)] = time.Now().Add(-2 * time.Hour)
+	c.seen[s][keyOf(recent)] = time.Now().Add(-time.Hour)
+
+	assert.Equal(t, 0, c.evictLeastUsed(3))
+
+	err := c.Objects(&MrrFilter{Kind: "pod", Namespace: "prod"}, &[]KubeObject{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.evictLeastUsed(1))
+	assert.Equal(t, []KubeObject{queried}, c.objects[s], "must keep objects that clients asked for")
+	assert.Equal(t, uint64(1), c.metrics.evictions[metricKey{s, "pod"}])
+	assert.Equal(t, uint64(1), c.metrics.evictions[metricKey{s, "service"}])
+}
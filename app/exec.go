@@ -0,0 +1,330 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultExecAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+//ExecConfig is the exec section of a user in the kubeconfig file. The command is a credential plugin
+//that prints an ExecCredential with a token, the same plugin that kubectl runs
+type ExecConfig struct {
+	APIVersion string       `yaml:"apiVersion,omitempty"`
+	Command    string       `yaml:"command"`
+	Args       []string     `yaml:"args,omitempty"`
+	Env        []ExecEnvVar `yaml:"env,omitempty"`
+}
+
+type ExecEnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+//execCredential is the object exchanged with credential plugins
+type execCredential struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Interactive bool `json:"interactive"`
+	} `json:"spec"`
+	Status *execCredentialStatus `json:"status,omitempty"`
+}
+
+type execCredentialStatus struct {
+	Token               string     `json:"token"`
+	ExpirationTimestamp *time.Time `json:"expirationTimestamp,omitempty"`
+}
+
+//valid tells whether the credential has a token that has not expired
+func (c *execCredential) valid() bool {
+	return c.Status != nil && c.Status.Token != "" &&
+		(c.Status.ExpirationTimestamp == nil || time.Now().Before(*c.Status.ExpirationTimestamp))
+}
+
+//tokenSource gives bearer tokens for requests to the API server
+type tokenSource interface {
+	token() (string, error)
+
+	//invalidate is called when the server rejected the token
+	invalidate(token string)
+}
+
+type staticToken string
+
+func (t staticToken) token() (string, error) {
+	return string(t), nil
+}
+
+func (t staticToken) invalidate(token string) {}
+
+//newTokenSource returns source of tokens of the user, or nil if the user does not authenticate with tokens
+func newTokenSource(u User) tokenSource {
+	if u.Exec != nil {
+		return &execTokenSource{config: *u.Exec, cache: execCache}
+	}
+	if u.Token != "" {
+		return staticToken(u.Token)
+	}
+	if u.TokenFile != "" {
+		return &fileToken{path: u.TokenFile}
+	}
+	return nil
+}
+
+//tokenFileRefresh is how often the token file is read again. Tokens of service accounts
+//mounted into pods are rotated by kubelet
+const tokenFileRefresh = time.Minute
+
+//fileToken reads the token from the file, again when it gets old or is rejected
+type fileToken struct {
+	path string
+
+	mu    sync.Mutex
+	value string
+	read  time.Time
+}
+
+func (t *fileToken) token() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.value != "" && time.Since(t.read) < tokenFileRefresh {
+		return t.value, nil
+	}
+
+	raw, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		return "", fmt.Errorf("could not read token file: %s", err)
+	}
+	t.value = strings.TrimSpace(string(raw))
+	t.read = time.Now()
+	return t.value, nil
+}
+
+func (t *fileToken) invalidate(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.value == token {
+		t.value = ""
+	}
+}
+
+//defaultExecCacheDir keeps credentials printed by plugins. Only kubemrr reads it, kubectl keeps its own
+const defaultExecCacheDir = "~/.kubemrr/cache/exec"
+
+//execTokens keeps tokens given by credential plugins until they expire or are rejected.
+//Clients of all clusters share it, so that users of several clusters with the same plugin
+//are prompted to log in once. Credentials are also written to dir, one ExecCredential per plugin
+//configuration, so that every run of get and watch reuses them. Empty dir keeps them in memory only
+type execTokens struct {
+	mu      sync.Mutex
+	entries map[string]*execToken
+	dir     string
+}
+
+type execToken struct {
+	//mu is held while the plugin runs, so that it runs once for concurrent requests
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+var execCache = &execTokens{entries: map[string]*execToken{}, dir: defaultExecCacheDir}
+
+func (c *execTokens) entry(key string) *execToken {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &execToken{}
+		c.entries[key] = e
+	}
+	return e
+}
+
+type execTokenSource struct {
+	config ExecConfig
+	cache  *execTokens
+}
+
+//key identifies plugin runs that give the same tokens: the hash of the command, its arguments,
+//environment and API version
+func (s *execTokenSource) key() string {
+	raw, _ := json.Marshal(s.config)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *execTokenSource) token() (string, error) {
+	e := s.cache.entry(s.key())
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.token != "" && (e.expires.IsZero() || time.Now().Before(e.expires)) {
+		return e.token, nil
+	}
+
+	cred := s.cache.read(s.key())
+	if cred == nil {
+		var err error
+		if cred, err = runExecPlugin(s.config); err != nil {
+			return "", err
+		}
+		s.cache.write(s.key(), cred)
+	}
+	e.token = cred.Status.Token
+	e.expires = time.Time{}
+	if cred.Status.ExpirationTimestamp != nil {
+		e.expires = *cred.Status.ExpirationTimestamp
+	}
+	return e.token, nil
+}
+
+func (s *execTokenSource) invalidate(token string) {
+	e := s.cache.entry(s.key())
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.token == token {
+		e.token = ""
+	}
+	if cred := s.cache.read(s.key()); cred != nil && cred.Status.Token == token {
+		s.cache.remove(s.key())
+	}
+}
+
+//path returns the file of the credential with the key, empty when credentials are kept in memory only
+func (c *execTokens) path(key string) string {
+	if c.dir == "" {
+		return ""
+	}
+	dir, err := substituteUserHome(c.dir)
+	if err != nil {
+		log.WithField("error", err).Debug("could not find the cache of credentials")
+		return ""
+	}
+	return filepath.Join(dir, key+".json")
+}
+
+//read returns the cached credential with the key, or nil when there is no valid one
+func (c *execTokens) read(key string) *execCredential {
+	path := c.path(key)
+	if path == "" {
+		return nil
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	cred := &execCredential{}
+	if err := json.Unmarshal(raw, cred); err != nil || !cred.valid() {
+		return nil
+	}
+	return cred
+}
+
+//write caches the credential, readable only by the user. It is written to a temporary file
+//and renamed, so that other processes never read a part of it
+func (c *execTokens) write(key string, cred *execCredential) {
+	path := c.path(key)
+	if path == "" {
+		return
+	}
+	raw, err := json.Marshal(cred)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+	}
+	var tmp *os.File
+	if err == nil {
+		tmp, err = ioutil.TempFile(filepath.Dir(path), key)
+	}
+	if err == nil {
+		_, err = tmp.Write(raw)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		log.WithField("error", err).Warn("could not cache the credential of the plugin")
+	}
+}
+
+func (c *execTokens) remove(key string) {
+	if path := c.path(key); path != "" {
+		os.Remove(path)
+	}
+}
+
+//execInteractive tells if the user can answer prompts of credential plugins, like kubectl does when
+//both stdin and stderr are terminals. Completion scripts redirect stderr, and the daemon has no terminal
+var execInteractive = func() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd())) && terminal.IsTerminal(int(os.Stderr.Fd()))
+}
+
+//runExecPlugin runs the credential plugin the way kubectl does and returns the credential it printed
+func runExecPlugin(c ExecConfig) (*execCredential, error) {
+	apiVersion := c.APIVersion
+	if apiVersion == "" {
+		apiVersion = defaultExecAPIVersion
+	}
+
+	info := execCredential{APIVersion: apiVersion, Kind: "ExecCredential"}
+	rawInfo, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(c.Command, c.Args...)
+	cmd.Env = append(os.Environ(), "KUBERNETES_EXEC_INFO="+string(rawInfo))
+	for _, e := range c.Env {
+		cmd.Env = append(cmd.Env, e.Name+"="+e.Value)
+	}
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	if execInteractive() {
+		//plugins that log in through a browser or a device code print the prompt and wait for the user
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = os.Stderr
+	} else {
+		//whatever the plugin prints would end up in the middle of the prompt during completion, or nowhere in the daemon
+		cmd.Stderr = stderr
+	}
+	err = cmd.Run()
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		log.WithField("plugin", c.Command).WithField("stderr", msg).Info("credential plugin printed messages")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("credential plugin %s failed: %s", c.Command, err)
+	}
+
+	cred := &execCredential{}
+	if err := json.Unmarshal(stdout.Bytes(), cred); err != nil {
+		return nil, fmt.Errorf("credential plugin %s printed invalid ExecCredential: %s", c.Command, err)
+	}
+	if cred.Kind != "ExecCredential" || cred.APIVersion != apiVersion {
+		return nil, fmt.Errorf("credential plugin %s printed %s of %s, expected ExecCredential of %s", c.Command, cred.Kind, cred.APIVersion, apiVersion)
+	}
+	if cred.Status == nil || strings.TrimSpace(cred.Status.Token) == "" {
+		return nil, errors.New("credential plugin " + c.Command + " did not print a token")
+	}
+	return cred, nil
+}
@@ -0,0 +1,255 @@
+package app
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//writePlugin writes a credential plugin that prints tokens token-1, token-2 and so on,
+//one for each run, expiring at the given time
+func writePlugin(t *testing.T, dir string, expires string) string {
+	script := fmt.Sprintf(`#!/bin/sh
+echo run >> %s/runs
+n=$(wc -l < %s/runs | tr -d ' ')
+echo '{"apiVersion": "client.authentication.k8s.io/v1beta1", "kind": "ExecCredential", "status": {"token": "'$PREFIX'-'$n'"%s}}'
+`, dir, dir, expires)
+	plugin := filepath.Join(dir, "plugin")
+	if err := ioutil.WriteFile(plugin, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return plugin
+}
+
+func TestExecTokenSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := ExecConfig{Command: writePlugin(t, dir, ""), Env: []ExecEnvVar{{"PREFIX", "token"}}}
+	cache := &execTokens{entries: map[string]*execToken{}}
+	a := &execTokenSource{config: config, cache: cache}
+	b := &execTokenSource{config: config, cache: cache}
+
+	token, err := a.token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	token, err = b.token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token, "sources of the same plugin share tokens")
+
+	b.invalidate("outdated")
+	token, _ = a.token()
+	assert.Equal(t, "token-1", token, "invalidation of another token is ignored")
+
+	b.invalidate("token-1")
+	token, _ = a.token()
+	assert.Equal(t, "token-2", token, "rejected token is replaced")
+}
+
+func TestExecTokenSourceExpiration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	expired := `, "expirationTimestamp": "` + time.Now().Add(-time.Minute).UTC().Format(time.RFC3339) + `"`
+	config := ExecConfig{Command: writePlugin(t, dir, expired), Env: []ExecEnvVar{{"PREFIX", "token"}}}
+	s := &execTokenSource{config: config, cache: &execTokens{entries: map[string]*execToken{}}}
+
+	s.token()
+	token, err := s.token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-2", token, "expired token is replaced")
+}
+
+//TestExecTokenHelperProcess asks for a token in another process, when run by TestExecTokenDiskCache
+func TestExecTokenHelperProcess(t *testing.T) {
+	dir := os.Getenv("KUBEMRR_TEST_EXEC_DIR")
+	if dir == "" {
+		return
+	}
+	config := ExecConfig{Command: filepath.Join(dir, "plugin"), Env: []ExecEnvVar{{"PREFIX", "token"}}}
+	s := &execTokenSource{config: config, cache: &execTokens{entries: map[string]*execToken{}, dir: filepath.Join(dir, "cache")}}
+	token, err := s.token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("token:" + token)
+}
+
+func TestExecTokenDiskCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := ExecConfig{Command: writePlugin(t, dir, ""), Env: []ExecEnvVar{{"PREFIX", "token"}}}
+	s := &execTokenSource{config: config, cache: &execTokens{entries: map[string]*execToken{}, dir: filepath.Join(dir, "cache")}}
+	token, err := s.token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	info, err := os.Stat(filepath.Join(dir, "cache", s.key()+".json"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "credentials are readable only by the user")
+	}
+
+	other := func() string {
+		cmd := exec.Command(os.Args[0], "-test.run=TestExecTokenHelperProcess")
+		cmd.Env = append(os.Environ(), "KUBEMRR_TEST_EXEC_DIR="+dir)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Helper process failed: %v", err)
+		}
+		i := strings.Index(string(out), "token:")
+		if i < 0 {
+			t.Fatalf("Helper process printed no token: %s", out)
+		}
+		return strings.Fields(string(out)[i+len("token:"):])[0]
+	}
+	assert.Equal(t, "token-1", other(), "another process reuses the cached token")
+
+	s.invalidate("token-1")
+	_, err = os.Stat(filepath.Join(dir, "cache", s.key()+".json"))
+	assert.True(t, os.IsNotExist(err), "rejected token is removed from the cache")
+	assert.Equal(t, "token-2", other(), "another process runs the plugin after the token was rejected")
+}
+
+func TestRunExecPluginStderr(t *testing.T) {
+	hook := &entriesHook{}
+	hooks := log.StandardLogger().Hooks
+	log.StandardLogger().Hooks = make(log.LevelHooks)
+	log.AddHook(hook)
+	defer func() { log.StandardLogger().Hooks = hooks }()
+	interactive := execInteractive
+	defer func() { execInteractive = interactive }()
+	plugin := ExecConfig{Command: "sh", Args: []string{"-c", "echo open https://sso >&2; exit 1"}}
+
+	execInteractive = func() bool { return false }
+	_, err := runExecPlugin(plugin)
+	assert.Error(t, err)
+	found := false
+	for _, e := range hook.entries {
+		if e.Data["stderr"] == "open https://sso" {
+			found = true
+		}
+	}
+	assert.True(t, found, "stderr of the plugin is logged")
+
+	//the user has to see prompts of the plugin right away
+	f, err := ioutil.TempFile("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	stderr := os.Stderr
+	os.Stderr = f
+	defer func() { os.Stderr = stderr }()
+	hook.entries = nil
+	execInteractive = func() bool { return true }
+	_, err = runExecPlugin(plugin)
+	os.Stderr = stderr
+	f.Close()
+	assert.Error(t, err)
+	printed, _ := ioutil.ReadFile(f.Name())
+	assert.Equal(t, "open https://sso\n", string(printed))
+	assert.Empty(t, hook.entries)
+}
+
+func TestRunExecPluginErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	plugin := writePlugin(t, dir, "")
+
+	tests := []struct {
+		config ExecConfig
+		err    string
+	}{
+		{ExecConfig{Command: filepath.Join(dir, "missing")}, "failed"},
+		{ExecConfig{Command: plugin, APIVersion: "client.authentication.k8s.io/v1"}, "expected ExecCredential of client.authentication.k8s.io/v1"},
+		{ExecConfig{Command: "echo", Args: []string{"not json"}}, "invalid ExecCredential"},
+		{ExecConfig{Command: "echo", Args: []string{`{"apiVersion": "client.authentication.k8s.io/v1beta1", "kind": "ExecCredential"}`}}, "did not print a token"},
+	}
+
+	for i, test := range tests {
+		_, err := runExecPlugin(test.config)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Test %d: expected error with %q, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestKubeClientToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	execCache = &execTokens{entries: map[string]*execToken{}}
+	cfg, _ := NewConfigFromURL(server.URL)
+	cfg.Contexts[0].Context.User = "sso"
+	cfg.Users = []UserWrap{{"sso", User{Exec: &ExecConfig{Command: writePlugin(t, dir, ""), Env: []ExecEnvVar{{"PREFIX", "sso"}}}}}}
+	client = NewKubeClient(cfg, KubeClientOptions{})
+
+	tokens := []string{}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		if len(tokens) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	assert.Error(t, client.Ping())
+	assert.NoError(t, client.Ping())
+	assert.NoError(t, client.Ping())
+	assert.Equal(t, []string{"Bearer sso-1", "Bearer sso-2", "Bearer sso-2"}, tokens)
+}
+
+func TestFileToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "token")
+	ioutil.WriteFile(path, []byte("first\n"), 0600)
+
+	s := &fileToken{path: path}
+	token, err := s.token()
+	assert.NoError(t, err)
+	assert.Equal(t, "first", token)
+
+	ioutil.WriteFile(path, []byte("second\n"), 0600)
+	token, _ = s.token()
+	assert.Equal(t, "first", token, "token is read again only after a while")
+
+	s.invalidate("first")
+	token, _ = s.token()
+	assert.Equal(t, "second", token, "rejected token is read again")
+
+	ioutil.WriteFile(path, []byte("third\n"), 0600)
+	s.read = time.Now().Add(-tokenFileRefresh)
+	token, _ = s.token()
+	assert.Equal(t, "third", token, "old token is read again")
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+)
+
+func NewExportCommand(f Factory) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "export",
+		Short: "Dump objects of the mirror",
+		Long: `
+DESCRIPTION:
+  Print all objects of the "kubemrr watch" process as JSON, in the format of its snapshot.
+  The dump helps to debug the mirror, and to seed a mirror on another machine with
+  "kubemrr import".
+
+  By default objects of all servers are printed, --server prints one of them.
+
+EXAMPLE:
+  kubemrr export > snap.json
+  kubemrr export --server https://prod.example.com -o snap.json
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := RunCommon(cmd); err != nil {
+				return err
+			}
+			return RunExport(f, cmd, args)
+		},
+	}
+
+	AddCommonFlags(cmd)
+	AddMirrorTLSFlags(cmd)
+	AddTokenFlag(cmd)
+	AddProfileFlag(cmd)
+	cmd.Flags().String("server", "", "URL of the exported Kubernetes API server, empty to export all servers")
+	cmd.Flags().StringP("output", "o", "", "File to write objects to, empty to print them")
+	return cmd
+}
+
+func RunExport(f Factory, cmd *cobra.Command, args []string) error {
+	server, err := cmd.Flags().GetString("server")
+	if err != nil {
+		return errors.New("could not parse value of --server")
+	}
+
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return errors.New("could not parse value of --output")
+	}
+
+	profile, err := GetProfile(cmd)
+	if err != nil {
+		return errors.New("could not parse value of --profile")
+	}
+
+	bind, err := GetBind(cmd)
+	if err != nil {
+		return fmt.Errorf("unexpected error: %s", err)
+	}
+
+	opts, err := GetMrrClientOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	client, err := f.MrrClient(bind, opts)
+	if err != nil {
+		return fmt.Errorf("could not create client to kubemrr: %s", err)
+	}
+
+	snapshot, err := client.Export(MrrFilter{Server: server, Profile: profile})
+	if err != nil {
+		return fmt.Errorf("could not export objects of kubemrr: %s", err)
+	}
+
+	raw, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	raw = append(raw, '\n')
+
+	if output == "" {
+		_, err = f.StdOut().Write(raw)
+		return err
+	}
+	output, err = substituteUserHome(output)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(output, raw, 0600)
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	s := KubeServer{URL: "https://foo.com"}
+	snapshot := CacheSnapshot{
+		Version: snapshotVersion,
+		Servers: []ServerSnapshot{{Server: s, Objects: []KubeObject{{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a"}}}}},
+	}
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snap.json")
+
+	tc := &TestMirrorClient{snapshot: snapshot}
+	cmd := NewExportCommand(&TestFactory{mrrClient: tc})
+	cmd.Flags().Set("server", "https://foo.com")
+	cmd.Flags().Set("output", path)
+	if err := cmd.RunE(cmd, []string{}); err != nil {
+		t.Fatalf("Unexpected error of export: %v", err)
+	}
+	if tc.lastFilter.Server != "https://foo.com" {
+		t.Errorf("Expected export of https://foo.com, got %v", tc.lastFilter)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	cmd = NewImportCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+	if err := cmd.RunE(cmd, []string{path}); err != nil {
+		t.Fatalf("Unexpected error of import: %v", err)
+	}
+	if tc.imported == nil || !reflect.DeepEqual(*tc.imported, snapshot) {
+		t.Errorf("Expected import of %+v, got %+v", snapshot, tc.imported)
+	}
+	if !strings.Contains(buf.String(), "Imported 1 objects of 1 servers") {
+		t.Errorf("Unexpected output [%s]", buf)
+	}
+
+	ioutil.WriteFile(path, []byte(`{"version": 42}`), 0600)
+	err = cmd.RunE(cmd, []string{path})
+	if err == nil || !strings.Contains(err.Error(), "version 42") {
+		t.Errorf("Expected error about version, got %v", err)
+	}
+}
+
+func TestCacheExportImport(t *testing.T) {
+	s1 := KubeServer{URL: "https://s1"}
+	s2 := KubeServer{URL: "https://s2"}
+	c := NewMrrCache()
+	c.updateKubeObject(s1, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a"}})
+	c.updateKubeObject(s2, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "b"}})
+
+	var snapshot CacheSnapshot
+	if err := c.Export(&MrrFilter{Server: "https://s2"}, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Servers) != 1 || snapshot.Servers[0].Server != s2 {
+		t.Fatalf("Expected export of %v, got %+v", s2, snapshot)
+	}
+
+	other := NewMrrCache()
+	var imported int
+	if err := other.Import(&snapshot, &imported); err != errAdminDisabled {
+		t.Errorf("Expected disabled admin API, got %v", err)
+	}
+
+	other.flusher = func(f MrrFilter) int { return 0 }
+	if err := other.Import(&snapshot, &imported); err != errAdminDisabled {
+		t.Errorf("Expected import to need the token, got %v", err)
+	}
+
+	other.tokenRequired = true
+	if err := other.Import(&snapshot, &imported); err != errAdminDisabled {
+		t.Errorf("Expected import to need its feature gate, got %v", err)
+	}
+
+	other.features = FeatureGates{AdminAPI: true}
+	if err := other.Import(&snapshot, &imported); err != nil || imported != 1 {
+		t.Errorf("Expected one imported object, got %d and error %v", imported, err)
+	}
+	var os []KubeObject
+	if err := other.Objects(&MrrFilter{Kind: "pod"}, &os); err != nil || len(os) != 1 || os[0].Name != "b" {
+		t.Errorf("Expected imported pod, got %v and error %v", os, err)
+	}
+}
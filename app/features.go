@@ -0,0 +1,135 @@
+package app
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//featureStage tells how mature a feature is. Alpha features are disabled by default,
+//beta ones are enabled, GA ones cannot be disabled and deprecated ones are going away
+type featureStage string
+
+const (
+	alpha      featureStage = "Alpha"
+	beta       featureStage = "Beta"
+	ga         featureStage = "GA"
+	deprecated featureStage = "Deprecated"
+)
+
+type featureSpec struct {
+	Default bool
+	Stage   featureStage
+}
+
+const (
+	//HTTPObjects serves cached objects as JSON on GET /objects of the mirror
+	HTTPObjects = "HTTPObjects"
+
+	//NameCompression compresses long lists of names printed by get to common prefixes
+	NameCompression = "NameCompression"
+
+	//SnapshotPersistence saves the cache to --snapshot and loads it on start
+	SnapshotPersistence = "SnapshotPersistence"
+
+	//Subscriptions streams changes of objects on GET /subscribe of the mirror
+	Subscriptions = "Subscriptions"
+
+	//AdminAPI accepts flush and import requests of clients that give the token of the mirror
+	AdminAPI = "AdminAPI"
+)
+
+//knownFeatures are all features that can be given in --feature-gates
+var knownFeatures = map[string]featureSpec{
+	HTTPObjects:         {Default: true, Stage: beta},
+	NameCompression:     {Default: true, Stage: beta},
+	SnapshotPersistence: {Default: false, Stage: alpha},
+	Subscriptions:       {Default: false, Stage: alpha},
+	AdminAPI:            {Default: false, Stage: alpha},
+}
+
+//FeatureGates holds values of features given in --feature-gates. Other features have default values
+type FeatureGates map[string]bool
+
+//Enabled tells if the feature is enabled
+func (g FeatureGates) Enabled(name string) bool {
+	if v, ok := g[name]; ok {
+		return v
+	}
+	return knownFeatures[name].Default
+}
+
+//State returns values of all known features
+func (g FeatureGates) State() map[string]bool {
+	res := map[string]bool{}
+	for name := range knownFeatures {
+		res[name] = g.Enabled(name)
+	}
+	return res
+}
+
+func (g FeatureGates) String() string {
+	names := []string{}
+	for name := range knownFeatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := []string{}
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%t (%s)", name, g.Enabled(name), knownFeatures[name].Stage))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+//parseFeatureGates reads gates given as Name=true,Other=false
+func parseFeatureGates(value string) (FeatureGates, error) {
+	res := FeatureGates{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("feature gate %s must be given as Name=true or Name=false", pair)
+		}
+		name := strings.TrimSpace(kv[0])
+		spec, ok := knownFeatures[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown feature gate %s", name)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of feature gate %s: %s", name, kv[1])
+		}
+
+		switch spec.Stage {
+		case ga:
+			if !enabled {
+				return nil, fmt.Errorf("feature gate %s is GA and cannot be disabled", name)
+			}
+		case deprecated:
+			log.WithField("feature", name).Warn("feature gate is deprecated and will be removed")
+		}
+		res[name] = enabled
+	}
+	return res, nil
+}
+
+func addFeatureGatesFlag(cmd *cobra.Command) {
+	cmd.Flags().String("feature-gates", "", "Comma-separated features to enable or disable, given as Name=true. Known features: "+FeatureGates{}.String())
+}
+
+//GetFeatureGates returns gates of the command given in --feature-gates
+func GetFeatureGates(cmd *cobra.Command) (FeatureGates, error) {
+	value, err := cmd.Flags().GetString("feature-gates")
+	if err != nil {
+		return nil, err
+	}
+	return parseFeatureGates(value)
+}
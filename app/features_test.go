@@ -0,0 +1,66 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFeatureGates(t *testing.T) {
+	defer delete(knownFeatures, "TestGA")
+	defer delete(knownFeatures, "TestDeprecated")
+	knownFeatures["TestGA"] = featureSpec{Default: true, Stage: ga}
+	knownFeatures["TestDeprecated"] = featureSpec{Default: false, Stage: deprecated}
+
+	tests := []struct {
+		value    string
+		expected FeatureGates
+		err      string
+	}{
+		{value: "", expected: FeatureGates{}},
+		{value: "HTTPObjects=false", expected: FeatureGates{HTTPObjects: false}},
+		{value: " HTTPObjects=false , NameCompression=true,", expected: FeatureGates{HTTPObjects: false, NameCompression: true}},
+		{value: "TestDeprecated=true", expected: FeatureGates{"TestDeprecated": true}},
+		{value: "TestGA=true", expected: FeatureGates{"TestGA": true}},
+		{value: "TestGA=false", err: "cannot be disabled"},
+		{value: "Unknown=true", err: "unknown feature gate"},
+		{value: "HTTPObjects", err: "must be given as"},
+		{value: "HTTPObjects=maybe", err: "invalid value"},
+	}
+
+	for _, test := range tests {
+		actual, err := parseFeatureGates(test.value)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Parsing %q: expected error %q, got %v", test.value, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parsing %q: unexpected error %v", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Parsing %q: expected %v, got %v", test.value, test.expected, actual)
+		}
+	}
+}
+
+func TestFeatureGatesEnabled(t *testing.T) {
+	g := FeatureGates{HTTPObjects: false}
+	if g.Enabled(HTTPObjects) {
+		t.Errorf("Expected disabled %s", HTTPObjects)
+	}
+	if !g.Enabled(NameCompression) {
+		t.Errorf("Expected %s enabled by default", NameCompression)
+	}
+
+	if g.Enabled(AdminAPI) {
+		t.Errorf("Expected alpha %s disabled by default", AdminAPI)
+	}
+
+	expected := map[string]bool{HTTPObjects: false, NameCompression: true, SnapshotPersistence: false, Subscriptions: false, AdminAPI: false}
+	if !reflect.DeepEqual(g.State(), expected) {
+		t.Errorf("Expected state %v, got %v", expected, g.State())
+	}
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//fileChangeDelay is how long files must stay unchanged before a change is reported.
+//Editors and kubectl write files in several steps, which are reported as one change
+const fileChangeDelay = 500 * time.Millisecond
+
+//watchFiles sends to changed every time one of the files is written, created, renamed or removed.
+//Directories of the files are watched, so that files replaced by renaming are noticed too
+func watchFiles(files []string, changed chan<- struct{}) (*fsnotify.Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := map[string]bool{}
+	watched := map[string]bool{}
+	for _, f := range files {
+		f, err := substituteUserHome(f)
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+		f, err = filepath.Abs(f)
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+		dir := filepath.Dir(f)
+		if !dirs[dir] {
+			//optional files, like the kubemrr config, may be in directories that do not exist
+			if err := w.Add(dir); err != nil && !os.IsNotExist(err) {
+				w.Close()
+				return nil, err
+			}
+		}
+		dirs[dir] = true
+		watched[f] = true
+	}
+
+	go func() {
+		var timer <-chan time.Time
+		for {
+			select {
+			case e, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if watched[filepath.Clean(e.Name)] && e.Op&fsnotify.Chmod == 0 {
+					log.WithField("file", e.Name).Debug("file has changed")
+					timer = time.After(fileChangeDelay)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.WithField("error", err).Warn("error while watching files")
+			case <-timer:
+				timer = nil
+				select {
+				case changed <- struct{}{}:
+				default:
+					//a reload is already pending and will read the latest files
+				}
+			}
+		}
+	}()
+	return w, nil
+}
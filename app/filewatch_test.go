@@ -0,0 +1,56 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func expectChange(t *testing.T, changed chan struct{}, msg string) {
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected change to be reported: %s", msg)
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := filepath.Join(dir, "config")
+	other := filepath.Join(dir, "other")
+	ioutil.WriteFile(config, []byte("a"), 0600)
+
+	changed := make(chan struct{}, 1)
+	w, err := watchFiles([]string{config, filepath.Join(dir, "missing", "kubemrr.yaml")}, changed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer w.Close()
+
+	ioutil.WriteFile(config, []byte("b"), 0600)
+	ioutil.WriteFile(config, []byte("c"), 0600)
+	expectChange(t, changed, "file is written")
+
+	select {
+	case <-changed:
+		t.Errorf("Expected writes in quick succession to be reported once")
+	case <-time.After(2 * fileChangeDelay):
+	}
+
+	ioutil.WriteFile(other, []byte("a"), 0600)
+	select {
+	case <-changed:
+		t.Errorf("Expected changes of other files to be ignored")
+	case <-time.After(2 * fileChangeDelay):
+	}
+
+	os.Rename(other, config)
+	expectChange(t, changed, "file is replaced")
+}
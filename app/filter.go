@@ -0,0 +1,124 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//FilterRule either includes or excludes objects that match it. Exactly one of the two is given
+type FilterRule struct {
+	Include *ObjectMatch `yaml:"include"`
+	Exclude *ObjectMatch `yaml:"exclude"`
+}
+
+//ObjectMatch matches objects that satisfy all of its conditions. Empty conditions match any object
+type ObjectMatch struct {
+	Kinds      []string `yaml:"kinds"`
+	Namespaces []string `yaml:"namespaces"`
+
+	//Names are regular expressions, at least one of which must match the name of the object
+	Names []string `yaml:"names"`
+
+	//Labels must all be set on the object to the given values
+	Labels map[string]string `yaml:"labels"`
+}
+
+type filterRule struct {
+	include bool
+	match   *ObjectMatch
+	names   []*regexp.Regexp
+}
+
+//objectFilter decides which objects are put into the cache. Rules are checked in order
+//and the first matching rule decides. Objects that match no rule are put into the cache
+type objectFilter struct {
+	rules []filterRule
+}
+
+func newObjectFilter(rules []FilterRule) (*objectFilter, error) {
+	f := &objectFilter{}
+	for i, r := range rules {
+		if (r.Include == nil) == (r.Exclude == nil) {
+			return nil, fmt.Errorf("rule %d must have either include or exclude", i+1)
+		}
+
+		fr := filterRule{include: r.Include != nil, match: r.Include}
+		if !fr.include {
+			fr.match = r.Exclude
+		}
+		for _, name := range fr.match.Names {
+			re, err := regexp.Compile(name)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d has invalid name %s: %s", i+1, name, err)
+			}
+			fr.names = append(fr.names, re)
+		}
+		f.rules = append(f.rules, fr)
+	}
+	return f, nil
+}
+
+func (r *filterRule) matches(o KubeObject) bool {
+	m := r.match
+	if len(m.Kinds) > 0 && !containsFold(m.Kinds, o.Kind) {
+		return false
+	}
+	if len(m.Namespaces) > 0 && !containsFold(m.Namespaces, o.Namespace) {
+		return false
+	}
+	if len(r.names) > 0 {
+		matched := false
+		for _, re := range r.names {
+			if re.MatchString(o.Name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for k, v := range m.Labels {
+		if actual, ok := o.Labels[k]; !ok || actual != v {
+			return false
+		}
+	}
+	return true
+}
+
+//allows checks whether the object is put into the cache. Nil filter allows all objects
+func (f *objectFilter) allows(o KubeObject) bool {
+	if f == nil {
+		return true
+	}
+	for i := range f.rules {
+		if f.rules[i].matches(o) {
+			return f.rules[i].include
+		}
+	}
+	return true
+}
+
+//filterObjects returns the allowed objects
+func (f *objectFilter) filterObjects(objects []KubeObject) []KubeObject {
+	if f == nil || len(f.rules) == 0 {
+		return objects
+	}
+	res := []KubeObject{}
+	for _, o := range objects {
+		if f.allows(o) {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestObjectFilter(t *testing.T) {
+	f, err := newObjectFilter([]FilterRule{
+		{Include: &ObjectMatch{Namespaces: []string{"kube-system"}, Names: []string{"^kube-dns"}}},
+		{Exclude: &ObjectMatch{Namespaces: []string{"kube-system"}}},
+		{Exclude: &ObjectMatch{Kinds: []string{"configmap"}, Labels: map[string]string{"owner": "helm"}}},
+		{Exclude: &ObjectMatch{Names: []string{"-canary$", "^tmp-"}}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		object   KubeObject
+		expected bool
+	}{
+		{KubeObject{TypeMeta: TypeMeta{"Pod"}, ObjectMeta: ObjectMeta{Name: "kube-dns-1", Namespace: "kube-system"}}, true},
+		{KubeObject{TypeMeta: TypeMeta{"Pod"}, ObjectMeta: ObjectMeta{Name: "kube-proxy-1", Namespace: "kube-system"}}, false},
+		{KubeObject{TypeMeta: TypeMeta{"ConfigMap"}, ObjectMeta: ObjectMeta{Name: "a", Labels: map[string]string{"owner": "helm"}}}, false},
+		{KubeObject{TypeMeta: TypeMeta{"configmap"}, ObjectMeta: ObjectMeta{Name: "a", Labels: map[string]string{"owner": "me"}}}, true},
+		{KubeObject{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "a", Labels: map[string]string{"owner": "helm"}}}, true},
+		{KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "web-canary"}}, false},
+		{KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "tmp-web"}}, false},
+		{KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "web"}}, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, f.allows(test.object), "object %+v", test.object)
+	}
+
+	objects := []KubeObject{tests[0].object, tests[1].object}
+	assert.Equal(t, []KubeObject{tests[0].object}, f.filterObjects(objects))
+
+	var none *objectFilter
+	assert.True(t, none.allows(tests[1].object))
+}
+
+func TestObjectFilterFailures(t *testing.T) {
+	tests := [][]FilterRule{
+		{{}},
+		{{Include: &ObjectMatch{}, Exclude: &ObjectMatch{}}},
+		{{Exclude: &ObjectMatch{Names: []string{"("}}}},
+	}
+
+	for _, test := range tests {
+		_, err := newObjectFilter(test)
+		assert.Error(t, err, "rules %+v", test)
+	}
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+func NewFlushCommand(f Factory) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "flush",
+		Short: "Clear the mirror and list objects again",
+		Long: `
+DESCRIPTION:
+  Remove objects from the "kubemrr watch" process and make it list them again
+  from Kubernetes API servers. It helps when mirrored objects are stale or wrong.
+
+  By default all servers are flushed, --server flushes one of them.
+  The mirror allows it only when it requires --token and is given
+  --feature-gates=AdminAPI=true.
+
+EXAMPLE:
+  kubemrr -a 10.5.1.6 -p 33033 flush --server https://prod.example.com
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := RunCommon(cmd); err != nil {
+				return err
+			}
+			return RunFlush(f, cmd, args)
+		},
+	}
+
+	AddCommonFlags(cmd)
+	AddMirrorTLSFlags(cmd)
+	AddTokenFlag(cmd)
+	AddProfileFlag(cmd)
+	cmd.Flags().String("server", "", "URL of the flushed Kubernetes API server, empty to flush all servers")
+	return cmd
+}
+
+func RunFlush(f Factory, cmd *cobra.Command, args []string) error {
+	server, err := cmd.Flags().GetString("server")
+	if err != nil {
+		return errors.New("could not parse value of --server")
+	}
+
+	profile, err := GetProfile(cmd)
+	if err != nil {
+		return errors.New("could not parse value of --profile")
+	}
+
+	bind, err := GetBind(cmd)
+	if err != nil {
+		return fmt.Errorf("unexpected error: %s", err)
+	}
+
+	opts, err := GetMrrClientOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	client, err := f.MrrClient(bind, opts)
+	if err != nil {
+		return fmt.Errorf("could not create client to kubemrr: %s", err)
+	}
+
+	restarted, err := client.Flush(MrrFilter{Server: server, Profile: profile})
+	if err != nil {
+		return fmt.Errorf("could not flush kubemrr: %s", err)
+	}
+
+	fmt.Fprintf(f.StdOut(), "Flushed the mirror, %d watchers are listing objects again\n", restarted)
+	return nil
+}
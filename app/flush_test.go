@@ -0,0 +1,32 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunFlush(t *testing.T) {
+	tc := &TestMirrorClient{flushed: 2}
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewFlushCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+	cmd.Flags().Set("server", "https://foo.com")
+
+	err := cmd.RunE(cmd, []string{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tc.lastFilter.Server != "https://foo.com" {
+		t.Errorf("Expected flush of https://foo.com, got %v", tc.lastFilter)
+	}
+	if !strings.Contains(buf.String(), "2 watchers") {
+		t.Errorf("Unexpected output [%s]", buf)
+	}
+
+	tc.err = errors.New("admin API is disabled")
+	err = cmd.RunE(cmd, []string{})
+	if err == nil || !strings.Contains(err.Error(), "admin API is disabled") {
+		t.Errorf("Expected error of the mirror, got %v", err)
+	}
+}
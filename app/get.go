@@ -1,13 +1,24 @@
 package app
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 	"io"
+	"io/ioutil"
+	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/tabwriter"
+	"text/template"
+	"time"
 )
 
 func NewGetCommand(f Factory) *cobra.Command {
@@ -25,16 +36,30 @@ DESCRIPTION:
     - ns, namespace, namespaces
     - configmap, configmaps
     - no, node, nodes
+  Other resources are resolved by discovery of the mirror and aliases of the --config file.
 
-  To filter alive resources it uses current context from the ~/.kube/conf file.
-  Additionally, it accepts --namespace, --context, --server and --cluster parameters
-  in "kubectl-flags".
+  To filter alive resources it uses current context from the ~/.kube/config file.
+  Additionally, it accepts --namespace, --context, --server, --cluster, --user and
+  --selector parameters in "kubectl-flags".
+
+  By default only names are printed, separated by spaces. See the README for other
+  outputs, resources and completion.
 
 EXAMPLE
   kubemrr -a 0.0.0.0 -p 33033 --kubect-flags="--namespace prod" get pod
+  kubemrr -a 0.0.0.0 -p 33033 get pod -o wide
+  kubemrr get po,svc -l app=checkout
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := RunCommon(cmd); err != nil {
+			if _, err := RunCommon(cmd); err != nil {
+				return err
+			}
+			if quiet, err := GetQuiet(cmd); err != nil {
+				return err
+			} else if quiet {
+				silence(cmd)
+			}
+			if err := applyCompLine(cmd); err != nil {
 				return err
 			}
 			return RunGet(f, cmd, args)
@@ -42,32 +67,151 @@ EXAMPLE
 	}
 
 	AddCommonFlags(cmd)
-	cmd.Flags().String("kubectl-flags", "", "An arbitrary string that contains flags accepted by kubectl")
+	AddLogLevelFlag(cmd)
+	AddMirrorTLSFlags(cmd)
+	AddTokenFlag(cmd)
+	AddProfileFlag(cmd)
+	AddFallbackFlag(cmd)
+	cmd.Flags().String("kubectl-flags", "", "Kubectl command line being completed. Its --namespace, --context, --cluster, --user, --selector and --kubeconfig filter printed objects")
+	cmd.Flags().StringP("output", "o", "", "Output format, one of: wide, json, yaml, custom-columns=HEADER:.field.path,..., go-template=TEMPLATE. By default only names are printed")
+	cmd.Flags().String("prefix", "", "Print only names that start with the prefix, the word being completed")
+	cmd.Flags().Bool("for-command", false, "Take the resource type from the kubectl command of --kubectl-flags, such as deployment of \"kubectl delete deployment\"")
+	cmd.Flags().Bool("comp-line", false, "Read --kubectl-flags and --prefix from $COMP_LINE and $COMP_POINT of bash completion, unless given")
+	cmd.Flags().StringP("selector", "l", "", "Label selector of printed objects, such as app=web,tier!=db")
+	cmd.Flags().String("regex", "", "Print only objects whose names match the regular expression")
+	cmd.Flags().String("phase", "", "Print only pods in the phase, such as Running")
+	cmd.Flags().Bool("ready-only", false, "Print only ready objects, such as nodes that are Ready and not cordoned")
+	cmd.Flags().BoolP("all-namespaces", "A", false, "Print objects of all namespaces, ignoring the namespace of the context")
+	cmd.Flags().String("sort-by", "", "Order of printed objects, one of: name, namespace, kind, creation. By default objects are printed in the order of the mirror")
+	cmd.Flags().Bool("reverse", false, "Print objects in descending order of --sort-by")
+	cmd.Flags().Bool("quiet", false, "Print no errors and warnings, only exit with non-zero code, by default taken from $"+quietEnv+". Completion scripts pass it")
+	cmd.Flags().Duration("timeout", defaultGetTimeout, "How long to wait for the mirror to connect and to answer")
+	cmd.Flags().Duration("max-stale", 0, "Fail when objects were last updated from the API server longer ago, 0 for no limit")
+	cmd.Flags().Bool("warn-stale", false, "Only warn when objects are older than --max-stale")
+	cmd.Flags().Bool("auto-start", false, "Start \"kubemrr watch --daemon\" for the current context when the mirror on loopback or a unix socket is not running")
+	cmd.Flags().Int("max-names", 500, "Maximum number of names printed for completion, with --prefix, --comp-line or --for-command, 0 for no limit. Longer lists are compressed to common prefixes")
 	return cmd
 }
 
 func RunGet(f Factory, cmd *cobra.Command, args []string) error {
+	if forCommand, _ := cmd.Flags().GetBool("for-command"); forCommand {
+		rawKubectlFlags, _ := cmd.Flags().GetString("kubectl-flags")
+		prefix, _ := cmd.Flags().GetString("prefix")
+		resource := commandResource(rawKubectlFlags, prefix)
+		if resource == "" {
+			return errors.New("the kubectl command does not name a resource type")
+		}
+		args = append([]string{resource}, args...)
+	}
 	if len(args) == 0 {
 		return errors.New("no resource type is given")
 	}
 
-	if len(args) > 1 {
-		return errors.New("only one argument is expected")
+	if len(args) > 2 {
+		return errors.New("at most two arguments are expected: resource type and pattern of names")
 	}
 
-	regex := "(po|pod|pods|svc|service|services|deployment|deployments|ns|namespace|namespaces|configmap|configmaps|no|node|nodes)"
-	argMatcher, err := regexp.Compile(regex)
+	output, err := cmd.Flags().GetString("output")
 	if err != nil {
-		return fmt.Errorf("unexpected error: %s", err)
+		return errors.New("could not parse value of --output")
+	}
+	var columns []customColumn
+	var tmpl *template.Template
+	if strings.HasPrefix(output, customColumnsPrefix) {
+		columns, err = parseCustomColumns(strings.TrimPrefix(output, customColumnsPrefix))
+		if err != nil {
+			return err
+		}
+		output = "custom-columns"
+	} else if strings.HasPrefix(output, goTemplatePrefix) {
+		tmpl, err = template.New("output").Parse(strings.TrimPrefix(output, goTemplatePrefix))
+		if err != nil {
+			return fmt.Errorf("invalid go-template: %s", err)
+		}
+		output = "go-template"
+	} else if output != "" && output != "wide" && output != "json" && output != "yaml" {
+		return fmt.Errorf("unsupported output format: %s", output)
 	}
 
-	if !argMatcher.MatchString(args[0]) {
-		return fmt.Errorf("unsupported resource type: %s", args[0])
+	if output == "" {
+		rawKubectlFlags, _ := cmd.Flags().GetString("kubectl-flags")
+		prefix, _ := cmd.Flags().GetString("prefix")
+		if completesPath(rawKubectlFlags, prefix) {
+			fmt.Fprintln(f.StdOut(), filesDirective)
+			return nil
+		}
+	}
+
+	if _, ok := kubeconfigResources[args[0]]; ok {
+		return runGetKubeconfig(f, cmd, args[0], output)
 	}
 
-	conf, err := f.HomeKubeconfig()
+	mrrConfig, err := GetMrrConfig(cmd)
 	if err != nil {
-		return fmt.Errorf("could not read kubeconfig: %s", err)
+		return err
+	}
+
+	//resources are resolved to kinds once the mirror is asked for short names that get does not know
+	var kinds []string
+	resources := ""
+	if args[0] == "ip" || args[0] == "ips" {
+		kinds = []string{"pod", "service"}
+		if output == "" {
+			output = "wide"
+		}
+	} else if args[0] == "container" || args[0] == "containers" {
+		if len(args) < 2 {
+			return errors.New("name of the pod is required to get its containers")
+		}
+		if output != "" {
+			return fmt.Errorf("output format %s is not supported for %s", output, args[0])
+		}
+		kinds = []string{"pod"}
+		output = "containers"
+	} else if args[0] == "port" || args[0] == "ports" {
+		if len(args) < 2 {
+			return errors.New("the pod or service is required to get its ports, such as svc/web")
+		}
+		if output != "" {
+			return fmt.Errorf("output format %s is not supported for %s", output, args[0])
+		}
+		kind := "pod"
+		if resource := typeOf(args[1]); resource != args[1] {
+			if kind, err = resourceKind(resource); err != nil {
+				return err
+			}
+		}
+		kinds = []string{kind}
+		output = "ports"
+	} else if args[0] == "kind" || args[0] == "kinds" {
+		if output != "" {
+			return fmt.Errorf("output format %s is not supported for %s", output, args[0])
+		}
+		kinds = []string{}
+		output = "kinds"
+	} else if args[0] == "image" || args[0] == "images" {
+		if output != "" && output != "wide" {
+			return fmt.Errorf("output format %s is not supported for %s", output, args[0])
+		}
+		kinds = []string{"pod", "deployment"}
+		output = "images" + output
+	} else if args[0] == "label" || args[0] == "labels" {
+		if output != "" {
+			return fmt.Errorf("output format %s is not supported for %s", output, args[0])
+		}
+		if len(args) > 1 {
+			resources = args[1]
+		} else {
+			kinds = allKinds
+		}
+		output = "labels"
+	} else {
+		resources = args[0]
+	}
+	if resources != "" && !needsDiscovery(resources, mrrConfig.Aliases) {
+		if kinds, err = resourceKinds(resources, mrrConfig.Aliases, nil); err != nil {
+			return err
+		}
 	}
 
 	rawKubectlFlags, err := cmd.Flags().GetString("kubectl-flags")
@@ -76,79 +220,619 @@ func RunGet(f Factory, cmd *cobra.Command, args []string) error {
 	}
 	kubectlFlags := parseKubectlFlags(rawKubectlFlags)
 
-	bind, err := GetBind(cmd)
+	conf, err := kubectlKubeconfig(f, kubectlFlags)
 	if err != nil {
-		return fmt.Errorf("unexpected error: %s", err)
+		return fmt.Errorf("could not read kubeconfig: %s", err)
+	}
+	if output == "labels" {
+		//the selector of "kubectl-flags" is the one being completed
+		kubectlFlags.selector = ""
 	}
 
-	client, err := f.MrrClient(bind)
+	dirConfig, err := findDirConfig(".")
 	if err != nil {
-		return fmt.Errorf("could not create client to kubemrr: %s", err)
+		return err
+	}
+	if kubectlFlags.context == "" && kubectlFlags.user == "" {
+		kubectlFlags.context = dirConfig.Context
+	}
+	if kubectlFlags.namespace == "" {
+		kubectlFlags.namespace = dirConfig.Namespace
 	}
 
-	if strings.HasPrefix(args[0], "p") {
-		err = outputNames(client, makeFilterFor("pod", &conf, kubectlFlags), f.StdOut())
-	} else if strings.HasPrefix(args[0], "s") {
-		err = outputNames(client, makeFilterFor("service", &conf, kubectlFlags), f.StdOut())
-	} else if strings.HasPrefix(args[0], "c") {
-		err = outputNames(client, makeFilterFor("configmap", &conf, kubectlFlags), f.StdOut())
-	} else if strings.HasPrefix(args[0], "na") || args[0] == "ns" {
-		err = outputNames(client, makeFilterFor("namespace", &conf, kubectlFlags), f.StdOut())
-	} else if strings.HasPrefix(args[0], "no") {
-		err = outputNames(client, makeFilterFor("node", &conf, kubectlFlags), f.StdOut())
-	} else {
-		err = outputNames(client, makeFilterFor("deployment", &conf, kubectlFlags), f.StdOut())
+	sortBy, err := cmd.Flags().GetString("sort-by")
+	if err != nil {
+		return errors.New("could not parse value of --sort-by")
+	}
+	if _, ok := objectOrders[sortBy]; !ok && sortBy != "" {
+		return fmt.Errorf("unsupported value of --sort-by: %s, expected name, namespace, kind or creation", sortBy)
+	}
+	reverse, err := cmd.Flags().GetBool("reverse")
+	if err != nil {
+		return errors.New("could not parse value of --reverse")
+	}
+
+	selector, err := cmd.Flags().GetString("selector")
+	if err != nil {
+		return errors.New("could not parse value of --selector")
+	}
+	if selector != "" {
+		kubectlFlags.selector = selector
+	}
+
+	if allNamespaces, _ := cmd.Flags().GetBool("all-namespaces"); allNamespaces {
+		kubectlFlags.allNamespaces = true
+	}
+	if _, err := parseSelector(kubectlFlags.selector); err != nil {
+		return err
+	}
+
+	names := MrrFilter{}
+	if len(args) > 1 && output != "labels" {
+		names.Names = nameOf(args[1])
+	}
+	names.NameRegex, err = cmd.Flags().GetString("regex")
+	if err != nil {
+		return errors.New("could not parse value of --regex")
+	}
+	if _, err := nameMatcher(&names); err != nil {
+		return err
+	}
+	phase, err := cmd.Flags().GetString("phase")
+	if err != nil {
+		return errors.New("could not parse value of --phase")
+	}
+	readyOnly, err := cmd.Flags().GetBool("ready-only")
+	if err != nil {
+		return errors.New("could not parse value of --ready-only")
+	}
+
+	bind, err := GetMirrorAddress(cmd)
+	if err != nil {
+		return fmt.Errorf("unexpected error: %s", err)
 	}
 
+	profile, err := GetProfile(cmd)
+	if err != nil {
+		return errors.New("could not parse value of --profile")
+	}
+
+	opts, err := GetMrrClientOptions(cmd)
 	if err != nil {
 		return err
 	}
+	if opts.Timeout, err = cmd.Flags().GetDuration("timeout"); err != nil || opts.Timeout <= 0 {
+		return errors.New("--timeout must be a positive duration")
+	}
+
+	client, err := f.MrrClient(bind, opts)
+	if autoStart, _ := cmd.Flags().GetBool("auto-start"); err != nil && autoStart {
+		context := kubectlFlags.context
+		if context == "" {
+			context = conf.CurrentContext
+		}
+		client, err = autoStartMirror(f, cmd, kubectlFlags.kubeconfig, context, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("could not create client to kubemrr: %s", err)
+	}
+	deadline := time.Now().Add(opts.Timeout)
+
+	if kinds == nil {
+		filter := makeFilterFor("", &conf, kubectlFlags)
+		filter.Profile = profile
+		filter.Deadline = deadline
+		discovered, err := client.Resources(filter)
+		if err != nil {
+			log.WithField("error", err).Debug("could not get short names of resources from the mirror")
+		}
+		if kinds, err = resourceKinds(resources, mrrConfig.Aliases, resourceNames(discovered)); err != nil {
+			return err
+		}
+	}
+
+	if output == "kinds" {
+		filter := makeFilterFor("", &conf, kubectlFlags)
+		filter.Profile = profile
+		filter.Deadline = deadline
+		discovered, err := client.Resources(filter)
+		if err != nil {
+			log.WithField("error", err).Debug("could not get resources from the mirror")
+		}
+		prefix, err := cmd.Flags().GetString("prefix")
+		if err != nil {
+			return errors.New("could not parse value of --prefix")
+		}
+		return outputKinds(discovered, mrrConfig.Aliases, prefix, f.StdOut())
+	}
+
+	onlyNames := output == "" && sortBy == ""
+	objects := []KubeObject{}
+	lists := []kindNames{}
+	for _, kind := range kinds {
+		filter := makeFilterFor(kind, &conf, kubectlFlags)
+		filter.Profile = profile
+		filter.Names, filter.NameRegex = names.Names, names.NameRegex
+		filter.Phase = phase
+		filter.ReadyOnly = readyOnly
+		filter.Deadline = deadline
+		if onlyNames {
+			names, err := client.Names(filter)
+			if err != nil {
+				return err
+			}
+			log.WithField("filter", filter).WithField("names", len(names)).Debug("got names")
+			lists = append(lists, kindNames{kind: kind, names: names})
+		} else {
+			res, err := client.Objects(filter)
+			if err != nil {
+				return err
+			}
+			log.
+				WithField("filter", filter).
+				WithField("objects", res).
+				Debugf("got objects")
+			for _, o := range res {
+				o.Kind = kind
+				objects = append(objects, o)
+			}
+		}
+		if err := checkStale(cmd, client, filter); err != nil {
+			return err
+		}
+	}
+
+	sortObjects(objects, sortBy, reverse)
+
+	switch output {
+	case "":
+		prefix, err := cmd.Flags().GetString("prefix")
+		if err != nil {
+			return errors.New("could not parse value of --prefix")
+		}
+		maxNames, err := cmd.Flags().GetInt("max-names")
+		if err != nil {
+			return errors.New("could not parse value of --max-names")
+		}
+		gates, err := GetFeatureGates(cmd)
+		if err != nil {
+			return err
+		}
+		if !gates.Enabled(NameCompression) || !completing(cmd) {
+			maxNames = 0
+		}
+		if !onlyNames {
+			lists = namesOf(objects)
+		}
+		return outputNames(lists, len(kinds) > 1, prefix, maxNames, f.StdOut())
+	case "images":
+		prefix, err := cmd.Flags().GetString("prefix")
+		if err != nil {
+			return errors.New("could not parse value of --prefix")
+		}
+		return outputImages(objects, prefix, f.StdOut())
+	case "imageswide":
+		return outputImagesWide(objects, f.StdOut())
+	case "labels":
+		prefix, err := cmd.Flags().GetString("prefix")
+		if err != nil {
+			return errors.New("could not parse value of --prefix")
+		}
+		return outputLabels(objects, prefix, f.StdOut())
+	case "containers":
+		prefix, err := cmd.Flags().GetString("prefix")
+		if err != nil {
+			return errors.New("could not parse value of --prefix")
+		}
+		return outputContainers(objects, prefix, f.StdOut())
+	case "ports":
+		prefix, err := cmd.Flags().GetString("prefix")
+		if err != nil {
+			return errors.New("could not parse value of --prefix")
+		}
+		return outputPorts(objects, prefix, f.StdOut())
+	case "json":
+		return outputJSON(objects, f.StdOut())
+	case "yaml":
+		return outputYAML(objects, f.StdOut())
+	case "custom-columns":
+		return outputCustomColumns(objects, columns, f.StdOut())
+	case "go-template":
+		if err := tmpl.Execute(f.StdOut(), objects); err != nil {
+			return fmt.Errorf("could not execute go-template: %s", err)
+		}
+		return nil
+	}
+	return outputWide(objects, conf.clusterNamesByServer(), time.Now(), f.StdOut())
+}
 
+//objectOrders compare objects by the values of --sort-by. Objects that compare equal are ordered by
+//kind, namespace and name, so that the order does not change from run to run
+var objectOrders = map[string]func(a *KubeObject, b *KubeObject) int{
+	"name":      func(a *KubeObject, b *KubeObject) int { return strings.Compare(a.Name, b.Name) },
+	"namespace": func(a *KubeObject, b *KubeObject) int { return strings.Compare(a.Namespace, b.Namespace) },
+	"kind":      func(a *KubeObject, b *KubeObject) int { return strings.Compare(a.Kind, b.Kind) },
+	"creation": func(a *KubeObject, b *KubeObject) int {
+		switch {
+		case a.CreationTimestamp.Before(b.CreationTimestamp):
+			return -1
+		case a.CreationTimestamp.After(b.CreationTimestamp):
+			return 1
+		}
+		return 0
+	},
+}
+
+//sortObjects orders the objects by the value of --sort-by, descending when reverse is set.
+//Empty or unknown value keeps the order
+func sortObjects(objects []KubeObject, sortBy string, reverse bool) {
+	order, ok := objectOrders[sortBy]
+	if !ok {
+		return
+	}
+	ties := []string{"kind", "namespace", "name"}
+	sort.SliceStable(objects, func(i, j int) bool {
+		c := order(&objects[i], &objects[j])
+		for _, tie := range ties {
+			if c != 0 {
+				break
+			}
+			c = objectOrders[tie](&objects[i], &objects[j])
+		}
+		if reverse {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+//goTemplatePrefix starts the output format that prints objects with a Go template
+const goTemplatePrefix = "go-template="
+
+//completing tells whether get prints names for a completion script rather than for a person,
+//who expects every name
+func completing(cmd *cobra.Command) bool {
+	compLine, _ := cmd.Flags().GetBool("comp-line")
+	forCommand, _ := cmd.Flags().GetBool("for-command")
+	return compLine || forCommand || cmd.Flags().Changed("prefix")
+}
+
+//applyCompLine sets --kubectl-flags to the command line being completed, up to the cursor, and --prefix
+//to the word under the cursor, as bash gives them to completion functions. Flags that are given are kept
+func applyCompLine(cmd *cobra.Command) error {
+	if compLine, _ := cmd.Flags().GetBool("comp-line"); !compLine {
+		return nil
+	}
+	line := []rune(os.Getenv("COMP_LINE"))
+	if point, err := strconv.Atoi(os.Getenv("COMP_POINT")); err == nil && point >= 0 && point < len(line) {
+		line = line[:point]
+	}
+
+	prefix := ""
+	if words := splitKubectlLine(string(line)); len(words) > 0 && !strings.ContainsAny(string(line[len(line)-1:]), " \t\n") {
+		prefix = words[len(words)-1]
+	}
+	if !cmd.Flags().Changed("kubectl-flags") {
+		if err := cmd.Flags().Set("kubectl-flags", string(line)); err != nil {
+			return err
+		}
+	}
+	if !cmd.Flags().Changed("prefix") {
+		if err := cmd.Flags().Set("prefix", prefix); err != nil {
+			return err
+		}
+	}
+	log.WithField("line", string(line)).WithField("prefix", prefix).Debug("read command line of completion")
 	return nil
 }
 
-type KubectlFlags struct {
-	namespace string
-	context   string
-	cluster   string
-	server    string
+//quietEnv is the environment variable that makes get quiet when --quiet is not given
+const quietEnv = "KUBEMRR_QUIET"
+
+//GetQuiet tells whether errors must not be printed. Completion scripts run get on every press of tab,
+//and whatever it prints ends up in the middle of the prompt
+func GetQuiet(cmd *cobra.Command) (bool, error) {
+	if cmd.Flags().Changed("quiet") {
+		return cmd.Flags().GetBool("quiet")
+	}
+	quiet, _ := strconv.ParseBool(os.Getenv(quietEnv))
+	return quiet, nil
 }
 
-var (
-	namespaceFlagRegex = regexp.MustCompile(`--namespace[ =]([\S]+)`)
-	serverFlagRegex    = regexp.MustCompile(`--server[ =]([\S]+)`)
-	contextFlagRegex   = regexp.MustCompile(`--context[ =]([\S]+)`)
-	clusterFlagRegex   = regexp.MustCompile(`--cluster[ =]([\S]+)`)
-)
+//silence stops the command from printing its error and discards logs
+func silence(cmd *cobra.Command) {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	log.SetOutput(ioutil.Discard)
+}
 
-func parseKubectlFlags(in string) *KubectlFlags {
-	res := KubectlFlags{}
+//defaultGetTimeout is how long get waits for the mirror by default. Get runs on every press of tab,
+//so it must give up before the user notices the shell is stuck
+const defaultGetTimeout = time.Second
+
+//autoStartTimeout is how long "get --auto-start" waits for the started mirror
+const autoStartTimeout = 5 * time.Second
+
+//autoStartMirror starts the mirror of the context in background on the address given by flags, and connects to it.
+//The mirror reads the given kubeconfig, or the one of --kubeconfig when it is empty.
+//Mirrors are started only on this host, so the address must be loopback or a unix socket
+func autoStartMirror(f Factory, cmd *cobra.Command, kubeconfig string, context string, opts MrrClientOptions) (MrrClient, error) {
+	bind, err := GetBind(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if !isLoopback(bind) {
+		return nil, fmt.Errorf("mirror at %s is not running and cannot be started on another host", bind)
+	}
+	if kubeconfig == "" {
+		if kubeconfig, err = cmd.Flags().GetString("kubeconfig"); err != nil {
+			return nil, errors.New("could not parse value of --kubeconfig")
+		}
+	}
+
+	args := []string{"--kubeconfig=" + kubeconfig, "--bind=" + bind}
+	if context != "" {
+		args = append(args, context)
+	} else {
+		args = append(args, "--all-contexts")
+	}
+	log.WithField("bind", bind).WithField("context", context).Info("mirror is not running, starting it")
+	if err := f.StartMirror(args); err != nil {
+		return nil, fmt.Errorf("could not start the mirror: %s", err)
+	}
+	return f.MrrClient(bind, opts)
+}
+
+var resourceMatcher = regexp.MustCompile("(po|pod|pods|svc|service|services|deployment|deployments|ns|namespace|namespaces|configmap|configmaps|no|node|nodes)")
 
-	for _, matches := range namespaceFlagRegex.FindAllStringSubmatch(in, -1) {
-		res.namespace = matches[1]
+//resourceKinds returns kinds of the resource types given in the command line separated by commas,
+//such as "po,svc" or "all", without repetitions. Aliases of the --config file are resolved first,
+//then names that get knows, then names discovered on servers, such as "pvc"
+func resourceKinds(resources string, aliases map[string]string, discovered map[string]string) ([]string, error) {
+	kinds := []string{}
+	for _, resource := range strings.Split(resources, ",") {
+		given := []string{}
+		if resource == "all" {
+			given = allKinds
+		} else if alias, ok := aliases[resource]; ok {
+			given = append(given, aliasKind(alias))
+		} else if kind, ok := discovered[strings.ToLower(resource)]; ok && !knownResource.MatchString(resource) {
+			given = append(given, kind)
+		} else {
+			kind, err := resourceKind(resource)
+			if err != nil {
+				return nil, err
+			}
+			given = append(given, kind)
+		}
+		for _, kind := range given {
+			if !containsString(kinds, kind) {
+				kinds = append(kinds, kind)
+			}
+		}
 	}
+	return kinds, nil
+}
 
-	for _, matches := range serverFlagRegex.FindAllStringSubmatch(in, -1) {
-		res.server = matches[1]
+//needsDiscovery tells if some of the resources separated by commas are neither aliases nor names that get knows
+func needsDiscovery(resources string, aliases map[string]string) bool {
+	for _, resource := range strings.Split(resources, ",") {
+		if _, ok := aliases[resource]; !ok && resource != "all" && !knownResource.MatchString(resource) {
+			return true
+		}
 	}
+	return false
+}
+
+//knownResource matches names of resources that get knows, and nothing else
+var knownResource = regexp.MustCompile("^" + resourceMatcher.String() + "$")
 
-	for _, matches := range contextFlagRegex.FindAllStringSubmatch(in, -1) {
-		res.context = matches[1]
+//aliasKind returns the kind of the resource an alias stands for. Resources that get does not
+//know are taken as kinds, such as custom resources
+func aliasKind(resource string) string {
+	if knownResource.MatchString(resource) {
+		kind, _ := resourceKind(resource)
+		return kind
+	}
+	return strings.ToLower(resource)
+}
+
+//completedResourceTypes are resource types printed by "get kinds" even when the mirror discovered none
+var completedResourceTypes = []string{"configmaps", "deployments", "namespaces", "nodes", "pods", "services"}
+
+//allKinds are the kinds given by the "all" resource, in the order their objects are printed
+var allKinds = []string{"pod", "service", "deployment", "configmap", "namespace", "node"}
+
+//resourceKind returns the kind of the resource type given in the command line, such as "po" or "services"
+func resourceKind(resource string) (string, error) {
+	if !resourceMatcher.MatchString(resource) {
+		return "", fmt.Errorf("unsupported resource type: %s", resource)
 	}
 
-	for _, matches := range clusterFlagRegex.FindAllStringSubmatch(in, -1) {
-		res.cluster = matches[1]
+	switch {
+	case strings.HasPrefix(resource, "p"):
+		return "pod", nil
+	case strings.HasPrefix(resource, "s"):
+		return "service", nil
+	case strings.HasPrefix(resource, "c"):
+		return "configmap", nil
+	case strings.HasPrefix(resource, "na") || resource == "ns":
+		return "namespace", nil
+	case strings.HasPrefix(resource, "no"):
+		return "node", nil
+	default:
+		return "deployment", nil
+	}
+}
+
+type KubectlFlags struct {
+	namespace  string
+	context    string
+	cluster    string
+	server     string
+	selector   string
+	kubeconfig string
+	user       string
+
+	allNamespaces bool
+}
+
+//kubectlValueFlags maps flags of kubectl whose values scope queries to the fields of KubectlFlags
+var kubectlValueFlags = map[string]func(res *KubectlFlags) *string{
+	"--namespace":  func(res *KubectlFlags) *string { return &res.namespace },
+	"-n":           func(res *KubectlFlags) *string { return &res.namespace },
+	"--server":     func(res *KubectlFlags) *string { return &res.server },
+	"--context":    func(res *KubectlFlags) *string { return &res.context },
+	"--cluster":    func(res *KubectlFlags) *string { return &res.cluster },
+	"--selector":   func(res *KubectlFlags) *string { return &res.selector },
+	"-l":           func(res *KubectlFlags) *string { return &res.selector },
+	"--kubeconfig": func(res *KubectlFlags) *string { return &res.kubeconfig },
+	"--user":       func(res *KubectlFlags) *string { return &res.user },
+}
+
+func parseKubectlFlags(in string) *KubectlFlags {
+	res := KubectlFlags{}
+
+	words := splitKubectlLine(in)
+	for i := 0; i < len(words); i++ {
+		name, value, hasValue := words[i], "", false
+		if j := strings.Index(name, "="); j > 0 && strings.HasPrefix(name, "-") {
+			name, value, hasValue = name[:j], name[j+1:], true
+		} else if strings.HasPrefix(name, "-n") && !strings.HasPrefix(name, "--") && len(name) > 2 {
+			//shorthand with glued value, such as -nkube-system
+			name, value, hasValue = "-n", name[2:], true
+		}
+
+		if name == "--all-namespaces" || name == "-A" {
+			res.allNamespaces = !hasValue || value == "true"
+			continue
+		}
+		field, ok := kubectlValueFlags[name]
+		if !ok {
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(words) {
+				//the value is being typed
+				continue
+			}
+			i++
+			value = words[i]
+		}
+		if value != "" {
+			*field(&res) = value
+		}
 	}
 
 	log.WithField("in", in).WithField("out", res).Debug("parsed kubectl flags")
 	return &res
 }
 
+//splitKubectlLine splits the command line into words the way the shell does,
+//so that quoted values with spaces, such as --namespace 'my ns', are kept whole.
+//Unterminated quotes end with the line, as the word is still being typed
+func splitKubectlLine(in string) []string {
+	words := []string{}
+	word := []rune{}
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range in {
+		switch {
+		case escaped:
+			word = append(word, r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word = append(word, r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, string(word))
+				word, inWord = word[:0], false
+			}
+		default:
+			word = append(word, r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+//kubectlResourceVerbs are commands of kubectl that are followed by the resource type and names of objects,
+//mapped to the number of their subcommands, such as "status" of "kubectl rollout status deployment"
+var kubectlResourceVerbs = map[string]int{
+	"get":       0,
+	"describe":  0,
+	"delete":    0,
+	"edit":      0,
+	"label":     0,
+	"annotate":  0,
+	"patch":     0,
+	"scale":     0,
+	"expose":    0,
+	"autoscale": 0,
+	"top":       0,
+	"wait":      0,
+	"taint":     0,
+	"rollout":   1,
+	"set":       1,
+}
+
+//kubectlNodeVerbs are commands of kubectl that are followed by the name of a node. The value
+//tells whether only ready nodes are completed: cordoned nodes are not drained or cordoned again
+var kubectlNodeVerbs = map[string]bool{"drain": true, "cordon": true, "uncordon": false}
+
+//kubectlFlagsWithValue are flags of kubectl whose values may be given as separate words
+var kubectlFlagsWithValue = map[string]bool{
+	"-o": true, "--output": true, "-c": true, "--container": true, "-f": true, "--filename": true,
+	"--field-selector": true, "--sort-by": true, "--template": true, "--timeout": true,
+	"--grace-period": true, "-p": true, "--patch": true, "--type": true, "--replicas": true,
+	"--user": true, "--token": true, "--as": true, "--request-timeout": true,
+}
+
+//commandResource returns the resource type named in the kubectl command, such as deployment of
+//"kubectl delete deployment web". The word being completed is not the resource type yet.
+//Empty string is returned when the command has no known verb or no resource type
+func commandResource(line string, prefix string) string {
+	words := splitKubectlLine(line)
+	if prefix != "" && len(words) > 0 && words[len(words)-1] == prefix {
+		words = words[:len(words)-1]
+	}
+	verb, positional := commandVerb(words)
+	if _, ok := kubectlNodeVerbs[verb]; ok || verb == "" || kubectlPodVerbs[verb] || len(positional) == 0 {
+		return ""
+	}
+	return typeOf(positional[0])
+}
+
+//kubectlKubeconfig reads the kubeconfig given by --kubeconfig of "kubectl-flags",
+//so that names are completed from the same file that kubectl reads. Without it the home kubeconfig is read
+func kubectlKubeconfig(f Factory, flags *KubectlFlags) (Config, error) {
+	if flags.kubeconfig != "" {
+		return parseKubeConfig(flags.kubeconfig)
+	}
+	return f.HomeKubeconfig()
+}
+
 func makeFilterFor(kind string, conf *Config, flags *KubectlFlags) MrrFilter {
 	f := MrrFilter{}
 	if conf != nil {
 		if flags != nil && flags.context != "" {
 			conf.CurrentContext = flags.context
+		} else if flags != nil && flags.user != "" {
+			if context := conf.userContext(flags.user, flags.cluster); context != "" {
+				conf.CurrentContext = context
+			}
 		}
 		f = conf.makeFilter()
 	}
@@ -162,32 +846,454 @@ func makeFilterFor(kind string, conf *Config, flags *KubectlFlags) MrrFilter {
 		if flags.server != "" {
 			f.Server = flags.server
 		}
+		f.Selector = flags.selector
 	}
 	f.Kind = kind
 
-	if kind == "node" {
+	if kind == "node" || (flags != nil && flags.allNamespaces) {
 		f.Namespace = ""
 	}
 
 	return f
 }
 
-func outputNames(c MrrClient, f MrrFilter, out io.Writer) error {
-	objects, err := c.Objects(f)
+//checkStale asks the mirror when objects of the filter were last updated from API servers, and fails
+//when it was longer ago than --max-stale. With --warn-stale it only warns
+func checkStale(cmd *cobra.Command, client MrrClient, filter MrrFilter) error {
+	maxStale, err := cmd.Flags().GetDuration("max-stale")
 	if err != nil {
-		return err
+		return errors.New("could not parse value of --max-stale")
+	}
+	if maxStale <= 0 {
+		return nil
+	}
+
+	synced, err := client.Synced(filter)
+	if err != nil {
+		return fmt.Errorf("could not tell when objects were updated: %s", err)
 	}
-	log.
-		WithField("filter", f).
-		WithField("objects", objects).
-		Debugf("got objects")
+	log.WithField("kind", filter.Kind).WithField("synced", synced).Debug("got sync time")
 
-	for i, o := range objects {
-		if i != 0 {
-			out.Write([]byte(" "))
+	var stale error
+	if synced.IsZero() {
+		stale = fmt.Errorf("objects of %s were never updated from the API server", filter.Kind)
+	} else if age := time.Since(synced); age > maxStale {
+		stale = fmt.Errorf("objects of %s were last updated at %s, %s ago, more than --max-stale=%s",
+			filter.Kind, synced.Format(time.RFC3339), age.Truncate(time.Second), maxStale)
+	}
+	if stale != nil {
+		if warn, _ := cmd.Flags().GetBool("warn-stale"); warn {
+			log.Warn(stale)
+			return nil
 		}
-		out.Write([]byte(o.Name))
 	}
+	return stale
+}
 
-	return nil
+//outputContainers prints names of containers of the pods that start with the prefix. Pods of the
+//same name in several namespaces or clusters usually run the same containers, so names are printed once
+func outputContainers(pods []KubeObject, prefix string, out io.Writer) error {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, o := range pods {
+		for _, name := range o.ContainerNames() {
+			if !seen[name] && strings.HasPrefix(name, prefix) {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	_, err := fmt.Fprint(out, strings.Join(names, " "))
+	return err
+}
+
+//outputKinds prints resource types that start with the prefix in alphabetical order: the ones get supports,
+//aliases of the config and plural names of resources discovered on the servers, custom resources included
+func outputKinds(discovered []APIResource, aliases map[string]string, prefix string, out io.Writer) error {
+	seen := map[string]bool{}
+	names := []string{}
+	add := func(name string) {
+		if !seen[name] && strings.HasPrefix(name, prefix) {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, name := range completedResourceTypes {
+		add(name)
+	}
+	for alias := range aliases {
+		add(alias)
+	}
+	for _, r := range discovered {
+		add(strings.ToLower(r.Name))
+	}
+	sort.Strings(names)
+	_, err := fmt.Fprint(out, strings.Join(names, " "))
+	return err
+}
+
+//outputPorts prints distinct ports of the objects that start with the prefix, in ascending order
+func outputPorts(objects []KubeObject, prefix string, out io.Writer) error {
+	ports := []int{}
+	seen := map[int]bool{}
+	for _, o := range objects {
+		for _, port := range o.PortNumbers() {
+			if !seen[port] && strings.HasPrefix(strconv.Itoa(port), prefix) {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+	sort.Ints(ports)
+	res := []string{}
+	for _, port := range ports {
+		res = append(res, strconv.Itoa(port))
+	}
+	_, err := fmt.Fprint(out, strings.Join(res, " "))
+	return err
+}
+
+//outputImages prints distinct images of the pods and deployments that start with the prefix, sorted
+func outputImages(objects []KubeObject, prefix string, out io.Writer) error {
+	images := []string{}
+	for _, o := range objects {
+		for _, image := range o.Images() {
+			if strings.HasPrefix(image, prefix) && !containsString(images, image) {
+				images = append(images, image)
+			}
+		}
+	}
+	sort.Strings(images)
+	_, err := fmt.Fprint(out, strings.Join(images, " "))
+	return err
+}
+
+//outputImagesWide prints a table of images and the pods and deployments that run them
+func outputImagesWide(objects []KubeObject, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "IMAGE\tNAMESPACE\tKIND\tNAME")
+	rows := []string{}
+	for _, o := range objects {
+		for _, image := range o.Images() {
+			rows = append(rows, fmt.Sprintf("%s\t%s\t%s\t%s\n", image, valueOrNone(o.Namespace), o.Kind, o.Name))
+		}
+	}
+	sort.Strings(rows)
+	for _, row := range rows {
+		fmt.Fprint(w, row)
+	}
+	return w.Flush()
+}
+
+//outputLabels prints distinct keys of labels of the objects followed by distinct key=value pairs,
+//each sorted, that start with the prefix
+func outputLabels(objects []KubeObject, prefix string, out io.Writer) error {
+	keys := map[string]bool{}
+	pairs := map[string]bool{}
+	for _, o := range objects {
+		for k, v := range o.Labels {
+			keys[k] = true
+			pairs[k+"="+v] = true
+		}
+	}
+
+	names := []string{}
+	for _, set := range []map[string]bool{keys, pairs} {
+		sorted := []string{}
+		for s := range set {
+			if strings.HasPrefix(s, prefix) {
+				sorted = append(sorted, s)
+			}
+		}
+		sort.Strings(sorted)
+		names = append(names, sorted...)
+	}
+	_, err := fmt.Fprint(out, strings.Join(names, " "))
+	return err
+}
+
+//kubeconfigResources are resources of get that are read from the kubeconfig file
+//instead of the mirror, to complete flags of kubectl such as --context
+var kubeconfigResources = map[string]func(c *Config) []string{
+	"context":  (*Config).contextNames,
+	"contexts": (*Config).contextNames,
+	"cluster":  (*Config).clusterNames,
+	"clusters": (*Config).clusterNames,
+	"user":     (*Config).userNames,
+	"users":    (*Config).userNames,
+}
+
+//runGetKubeconfig prints names read from the kubeconfig file that start with --prefix
+func runGetKubeconfig(f Factory, cmd *cobra.Command, resource string, output string) error {
+	if output != "" {
+		return fmt.Errorf("output format %s is not supported for %s", output, resource)
+	}
+	rawKubectlFlags, err := cmd.Flags().GetString("kubectl-flags")
+	if err != nil {
+		return fmt.Errorf("unexpected error: %s", err)
+	}
+	conf, err := kubectlKubeconfig(f, parseKubectlFlags(rawKubectlFlags))
+	if err != nil {
+		return fmt.Errorf("could not read kubeconfig: %s", err)
+	}
+	prefix, err := cmd.Flags().GetString("prefix")
+	if err != nil {
+		return errors.New("could not parse value of --prefix")
+	}
+
+	res := []string{}
+	for _, name := range kubeconfigResources[resource](&conf) {
+		if strings.HasPrefix(name, prefix) {
+			res = append(res, name)
+		}
+	}
+	_, err = fmt.Fprint(f.StdOut(), strings.Join(res, " "))
+	return err
+}
+
+//kindNames are names of objects of a kind. Completion asks the mirror only for names,
+//so they are printed without making objects of them
+type kindNames struct {
+	kind  string
+	names []string
+}
+
+//namesOf groups names of the objects by kind, keeping the order of the objects
+func namesOf(objects []KubeObject) []kindNames {
+	lists := []kindNames{}
+	for _, o := range objects {
+		if len(lists) == 0 || lists[len(lists)-1].kind != o.Kind {
+			lists = append(lists, kindNames{kind: o.Kind})
+		}
+		last := &lists[len(lists)-1]
+		last.names = append(last.names, o.Name)
+	}
+	return lists
+}
+
+//namesBuffers are reused to print names, so that completion does not build a string of all names
+var namesBuffers = sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
+
+//outputNames prints names that start with the prefix. Names are prefixed with kinds, such as "pod/web",
+//when objects of several kinds are printed. They are written to out at once from a pooled buffer,
+//unless there are more than maxNames of them and they have to be compressed first
+func outputNames(lists []kindNames, withKind bool, prefix string, maxNames int, out io.Writer) error {
+	if maxNames > 0 {
+		n := 0
+		for _, l := range lists {
+			for _, name := range l.names {
+				if hasNamePrefix(l.kind, name, withKind, prefix) {
+					n++
+				}
+			}
+		}
+		if n > maxNames {
+			names := make([]string, 0, n)
+			for _, l := range lists {
+				for _, name := range l.names {
+					if hasNamePrefix(l.kind, name, withKind, prefix) {
+						if withKind {
+							name = l.kind + "/" + name
+						}
+						names = append(names, name)
+					}
+				}
+			}
+			_, err := io.WriteString(out, strings.Join(compressNames(names, prefix, maxNames), " "))
+			return err
+		}
+	}
+
+	buf := namesBuffers.Get().(*bytes.Buffer)
+	defer namesBuffers.Put(buf)
+	buf.Reset()
+	for _, l := range lists {
+		for _, name := range l.names {
+			if !hasNamePrefix(l.kind, name, withKind, prefix) {
+				continue
+			}
+			if buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			if withKind {
+				buf.WriteString(l.kind)
+				buf.WriteByte('/')
+			}
+			buf.WriteString(name)
+		}
+	}
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+//hasNamePrefix checks whether the name, prefixed with the kind when withKind is set, starts with the prefix
+func hasNamePrefix(kind string, name string, withKind bool, prefix string) bool {
+	if !withKind {
+		return strings.HasPrefix(name, prefix)
+	}
+	if len(prefix) <= len(kind) {
+		return strings.HasPrefix(kind, prefix)
+	}
+	return prefix[:len(kind)] == kind && prefix[len(kind)] == '/' && strings.HasPrefix(name, prefix[len(kind)+1:])
+}
+
+//nameDelimiters separate segments of names, such as "web-5d8f7-x2x9k" or "kube-dns.kube-system"
+const nameDelimiters = "-."
+
+//compressNames returns the names if there are at most max of them. Otherwise it returns at most max
+//candidates, which are either names or prefixes shared by several names. Prefixes are cut after
+//delimiters, going as many segments deep as the limit allows. All names start with the typed prefix
+func compressNames(names []string, typed string, max int) []string {
+	if max <= 0 || len(names) <= max {
+		return names
+	}
+
+	//names of objects in different namespaces or servers repeat
+	common := longestCommonPrefix(names)
+	if distinct := namesAtDepth(names, len(common), 0); len(distinct) <= max {
+		return distinct
+	}
+
+	prev := []string{common}
+	for depth := 1; ; depth++ {
+		level := namesAtDepth(names, len(common), depth)
+		if len(level) <= max {
+			prev = level
+			continue
+		}
+		if depth == 1 && len(common) <= len(typed) {
+			//even the first segments do not fit, and the common prefix is already typed
+			return level[:max]
+		}
+		return prev
+	}
+}
+
+//namesAtDepth groups names by their prefixes that end with the depth-th delimiter after the start.
+//A group of one name gives the name itself, a larger group gives the longest prefix of its names.
+//Depth 0 gives the sorted names without repetitions
+func namesAtDepth(names []string, start int, depth int) []string {
+	groups := map[string][]string{}
+	keys := []string{}
+	for _, name := range names {
+		key := name
+		found := 0
+		for i := start; i < len(name); i++ {
+			if strings.IndexByte(nameDelimiters, name[i]) >= 0 {
+				found++
+				if found == depth {
+					key = name[:i+1]
+					break
+				}
+			}
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], name)
+	}
+
+	res := []string{}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		group := groups[key]
+		candidate := group[0]
+		if len(group) > 1 {
+			candidate = longestCommonPrefix(group)
+		}
+		if !seen[candidate] {
+			seen[candidate] = true
+			res = append(res, candidate)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func longestCommonPrefix(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	prefix := names[0]
+	for _, name := range names[1:] {
+		i := 0
+		for i < len(prefix) && i < len(name) && prefix[i] == name[i] {
+			i++
+		}
+		prefix = prefix[:i]
+	}
+	return prefix
+}
+
+//outputWide prints a table of namespaces, kinds, names, IPs, clusters and ages of the objects.
+//Clusters are named by the given names of servers, or by URLs of servers without names
+func outputWide(objects []KubeObject, clusters map[string]string, now time.Time, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tKIND\tNAME\tIP\tCLUSTER\tAGE")
+	for _, o := range objects {
+		cluster, ok := clusters[normalizeServerURL(o.Server)]
+		if !ok {
+			cluster = o.Server
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			valueOrNone(o.Namespace), o.Kind, o.Name, valueOrNone(o.IP()), valueOrNone(cluster), age(o.CreationTimestamp, now))
+	}
+	return w.Flush()
+}
+
+//age formats time since the object was created the way kubectl does, such as "45s", "12m", "5h" or "3d"
+func age(created time.Time, now time.Time) string {
+	if created.IsZero() {
+		return "<unknown>"
+	}
+	d := now.Sub(created)
+	switch {
+	case d < 0:
+		return "0s"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d < 2*365*24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	default:
+		return fmt.Sprintf("%dy", int(d.Hours()/24/365))
+	}
+}
+
+func outputJSON(objects []KubeObject, out io.Writer) error {
+	raw, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(raw, '\n'))
+	return err
+}
+
+//outputYAML prints the objects with the same fields as outputJSON
+func outputYAML(objects []KubeObject, out io.Writer) error {
+	raw, err := json.Marshal(objects)
+	if err != nil {
+		return err
+	}
+	//objects are converted through JSON, so that fields are named by their json tags
+	var generic []interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return err
+	}
+	raw, err = yaml.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(raw)
+	return err
+}
+
+func valueOrNone(v string) string {
+	if v == "" {
+		return "<none>"
+	}
+	return v
 }
@@ -2,10 +2,16 @@ package app
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRunGetInvalidArgs(t *testing.T) {
@@ -18,8 +24,8 @@ func TestRunGetInvalidArgs(t *testing.T) {
 			output: "no resource",
 		},
 		{
-			args:   []string{"1", "2"},
-			output: "one argument",
+			args:   []string{"1", "2", "3"},
+			output: "at most two arguments",
 		},
 		{
 			args:   []string{"k8s-resource"},
@@ -27,7 +33,7 @@ func TestRunGetInvalidArgs(t *testing.T) {
 		},
 	}
 
-	f := &TestFactory{}
+	f := &TestFactory{mrrClient: &TestMirrorClient{}}
 	cmd := NewGetCommand(f)
 
 	for i, test := range tests {
@@ -91,6 +97,10 @@ func TestRunGet(t *testing.T) {
 			if err != nil {
 				t.Errorf("Running [get %v]: got error: %v", alias, err)
 			} else {
+				if tc.lastFilter.Deadline.IsZero() {
+					t.Errorf("Running [get %v]: expected deadline of the query", alias)
+				}
+				tc.lastFilter.Deadline = time.Time{}
 				if !reflect.DeepEqual(tc.lastFilter, test.expectedFilter) {
 					t.Errorf("Running [get %v]: expected filter %v, got %v", alias, test.expectedFilter, tc.lastFilter)
 				}
@@ -102,6 +112,97 @@ func TestRunGet(t *testing.T) {
 	}
 }
 
+func TestRunGetSeveralKinds(t *testing.T) {
+	tc := &TestMirrorClient{
+		objects: []KubeObject{
+			{ObjectMeta: ObjectMeta{Name: "o1"}},
+			{ObjectMeta: ObjectMeta{Name: "o2"}},
+		},
+	}
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+
+	err := cmd.RunE(cmd, []string{"po,svc,pods"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if buf.String() != "pod/o1 pod/o2 service/o1 service/o2" {
+		t.Errorf("Expected names prefixed with kinds, got [%v]", buf)
+	}
+
+	buf.Reset()
+	cmd.Flags().Set("prefix", "service/")
+	cmd.RunE(cmd, []string{"po,svc"})
+	if buf.String() != "service/o1 service/o2" {
+		t.Errorf("Expected names of services, got [%v]", buf)
+	}
+
+	buf.Reset()
+	cmd.Flags().Set("prefix", "")
+	cmd.RunE(cmd, []string{"all"})
+	if !strings.HasPrefix(buf.String(), "pod/o1 pod/o2 service/o1") || !strings.HasSuffix(buf.String(), "node/o1 node/o2") {
+		t.Errorf("Expected names of all kinds, got [%v]", buf)
+	}
+
+	err = cmd.RunE(cmd, []string{"po,k8s-resource"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported resource type") {
+		t.Errorf("Expected error about resource type, got %v", err)
+	}
+}
+
+func TestRunGetSortBy(t *testing.T) {
+	now := time.Now()
+	tc := &TestMirrorClient{
+		objects: []KubeObject{
+			{ObjectMeta: ObjectMeta{Name: "web", Namespace: "prod", CreationTimestamp: now.Add(-time.Hour)}},
+			{ObjectMeta: ObjectMeta{Name: "api", Namespace: "prod", CreationTimestamp: now}},
+			{ObjectMeta: ObjectMeta{Name: "web", Namespace: "dev", CreationTimestamp: now.Add(-2 * time.Hour)}},
+		},
+	}
+
+	tests := []struct {
+		args     []string
+		sortBy   string
+		reverse  bool
+		expected string
+	}{
+		{args: []string{"po"}, expected: "web api web"},
+		{args: []string{"po"}, sortBy: "name", expected: "api web web"},
+		{args: []string{"po"}, sortBy: "name", reverse: true, expected: "web web api"},
+		{args: []string{"po"}, sortBy: "creation", reverse: true, expected: "api web web"},
+		{args: []string{"po,svc"}, sortBy: "name", expected: "pod/api service/api pod/web pod/web service/web service/web"},
+		{args: []string{"po,svc"}, sortBy: "kind", reverse: true, expected: "service/web service/api service/web pod/web pod/api pod/web"},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer([]byte{})
+		cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+		cmd.Flags().Set("sort-by", test.sortBy)
+		cmd.Flags().Set("reverse", fmt.Sprint(test.reverse))
+		if err := cmd.RunE(cmd, test.args); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if buf.String() != test.expected {
+			t.Errorf("Test %d: expected [%s], got [%s]", i, test.expected, buf)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+	cmd.Flags().Set("sort-by", "size")
+	cmd.Flags().Set("output", "wide")
+	if err := cmd.RunE(cmd, []string{"po"}); err == nil || !strings.Contains(err.Error(), "--sort-by") {
+		t.Errorf("Expected error about --sort-by, got %v", err)
+	}
+
+	cmd.Flags().Set("sort-by", "namespace")
+	cmd.RunE(cmd, []string{"po"})
+	if !strings.Contains(buf.String(), "dev        pod   web") || strings.Index(buf.String(), "dev") > strings.Index(buf.String(), "prod") {
+		t.Errorf("Expected table sorted by namespace, got\n%s", buf)
+	}
+}
+
 func TestRunGetWithKubectlFlags(t *testing.T) {
 	tc := &TestMirrorClient{}
 	f := &TestFactory{mrrClient: tc}
@@ -182,6 +283,26 @@ func TestRunGetWithKubectlFlags(t *testing.T) {
 			kubectlCmd:        "--namespace=ns4 --context=c2",
 			expectedNamespace: "ns4",
 		},
+		{
+			kubectlCmd:        "--context=c2 -n ns4",
+			expectedNamespace: "ns4",
+		},
+		{
+			kubectlCmd:        "get po -n=ns4",
+			expectedNamespace: "ns4",
+		},
+		{
+			kubectlCmd:        "get po -nns4",
+			expectedNamespace: "ns4",
+		},
+		{
+			kubectlCmd:        "-n ns3 get po --namespace ns4",
+			expectedNamespace: "ns4",
+		},
+		{
+			kubectlCmd:        "--context=c-2 --no-headers get po",
+			expectedNamespace: "ns2",
+		},
 		{
 			kubectlCmd:     "--server=y1.com --cluster=cluster_2",
 			expectedServer: "y1.com",
@@ -208,6 +329,43 @@ func TestRunGetWithKubectlFlags(t *testing.T) {
 	}
 }
 
+func TestRunGetAllNamespaces(t *testing.T) {
+	tests := []struct {
+		kubectlFlags  string
+		allNamespaces bool
+		expected      string
+	}{
+		{kubectlFlags: "get po", expected: "ns1"},
+		{kubectlFlags: "get po", allNamespaces: true, expected: ""},
+		{kubectlFlags: "get po -A", expected: ""},
+		{kubectlFlags: "get po --all-namespaces ", expected: ""},
+		{kubectlFlags: "get po --all-namespaces=true", expected: ""},
+		{kubectlFlags: "get po --all-namespaces=false", expected: "ns1"},
+		{kubectlFlags: "get po -Aweird", expected: "ns1"},
+		{kubectlFlags: "get po --namespace=ns2 -A", expected: ""},
+	}
+
+	for i, test := range tests {
+		tc := &TestMirrorClient{}
+		f := &TestFactory{mrrClient: tc, stdOut: ioutil.Discard}
+		f.kubeconfig = Config{
+			CurrentContext: "c1",
+			Contexts:       []ContextWrap{{"c1", Context{Cluster: "cluster_1", Namespace: "ns1"}}},
+			Clusters:       []ClusterWrap{{"cluster_1", Cluster{Server: "x1.com"}}},
+		}
+		cmd := NewGetCommand(f)
+		cmd.Flags().Set("kubectl-flags", test.kubectlFlags)
+		cmd.Flags().Set("all-namespaces", fmt.Sprint(test.allNamespaces))
+		if err := cmd.RunE(cmd, []string{"po"}); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if tc.lastFilter.Namespace != test.expected {
+			t.Errorf("Test %d: expected namespace %q, got %q", i, test.expected, tc.lastFilter.Namespace)
+		}
+	}
+}
+
 func TestRunGetClientError(t *testing.T) {
 	tc := &TestMirrorClient{
 		err: fmt.Errorf("TestFailure"),
@@ -223,3 +381,1131 @@ func TestRunGetClientError(t *testing.T) {
 		}
 	}
 }
+
+func TestRunGetOutput(t *testing.T) {
+	tc := &TestMirrorClient{
+		objects: []KubeObject{
+			{ObjectMeta: ObjectMeta{Name: "web", Namespace: "prod", CreationTimestamp: time.Now().Add(-90 * time.Minute)}, Status: ObjectStatus{PodIP: "10.1.2.3"}, Server: "https://s1"},
+			{ObjectMeta: ObjectMeta{Name: "headless", Namespace: "prod"}, Spec: ObjectSpec{ClusterIP: "None"}},
+		},
+	}
+
+	tests := []struct {
+		args   []string
+		output string
+		lines  []string
+	}{
+		{
+			args:   []string{"po"},
+			output: "wide",
+			lines: []string{
+				"NAMESPACE  KIND  NAME      IP        CLUSTER  AGE",
+				"prod       pod   web       10.1.2.3  prod     1h",
+				"prod       pod   headless  <none>    <none>   <unknown>",
+			},
+		},
+		{
+			args: []string{"ips"},
+			lines: []string{
+				"NAMESPACE  KIND     NAME      IP        CLUSTER  AGE",
+				"prod       pod      web       10.1.2.3  prod     1h",
+				"prod       pod      headless  <none>    <none>   <unknown>",
+				"prod       service  web       10.1.2.3  prod     1h",
+				"prod       service  headless  <none>    <none>   <unknown>",
+			},
+		},
+		{
+			args:   []string{"svc"},
+			output: "json",
+			lines: []string{
+				`"kind": "service",`,
+				`"server": "https://s1"`,
+				`"name": "web",`,
+				`"podIP": "10.1.2.3"`,
+				`"clusterIP": "None"`,
+			},
+		},
+		{
+			args:   []string{"po"},
+			output: "custom-columns=NAME:.metadata.name,IP:.status.podIP",
+			lines: []string{
+				"NAME      IP",
+				"web       10.1.2.3",
+				"headless  <none>",
+			},
+		},
+		{
+			args:   []string{"po"},
+			output: `go-template={{range .}}{{.Namespace}}/{{.Name}} {{.IP}}{{"\n"}}{{end}}`,
+			lines: []string{
+				"prod/web 10.1.2.3\n",
+				"prod/headless \n",
+			},
+		},
+		{
+			args:   []string{"po"},
+			output: "yaml",
+			lines: []string{
+				"- kind: pod",
+				"  metadata:\n    creationTimestamp:",
+				"    name: web\n    namespace: prod",
+				"  server: https://s1",
+				"    podIP: 10.1.2.3",
+			},
+		},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer([]byte{})
+		f := &TestFactory{mrrClient: tc, stdOut: buf}
+		f.kubeconfig = Config{Clusters: []ClusterWrap{{"prod", Cluster{Server: "https://s1:443"}}}}
+		cmd := NewGetCommand(f)
+		cmd.Flags().Set("output", test.output)
+
+		err := cmd.RunE(cmd, test.args)
+		if err != nil {
+			t.Errorf("Test %d: unexpected error: %v", i, err)
+			continue
+		}
+		for _, line := range test.lines {
+			if !strings.Contains(buf.String(), line) {
+				t.Errorf("Test %d: output [%v] does not contain [%v]", i, buf.String(), line)
+			}
+		}
+	}
+
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc})
+	cmd.Flags().Set("output", "xml")
+	err := cmd.RunE(cmd, []string{"po"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported output format") {
+		t.Errorf("Expected error about output format, got %v", err)
+	}
+
+	cmd.Flags().Set("output", "go-template={{range .}")
+	err = cmd.RunE(cmd, []string{"po"})
+	if err == nil || !strings.Contains(err.Error(), "invalid go-template") {
+		t.Errorf("Expected error about template, got %v", err)
+	}
+}
+
+func TestAge(t *testing.T) {
+	now := time.Date(2017, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		created  time.Time
+		expected string
+	}{
+		{time.Time{}, "<unknown>"},
+		{now.Add(time.Second), "0s"},
+		{now.Add(-45 * time.Second), "45s"},
+		{now.Add(-12*time.Minute - 30*time.Second), "12m"},
+		{now.Add(-5 * time.Hour), "5h"},
+		{now.Add(-47 * time.Hour), "47h"},
+		{now.Add(-72 * time.Hour), "3d"},
+		{now.Add(-3 * 365 * 24 * time.Hour), "3y"},
+	}
+
+	for _, test := range tests {
+		if actual := age(test.created, now); actual != test.expected {
+			t.Errorf("Age of %v: expected %s, got %s", test.created, test.expected, actual)
+		}
+	}
+}
+
+func TestRunGetWithDirConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, ".kubemrr"), []byte("context: c2\nnamespace: payments\n"), 0644)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+
+	tc := &TestMirrorClient{}
+	f := &TestFactory{mrrClient: tc}
+	f.kubeconfig = Config{
+		CurrentContext: "c1",
+		Contexts: []ContextWrap{
+			{"c1", Context{Cluster: "cluster_1", Namespace: "ns1"}},
+			{"c2", Context{Cluster: "cluster_2", Namespace: "ns2"}},
+		},
+		Clusters: []ClusterWrap{
+			{"cluster_1", Cluster{Server: "x1.com"}},
+			{"cluster_2", Cluster{Server: "x2.com"}},
+		},
+	}
+	cmd := NewGetCommand(f)
+
+	cmd.RunE(cmd, []string{"po"})
+	expected := MrrFilter{Server: "x2.com", Namespace: "payments", Kind: "pod"}
+	tc.lastFilter.Deadline = time.Time{}
+	if !reflect.DeepEqual(tc.lastFilter, expected) {
+		t.Errorf("Expected filter %v, got %v", expected, tc.lastFilter)
+	}
+
+	cmd.Flags().Set("kubectl-flags", "--context=c1 --namespace=ns3")
+	cmd.RunE(cmd, []string{"po"})
+	expected = MrrFilter{Server: "x1.com", Namespace: "ns3", Kind: "pod"}
+	tc.lastFilter.Deadline = time.Time{}
+	if !reflect.DeepEqual(tc.lastFilter, expected) {
+		t.Errorf("Expected kubectl flags to take precedence, got filter %v", tc.lastFilter)
+	}
+}
+
+func TestCompressNames(t *testing.T) {
+	many := func(prefix string, n int) []string {
+		res := []string{}
+		for i := 0; i < n; i++ {
+			res = append(res, fmt.Sprintf("%s%d", prefix, i))
+		}
+		return res
+	}
+	join := func(lists ...[]string) []string {
+		res := []string{}
+		for _, l := range lists {
+			res = append(res, l...)
+		}
+		return res
+	}
+
+	tests := []struct {
+		names    []string
+		typed    string
+		max      int
+		expected []string
+	}{
+		{[]string{"b", "a"}, "", 0, []string{"b", "a"}},
+		{[]string{"b", "a"}, "", 2, []string{"b", "a"}},
+		{[]string{"a", "a", "b"}, "", 2, []string{"a", "b"}},
+		{
+			join(many("web-5d8f7-", 10), many("api-77c4-", 10), []string{"db"}),
+			"", 5,
+			[]string{"api-77c4-", "db", "web-5d8f7-"},
+		},
+		{
+			join(many("web-5d8f7-", 10), many("web-6e9a8-", 10)),
+			"web-", 5,
+			[]string{"web-5d8f7-", "web-6e9a8-"},
+		},
+		{
+			many("web-5d8f7-", 10),
+			"w", 5,
+			[]string{"web-5d8f7-"},
+		},
+		{
+			many("pod-", 10),
+			"pod-", 3,
+			[]string{"pod-0", "pod-1", "pod-2"},
+		},
+		{
+			join(many("kube-dns.kube-system.", 3), many("kube-proxy.kube-system.", 3)),
+			"", 2,
+			[]string{"kube-dns.kube-system.", "kube-proxy.kube-system."},
+		},
+	}
+
+	for i, test := range tests {
+		res := compressNames(test.names, test.typed, test.max)
+		if !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, test.expected, res)
+		}
+	}
+}
+
+func TestRunGetPrefix(t *testing.T) {
+	tc := &TestMirrorClient{
+		objects: []KubeObject{
+			{ObjectMeta: ObjectMeta{Name: "web-1"}},
+			{ObjectMeta: ObjectMeta{Name: "web-2"}},
+			{ObjectMeta: ObjectMeta{Name: "api-1"}},
+			{ObjectMeta: ObjectMeta{Name: "api-2"}},
+		},
+	}
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+	cmd.Flags().Set("max-names", "3")
+
+	cmd.RunE(cmd, []string{"po"})
+	if buf.String() != "web-1 web-2 api-1 api-2" {
+		t.Errorf("Expected all names without completion, got [%v]", buf)
+	}
+
+	buf.Reset()
+	cmd.Flags().Set("prefix", "")
+	cmd.RunE(cmd, []string{"po"})
+	if buf.String() != "api- web-" {
+		t.Errorf("Expected compressed names, got [%v]", buf)
+	}
+
+	buf.Reset()
+	cmd.Flags().Set("prefix", "web")
+	cmd.RunE(cmd, []string{"po"})
+	if buf.String() != "web-1 web-2" {
+		t.Errorf("Expected names with the prefix, got [%v]", buf)
+	}
+}
+
+func TestRunGetSelector(t *testing.T) {
+	tests := []struct {
+		selector     string
+		kubectlFlags string
+		expected     string
+	}{
+		{selector: "", kubectlFlags: "", expected: ""},
+		{selector: "app=web", kubectlFlags: "", expected: "app=web"},
+		{selector: "", kubectlFlags: "get pods -l app=api", expected: "app=api"},
+		{selector: "", kubectlFlags: "get pods --selector=tier!=db", expected: "tier!=db"},
+		{selector: "app=web", kubectlFlags: "get pods -l app=api", expected: "app=web"},
+		{selector: "", kubectlFlags: "get pods --show-labels", expected: ""},
+	}
+
+	for i, test := range tests {
+		tc := &TestMirrorClient{}
+		cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: ioutil.Discard})
+		cmd.Flags().Set("selector", test.selector)
+		cmd.Flags().Set("kubectl-flags", test.kubectlFlags)
+		if err := cmd.RunE(cmd, []string{"po"}); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if tc.lastFilter.Selector != test.expected {
+			t.Errorf("Test %d: expected selector %q, got %q", i, test.expected, tc.lastFilter.Selector)
+		}
+	}
+
+	cmd := NewGetCommand(&TestFactory{mrrClient: &TestMirrorClient{}})
+	cmd.Flags().Set("selector", "app in web")
+	if err := cmd.RunE(cmd, []string{"po"}); err == nil || !strings.Contains(err.Error(), "invalid selector") {
+		t.Errorf("Expected invalid selector error, got %v", err)
+	}
+}
+
+func TestRunGetNames(t *testing.T) {
+	tc := &TestMirrorClient{}
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: ioutil.Discard})
+	cmd.Flags().Set("regex", "^web-")
+	if err := cmd.RunE(cmd, []string{"po", "*-1"}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if tc.lastFilter.Names != "*-1" || tc.lastFilter.NameRegex != "^web-" {
+		t.Errorf("Expected names *-1 and regex ^web-, got %+v", tc.lastFilter)
+	}
+
+	cmd = NewGetCommand(&TestFactory{mrrClient: &TestMirrorClient{}})
+	if err := cmd.RunE(cmd, []string{"po", "["}); err == nil || !strings.Contains(err.Error(), "invalid name pattern") {
+		t.Errorf("Expected invalid pattern error, got %v", err)
+	}
+
+	cmd = NewGetCommand(&TestFactory{mrrClient: &TestMirrorClient{}})
+	if err := cmd.RunE(cmd, []string{"po", "a", "b"}); err == nil {
+		t.Errorf("Expected error for three arguments")
+	}
+}
+
+func TestRunGetContainers(t *testing.T) {
+	pod := func(containers ...string) KubeObject {
+		o := KubeObject{ObjectMeta: ObjectMeta{Name: "web-1"}}
+		for _, c := range containers {
+			o.Spec.Containers = append(o.Spec.Containers, Container{Name: c})
+		}
+		return o
+	}
+	withInit := pod("web", "proxy")
+	withInit.Spec.InitContainers = []Container{{Name: "migrate"}}
+	tc := &TestMirrorClient{objects: []KubeObject{withInit, pod("web", "logs")}}
+
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+	if err := cmd.RunE(cmd, []string{"containers", "web-1"}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if buf.String() != "web proxy migrate logs" {
+		t.Errorf("Unexpected containers %q", buf.String())
+	}
+	if tc.lastFilter.Kind != "pod" || tc.lastFilter.Names != "web-1" {
+		t.Errorf("Expected request for pod web-1, got %+v", tc.lastFilter)
+	}
+
+	buf.Reset()
+	cmd = NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+	cmd.Flags().Set("prefix", "p")
+	if err := cmd.RunE(cmd, []string{"container", "web-1"}); err != nil || buf.String() != "proxy" {
+		t.Errorf("Expected only proxy, got %q and error %v", buf.String(), err)
+	}
+
+	cmd = NewGetCommand(&TestFactory{mrrClient: tc})
+	if err := cmd.RunE(cmd, []string{"containers"}); err == nil {
+		t.Errorf("Expected error without name of the pod")
+	}
+}
+
+func TestRunGetImages(t *testing.T) {
+	pod := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "web-1", Namespace: "prod"}}
+	pod.Spec.Containers = []Container{{Name: "web", Image: "web:3"}, {Name: "proxy", Image: "envoy:1.2"}}
+	deployment := KubeObject{TypeMeta: TypeMeta{"deployment"}, ObjectMeta: ObjectMeta{Name: "web", Namespace: "prod"}}
+	deployment.Spec.Template = &PodTemplate{Spec: ObjectSpec{Containers: []Container{{Name: "web", Image: "web:3"}}}}
+	tc := &TestMirrorClient{objects: []KubeObject{pod, deployment}}
+
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+	if err := cmd.RunE(cmd, []string{"images"}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if buf.String() != "envoy:1.2 web:3" {
+		t.Errorf("Unexpected images %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := outputImagesWide([]KubeObject{pod, deployment}, buf); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := `IMAGE      NAMESPACE  KIND        NAME
+envoy:1.2  prod       pod         web-1
+web:3      prod       deployment  web
+web:3      prod       pod         web-1
+`
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	cmd = NewGetCommand(&TestFactory{mrrClient: tc})
+	cmd.Flags().Set("output", "json")
+	if err := cmd.RunE(cmd, []string{"images"}); err == nil {
+		t.Errorf("Expected error for unsupported output")
+	}
+}
+
+func TestRunGetLabels(t *testing.T) {
+	tc := &TestMirrorClient{objects: []KubeObject{
+		{ObjectMeta: ObjectMeta{Name: "a", Labels: map[string]string{"app": "web", "tier": "front"}}},
+		{ObjectMeta: ObjectMeta{Name: "b", Labels: map[string]string{"app": "api"}}},
+		{ObjectMeta: ObjectMeta{Name: "c"}},
+	}}
+
+	tests := []struct {
+		args     []string
+		prefix   string
+		expected string
+	}{
+		{args: []string{"labels", "po"}, expected: "app tier app=api app=web tier=front"},
+		{args: []string{"labels", "po"}, prefix: "app=", expected: "app=api app=web"},
+		{args: []string{"label"}, prefix: "t", expected: "tier tier=front"},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer([]byte{})
+		cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+		cmd.Flags().Set("prefix", test.prefix)
+		cmd.Flags().Set("kubectl-flags", "get po -l ap")
+		if err := cmd.RunE(cmd, test.args); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if buf.String() != test.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, test.expected, buf.String())
+		}
+		if tc.lastFilter.Selector != "" || tc.lastFilter.Names != "" {
+			t.Errorf("Test %d: expected no selector and pattern of names, got %+v", i, tc.lastFilter)
+		}
+	}
+
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc})
+	if err := cmd.RunE(cmd, []string{"labels", "bananas"}); err == nil {
+		t.Errorf("Expected error for unsupported resource")
+	}
+}
+
+func TestRunGetAliases(t *testing.T) {
+	tests := []struct {
+		resource string
+		expected string
+	}{
+		{resource: "d", expected: "deployment"},
+		{resource: "vs", expected: "virtualservice"},
+		{resource: "po,d", expected: "deployment"},
+	}
+
+	for i, test := range tests {
+		tc := &TestMirrorClient{}
+		cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: ioutil.Discard})
+		cmd.Flags().Set("config", "test_data/kubemrr_config_valid")
+		if err := cmd.RunE(cmd, []string{test.resource}); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if tc.lastFilter.Kind != test.expected {
+			t.Errorf("Test %d: expected kind %s, got %s", i, test.expected, tc.lastFilter.Kind)
+		}
+	}
+
+	kinds, err := resourceKinds("po,vs,d", map[string]string{"vs": "VirtualService", "d": "deployments"}, nil)
+	if err != nil || !reflect.DeepEqual(kinds, []string{"pod", "virtualservice", "deployment"}) {
+		t.Errorf("Unexpected kinds %v and error %v", kinds, err)
+	}
+}
+
+func TestRunGetShortNames(t *testing.T) {
+	tc := &TestMirrorClient{resources: []APIResource{
+		{Name: "persistentvolumeclaims", Kind: "PersistentVolumeClaim", ShortNames: []string{"pvc"}},
+		{Name: "networkpolicies", Kind: "NetworkPolicy", ShortNames: []string{"netpol"}},
+		{Name: "deployments", Kind: "Deployment", ShortNames: []string{"deploy"}},
+	}}
+
+	tests := []struct {
+		resource string
+		expected string
+	}{
+		{resource: "pvc", expected: "persistentvolumeclaim"},
+		{resource: "netpol", expected: "networkpolicy"},
+		{resource: "deploy", expected: "deployment"},
+		{resource: "po,deploy", expected: "deployment"},
+		{resource: "svc", expected: "service"},
+	}
+
+	for i, test := range tests {
+		cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: ioutil.Discard})
+		if err := cmd.RunE(cmd, []string{test.resource}); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if tc.lastFilter.Kind != test.expected {
+			t.Errorf("Test %d: expected kind %s, got %s", i, test.expected, tc.lastFilter.Kind)
+		}
+	}
+
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc})
+	if err := cmd.RunE(cmd, []string{"bananas"}); err == nil || !strings.Contains(err.Error(), "unsupported resource type") {
+		t.Errorf("Expected unsupported resource error, got %v", err)
+	}
+}
+
+func TestRunGetKubeconfig(t *testing.T) {
+	conf := Config{
+		Contexts: []ContextWrap{{Name: "prod"}, {Name: "dev"}, {Name: "prod-eu"}},
+		Clusters: []ClusterWrap{{Name: "gke-prod"}, {Name: "gke-dev"}},
+		Users:    []UserWrap{{Name: "admin"}},
+	}
+	tests := []struct {
+		resource string
+		prefix   string
+		expected string
+	}{
+		{resource: "contexts", prefix: "", expected: "prod dev prod-eu"},
+		{resource: "contexts", prefix: "prod", expected: "prod prod-eu"},
+		{resource: "context", prefix: "qa", expected: ""},
+		{resource: "clusters", prefix: "", expected: "gke-prod gke-dev"},
+		{resource: "cluster", prefix: "gke-d", expected: "gke-dev"},
+		{resource: "users", prefix: "", expected: "admin"},
+	}
+
+	for i, test := range tests {
+		tc := &TestMirrorClient{}
+		buf := bytes.NewBuffer([]byte{})
+		cmd := NewGetCommand(&TestFactory{kubeconfig: conf, mrrClient: tc, stdOut: buf})
+		cmd.Flags().Set("prefix", test.prefix)
+		if err := cmd.RunE(cmd, []string{test.resource}); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if buf.String() != test.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, test.expected, buf.String())
+		}
+		if !reflect.DeepEqual(tc.lastFilter, MrrFilter{}) {
+			t.Errorf("Test %d: expected no request to the mirror, got %+v", i, tc.lastFilter)
+		}
+	}
+
+	cmd := NewGetCommand(&TestFactory{kubeconfig: conf})
+	cmd.Flags().Set("output", "json")
+	if err := cmd.RunE(cmd, []string{"contexts"}); err == nil {
+		t.Errorf("Expected error for unsupported output")
+	}
+}
+
+func TestRunGetNameCompressionDisabled(t *testing.T) {
+	tc := &TestMirrorClient{
+		objects: []KubeObject{
+			{ObjectMeta: ObjectMeta{Name: "web-1"}},
+			{ObjectMeta: ObjectMeta{Name: "web-2"}},
+		},
+	}
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+	cmd.Flags().Set("max-names", "1")
+	cmd.Flags().Set("prefix", "")
+	cmd.Flags().Set("feature-gates", "NameCompression=false")
+
+	cmd.RunE(cmd, []string{"po"})
+	if buf.String() != "web-1 web-2" {
+		t.Errorf("Expected all names, got [%v]", buf)
+	}
+}
+
+func TestCompletesPath(t *testing.T) {
+	tests := []struct {
+		line     string
+		prefix   string
+		expected bool
+	}{
+		{line: "kubectl apply -f ", expected: true},
+		{line: "kubectl apply -f dep", prefix: "dep", expected: true},
+		{line: "kubectl delete --filename ", expected: true},
+		{line: "kubectl apply --filename=dep", prefix: "--filename=dep", expected: true},
+		{line: "kubectl kustomize -k ", expected: true},
+		{line: "kubectl get pod -f deploy.yaml web", prefix: "web", expected: false},
+		{line: "kubectl logs -f ", expected: false},
+		{line: "kubectl logs -f web", prefix: "web", expected: false},
+		{line: "kubectl get pod ", expected: false},
+		{line: "", expected: false},
+	}
+
+	for _, test := range tests {
+		actual := completesPath(test.line, test.prefix)
+		if actual != test.expected {
+			t.Errorf("completesPath(%q, %q) is %v, expected %v", test.line, test.prefix, actual, test.expected)
+		}
+	}
+}
+
+func TestRunGetPath(t *testing.T) {
+	tc := &TestMirrorClient{
+		objects: []KubeObject{{ObjectMeta: ObjectMeta{Name: "o1"}}},
+	}
+	buf := bytes.NewBuffer([]byte{})
+	f := &TestFactory{mrrClient: tc, stdOut: buf}
+	cmd := NewGetCommand(f)
+	cmd.Flags().Set("kubectl-flags", "kubectl delete -f ")
+
+	err := cmd.RunE(cmd, []string{"pod"})
+	if err != nil {
+		t.Fatalf("Running [get pod]: got error: %v", err)
+	}
+	if buf.String() != filesDirective+"\n" {
+		t.Errorf("Running [get pod]: output [%v] was not equal to expected [%v]", buf, filesDirective)
+	}
+	if tc.lastFilter != (MrrFilter{}) {
+		t.Errorf("Running [get pod]: mirror was queried with %v", tc.lastFilter)
+	}
+}
+
+func TestRunGetAutoStart(t *testing.T) {
+	tc := &TestMirrorClient{
+		objects: []KubeObject{{ObjectMeta: ObjectMeta{Name: "o1"}}},
+	}
+	buf := bytes.NewBuffer([]byte{})
+	f := &TestFactory{mrrClient: tc, stdOut: buf, mrrClientErr: fmt.Errorf("connection refused")}
+	f.kubeconfig = Config{CurrentContext: "dev"}
+	cmd := NewGetCommand(f)
+
+	err := cmd.RunE(cmd, []string{"pod"})
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Expected error without --auto-start, got %v", err)
+	}
+	if f.mirrorStarted != nil {
+		t.Errorf("Expected the mirror not to be started, got %v", f.mirrorStarted)
+	}
+
+	cmd.Flags().Set("auto-start", "true")
+	cmd.Flags().Set("kubectl-flags", "--context=prod")
+	err = cmd.RunE(cmd, []string{"pod"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"--kubeconfig=~/.kube/config", "--bind=127.0.0.1:33033", "prod"}
+	if !reflect.DeepEqual(f.mirrorStarted, expected) {
+		t.Errorf("Expected the mirror to be started with %v, got %v", expected, f.mirrorStarted)
+	}
+	if buf.String() != "o1" {
+		t.Errorf("Unexpected output [%s]", buf)
+	}
+
+	f.mrrClientErr = fmt.Errorf("connection refused")
+	f.mirrorStarted = nil
+	cmd.Flags().Set("address", "10.5.1.6")
+	err = cmd.RunE(cmd, []string{"pod"})
+	if err == nil || f.mirrorStarted != nil {
+		t.Errorf("Expected remote mirror not to be started, got %v", f.mirrorStarted)
+	}
+}
+
+func TestRunGetMaxStale(t *testing.T) {
+	tests := []struct {
+		synced time.Time
+		args   []string
+		stale  bool
+	}{
+		{synced: time.Time{}, args: nil},
+		{synced: time.Now(), args: []string{"--max-stale=1m"}},
+		{synced: time.Now().Add(-time.Hour), args: []string{"--max-stale=1m"}, stale: true},
+		{synced: time.Time{}, args: []string{"--max-stale=1m"}, stale: true},
+		{synced: time.Now().Add(-time.Hour), args: []string{"--max-stale=1m", "--warn-stale"}},
+	}
+
+	for i, test := range tests {
+		tc := &TestMirrorClient{synced: test.synced}
+		cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: ioutil.Discard})
+		cmd.ParseFlags(test.args)
+		err := cmd.RunE(cmd, []string{"pod"})
+		if test.stale && err == nil {
+			t.Errorf("Test %d: expected error about stale objects", i)
+		}
+		if !test.stale && err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+		}
+	}
+}
+
+func TestRunGetTimeout(t *testing.T) {
+	tc := &TestMirrorClient{}
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: ioutil.Discard})
+	cmd.Flags().Set("timeout", "300ms")
+	start := time.Now()
+	if err := cmd.RunE(cmd, []string{"pod"}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if d := tc.lastFilter.Deadline.Sub(start); d <= 0 || d > 300*time.Millisecond+time.Second/10 {
+		t.Errorf("Expected deadline in 300ms, got in %s", d)
+	}
+
+	cmd.Flags().Set("timeout", "0s")
+	if err := cmd.RunE(cmd, []string{"pod"}); err == nil {
+		t.Errorf("Expected error for zero timeout")
+	}
+}
+
+func TestGetQuiet(t *testing.T) {
+	defer os.Unsetenv(quietEnv)
+	tests := []struct {
+		env   string
+		flag  string
+		quiet bool
+	}{
+		{},
+		{env: "true", quiet: true},
+		{env: "banana"},
+		{flag: "true", quiet: true},
+		{env: "true", flag: "false"},
+	}
+
+	for i, test := range tests {
+		os.Setenv(quietEnv, test.env)
+		cmd := NewGetCommand(&TestFactory{})
+		if test.flag != "" {
+			cmd.Flags().Set("quiet", test.flag)
+		}
+		quiet, err := GetQuiet(cmd)
+		if err != nil || quiet != test.quiet {
+			t.Errorf("Test %d: expected quiet %v, got %v, %v", i, test.quiet, quiet, err)
+		}
+	}
+}
+
+func TestRunGetQuiet(t *testing.T) {
+	defer log.SetOutput(os.Stdout)
+	cmd := NewGetCommand(&TestFactory{mrrClientErr: errors.New("connection refused")})
+	cmd.Flags().Set("quiet", "true")
+	if err := cmd.RunE(cmd, []string{"pod"}); err == nil {
+		t.Errorf("Expected error to make get exit with non-zero code")
+	}
+	if !cmd.SilenceErrors {
+		t.Errorf("Expected the error not to be printed")
+	}
+}
+
+func TestRunGetKubeconfigInKubectlFlags(t *testing.T) {
+	tc := &TestMirrorClient{}
+	f := &TestFactory{mrrClient: tc, stdOut: ioutil.Discard}
+	f.kubeconfig = Config{
+		CurrentContext: "c1",
+		Contexts:       []ContextWrap{{"c1", Context{Cluster: "cluster_1", Namespace: "ns1"}}},
+		Clusters:       []ClusterWrap{{"cluster_1", Cluster{Server: "x1.com"}}},
+	}
+
+	tests := []struct {
+		kubectlFlags string
+		expected     MrrFilter
+	}{
+		{kubectlFlags: "get po", expected: MrrFilter{Server: "x1.com", Namespace: "ns1", Kind: "pod"}},
+		{kubectlFlags: "--kubeconfig=test_data/kubeconfig_valid get po", expected: MrrFilter{Server: "https://foo.com", Namespace: "blue", Kind: "pod"}},
+		{kubectlFlags: "--kubeconfig test_data/kubeconfig_valid --context dev get po", expected: MrrFilter{Server: "https://bar.com", Namespace: "red", Kind: "pod"}},
+	}
+
+	for i, test := range tests {
+		cmd := NewGetCommand(f)
+		cmd.Flags().Set("kubectl-flags", test.kubectlFlags)
+		if err := cmd.RunE(cmd, []string{"po"}); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		tc.lastFilter.Deadline = time.Time{}
+		if !reflect.DeepEqual(tc.lastFilter, test.expected) {
+			t.Errorf("Test %d: expected filter %v, got %v", i, test.expected, tc.lastFilter)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewGetCommand(&TestFactory{kubeconfig: f.kubeconfig, stdOut: buf})
+	cmd.Flags().Set("kubectl-flags", "--kubeconfig=test_data/kubeconfig_valid config use-context")
+	if err := cmd.RunE(cmd, []string{"contexts"}); err != nil || buf.String() != "dev prod" {
+		t.Errorf("Expected contexts of the given kubeconfig, got %q, %v", buf.String(), err)
+	}
+
+	cmd = NewGetCommand(f)
+	cmd.Flags().Set("kubectl-flags", "--kubeconfig=test_data/kubeconfig_missing get po")
+	if err := cmd.RunE(cmd, []string{"po"}); err == nil {
+		t.Errorf("Expected error for missing kubeconfig")
+	}
+}
+
+func TestParseKubectlFlagsQuoted(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected KubectlFlags
+	}{
+		{in: `get po --server="https://x y"`, expected: KubectlFlags{server: "https://x y"}},
+		{in: `get po --namespace 'my ns'`, expected: KubectlFlags{namespace: "my ns"}},
+		{in: `get po -n "my ns" -l 'app in (a, b)'`, expected: KubectlFlags{namespace: "my ns", selector: "app in (a, b)"}},
+		{in: `get po --context=my\ context`, expected: KubectlFlags{context: "my context"}},
+		{in: `get po --kubeconfig "/tmp/a b/config" --namespace`, expected: KubectlFlags{kubeconfig: "/tmp/a b/config"}},
+		{in: `get po -n 'my`, expected: KubectlFlags{namespace: "my"}},
+		{in: `get po --all-namespaces=false -n ns`, expected: KubectlFlags{namespace: "ns"}},
+	}
+
+	for i, test := range tests {
+		if actual := parseKubectlFlags(test.in); !reflect.DeepEqual(*actual, test.expected) {
+			t.Errorf("Test %d: expected %+v, got %+v", i, test.expected, *actual)
+		}
+	}
+}
+
+func TestSplitKubectlLine(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected []string
+	}{
+		{in: "", expected: []string{}},
+		{in: "  get  po ", expected: []string{"get", "po"}},
+		{in: `a "b c" 'd e' f\ g`, expected: []string{"a", "b c", "d e", "f g"}},
+		{in: `--server="https://x y"`, expected: []string{"--server=https://x y"}},
+		{in: `'a\b' "c\"d" ''`, expected: []string{`a\b`, `c"d`, ""}},
+	}
+
+	for i, test := range tests {
+		if actual := splitKubectlLine(test.in); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestRunGetUserInKubectlFlags(t *testing.T) {
+	tc := &TestMirrorClient{}
+	f := &TestFactory{mrrClient: tc, stdOut: ioutil.Discard}
+	f.kubeconfig = Config{
+		CurrentContext: "team",
+		Contexts: []ContextWrap{
+			{"admin", Context{Cluster: "prod", User: "admin", Namespace: "kube-system"}},
+			{"team", Context{Cluster: "prod", User: "dev", Namespace: "checkout"}},
+			{"staging", Context{Cluster: "staging", User: "admin", Namespace: "qa"}},
+		},
+		Clusters: []ClusterWrap{{"prod", Cluster{Server: "prod.com"}}, {"staging", Cluster{Server: "staging.com"}}},
+	}
+
+	tests := []struct {
+		kubectlFlags string
+		expected     MrrFilter
+	}{
+		{kubectlFlags: "get po", expected: MrrFilter{Server: "prod.com", Namespace: "checkout", Kind: "pod"}},
+		{kubectlFlags: "get po --user admin", expected: MrrFilter{Server: "prod.com", Namespace: "kube-system", Kind: "pod"}},
+		{kubectlFlags: "get po --user=admin --cluster=staging", expected: MrrFilter{Server: "staging.com", Namespace: "qa", Kind: "pod"}},
+		{kubectlFlags: "get po --user=admin --context=team", expected: MrrFilter{Server: "prod.com", Namespace: "checkout", Kind: "pod"}},
+		{kubectlFlags: "get po --user=nobody", expected: MrrFilter{Server: "prod.com", Namespace: "checkout", Kind: "pod"}},
+	}
+
+	for i, test := range tests {
+		cmd := NewGetCommand(f)
+		cmd.Flags().Set("kubectl-flags", test.kubectlFlags)
+		if err := cmd.RunE(cmd, []string{"po"}); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		tc.lastFilter.Deadline = time.Time{}
+		if !reflect.DeepEqual(tc.lastFilter, test.expected) {
+			t.Errorf("Test %d: expected filter %v, got %v", i, test.expected, tc.lastFilter)
+		}
+	}
+}
+
+func TestRunGetCompLine(t *testing.T) {
+	defer os.Unsetenv("COMP_LINE")
+	defer os.Unsetenv("COMP_POINT")
+	tc := &TestMirrorClient{objects: []KubeObject{
+		{ObjectMeta: ObjectMeta{Name: "web-1"}},
+		{ObjectMeta: ObjectMeta{Name: "api-1"}},
+	}}
+
+	tests := []struct {
+		line      string
+		point     string
+		namespace string
+		expected  string
+	}{
+		{line: "kubectl logs -n prod we", point: "23", namespace: "prod", expected: "web-1"},
+		{line: "kubectl logs -n prod ", point: "21", namespace: "prod", expected: "web-1 api-1"},
+		{line: "kubectl logs -n 'my ns' a --context x", point: "25", namespace: "my ns", expected: "api-1"},
+		{line: "kubectl logs -n prod we", point: "", namespace: "prod", expected: "web-1"},
+	}
+
+	for i, test := range tests {
+		os.Setenv("COMP_LINE", test.line)
+		os.Setenv("COMP_POINT", test.point)
+		buf := bytes.NewBuffer([]byte{})
+		cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+		cmd.Flags().Set("comp-line", "true")
+		if err := cmd.RunE(cmd, []string{"pod"}); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if tc.lastFilter.Namespace != test.namespace {
+			t.Errorf("Test %d: expected namespace %q, got %q", i, test.namespace, tc.lastFilter.Namespace)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, test.expected, buf.String())
+		}
+	}
+
+	os.Setenv("COMP_LINE", "kubectl logs -n prod we")
+	os.Setenv("COMP_POINT", "23")
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+	cmd.Flags().Set("comp-line", "true")
+	cmd.Flags().Set("prefix", "a")
+	if err := cmd.RunE(cmd, []string{"pod"}); err != nil || buf.String() != "api-1" {
+		t.Errorf("Expected the given prefix to be kept, got %q, %v", buf.String(), err)
+	}
+}
+
+func TestRunGetPhase(t *testing.T) {
+	tc := &TestMirrorClient{}
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: ioutil.Discard})
+	cmd.Flags().Set("phase", "Running")
+	if err := cmd.RunE(cmd, []string{"pod"}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if tc.lastFilter.Phase != "Running" {
+		t.Errorf("Expected phase of the filter to be Running, got %q", tc.lastFilter.Phase)
+	}
+}
+
+func TestRunGetReadyOnly(t *testing.T) {
+	tc := &TestMirrorClient{}
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: ioutil.Discard})
+	cmd.Flags().Set("ready-only", "true")
+	if err := cmd.RunE(cmd, []string{"nodes"}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !tc.lastFilter.ReadyOnly {
+		t.Errorf("Expected only ready nodes to be asked for, got %+v", tc.lastFilter)
+	}
+}
+
+func TestCommandResource(t *testing.T) {
+	tests := []struct {
+		line     string
+		prefix   string
+		expected string
+	}{
+		{line: "kubectl delete deployment ", expected: "deployment"},
+		{line: "kubectl delete deployment we", prefix: "we", expected: "deployment"},
+		{line: "kubectl delete deployment web ", expected: "deployment"},
+		{line: "kubectl -n prod edit svc/web", prefix: "", expected: "svc"},
+		{line: "kubectl --context prod -o yaml get po,svc ", expected: "po,svc"},
+		{line: "kubectl rollout status deploy ", expected: "deploy"},
+		{line: "kubectl set image deploy ", expected: "deploy"},
+		{line: "kubectl top pod ", expected: "pod"},
+		{line: "kubectl delete deploy", prefix: "deploy", expected: ""},
+		{line: "kubectl rollout status ", expected: ""},
+		{line: "kubectl logs ", expected: ""},
+		{line: "kubectl drain node-1 ", expected: ""},
+		{line: "kubectl taint nodes ", expected: "nodes"},
+		{line: "kus --context x kus get cm ", expected: "cm"},
+	}
+
+	for i, test := range tests {
+		if actual := commandResource(test.line, test.prefix); actual != test.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestRunGetForCommand(t *testing.T) {
+	tc := &TestMirrorClient{}
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: ioutil.Discard})
+	cmd.Flags().Set("for-command", "true")
+	cmd.Flags().Set("kubectl-flags", "kubectl delete deployment web ")
+	if err := cmd.RunE(cmd, []string{}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if tc.lastFilter.Kind != "deployment" {
+		t.Errorf("Expected deployments to be asked for, got %q", tc.lastFilter.Kind)
+	}
+
+	cmd.Flags().Set("kubectl-flags", "kubectl delete deployment web ")
+	if err := cmd.RunE(cmd, []string{"api-*"}); err != nil || tc.lastFilter.Names != "api-*" {
+		t.Errorf("Expected the argument to be a pattern of names, got %+v, %v", tc.lastFilter, err)
+	}
+
+	cmd.Flags().Set("kubectl-flags", "kubectl logs ")
+	if err := cmd.RunE(cmd, []string{}); err == nil {
+		t.Errorf("Expected error for command without resource type")
+	}
+}
+
+func TestRunGetPorts(t *testing.T) {
+	tc := &TestMirrorClient{objects: []KubeObject{
+		{ObjectMeta: ObjectMeta{Name: "web"}, Spec: ObjectSpec{Ports: []Port{{Name: "https", Port: 443}, {Name: "http", Port: 80}}}},
+		{ObjectMeta: ObjectMeta{Name: "web"}, Spec: ObjectSpec{Containers: []Container{{Name: "web", Ports: []Port{{ContainerPort: 8080}, {ContainerPort: 80}}}}}},
+	}}
+
+	tests := []struct {
+		target   string
+		prefix   string
+		kind     string
+		expected string
+	}{
+		{target: "svc/web", kind: "service", expected: "80 443 8080"},
+		{target: "web", kind: "pod", expected: "80 443 8080"},
+		{target: "deployment/web", prefix: "8", kind: "deployment", expected: "80 8080"},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer([]byte{})
+		cmd := NewGetCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+		cmd.Flags().Set("prefix", test.prefix)
+		if err := cmd.RunE(cmd, []string{"ports", test.target}); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if tc.lastFilter.Kind != test.kind || tc.lastFilter.Names != "web" {
+			t.Errorf("Test %d: expected %s web to be asked for, got %+v", i, test.kind, tc.lastFilter)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, test.expected, buf.String())
+		}
+	}
+
+	cmd := NewGetCommand(&TestFactory{mrrClient: tc})
+	if err := cmd.RunE(cmd, []string{"ports"}); err == nil {
+		t.Errorf("Expected error without target")
+	}
+	if err := cmd.RunE(cmd, []string{"ports", "bananas/web"}); err == nil {
+		t.Errorf("Expected error for unsupported resource type")
+	}
+}
+
+func TestRunGetKinds(t *testing.T) {
+	tc := &TestMirrorClient{resources: []APIResource{
+		{Name: "pods", Kind: "Pod"},
+		{Name: "persistentvolumeclaims", Kind: "PersistentVolumeClaim", ShortNames: []string{"pvc"}},
+		{Name: "virtualservices", Kind: "VirtualService", ShortNames: []string{"vs"}},
+	}}
+	conf := Config{
+		CurrentContext: "c1",
+		Contexts:       []ContextWrap{{Name: "c1", Context: Context{Cluster: "cluster_1"}}},
+		Clusters:       []ClusterWrap{{Name: "cluster_1", Cluster: Cluster{Server: "https://s1"}}},
+	}
+
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{prefix: "", expected: "configmaps deployments namespaces nodes persistentvolumeclaims pods services virtualservices"},
+		{prefix: "p", expected: "persistentvolumeclaims pods"},
+		{prefix: "vi", expected: "virtualservices"},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer([]byte{})
+		cmd := NewGetCommand(&TestFactory{mrrClient: tc, kubeconfig: conf, stdOut: buf})
+		cmd.Flags().Set("prefix", test.prefix)
+		if err := cmd.RunE(cmd, []string{"kinds"}); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if buf.String() != test.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, test.expected, buf.String())
+		}
+		if tc.lastFilter.Server != "https://s1" {
+			t.Errorf("Test %d: expected resources of the current cluster to be asked for, got %+v", i, tc.lastFilter)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewGetCommand(&TestFactory{mrrClient: &TestMirrorClient{err: errors.New("old mirror")}, kubeconfig: conf, stdOut: buf})
+	cmd.Flags().Set("prefix", "s")
+	if err := cmd.RunE(cmd, []string{"kinds"}); err != nil || buf.String() != "services" {
+		t.Errorf("Expected supported types when the mirror does not discover, got %q, %v", buf.String(), err)
+	}
+	cmd.Flags().Set("output", "wide")
+	if err := cmd.RunE(cmd, []string{"kinds"}); err == nil {
+		t.Errorf("Expected error for unsupported output format")
+	}
+}
+
+func TestHasNamePrefix(t *testing.T) {
+	tests := []struct {
+		kind     string
+		name     string
+		withKind bool
+		prefix   string
+		expected bool
+	}{
+		{"pod", "web", false, "", true},
+		{"pod", "web", false, "we", true},
+		{"pod", "web", false, "pod/", false},
+		{"pod", "web", true, "", true},
+		{"pod", "web", true, "po", true},
+		{"pod", "web", true, "pod", true},
+		{"pod", "web", true, "pod/", true},
+		{"pod", "web", true, "pod/w", true},
+		{"pod", "web", true, "pod/x", false},
+		{"pod", "web", true, "pods", false},
+		{"pod", "web", true, "service/", false},
+	}
+
+	for _, test := range tests {
+		if actual := hasNamePrefix(test.kind, test.name, test.withKind, test.prefix); actual != test.expected {
+			t.Errorf("Expected %v for %s/%s with kind %v and prefix %q, got %v", test.expected, test.kind, test.name, test.withKind, test.prefix, actual)
+		}
+	}
+}
+
+func TestOutputNamesAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	lists := []kindNames{{kind: "pod", names: make([]string, 10000)}}
+	for i := range lists[0].names {
+		lists[0].names[i] = fmt.Sprintf("web-%d", i)
+	}
+	out := &bytes.Buffer{}
+	allocs := testing.AllocsPerRun(10, func() {
+		out.Reset()
+		outputNames(lists, true, "pod/web-1", 0, out)
+	})
+	if allocs > 1 {
+		t.Errorf("Expected names to be written from a pooled buffer, allocated %v times", allocs)
+	}
+}
+
+func BenchmarkOutputNames(b *testing.B) {
+	lists := []kindNames{{kind: "pod", names: make([]string, 10000)}}
+	for i := range lists[0].names {
+		lists[0].names[i] = fmt.Sprintf("web-%d", i)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		outputNames(lists, false, "web", 0, ioutil.Discard)
+	}
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"net"
+	"net/http"
+	"strings"
+)
+
+//objectsPath is the path of the HTTP endpoint that returns cached objects as JSON,
+//for clients that do not speak net/rpc, like curl and scripts
+const objectsPath = "/objects"
+
+//objectsHandler answers GET /objects?kind=pod&namespace=ns&server=url&profile=name with the cached objects
+//that match the filter. Kind is required and accepts the same names as "kubemrr get"
+func objectsHandler(c *MrrCache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query()
+		if q.Get("kind") == "" {
+			http.Error(w, "kind is required", http.StatusBadRequest)
+			return
+		}
+		kind, err := resourceKind(q.Get("kind"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := parseSelector(q.Get("selector")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f := MrrFilter{Server: q.Get("server"), Profile: q.Get("profile"), Namespace: q.Get("namespace"), Kind: kind, Selector: q.Get("selector"),
+			Names: q.Get("name"), NameRegex: q.Get("regex"), Phase: q.Get("phase"), ReadyOnly: q.Get("ready") == "true"}
+		if _, err := nameMatcher(&f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if kind == "node" {
+			f.Namespace = ""
+		}
+		objects := []KubeObject{}
+		if err := c.Objects(&f, &objects); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(objects); err != nil {
+			log.WithField("error", err).Warn("could not write objects")
+		}
+	})
+}
+
+//watchersPath is the path of the HTTP endpoint with state of watchers of each server and kind
+const watchersPath = "/watchers"
+
+//watchersHandler answers GET /watchers?server=url&profile=name with state of watchers of the server, or of all servers
+func watchersHandler(c *MrrCache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := c.status(serverFilter(r))
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status.Servers); err != nil {
+			log.WithField("error", err).Warn("could not write state of watchers")
+		}
+	})
+}
+
+//flushPath is the path of the admin endpoint that flushes the cache
+const flushPath = "/admin/flush"
+
+//flushHandler answers POST /admin/flush?server=url&profile=name by flushing objects of the server, or of all servers
+func flushHandler(c *MrrCache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var restarted int
+		err := c.Flush(serverFilter(r), &restarted)
+		if err == errAdminDisabled {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"restarted": restarted})
+	})
+}
+
+//serverFilter returns the filter of servers given by server and profile parameters of the request
+func serverFilter(r *http.Request) *MrrFilter {
+	q := r.URL.Query()
+	return &MrrFilter{Server: q.Get("server"), Profile: q.Get("profile")}
+}
+
+//requireToken passes to the handler only requests that give the token as "Authorization: Bearer <token>".
+//Empty token lets all requests pass
+func requireToken(token string, h http.Handler) http.Handler {
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			log.WithField("remote", r.RemoteAddr).Warn("rejected request without valid token")
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+//serveUntilShutdown serves clients until opts.Shutdown is closed. Then it stops accepting connections,
+//ends subscriptions and waits up to opts.DrainTimeout for requests that are being answered
+func serveUntilShutdown(l net.Listener, c *MrrCache, h http.Handler, opts MrrServerOptions) error {
+	srv := &http.Server{Handler: h}
+	drained := make(chan error, 1)
+	go func() {
+		<-opts.Shutdown
+		ctx := context.Background()
+		if opts.DrainTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.DrainTimeout)
+			defer cancel()
+		}
+
+		c.closeSubscribers()
+		err := srv.Shutdown(ctx)
+
+		//requests over net/rpc run on hijacked connections that the server does not track
+		done := make(chan struct{})
+		go func() {
+			c.drain()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		drained <- err
+	}()
+
+	if err := srv.Serve(l); err != http.ErrServerClosed {
+		return err
+	}
+	return <-drained
+}
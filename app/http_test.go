@@ -0,0 +1,241 @@
+package app
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestObjectsHandler(t *testing.T) {
+	c := NewMrrCache()
+	s1 := KubeServer{URL: "https://s1"}
+	s2 := KubeServer{URL: "https://s2"}
+	c.objects[s1] = []KubeObject{
+		{TypeMeta: TypeMeta{Kind: "pod"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "prod"}},
+		{TypeMeta: TypeMeta{Kind: "pod"}, ObjectMeta: ObjectMeta{Name: "b", Namespace: "dev"}},
+		{TypeMeta: TypeMeta{Kind: "service"}, ObjectMeta: ObjectMeta{Name: "c", Namespace: "prod"}},
+	}
+	c.objects[s2] = []KubeObject{
+		{TypeMeta: TypeMeta{Kind: "pod"}, ObjectMeta: ObjectMeta{Name: "d", Namespace: "prod"}},
+	}
+	server := httptest.NewServer(objectsHandler(c))
+	defer server.Close()
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{query: "kind=pod", expected: []string{"a", "b", "d"}},
+		{query: "kind=po&namespace=prod", expected: []string{"a", "d"}},
+		{query: "kind=pods&server=https://s1", expected: []string{"a", "b"}},
+		{query: "kind=svc", expected: []string{"c"}},
+		{query: "kind=pod&name=[ab]", expected: []string{"a", "b"}},
+		{query: "kind=pod&regex=^(a|d)$", expected: []string{"a", "d"}},
+		{query: "kind=deployment", expected: []string{}},
+	}
+
+	for _, test := range tests {
+		resp, err := http.Get(server.URL + "/objects?" + test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects := []KubeObject{}
+		err = json.NewDecoder(resp.Body).Decode(&objects)
+		resp.Body.Close()
+		if err != nil {
+			t.Errorf("GET %s: could not decode response: %s", test.query, err)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: expected status 200, got %d", test.query, resp.StatusCode)
+		}
+		if resp.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("GET %s: unexpected content type %s", test.query, resp.Header.Get("Content-Type"))
+		}
+		names := []string{}
+		for _, o := range objects {
+			names = append(names, o.Name)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("GET %s: expected %v, got %v", test.query, test.expected, names)
+		}
+	}
+}
+
+func TestObjectsHandlerErrors(t *testing.T) {
+	c := NewMrrCache()
+	c.objects[KubeServer{URL: "https://s1"}] = []KubeObject{}
+	server := httptest.NewServer(objectsHandler(c))
+	defer server.Close()
+
+	tests := []struct {
+		method string
+		query  string
+		status int
+	}{
+		{method: "GET", query: "", status: http.StatusBadRequest},
+		{method: "GET", query: "kind=bananas", status: http.StatusBadRequest},
+		{method: "GET", query: "kind=pod&name=[", status: http.StatusBadRequest},
+		{method: "GET", query: "kind=pod&regex=(", status: http.StatusBadRequest},
+		{method: "GET", query: "kind=pod&server=https://unknown", status: http.StatusNotFound},
+		{method: "POST", query: "kind=pod", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, server.URL+"/objects?"+test.query, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %s: expected status %d, got %d", test.method, test.query, test.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		token         string
+		authorization string
+		status        int
+	}{
+		{token: "", authorization: "", status: http.StatusOK},
+		{token: "secret", authorization: "Bearer secret", status: http.StatusOK},
+		{token: "secret", authorization: "", status: http.StatusUnauthorized},
+		{token: "secret", authorization: "Bearer other", status: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/objects?kind=pod", nil)
+		if test.authorization != "" {
+			r.Header.Set("Authorization", test.authorization)
+		}
+		w := httptest.NewRecorder()
+		requireToken(test.token, ok).ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("Token %q, authorization %q: expected status %d, got %d", test.token, test.authorization, test.status, w.Code)
+		}
+	}
+}
+
+func TestFlushHandler(t *testing.T) {
+	c := NewMrrCache()
+	flushed := []MrrFilter{}
+	c.flusher = func(f MrrFilter) int {
+		flushed = append(flushed, f)
+		return 1
+	}
+
+	h := requireToken("secret", flushHandler(c))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/admin/flush", nil))
+	if w.Code != http.StatusUnauthorized || len(flushed) > 0 {
+		t.Errorf("Expected flush without token to be rejected, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/admin/flush?server=https://s1&profile=alice", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"restarted":1}` {
+		t.Errorf("Unexpected response %d %s", w.Code, w.Body)
+	}
+	if !reflect.DeepEqual(flushed, []MrrFilter{{Server: "https://s1", Profile: "alice"}}) {
+		t.Errorf("Expected flush of https://s1 of alice, got %v", flushed)
+	}
+
+	w = httptest.NewRecorder()
+	flushHandler(c).ServeHTTP(w, httptest.NewRequest("GET", "/admin/flush", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	c.flusher = nil
+	w = httptest.NewRecorder()
+	flushHandler(c).ServeHTTP(w, httptest.NewRequest("POST", "/admin/flush", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestWatchersHandler(t *testing.T) {
+	c := NewMrrCache()
+	c.updateKubeObject(KubeServer{URL: "https://s1"}, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a"}})
+	c.metrics.watchReconnected(KubeServer{URL: "https://s1"}, "pod")
+
+	w := httptest.NewRecorder()
+	watchersHandler(c).ServeHTTP(w, httptest.NewRequest("GET", "/watchers?server=https://s1", nil))
+	servers := []ServerStatus{}
+	if err := json.NewDecoder(w.Body).Decode(&servers); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if len(servers) != 1 || len(servers[0].Kinds) != 1 || servers[0].Kinds[0].Objects != 1 || servers[0].Kinds[0].Reconnects != 1 {
+		t.Errorf("Unexpected state of watchers %+v", servers)
+	}
+}
+
+func TestServeUntilShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewMrrCache()
+	started := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("ok"))
+	})
+	shutdown := make(chan struct{})
+	served := make(chan error, 1)
+	go func() {
+		served <- serveUntilShutdown(l, c, slow, MrrServerOptions{Shutdown: shutdown, DrainTimeout: time.Second})
+	}()
+
+	answered := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + l.Addr().String())
+		if err != nil {
+			answered <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		answered <- string(body)
+	}()
+
+	<-started
+	c.requests.RLock()
+	close(shutdown)
+
+	select {
+	case err := <-served:
+		t.Fatalf("Expected to wait for requests of clients, stopped with %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if body := <-answered; body != "ok" {
+		t.Errorf("Expected in-flight request to be answered, got %q", body)
+	}
+	if _, err := net.Dial("tcp", l.Addr().String()); err == nil {
+		t.Errorf("Expected new connections to be refused")
+	}
+
+	c.requests.RUnlock()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected to stop after requests are answered")
+	}
+}
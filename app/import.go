@@ -0,0 +1,86 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+)
+
+func NewImportCommand(f Factory) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "import [file]",
+		Short: "Put dumped objects into the mirror",
+		Long: `
+DESCRIPTION:
+  Put objects dumped by "kubemrr export" into the "kubemrr watch" process, replacing
+  objects of the same servers. "-" reads the dump from standard input.
+
+  Watched servers list their objects again as usual, objects of other servers stay
+  until they are flushed. It helps to seed a fresh mirror and to give offline demos.
+  The mirror allows it only when it requires --token and is given
+  --feature-gates=AdminAPI=true.
+
+EXAMPLE:
+  kubemrr import snap.json
+  ssh build-host kubemrr export | kubemrr import -
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := RunCommon(cmd); err != nil {
+				return err
+			}
+			return RunImport(f, cmd, args)
+		},
+	}
+
+	AddCommonFlags(cmd)
+	AddMirrorTLSFlags(cmd)
+	AddTokenFlag(cmd)
+	return cmd
+}
+
+func RunImport(f Factory, cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("exactly one file is expected")
+	}
+
+	var raw []byte
+	var err error
+	if args[0] == "-" {
+		raw, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		raw, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("could not read %s: %s", args[0], err)
+	}
+
+	snapshot, err := parseSnapshot(raw)
+	if err != nil {
+		return fmt.Errorf("could not parse %s: %s", args[0], err)
+	}
+
+	bind, err := GetBind(cmd)
+	if err != nil {
+		return fmt.Errorf("unexpected error: %s", err)
+	}
+
+	opts, err := GetMrrClientOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	client, err := f.MrrClient(bind, opts)
+	if err != nil {
+		return fmt.Errorf("could not create client to kubemrr: %s", err)
+	}
+
+	imported, err := client.Import(snapshot)
+	if err != nil {
+		return fmt.Errorf("could not import objects into kubemrr: %s", err)
+	}
+
+	fmt.Fprintf(f.StdOut(), "Imported %d objects of %d servers\n", imported, len(snapshot.Servers))
+	return nil
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"sort"
+	"strings"
+)
+
+//scopeKey identifies objects of a kind in a namespace
+type scopeKey struct {
+	kind      string
+	namespace string
+}
+
+//objectIndex shards objects of a server by kind and namespace, so that requests of clients
+//do not scan all objects of the server. Key with empty namespace holds objects of the kind
+//in all namespaces. Objects of a shard keep the order they were added in, until one of them is removed
+type objectIndex map[scopeKey]*shard
+
+//shard holds objects of a kind in a namespace with their positions, so that events
+//update objects without scanning the shard
+type shard struct {
+	objects   []KubeObject
+	positions map[nameKey]int
+}
+
+type nameKey struct {
+	namespace string
+	name      string
+}
+
+func newObjectIndex(objects []KubeObject) objectIndex {
+	ix := objectIndex{}
+	for _, o := range objects {
+		ix.put(o)
+	}
+	return ix
+}
+
+//scopesOf returns keys the object is indexed under
+func scopesOf(o KubeObject) []scopeKey {
+	k := scopeKey{strings.ToLower(o.Kind), strings.ToLower(o.Namespace)}
+	if k.namespace == "" {
+		return []scopeKey{k}
+	}
+	return []scopeKey{{kind: k.kind}, k}
+}
+
+//put adds the object to the index, or replaces the indexed object with the same name and namespace
+func (ix objectIndex) put(o KubeObject) {
+	n := nameKey{o.Namespace, o.Name}
+	for _, k := range scopesOf(o) {
+		s, ok := ix[k]
+		if !ok {
+			s = &shard{positions: make(map[nameKey]int)}
+			ix[k] = s
+		}
+		if i, ok := s.positions[n]; ok {
+			s.objects[i] = o
+			continue
+		}
+		s.positions[n] = len(s.objects)
+		s.objects = append(s.objects, o)
+	}
+}
+
+//remove deletes the object with the same name and namespace from the index.
+//The last object of the shard takes its place, so that removal does not depend on the size of the shard
+func (ix objectIndex) remove(o KubeObject) {
+	n := nameKey{o.Namespace, o.Name}
+	for _, k := range scopesOf(o) {
+		s, ok := ix[k]
+		if !ok {
+			continue
+		}
+		i, ok := s.positions[n]
+		if !ok {
+			continue
+		}
+		if len(s.objects) == 1 {
+			delete(ix, k)
+			continue
+		}
+		last := len(s.objects) - 1
+		moved := s.objects[last]
+		s.objects[i] = moved
+		s.objects[last] = KubeObject{}
+		s.objects = s.objects[:last]
+		delete(s.positions, n)
+		if i != last {
+			s.positions[nameKey{moved.Namespace, moved.Name}] = i
+		}
+	}
+}
+
+//find returns objects of the kind in the namespace. Empty namespace returns objects of all
+//namespaces. Namespaces do not belong to namespaces, so all of them are returned.
+//Objects are not copied, so callers must hold at least the read lock while they use them
+func (ix objectIndex) find(kind string, namespace string) []KubeObject {
+	k := scopeKey{kind: strings.ToLower(kind)}
+	if k.kind == "namespace" {
+		return ix.namespaces()
+	}
+	k.namespace = strings.ToLower(namespace)
+	return ix.objects(k)
+}
+
+func (ix objectIndex) objects(k scopeKey) []KubeObject {
+	if s, ok := ix[k]; ok {
+		return s.objects
+	}
+	return nil
+}
+
+//namespaces returns mirrored namespaces followed by namespaces that are only seen in metadata
+//of other objects, sorted by name. The latter are known even when namespaces are not mirrored,
+//for example when the user may not list them
+func (ix objectIndex) namespaces() []KubeObject {
+	mirrored := ix.objects(scopeKey{kind: "namespace"})
+	known := map[string]bool{}
+	for _, o := range mirrored {
+		known[strings.ToLower(o.Name)] = true
+	}
+
+	observed := []string{}
+	for k, s := range ix {
+		if k.namespace != "" && !known[k.namespace] {
+			known[k.namespace] = true
+			observed = append(observed, s.objects[0].Namespace)
+		}
+	}
+	if len(observed) == 0 {
+		return mirrored
+	}
+
+	sort.Strings(observed)
+	res := make([]KubeObject, 0, len(mirrored)+len(observed))
+	res = append(res, mirrored...)
+	for _, ns := range observed {
+		res = append(res, KubeObject{TypeMeta: TypeMeta{Kind: "namespace"}, ObjectMeta: ObjectMeta{Name: ns}})
+	}
+	return res
+}
+
+//indexOf returns the index of objects of the server, building it when the objects changed
+//since the last request. Caller must hold at least the read lock
+func (c *MrrCache) indexOf(server KubeServer) objectIndex {
+	c.indexMu.Lock()
+	defer c.indexMu.Unlock()
+	ix, ok := c.index[server]
+	if !ok {
+		ix = newObjectIndex(c.objects[server])
+		c.index[server] = ix
+	}
+	return ix
+}
+
+//reindexLocked drops the index and cached results of the server after its objects were changed at once.
+//The index is built again on the next request. Caller must hold the write lock
+func (c *MrrCache) reindexLocked(server KubeServer) {
+	delete(c.index, server)
+	delete(c.positions, server)
+	c.results.invalidate(server, "")
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func TestObjectIndexShards(t *testing.T) {
+	ix := objectIndex{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		ix.put(KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: name, Namespace: "prod"}})
+	}
+	ix.remove(KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "b", Namespace: "prod"}})
+	ix.put(KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "c", Namespace: "prod", ResourceVersion: "2"}})
+	ix.remove(KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "x", Namespace: "prod"}})
+
+	for _, k := range []scopeKey{{kind: "pod"}, {"pod", "prod"}} {
+		actual := []string{}
+		for _, o := range ix.objects(k) {
+			actual = append(actual, o.Name+o.ResourceVersion)
+		}
+		if fmt.Sprint(actual) != "[a d c2]" {
+			t.Errorf("Expected d in place of b, [a d c2] in shard %+v, got %v", k, actual)
+		}
+		for i, o := range ix[k].objects {
+			if p := ix[k].positions[nameKey{o.Namespace, o.Name}]; p != i {
+				t.Errorf("Expected %s at %d in shard %+v, got %d", o.Name, i, k, p)
+			}
+		}
+	}
+
+	ix.remove(KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "prod"}})
+	ix.remove(KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "c", Namespace: "prod"}})
+	ix.remove(KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "d", Namespace: "prod"}})
+	if len(ix) != 0 {
+		t.Errorf("Expected empty shards to be dropped, got %+v", ix)
+	}
+}
+
+func TestUpdateKubeObjectPositions(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	a := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a"}}
+	b := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "b"}}
+	c.replaceKubeObjects(s, "pod", "", []KubeObject{a, b})
+	c.deleteKubeObject(s, a)
+	b.ResourceVersion = "2"
+	c.updateKubeObject(s, b)
+	c.updateKubeObject(s, a)
+
+	if fmt.Sprint(c.objects[s]) != fmt.Sprint([]KubeObject{b, a}) {
+		t.Errorf("Expected %+v, got %+v", []KubeObject{b, a}, c.objects[s])
+	}
+}
+
+//largeCache returns a cache with a server of n pods in 100 namespaces, like a big cluster
+func largeCache(n int) (*MrrCache, KubeServer) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "https://large"}
+	pods := make([]KubeObject, 0, n)
+	for i := 0; i < n; i++ {
+		app := fmt.Sprintf("app%d", i%500)
+		pods = append(pods, KubeObject{
+			TypeMeta: TypeMeta{"pod"},
+			ObjectMeta: ObjectMeta{
+				Name:            fmt.Sprintf("%s-%d", app, i),
+				Namespace:       fmt.Sprintf("ns%d", i%100),
+				ResourceVersion: "1",
+				Labels:          map[string]string{"app": app, "tier": "web"},
+			},
+			Spec:   ObjectSpec{Containers: []Container{{Name: app, Image: "registry/" + app + ":1.0"}}},
+			Status: ObjectStatus{Phase: "Running"},
+		})
+	}
+	c.replaceKubeObjects(s, "pod", "", pods)
+	return c, s
+}
+
+func BenchmarkObjects(b *testing.B) {
+	defer quietLogs()()
+	c, _ := largeCache(100000)
+	c.indexOf(KubeServer{URL: "https://large"})
+	filters := []MrrFilter{
+		{Kind: "pod"},
+		{Kind: "pod", Namespace: "ns7"},
+		{Kind: "pod", Selector: "app=app42"},
+	}
+	for _, f := range filters {
+		b.Run(fmt.Sprintf("namespace=%s,selector=%s", f.Namespace, f.Selector), func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.results = newResultCache()
+				var os []KubeObject
+				if err := c.Objects(&f, &os); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNamesCached(b *testing.B) {
+	defer quietLogs()()
+	c, _ := largeCache(100000)
+	f := MrrFilter{Kind: "pod", Namespace: "ns7"}
+	var names []byte
+	c.Names(&f, &names)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.Names(&f, &names); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUpdateKubeObject(b *testing.B) {
+	defer quietLogs()()
+	c, s := largeCache(100000)
+	var os []KubeObject
+	c.Objects(&MrrFilter{Kind: "pod"}, &os)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		o := os[i%len(os)]
+		o.ResourceVersion = fmt.Sprint(i)
+		c.updateKubeObject(s, o)
+	}
+}
+
+//BenchmarkChurn deletes a pod and adds a new one, as rolling deployments do on big clusters
+func BenchmarkChurn(b *testing.B) {
+	defer quietLogs()()
+	c, s := largeCache(100000)
+	var os []KubeObject
+	c.Objects(&MrrFilter{Kind: "pod"}, &os)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		o := os[i%len(os)]
+		c.deleteKubeObject(s, o)
+		o.Name = fmt.Sprintf("%s-%d", o.Name, i)
+		os[i%len(os)] = o
+		c.updateKubeObject(s, o)
+	}
+}
+
+//quietLogs stops debug logs of a benchmark, which would measure logging rather than the cache.
+//It returns the function that restores the level
+func quietLogs() func() {
+	level := log.GetLevel()
+	log.SetLevel(log.InfoLevel)
+	return func() { log.SetLevel(level) }
+}
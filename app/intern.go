@@ -0,0 +1,52 @@
+package app
+
+//maxInterned bounds the number of interned strings. When it is reached, interning starts over,
+//so that strings of objects that are long gone are not kept forever
+const maxInterned = 1 << 16
+
+//interner keeps a single copy of strings that repeat across objects: kinds, namespaces, labels,
+//phases, containers and images. With 100k objects, each of them would otherwise be held
+//as many times, and scanned by every garbage collection
+type interner map[string]string
+
+func (in interner) intern(s string) string {
+	if v, ok := in[s]; ok {
+		return v
+	}
+	if len(in) >= maxInterned {
+		for k := range in {
+			delete(in, k)
+		}
+	}
+	in[s] = s
+	return s
+}
+
+//object returns the object with its repeated strings interned. Containers and conditions
+//of the object are interned in place, labels are copied
+func (in interner) object(o KubeObject) KubeObject {
+	o.Kind = in.intern(o.Kind)
+	o.Namespace = in.intern(o.Namespace)
+	if len(o.Labels) > 0 {
+		labels := make(map[string]string, len(o.Labels))
+		for k, v := range o.Labels {
+			labels[in.intern(k)] = in.intern(v)
+		}
+		o.Labels = labels
+	}
+	o.Status.Phase = in.intern(o.Status.Phase)
+	for i := range o.Status.Conditions {
+		o.Status.Conditions[i].Type = in.intern(o.Status.Conditions[i].Type)
+		o.Status.Conditions[i].Status = in.intern(o.Status.Conditions[i].Status)
+	}
+	in.containers(o.Spec.Containers)
+	in.containers(o.Spec.InitContainers)
+	return o
+}
+
+func (in interner) containers(cs []Container) {
+	for i := range cs {
+		cs[i].Name = in.intern(cs[i].Name)
+		cs[i].Image = in.intern(cs[i].Image)
+	}
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"unsafe"
+)
+
+func TestInternerObject(t *testing.T) {
+	in := interner{}
+	object := func() KubeObject {
+		return KubeObject{
+			TypeMeta:   TypeMeta{string([]byte("pod"))},
+			ObjectMeta: ObjectMeta{Name: "web", Namespace: string([]byte("prod")), Labels: map[string]string{"app": string([]byte("web"))}},
+			Spec:       ObjectSpec{Containers: []Container{{Name: "web", Image: string([]byte("nginx:1.0"))}}},
+		}
+	}
+	a := object()
+	expected := object()
+	a = in.object(a)
+	b := in.object(object())
+
+	if !reflect.DeepEqual(a, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, a)
+	}
+	same := func(x, y string) bool {
+		return (*reflect.StringHeader)(unsafe.Pointer(&x)).Data == (*reflect.StringHeader)(unsafe.Pointer(&y)).Data
+	}
+	if !same(a.Kind, b.Kind) || !same(a.Namespace, b.Namespace) || !same(a.Labels["app"], b.Labels["app"]) || !same(a.Spec.Containers[0].Image, b.Spec.Containers[0].Image) {
+		t.Errorf("Expected repeated strings to be shared by the objects")
+	}
+}
+
+func TestInternerLimit(t *testing.T) {
+	in := interner{}
+	for i := 0; i < maxInterned; i++ {
+		in[strconv.Itoa(i)] = ""
+	}
+	in.intern("new")
+	if len(in) != 1 {
+		t.Errorf("Expected interning to start over, got %d strings", len(in))
+	}
+}
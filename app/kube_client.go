@@ -2,14 +2,20 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	log "github.com/Sirupsen/logrus"
 	"io"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"net/url"
+	"sort"
 	"sync"
+	"text/template"
+	"time"
 )
 
 type EventType string
@@ -18,48 +24,198 @@ const (
 	Added    EventType = "ADDED"
 	Modified EventType = "MODIFIED"
 	Deleted  EventType = "DELETED"
+	Bookmark EventType = "BOOKMARK"
+	Error    EventType = "ERROR"
 )
 
+//APIError is returned when the server answers with an error status or ends a watch with an error event
+type APIError struct {
+	Code    int
+	Message string
+
+	//RetryAfter is how long the server asked to wait before the next request
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.Code, e.Message)
+}
+
+func newAPIError(req *http.Request, resp *http.Response) *APIError {
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		body = []byte(err.Error())
+	}
+	return &APIError{
+		Code:       resp.StatusCode,
+		Message:    fmt.Sprintf("%s %s: %s", req.Method, req.URL, string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+//isExpired checks whether the watch failed because the requested resource version is too old
+func isExpired(err error) bool {
+	we, ok := err.(*APIError)
+	return ok && we.Code == http.StatusGone
+}
+
 type ObjectEvent struct {
 	Type   EventType   `json:"type"`
 	Object *KubeObject `json:"object"`
 }
 
 type ObjectList struct {
-	Objects []KubeObject `json:"items"`
+	Metadata ListMeta     `json:"metadata"`
+	Objects  []KubeObject `json:"items"`
+}
+
+//ListMeta is metadata of a list of objects
+type ListMeta struct {
+	//ResourceVersion is the version of the list, from which a watch continues
+	ResourceVersion string `json:"resourceVersion,omitempty"`
 }
 
 type KubeClient interface {
 	Server() KubeServer
 	Ping() error
-	WatchObjects(kind string, out chan *ObjectEvent) error
-	GetObjects(kind string) ([]KubeObject, error)
+	WatchObjects(kind string, opts ListOptions, out chan *ObjectEvent) error
+	GetObjects(kind string, opts ListOptions) ([]KubeObject, error)
+
+	//ListObjects returns objects like GetObjects, and the resource version of the list
+	ListObjects(kind string, opts ListOptions) ([]KubeObject, string, error)
+
+	//ClusterID returns the UID of the kube-system namespace, which identifies the cluster
+	//no matter by which URL it is reached
+	ClusterID() (string, error)
+
+	//Resources returns resources discovered on the server, one for each kind
+	Resources() ([]APIResource, error)
+	Close()
+}
+
+//ListOptions narrow down objects that are watched or listed
+type ListOptions struct {
+	//Namespace limits objects to one namespace. Empty namespace means all namespaces
+	Namespace string
+
+	//ResourceVersion is the version after which a watch starts
+	ResourceVersion string
+}
+
+//defaultResources are used for the supported kinds when the server does not tell about them
+var defaultResources = map[string]APIResource{
+	"pod":        {Name: "pods", Kind: "Pod", Namespaced: true, GroupVersion: "v1"},
+	"service":    {Name: "services", Kind: "Service", Namespaced: true, GroupVersion: "v1"},
+	"deployment": {Name: "deployments", Kind: "Deployment", Namespaced: true, GroupVersion: "extensions/v1beta1"},
+	"configmap":  {Name: "configmaps", Kind: "ConfigMap", Namespaced: true, GroupVersion: "v1"},
+	"namespace":  {Name: "namespaces", Kind: "Namespace", GroupVersion: "v1"},
+	"node":       {Name: "nodes", Kind: "Node", GroupVersion: "v1"},
+}
+
+//isNamespaced checks whether objects of the kind belong to namespaces
+func isNamespaced(kind string) bool {
+	r, ok := defaultResources[kind]
+	return !ok || r.Namespaced
 }
 
 type DefaultKubeClient struct {
-	client  *http.Client
-	baseURL *url.URL
+	client   *http.Client
+	baseURL  *url.URL
+	ctx      context.Context
+	cancel   context.CancelFunc
+	paths    map[string]*template.Template
+	protobuf bool
+	selector string
+	fields   map[string]string
+	limiter  *tokenBucket
+	tokens   tokenSource
+	tlsErr   error
+	profile  string
+
+	discoveryCache *discoveryCache
+	discoveryOnce  sync.Once
+	resourcesMu    sync.RWMutex
+	resources      map[string]APIResource
 }
 
 //NewKubeClient returns a client that talks to Kubenetes API server.
 //It talks to only one server, and uses configuration of the current context in the
 //given config
-func NewKubeClient(config *Config) KubeClient {
-	tlsConfig, _ := config.GenerateTLSConfig()
+func NewKubeClient(config *Config, opts KubeClientOptions) KubeClient {
+	//requests fail with the error of TLS configuration, so that it is reported instead of handshake failures
+	tlsConfig, tlsErr := config.GenerateTLSConfig()
+	//the transport asks for gzip and decompresses responses itself, as long as
+	//requests do not set Accept-Encoding. It makes initial lists of large clusters much smaller
 	tr := &http.Transport{
 		TLSClientConfig: tlsConfig,
+		Proxy:           http.ProxyFromEnvironment,
+	}
+	if proxy := config.getCurrentCluster().ProxyURL; proxy != "" {
+		//proxy-url of the cluster takes precedence over HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+		u, err := parseProxyURL(proxy)
+		if err != nil {
+			log.WithField("server", config.getCurrentCluster().Server).WithField("error", err).Warn("ignoring proxy-url of the cluster")
+		} else {
+			tr.Proxy = http.ProxyURL(u)
+		}
 	}
 	httpClient := &http.Client{Transport: tr}
 
 	url, _ := url.Parse(config.getCurrentCluster().Server)
-	return &DefaultKubeClient{
-		client:  httpClient,
-		baseURL: url,
+	ctx, cancel := context.WithCancel(context.Background())
+	paths := make(map[string]*template.Template)
+	for kind, path := range opts.Paths {
+		t, err := template.New(kind).Parse(path)
+		if err == nil {
+			paths[kind] = t
+		}
+	}
+
+	kc := &DefaultKubeClient{
+		client:   httpClient,
+		baseURL:  url,
+		ctx:      ctx,
+		cancel:   cancel,
+		paths:    paths,
+		protobuf: opts.Protobuf,
+		selector: opts.LabelSelector,
+		fields:   opts.FieldSelectors,
+		limiter:  newTokenBucket(opts.QPS, opts.Burst),
+		tokens:   newTokenSource(config.getUser(config.getCurrentContext().User)),
+		tlsErr:   tlsErr,
+		profile:  opts.Profile,
+	}
+	if opts.DiscoveryCacheDir != "" {
+		kc.discoveryCache = &discoveryCache{opts.DiscoveryCacheDir}
+	}
+	return kc
+}
+
+//parseProxyURL checks that the proxy is given as an http, https or socks5 URL
+func parseProxyURL(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		if u.Host == "" {
+			return nil, fmt.Errorf("proxy url %s does not have a host", proxy)
+		}
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme of proxy url %s, expected http, https or socks5", proxy)
 	}
 }
 
 func (kc *DefaultKubeClient) Server() KubeServer {
-	return KubeServer{kc.baseURL.String()}
+	return KubeServer{URL: kc.baseURL.String(), Profile: kc.profile}
+}
+
+//Close aborts requests in flight, including open watch connections.
+//The client must not be used after it was closed
+func (kc *DefaultKubeClient) Close() {
+	kc.cancel()
 }
 
 func (kc *DefaultKubeClient) Ping() error {
@@ -70,53 +226,181 @@ func (kc *DefaultKubeClient) Ping() error {
 	return kc.do(req, nil)
 }
 
-func (kc *DefaultKubeClient) WatchObjects(kind string, out chan *ObjectEvent) error {
-	switch kind {
-	case "pod":
-		return kc.watch("api/v1/pods?watch=true", out)
-	case "service":
-		return kc.watch("api/v1/services?watch=true", out)
-	case "deployment":
-		return kc.watch("/apis/extensions/v1beta1/deployments?watch=true", out)
-	default:
-		return fmt.Errorf("unsupported kind: %s", kind)
-	}
-}
-
-func (kc *DefaultKubeClient) GetObjects(kind string) ([]KubeObject, error) {
-	switch kind {
-	case "node":
-		return kc.get("api/v1/nodes", kind)
-	case "configmap":
-		return kc.get("api/v1/configmaps", kind)
-	case "service":
-		return kc.get("api/v1/services", kind)
-	case "deployment":
-		return kc.get("/apis/extensions/v1beta1/deployments", kind)
-	case "namespace":
-		return kc.get("api/v1/namespaces", kind)
-	default:
-		return []KubeObject{}, fmt.Errorf("unsupported kind: %s", kind)
+func (kc *DefaultKubeClient) ClusterID() (string, error) {
+	req, err := kc.newRequest("GET", "/api/v1/namespaces/kube-system", nil)
+	if err != nil {
+		return "", err
+	}
+	var ns KubeObject
+	if err := kc.do(req, &ns); err != nil {
+		return "", err
 	}
+	if ns.UID == "" {
+		return "", errors.New("namespace kube-system does not have uid")
+	}
+	return ns.UID, nil
 }
 
-func (kc *DefaultKubeClient) get(url string, kind string) ([]KubeObject, error) {
-	req, err := kc.newRequest("GET", url, nil)
+//WatchObjects sends changes of objects of the kind to the channel. If the resource version
+//is given, the watch starts after it, otherwise it starts with the current objects
+func (kc *DefaultKubeClient) WatchObjects(kind string, opts ListOptions, out chan *ObjectEvent) error {
+	u, err := kc.listURL(kind, opts)
+	if err != nil {
+		return err
+	}
+
+	q := u.Query()
+	q.Set("watch", "true")
+	q.Set("allowWatchBookmarks", "true")
+	if opts.ResourceVersion != "" {
+		q.Set("resourceVersion", opts.ResourceVersion)
+	}
+	u.RawQuery = q.Encode()
+	return kc.watch(u.String(), out)
+}
+
+func (kc *DefaultKubeClient) GetObjects(kind string, opts ListOptions) ([]KubeObject, error) {
+	objects, _, err := kc.ListObjects(kind, opts)
+	return objects, err
+}
+
+func (kc *DefaultKubeClient) ListObjects(kind string, opts ListOptions) ([]KubeObject, string, error) {
+	u, err := kc.listURL(kind, opts)
+	if err != nil {
+		return []KubeObject{}, "", err
+	}
+
+	list, err := kc.get(u.String(), kind)
+	if err != nil {
+		return []KubeObject{}, "", err
+	}
+	return list.Objects, list.Metadata.ResourceVersion, nil
+}
+
+//listURL returns the URL that lists objects of the kind selected by the options and the client
+func (kc *DefaultKubeClient) listURL(kind string, opts ListOptions) (*url.URL, error) {
+	u, err := kc.kindURL(kind, opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	if kc.selector != "" {
+		q.Set("labelSelector", kc.selector)
+	}
+	if fields := kc.fields[kind]; fields != "" {
+		q.Set("fieldSelector", fields)
+	}
+	u.RawQuery = q.Encode()
+	return u, nil
+}
+
+//kindURL returns the URL of the objects of the given kind in the namespace,
+//relative to the URL of the server. Custom paths take precedence over the default ones
+func (kc *DefaultKubeClient) kindURL(kind string, namespace string) (*url.URL, error) {
+	var path string
+	if t, ok := kc.paths[kind]; ok {
+		buf := new(bytes.Buffer)
+		err := t.Execute(buf, struct{ Namespace string }{namespace})
+		if err != nil {
+			return nil, fmt.Errorf("could not make path of %s: %s", kind, err)
+		}
+		path = buf.String()
+	} else if r, ok := kc.resource(kind); ok {
+		path = r.path(namespace)
+	} else if r, ok := defaultResources[kind]; ok {
+		path = r.path(namespace)
+	} else {
+		return nil, fmt.Errorf("unsupported kind: %s", kind)
+	}
+
+	return url.Parse(path)
+}
+
+//resource returns the discovered resource of the kind. Discovery happens once per client
+func (kc *DefaultKubeClient) resource(kind string) (APIResource, bool) {
+	kc.discoveryOnce.Do(kc.loadResources)
+
+	kc.resourcesMu.RLock()
+	defer kc.resourcesMu.RUnlock()
+	r, ok := kc.resources[kind]
+	return r, ok
+}
+
+func (kc *DefaultKubeClient) Resources() ([]APIResource, error) {
+	kc.discoveryOnce.Do(kc.loadResources)
+
+	kc.resourcesMu.RLock()
+	defer kc.resourcesMu.RUnlock()
+	if kc.resources == nil {
+		return nil, errors.New("resources of the server are not discovered")
+	}
+	res := []APIResource{}
+	for _, r := range kc.resources {
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Kind < res[j].Kind })
+	return res, nil
+}
+
+//loadResources takes resources from the discovery cache, if there are any, and validates
+//them in background. Otherwise it waits for discovery to finish
+func (kc *DefaultKubeClient) loadResources() {
+	if kc.discoveryCache != nil {
+		resources, err := kc.discoveryCache.load(kc.Server())
+		if err == nil {
+			log.WithField("server", kc.baseURL.String()).Debug("using cached discovery")
+			kc.setResources(resources)
+			go kc.refreshResources()
+			return
+		}
+	}
+
+	kc.refreshResources()
+}
+
+func (kc *DefaultKubeClient) refreshResources() {
+	l := log.WithField("server", kc.baseURL.String())
+	resources, err := kc.discover()
 	if err != nil {
-		return []KubeObject{}, err
+		l.WithField("error", err).Warn("discovery failed, using default paths")
+		return
 	}
 
+	kc.setResources(resources)
+	l.Infof("discovered %d resources", len(resources))
+
+	if kc.discoveryCache != nil {
+		if err := kc.discoveryCache.save(kc.Server(), resources); err != nil {
+			l.WithField("error", err).Warn("could not save discovery cache")
+		}
+	}
+}
+
+func (kc *DefaultKubeClient) setResources(resources []APIResource) {
+	kc.resourcesMu.Lock()
+	defer kc.resourcesMu.Unlock()
+	kc.resources = kindResources(resources)
+}
+
+func (kc *DefaultKubeClient) get(url string, kind string) (ObjectList, error) {
 	var list ObjectList
+	req, err := kc.newRequest("GET", url, nil)
+	if err != nil {
+		return list, err
+	}
+	kc.acceptProtobuf(req)
+
 	err = kc.do(req, &list)
 	if err != nil {
-		return []KubeObject{}, err
+		return list, err
 	}
 
 	for i := range list.Objects {
 		list.Objects[i].Kind = kind
 	}
 
-	return list.Objects, nil
+	return list, nil
 }
 
 func (kc *DefaultKubeClient) watch(url string, out chan *ObjectEvent) error {
@@ -124,34 +408,69 @@ func (kc *DefaultKubeClient) watch(url string, out chan *ObjectEvent) error {
 	if err != nil {
 		return err
 	}
+	kc.acceptProtobuf(req)
 
-	res, err := kc.client.Do(req)
+	res, err := kc.send(req)
 	if err != nil {
 		return err
 	}
 	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("Failed to watch pods: %d", res.StatusCode)
+		return newAPIError(req, res)
 	}
 
-	d := json.NewDecoder(res.Body)
+	var next func() (*ObjectEvent, error)
+	if isProtobuf(res.Header) {
+		next = func() (*ObjectEvent, error) {
+			frame, err := readProtobufFrame(res.Body)
+			if err != nil {
+				return nil, err
+			}
+			return decodeProtobufEvent(frame)
+		}
+	} else {
+		d := json.NewDecoder(res.Body)
+		next = func() (*ObjectEvent, error) {
+			//the object of an error event is a Status, which keeps its code next to metadata
+			var event struct {
+				Type   EventType `json:"type"`
+				Object struct {
+					KubeObject
+					Code    int    `json:"code"`
+					Message string `json:"message"`
+				} `json:"object"`
+			}
+			if err := d.Decode(&event); err != nil {
+				return nil, err
+			}
+			if event.Type == Error {
+				return nil, &APIError{Code: event.Object.Code, Message: event.Object.Message}
+			}
+			return &ObjectEvent{Type: event.Type, Object: &event.Object.KubeObject}, nil
+		}
+	}
 
 	for {
-		var event ObjectEvent
-		err := d.Decode(&event)
+		event, err := next()
 
 		if err == io.EOF {
 			return nil
 		}
 
+		if _, ok := err.(*APIError); ok {
+			return err
+		}
+
 		if err != nil {
 			return fmt.Errorf("Could not decode data into pod event: %s", err)
 		}
 
-		out <- &event
+		select {
+		case out <- event:
+		case <-kc.ctx.Done():
+			return kc.ctx.Err()
+		}
 	}
-
-	return nil
 }
 
 func (kc *DefaultKubeClient) newRequest(method string, urlStr string, body interface{}) (*http.Request, error) {
@@ -174,6 +493,7 @@ func (kc *DefaultKubeClient) newRequest(method string, urlStr string, body inter
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(kc.ctx)
 
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
@@ -182,8 +502,41 @@ func (kc *DefaultKubeClient) newRequest(method string, urlStr string, body inter
 	return req, nil
 }
 
+//acceptProtobuf asks the server to encode objects in protobuf, which is much cheaper to decode.
+//Servers answer in JSON for kinds that have no protobuf encoding
+func (kc *DefaultKubeClient) acceptProtobuf(req *http.Request) {
+	if kc.protobuf {
+		req.Header.Set("Accept", protobufContentType+", application/json")
+	}
+}
+
+//send makes the request once the rate limit allows it
+func (kc *DefaultKubeClient) send(req *http.Request) (*http.Response, error) {
+	if kc.tlsErr != nil {
+		return nil, kc.tlsErr
+	}
+	if err := kc.limiter.wait(kc.ctx); err != nil {
+		return nil, err
+	}
+	if kc.tokens == nil {
+		return kc.client.Do(req)
+	}
+
+	token, err := kc.tokens.token()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := kc.client.Do(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		//the next request gets a new token from the credential plugin
+		kc.tokens.invalidate(token)
+	}
+	return resp, err
+}
+
 func (c *DefaultKubeClient) do(req *http.Request, v interface{}) error {
-	resp, err := c.client.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
 		return err
 	}
@@ -195,11 +548,15 @@ func (c *DefaultKubeClient) do(req *http.Request, v interface{}) error {
 	}()
 
 	if resp.StatusCode >= 300 {
+		return newAPIError(req, resp)
+	}
+
+	if list, ok := v.(*ObjectList); ok && isProtobuf(resp.Header) {
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			body = []byte(err.Error())
+			return err
 		}
-		return fmt.Errorf("unexpected status for %s %s: %s %s", req.Method, req.URL, resp.Status, string(body))
+		return decodeProtobufList(body, list)
 	}
 
 	if v != nil {
@@ -215,6 +572,14 @@ func (c *DefaultKubeClient) do(req *http.Request, v interface{}) error {
 type TestKubeClient struct {
 	baseURL *url.URL
 	pings   int
+	closed  chan struct{}
+	profile string
+
+	clusterID    string
+	clusterIDErr error
+
+	resources    []APIResource
+	resourcesErr error
 
 	objectEvents  []*ObjectEvent
 	objectEventsF func() []*ObjectEvent
@@ -222,15 +587,19 @@ type TestKubeClient struct {
 	watchObjectHits  map[string]int
 	watchObjectLock  *sync.RWMutex
 	watchObjectError error
+	watchOptions     []ListOptions
 
-	objects       []KubeObject
-	objectsF      func() []KubeObject
-	getObjectHits map[string]int
+	objects          []KubeObject
+	objectsF         func() []KubeObject
+	getObjectHits    map[string]int
+	getObjectOptions []ListOptions
+	listVersion      string
 }
 
 func NewTestKubeClient() *TestKubeClient {
 	kc := &TestKubeClient{}
 	kc.baseURL, _ = url.Parse(fmt.Sprintf("http://random-url-%d.com", rand.Intn(999)))
+	kc.closed = make(chan struct{})
 	kc.watchObjectLock = &sync.RWMutex{}
 	kc.watchObjectHits = map[string]int{}
 	kc.objectEventsF = func() []*ObjectEvent { return []*ObjectEvent{} }
@@ -241,7 +610,7 @@ func NewTestKubeClient() *TestKubeClient {
 }
 
 func (kc *TestKubeClient) Server() KubeServer {
-	return KubeServer{kc.baseURL.String()}
+	return KubeServer{URL: kc.baseURL.String(), Profile: kc.profile}
 }
 
 func (kc *TestKubeClient) Ping() error {
@@ -249,29 +618,60 @@ func (kc *TestKubeClient) Ping() error {
 	return nil
 }
 
-func (kc *TestKubeClient) WatchObjects(kind string, out chan *ObjectEvent) error {
+func (kc *TestKubeClient) ClusterID() (string, error) {
+	return kc.clusterID, kc.clusterIDErr
+}
+
+func (kc *TestKubeClient) Resources() ([]APIResource, error) {
+	return kc.resources, kc.resourcesErr
+}
+
+func (kc *TestKubeClient) Close() {
+	select {
+	case <-kc.closed:
+	default:
+		close(kc.closed)
+	}
+}
+
+func (kc *TestKubeClient) isClosed() bool {
+	select {
+	case <-kc.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (kc *TestKubeClient) WatchObjects(kind string, opts ListOptions, out chan *ObjectEvent) error {
 	kc.watchObjectLock.Lock()
 	kc.watchObjectHits[kind] += 1
+	kc.watchOptions = append(kc.watchOptions, opts)
+	hits := kc.watchObjectHits[kind]
 	kc.watchObjectLock.Unlock()
 
-	for i := range kc.objectEvents {
-		out <- kc.objectEvents[i]
-	}
-
-	for _, o := range kc.objectEventsF() {
-		out <- o
+	events := append([]*ObjectEvent{}, kc.objectEvents...)
+	events = append(events, kc.objectEventsF()...)
+	for _, e := range events {
+		select {
+		case out <- e:
+		case <-kc.closed:
+			return errors.New("client was closed")
+		}
 	}
 
-	if kc.watchObjectHits[kind] < 5 && kc.watchObjectError != nil {
+	if hits < 5 && kc.watchObjectError != nil {
 		return kc.watchObjectError
 	}
 
-	select {}
+	<-kc.closed
+	return errors.New("client was closed")
 }
 
-func (kc *TestKubeClient) GetObjects(kind string) ([]KubeObject, error) {
+func (kc *TestKubeClient) GetObjects(kind string, opts ListOptions) ([]KubeObject, error) {
 	kc.watchObjectLock.Lock()
 	kc.getObjectHits[kind] += 1
+	kc.getObjectOptions = append(kc.getObjectOptions, opts)
 	kc.watchObjectLock.Unlock()
 
 	if len(kc.objects) == 0 {
@@ -280,3 +680,8 @@ func (kc *TestKubeClient) GetObjects(kind string) ([]KubeObject, error) {
 		return kc.objects, nil
 	}
 }
+
+func (kc *TestKubeClient) ListObjects(kind string, opts ListOptions) ([]KubeObject, string, error) {
+	objects, err := kc.GetObjects(kind, opts)
+	return objects, kc.listVersion, err
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+//rotatingFile is a log file that is renamed to FILE.1 once it grows over maxSize bytes, while older
+//files are shifted to FILE.2 and so on. At most maxFiles rotated files are kept, and zero maxSize
+//means the file is never rotated
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	//rotateFailed tells that the last rotation failed and was reported, so that it is not reported again
+	rotateFailed bool
+}
+
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	path, err := substituteUserHome(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, size, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles, file: file, size: size}, nil
+}
+
+//openLogFile opens the file for appending and returns its size
+func openLogFile(path string) (*os.File, int64, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not open log file: %s", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("could not open log file: %s", err)
+	}
+	return file, info.Size(), nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		f.rotateOrReport()
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+//rotated returns the path of the rotated file with the number, such as FILE.1 for the newest one
+func (f *rotatingFile) rotated(n int) string {
+	return f.path + "." + strconv.Itoa(n)
+}
+
+//rotateOrReport rotates the file. When it fails, logs keep going to the current file, the error is
+//reported to stderr once, and rotation is tried again after another maxSize bytes
+func (f *rotatingFile) rotateOrReport() {
+	err := f.rotate()
+	if err == nil {
+		f.rotateFailed = false
+		return
+	}
+	if !f.rotateFailed {
+		fmt.Fprintf(os.Stderr, "could not rotate log file %s, still writing to it: %s\n", f.path, err)
+		f.rotateFailed = true
+	}
+	f.size = 0
+}
+
+//rotate shifts rotated files, removing the oldest one, renames the file to FILE.1 and starts a new one.
+//The current file is closed only once the new one is open, so that a failed rotation loses no logs
+func (f *rotatingFile) rotate() error {
+	renames := [][2]string{}
+	for i := f.maxFiles - 1; i >= 1; i-- {
+		renames = append(renames, [2]string{f.rotated(i), f.rotated(i + 1)})
+	}
+	if f.maxFiles > 0 {
+		renames = append(renames, [2]string{f.path, f.rotated(1)})
+	} else if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, r := range renames {
+		if err := os.Rename(r[0], r[1]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	file, size, err := openLogFile(f.path)
+	if err != nil {
+		return err
+	}
+	f.file.Close()
+	f.file, f.size = file, size
+	return nil
+}
+
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
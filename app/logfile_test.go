@@ -0,0 +1,116 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logs", "kubemrr.log")
+
+	f, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n", "line 4\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := map[string]string{path: "line 4\n", path + ".1": "line 3\n", path + ".2": "line 2\n"}
+	for file, content := range expected {
+		if actual, err := ioutil.ReadFile(file); err != nil || string(actual) != content {
+			t.Errorf("Expected %s to contain %q, got %q, %v", file, content, actual, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 rotated files to be kept, got %v", err)
+	}
+
+	f.Close()
+	f, err = openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.Write([]byte("line 5\n"))
+	if actual, _ := ioutil.ReadFile(path + ".1"); string(actual) != "line 4\n" {
+		t.Errorf("Expected size of the existing file to count, got %q in the rotated file", actual)
+	}
+}
+
+func TestRotatingFileUnlimited(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kubemrr.log")
+
+	f, err := openRotatingFile(path, 0, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+	f.Write([]byte("line 1\n"))
+	f.Write([]byte("line 2\n"))
+	if actual, _ := ioutil.ReadFile(path); string(actual) != "line 1\nline 2\n" {
+		t.Errorf("Expected the file not to be rotated, got %q", actual)
+	}
+}
+
+func TestRotatingFileFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kubemrr.log")
+
+	//a directory that is not empty cannot be replaced by the rotated file
+	if err := os.MkdirAll(filepath.Join(path+".1", "keep"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := ioutil.TempFile(dir, "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(orig *os.File) { os.Stderr = orig }(os.Stderr)
+	os.Stderr = stderr
+
+	f, err := openRotatingFile(path, 10, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if actual, _ := ioutil.ReadFile(path); string(actual) != "line 1\nline 2\nline 3\n" {
+		t.Errorf("Expected logs to go to the current file, got %q", actual)
+	}
+	reported, _ := ioutil.ReadFile(stderr.Name())
+	if n := strings.Count(string(reported), "could not rotate log file"); n != 1 {
+		t.Errorf("Expected the failure to be reported once, got %q", reported)
+	}
+
+	os.RemoveAll(path + ".1")
+	f.Write([]byte("line 4\n"))
+	if actual, _ := ioutil.ReadFile(path + ".1"); string(actual) != "line 1\nline 2\nline 3\n" {
+		t.Errorf("Expected rotation to be tried again, got %q in the rotated file", actual)
+	}
+	if actual, _ := ioutil.ReadFile(path); string(actual) != "line 4\n" {
+		t.Errorf("Expected a new file after rotation, got %q", actual)
+	}
+}
@@ -1,17 +1,153 @@
 package app
 
 import (
+	"encoding/json"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"os"
+	"sync"
+	"time"
 )
 
+//maxRecentLogs is the number of the latest log lines kept in memory for report bundles
+const maxRecentLogs = 500
+
+//recentLogs keeps the latest log entries of the mirror, installed by watch
+var recentLogs = &logRing{}
+
 func init() {
 	log.SetOutput(os.Stdout)
 	log.SetLevel(log.InfoLevel)
-	log.SetFormatter(&log.JSONFormatter{TimestampFormat: "2006-01-02T15:04:05.000"})
+	log.SetFormatter(defaultLogFormatter())
+}
+
+//defaultLogFormatter is the formatter of logs without --log-format and --verbose
+func defaultLogFormatter() log.Formatter {
+	return &log.JSONFormatter{TimestampFormat: "2006-01-02T15:04:05.000"}
 }
 
 func enableDebug() {
 	log.SetLevel(log.DebugLevel)
 	log.SetFormatter(&log.TextFormatter{})
 }
+
+//logLevels are levels of logs by the values of --log-level
+var logLevels = map[string]log.Level{
+	"debug": log.DebugLevel,
+	"info":  log.InfoLevel,
+	"warn":  log.WarnLevel,
+	"error": log.ErrorLevel,
+}
+
+//setLogLevel sets the level of logs by the value of --log-level.
+//Empty level keeps the current one: info, or debug with --verbose
+func setLogLevel(level string) error {
+	if level == "" {
+		return nil
+	}
+	l, ok := logLevels[level]
+	if !ok {
+		return fmt.Errorf("unsupported log level: %s, expected debug, info, warn or error", level)
+	}
+	log.SetLevel(l)
+	return nil
+}
+
+//logFormatters are formatters of logs by the values of --log-format
+var logFormatters = map[string]func() log.Formatter{
+	"text": func() log.Formatter { return &log.TextFormatter{} },
+	"json": func() log.Formatter { return &jsonLogFormatter{} },
+}
+
+//setLogFormat sets the formatter of logs by the value of --log-format.
+//Empty format keeps the current one: the default JSON, or text with --verbose
+func setLogFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	formatter, ok := logFormatters[format]
+	if !ok {
+		return fmt.Errorf("unsupported log format: %s, expected text or json", format)
+	}
+	log.SetFormatter(formatter())
+	return nil
+}
+
+//jsonLogFormatter prints each entry as a JSON object on its own line, with timestamp, level and msg
+//followed by fields of the entry, such as cluster and kind, so that collectors like Loki can query them
+type jsonLogFormatter struct{}
+
+func (f *jsonLogFormatter) Format(e *log.Entry) ([]byte, error) {
+	data := make(log.Fields, len(e.Data)+3)
+	for k, v := range e.Data {
+		//errors have no exported fields and are encoded as {} otherwise
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		data[k] = v
+	}
+	data["timestamp"] = e.Time.Format(time.RFC3339Nano)
+	data["level"] = e.Level.String()
+	data["msg"] = e.Message
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal fields of the log entry to JSON: %s", err)
+	}
+	return append(b, '\n'), nil
+}
+
+//logRing is a logrus hook that remembers the latest entries of level info and above.
+//Entries are formatted only when they are asked for, which is rare
+type logRing struct {
+	mu        sync.Mutex
+	installed bool
+	entries   []log.Entry
+}
+
+//install adds the ring to hooks of the standard logger, once
+func (r *logRing) install() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.installed {
+		log.AddHook(r)
+		r.installed = true
+	}
+}
+
+func (r *logRing) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel, log.InfoLevel}
+}
+
+func (r *logRing) Fire(e *log.Entry) error {
+	data := make(log.Fields, len(e.Data))
+	for k, v := range e.Data {
+		data[k] = v
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, log.Entry{Time: e.Time, Level: e.Level, Message: e.Message, Data: data})
+	if len(r.entries) > maxRecentLogs {
+		r.entries = r.entries[len(r.entries)-maxRecentLogs:]
+	}
+	return nil
+}
+
+//get returns the remembered entries as lines of JSON, the format that does not depend on --log-format
+func (r *logRing) get() []string {
+	r.mu.Lock()
+	entries := append([]log.Entry{}, r.entries...)
+	r.mu.Unlock()
+
+	lines := make([]string, 0, len(entries))
+	f := &jsonLogFormatter{}
+	for i := range entries {
+		b, err := f.Format(&entries[i])
+		if err != nil {
+			continue
+		}
+		lines = append(lines, string(b))
+	}
+	return lines
+}
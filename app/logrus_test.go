@@ -0,0 +1,98 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestJSONLogFormatter(t *testing.T) {
+	e := log.WithFields(log.Fields{"cluster": "prod", "kind": "pod", "error": errors.New("connection refused")})
+	e.Time = time.Date(2017, 5, 1, 12, 0, 0, 0, time.UTC)
+	e.Level = log.WarnLevel
+	e.Message = "watch connection was closed, retrying"
+
+	b, err := (&jsonLogFormatter{}).Format(e)
+	assert.NoError(t, err)
+	assert.Equal(t, byte('\n'), b[len(b)-1])
+
+	var actual map[string]string
+	assert.NoError(t, json.Unmarshal(b, &actual))
+	expected := map[string]string{
+		"timestamp": "2017-05-01T12:00:00Z",
+		"level":     "warning",
+		"msg":       "watch connection was closed, retrying",
+		"cluster":   "prod",
+		"kind":      "pod",
+		"error":     "connection refused",
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestSetLogFormat(t *testing.T) {
+	defer log.SetFormatter(defaultLogFormatter())
+
+	log.SetFormatter(defaultLogFormatter())
+	assert.NoError(t, setLogFormat(""))
+	assert.IsType(t, &log.JSONFormatter{}, log.StandardLogger().Formatter)
+	assert.NoError(t, setLogFormat("text"))
+	assert.IsType(t, &log.TextFormatter{}, log.StandardLogger().Formatter)
+	assert.NoError(t, setLogFormat(""))
+	assert.IsType(t, &log.TextFormatter{}, log.StandardLogger().Formatter)
+	assert.NoError(t, setLogFormat("json"))
+	assert.IsType(t, &jsonLogFormatter{}, log.StandardLogger().Formatter)
+	assert.Error(t, setLogFormat("logfmt"))
+}
+
+func TestRunCommonLogLevel(t *testing.T) {
+	defer log.SetLevel(log.InfoLevel)
+
+	tests := []struct {
+		level    string
+		expected log.Level
+	}{
+		{level: "debug", expected: log.DebugLevel},
+		{level: "warn", expected: log.WarnLevel},
+		{level: "error", expected: log.ErrorLevel},
+		{level: "", expected: log.InfoLevel},
+	}
+
+	for i, test := range tests {
+		log.SetLevel(log.InfoLevel)
+		cmd := NewGetCommand(&TestFactory{})
+		cmd.Flags().Set("log-level", test.level)
+		if _, err := RunCommon(cmd); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if log.GetLevel() != test.expected {
+			t.Errorf("Test %d: expected level %v, got %v", i, test.expected, log.GetLevel())
+		}
+	}
+
+	cmd := NewWatchCommand(&TestFactory{})
+	cmd.Flags().Set("log-level", "verbose")
+	if _, err := RunCommon(cmd); err == nil {
+		t.Errorf("Expected error for unsupported log level")
+	}
+}
+
+func TestLogRing(t *testing.T) {
+	r := &logRing{}
+	for i := 0; i < maxRecentLogs+2; i++ {
+		e := log.WithField("n", i)
+		e.Time = time.Date(2017, 5, 1, 12, 0, 0, 0, time.UTC)
+		e.Level = log.InfoLevel
+		e.Message = "watch started"
+		assert.NoError(t, r.Fire(e))
+	}
+
+	lines := r.get()
+	if assert.Len(t, lines, maxRecentLogs) {
+		assert.Equal(t, `{"level":"info","msg":"watch started","n":2,"timestamp":"2017-05-01T12:00:00Z"}`+"\n", lines[0])
+	}
+	assert.NotContains(t, r.Levels(), log.DebugLevel)
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"runtime"
+	"sync"
+)
+
+//parallelMatchMin is the number of candidates from which objects are matched against the filter of a client
+//by a pool of goroutines. Fewer objects are matched faster by the goroutine that answers the client
+const parallelMatchMin = 8192
+
+//matchChunkSize is the number of objects that a goroutine of the pool matches at a time
+const matchChunkSize = 2048
+
+//serverObjects are objects of the kind and namespace of the filter, mirrored from the server
+type serverObjects struct {
+	server  string
+	objects []KubeObject
+}
+
+//matchObjects returns the candidates that match, with their servers set, in the order of the candidates.
+//Large sets of candidates are split into chunks matched by as many goroutines as there are cores.
+//Chunks collect positions of matching objects, so each matching object is copied only once, into the result
+func matchObjects(f *MrrFilter, candidates []serverObjects, matches func(o *KubeObject) bool) ([]KubeObject, error) {
+	chunks := []serverObjects{}
+	total := 0
+	for _, c := range candidates {
+		total += len(c.objects)
+		for i := 0; i < len(c.objects); i += matchChunkSize {
+			end := i + matchChunkSize
+			if end > len(c.objects) {
+				end = len(c.objects)
+			}
+			chunks = append(chunks, serverObjects{server: c.server, objects: c.objects[i:end]})
+		}
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(chunks) {
+		workers = len(chunks)
+	}
+	matched := make([][]int, len(chunks))
+	if total < parallelMatchMin || workers < 2 {
+		for i, c := range chunks {
+			if f.expired() {
+				return nil, errDeadlineExceeded
+			}
+			matched[i] = matchChunk(c, matches)
+		}
+		return collectMatched(chunks, matched), nil
+	}
+
+	next := make(chan int, len(chunks))
+	for i := range chunks {
+		next <- i
+	}
+	close(next)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	expired := false
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if f.expired() {
+					mu.Lock()
+					expired = true
+					mu.Unlock()
+					return
+				}
+				matched[i] = matchChunk(chunks[i], matches)
+			}
+		}()
+	}
+	wg.Wait()
+	if expired {
+		return nil, errDeadlineExceeded
+	}
+	return collectMatched(chunks, matched), nil
+}
+
+//collectMatched copies matching objects of the chunks, given by their positions, and sets their servers
+func collectMatched(chunks []serverObjects, matched [][]int) []KubeObject {
+	n := 0
+	for _, m := range matched {
+		n += len(m)
+	}
+	res := make([]KubeObject, n)
+	j := 0
+	for i, m := range matched {
+		for _, pos := range m {
+			res[j] = chunks[i].objects[pos]
+			res[j].Server = chunks[i].server
+			j++
+		}
+	}
+	return res
+}
+
+//matchChunk returns positions of objects of the chunk that match
+func matchChunk(c serverObjects, matches func(o *KubeObject) bool) []int {
+	var res []int
+	for i := range c.objects {
+		if matches(&c.objects[i]) {
+			res = append(res, i)
+		}
+	}
+	return res
+}
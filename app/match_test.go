@@ -0,0 +1,56 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMatchObjects(t *testing.T) {
+	candidates := []serverObjects{}
+	expected := []KubeObject{}
+	for _, server := range []string{"s1", "s2", "s3"} {
+		objects := []KubeObject{}
+		for i := 0; i < parallelMatchMin; i++ {
+			o := KubeObject{ObjectMeta: ObjectMeta{Name: fmt.Sprintf("pod-%d", i)}}
+			objects = append(objects, o)
+			if i%3 == 0 {
+				o.Server = server
+				expected = append(expected, o)
+			}
+		}
+		candidates = append(candidates, serverObjects{server: server, objects: objects})
+	}
+	matches := func(o *KubeObject) bool {
+		var i int
+		fmt.Sscanf(o.Name, "pod-%d", &i)
+		return i%3 == 0
+	}
+
+	actual, err := matchObjects(&MrrFilter{}, candidates, matches)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %d objects, got %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		if actual[i].Name != expected[i].Name || actual[i].Server != expected[i].Server {
+			t.Fatalf("Expected objects in the order of candidates, got %s of %s at %d instead of %s of %s",
+				actual[i].Name, actual[i].Server, i, expected[i].Name, expected[i].Server)
+		}
+	}
+	if candidates[0].objects[0].Server != "" {
+		t.Errorf("Expected candidates not to be changed, got %+v", candidates[0].objects[0])
+	}
+
+	small := []serverObjects{{server: "s1", objects: candidates[0].objects[:10]}}
+	if actual, err := matchObjects(&MrrFilter{}, small, matches); err != nil || len(actual) != 4 {
+		t.Errorf("Expected 4 objects of few candidates, got %d, %v", len(actual), err)
+	}
+
+	expired := &MrrFilter{Deadline: time.Now().Add(-time.Second)}
+	if _, err := matchObjects(expired, candidates, matches); err != errDeadlineExceeded {
+		t.Errorf("Expected deadline error, got %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//metricsPath is the path of the HTTP endpoint with metrics of the mirror in the Prometheus text format
+const metricsPath = "/metrics"
+
+//latencyBuckets are upper bounds of buckets of the histograms of request latencies and filtering, in seconds
+var latencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+//lagBuckets are upper bounds of buckets of the histogram of lags of watch events, in seconds
+var lagBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+//metricKey identifies counters of watchers of a kind of a server
+type metricKey struct {
+	server KubeServer
+	kind   string
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+//mean returns the mean of observed values as a duration, zero when nothing was observed
+func (h *histogram) mean() time.Duration {
+	if h == nil || h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / float64(h.count) * float64(time.Second))
+}
+
+//write prints buckets, sum and count of the histogram with the labels
+func (h *histogram) write(out *bytes.Buffer, name string, pairs ...string) {
+	for i, b := range h.buckets {
+		fmt.Fprintf(out, "%s_bucket%s %d\n", name, labels(append(pairs, "le", fmt.Sprint(b))...), h.counts[i])
+	}
+	fmt.Fprintf(out, "%s_bucket%s %d\n", name, labels(append(pairs, "le", "+Inf")...), h.count)
+	fmt.Fprintf(out, "%s_sum%s %g\n", name, labels(pairs...), h.sum)
+	fmt.Fprintf(out, "%s_count%s %d\n", name, labels(pairs...), h.count)
+}
+
+//mrrMetrics counts what happens to the mirror. Numbers of objects are not counted,
+//they are taken from the cache when metrics are requested
+type mrrMetrics struct {
+	mu         sync.Mutex
+	reconnects map[metricKey]uint64
+	apiErrors  map[metricKey]uint64
+	evictions  map[metricKey]uint64
+	latencies  map[string]*histogram
+
+	//changes counts objects added, modified and deleted in the cache
+	changes map[EventType]map[metricKey]uint64
+	//filtering is the histogram of how long it takes to find objects that match filters of clients
+	filtering *histogram
+	//lags are histograms of how long after their creation objects reach the cache
+	lags map[metricKey]*histogram
+
+	lastEvents map[metricKey]time.Time
+	lastErrors map[metricKey]watchError
+}
+
+//watchError is the last error of requests to the API server for a kind
+type watchError struct {
+	message string
+	time    time.Time
+}
+
+func newMrrMetrics() *mrrMetrics {
+	return &mrrMetrics{
+		reconnects: map[metricKey]uint64{},
+		apiErrors:  map[metricKey]uint64{},
+		evictions:  map[metricKey]uint64{},
+		latencies:  map[string]*histogram{},
+		changes:    map[EventType]map[metricKey]uint64{Added: {}, Modified: {}, Deleted: {}},
+		filtering:  newHistogram(latencyBuckets),
+		lags:       map[metricKey]*histogram{},
+		lastEvents: map[metricKey]time.Time{},
+		lastErrors: map[metricKey]watchError{},
+	}
+}
+
+//watchReconnected counts closed watch connections of the kind that are opened again
+func (m *mrrMetrics) watchReconnected(server KubeServer, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconnects[metricKey{server, kind}]++
+}
+
+//apiError counts failed requests to the API server and remembers the last error
+func (m *mrrMetrics) apiError(server KubeServer, kind string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := metricKey{server, kind}
+	m.apiErrors[k]++
+	m.lastErrors[k] = watchError{message: err.Error(), time: time.Now()}
+}
+
+//evicted counts objects of the kind evicted from the cache before servers deleted them
+func (m *mrrMetrics) evicted(server KubeServer, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evictions[metricKey{server, strings.ToLower(kind)}]++
+}
+
+//changed counts the change of an object of the kind in the cache
+func (m *mrrMetrics) changed(server KubeServer, kind string, t EventType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if counters, ok := m.changes[t]; ok {
+		counters[metricKey{server, strings.ToLower(kind)}]++
+	}
+}
+
+//filtered records how long it took to find objects that match a filter of a client
+func (m *mrrMetrics) filtered(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.filtering.observe(d.Seconds())
+}
+
+//eventLag records how long after its creation the object of the kind reached the cache
+func (m *mrrMetrics) eventLag(server KubeServer, kind string, lag time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := metricKey{server, kind}
+	h, ok := m.lags[k]
+	if !ok {
+		h = newHistogram(lagBuckets)
+		m.lags[k] = h
+	}
+	h.observe(lag.Seconds())
+}
+
+//watchEvent remembers when the last event of the watch of the kind was received
+func (m *mrrMetrics) watchEvent(server KubeServer, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastEvents[metricKey{server, kind}] = time.Now()
+}
+
+//addWatcherStatus fills reconnects, last events and errors of watchers of kinds in the status.
+//Kinds and servers that have no objects in the cache yet are added
+func (m *mrrMetrics) addWatcherStatus(s *MrrStatus, f *MrrFilter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := map[metricKey]bool{}
+	for k := range m.reconnects {
+		keys[k] = true
+	}
+	for k := range m.lastErrors {
+		keys[k] = true
+	}
+	for k := range m.lastEvents {
+		keys[k] = true
+	}
+	for _, counters := range m.changes {
+		for k := range counters {
+			keys[k] = true
+		}
+	}
+
+	for k := range keys {
+		if !matchesServer(f, k.server) {
+			continue
+		}
+		ks := s.kindStatus(k.server, k.kind)
+		ks.Reconnects = int(m.reconnects[k])
+		ks.Added = int(m.changes[Added][k])
+		ks.Modified = int(m.changes[Modified][k])
+		ks.Deleted = int(m.changes[Deleted][k])
+		ks.EventLag = m.lags[k].mean()
+		ks.LastEvent = m.lastEvents[k]
+		if e, ok := m.lastErrors[k]; ok {
+			ks.LastError = e.message
+			ks.LastErrorTime = e.time
+		}
+	}
+
+	s.Requests = map[string]int{}
+	for method, h := range m.latencies {
+		s.Requests[method] = int(h.count)
+	}
+	s.FilterTime = m.filtering.mean()
+}
+
+//observeRequest records latency of the request of a client that started at the given time
+func (m *mrrMetrics) observeRequest(method string, started time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.latencies[method]
+	if !ok {
+		h = newHistogram(latencyBuckets)
+		m.latencies[method] = h
+	}
+	h.observe(time.Since(started).Seconds())
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+//labels formats pairs of label names and values. Labels with empty values are left out,
+//which Prometheus treats the same way
+func labels(pairs ...string) string {
+	res := []string{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			continue
+		}
+		res = append(res, fmt.Sprintf(`%s="%s"`, pairs[i], labelEscaper.Replace(pairs[i+1])))
+	}
+	return "{" + strings.Join(res, ",") + "}"
+}
+
+func writeCounters(out *bytes.Buffer, name string, help string, counters map[metricKey]uint64) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	writeCounterLines(out, name, counters)
+}
+
+//writeCounterLines prints the counters ordered by servers and kinds, with the extra pairs of labels
+func writeCounterLines(out *bytes.Buffer, name string, counters map[metricKey]uint64, extra ...string) {
+	keys := []metricKey{}
+	for k := range counters {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].server != keys[j].server {
+			return KubeServers{keys[i].server, keys[j].server}.Less(0, 1)
+		}
+		return keys[i].kind < keys[j].kind
+	})
+	for _, k := range keys {
+		fmt.Fprintf(out, "%s%s %d\n", name, labels(append([]string{"server", k.server.URL, "profile", k.server.Profile, "kind", k.kind}, extra...)...), counters[k])
+	}
+}
+
+//write prints the metrics and the numbers of objects in the status of the cache in the Prometheus text format
+func (m *mrrMetrics) write(out *bytes.Buffer, status MrrStatus) {
+	fmt.Fprintf(out, "# HELP kubemrr_objects Number of mirrored objects\n# TYPE kubemrr_objects gauge\n")
+	for _, s := range status.Servers {
+		for _, k := range s.Kinds {
+			fmt.Fprintf(out, "kubemrr_objects%s %d\n", labels("server", s.Server, "profile", s.Profile, "kind", k.Kind), k.Objects)
+		}
+	}
+
+	fmt.Fprintf(out, "# HELP kubemrr_last_update_timestamp_seconds When mirrored objects last changed or were listed\n")
+	fmt.Fprintf(out, "# TYPE kubemrr_last_update_timestamp_seconds gauge\n")
+	for _, s := range status.Servers {
+		for _, k := range s.Kinds {
+			if !k.Updated.IsZero() {
+				fmt.Fprintf(out, "kubemrr_last_update_timestamp_seconds%s %d\n", labels("server", s.Server, "profile", s.Profile, "kind", k.Kind), k.Updated.Unix())
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	writeCounters(out, "kubemrr_watch_reconnects_total", "Number of times watch connections were opened again", m.reconnects)
+	writeCounters(out, "kubemrr_api_errors_total", "Number of failed requests to API servers", m.apiErrors)
+	writeCounters(out, "kubemrr_evicted_objects_total", "Number of objects evicted by --object-ttl or --max-objects", m.evictions)
+
+	name := "kubemrr_request_duration_seconds"
+	fmt.Fprintf(out, "# HELP %s Latency of requests of clients\n# TYPE %s histogram\n", name, name)
+	methods := []string{}
+	for method := range m.latencies {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		m.latencies[method].write(out, name, "method", method)
+	}
+
+	name = "kubemrr_object_changes_total"
+	fmt.Fprintf(out, "# HELP %s Number of objects added, modified and deleted in the mirror\n# TYPE %s counter\n", name, name)
+	for _, t := range []EventType{Added, Modified, Deleted} {
+		writeCounterLines(out, name, m.changes[t], "type", strings.ToLower(string(t)))
+	}
+
+	name = "kubemrr_filter_duration_seconds"
+	fmt.Fprintf(out, "# HELP %s Time to find objects that match filters of clients\n# TYPE %s histogram\n", name, name)
+	m.filtering.write(out, name)
+
+	name = "kubemrr_watch_event_lag_seconds"
+	fmt.Fprintf(out, "# HELP %s Time from creation of objects to their arrival in the mirror, for objects created while watching\n# TYPE %s histogram\n", name, name)
+	keys := []metricKey{}
+	for k := range m.lags {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].server != keys[j].server {
+			return KubeServers{keys[i].server, keys[j].server}.Less(0, 1)
+		}
+		return keys[i].kind < keys[j].kind
+	})
+	for _, k := range keys {
+		m.lags[k].write(out, name, "server", k.server.URL, "profile", k.server.Profile, "kind", k.kind)
+	}
+}
+
+//metricsHandler serves metrics of the mirror to Prometheus
+func metricsHandler(c *MrrCache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out := &bytes.Buffer{}
+		c.metrics.write(out, c.status(&MrrFilter{}))
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(out.Bytes())
+	})
+}
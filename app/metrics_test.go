@@ -0,0 +1,72 @@
+package app
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "https://s1"}
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{Kind: "pod"}, ObjectMeta: ObjectMeta{Name: "a"}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{Kind: "pod"}, ObjectMeta: ObjectMeta{Name: "b"}})
+	c.metrics.watchReconnected(s, "pod")
+	c.metrics.watchReconnected(s, "pod")
+	c.metrics.apiError(s, "service", errors.New("forbidden"))
+	c.metrics.observeRequest("Objects", time.Now().Add(-30*time.Millisecond))
+	c.metrics.eventLag(s, "pod", 2*time.Second)
+	c.deleteKubeObject(s, KubeObject{TypeMeta: TypeMeta{Kind: "pod"}, ObjectMeta: ObjectMeta{Name: "b"}})
+	var objects []KubeObject
+	c.Objects(&MrrFilter{Kind: "pod"}, &objects)
+
+	w := httptest.NewRecorder()
+	metricsHandler(c).ServeHTTP(w, httptest.NewRequest("GET", metricsPath, nil))
+	raw, _ := ioutil.ReadAll(w.Body)
+	body := string(raw)
+
+	expected := []string{
+		"# TYPE kubemrr_objects gauge",
+		`kubemrr_objects{server="https://s1",kind="pod"} 1`,
+		`kubemrr_last_update_timestamp_seconds{server="https://s1",kind="pod"} `,
+		`kubemrr_watch_reconnects_total{server="https://s1",kind="pod"} 2`,
+		`kubemrr_api_errors_total{server="https://s1",kind="service"} 1`,
+		`kubemrr_request_duration_seconds_bucket{method="Objects",le="0.025"} 1`,
+		`kubemrr_request_duration_seconds_bucket{method="Objects",le="0.05"} 2`,
+		`kubemrr_request_duration_seconds_bucket{method="Objects",le="+Inf"} 2`,
+		`kubemrr_request_duration_seconds_count{method="Objects"} 2`,
+		`kubemrr_object_changes_total{server="https://s1",kind="pod",type="added"} 2`,
+		`kubemrr_object_changes_total{server="https://s1",kind="pod",type="deleted"} 1`,
+		`kubemrr_filter_duration_seconds_count{} 1`,
+		`kubemrr_watch_event_lag_seconds_bucket{server="https://s1",kind="pod",le="1"} 0`,
+		`kubemrr_watch_event_lag_seconds_bucket{server="https://s1",kind="pod",le="2.5"} 1`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(body, e) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", e, body)
+		}
+	}
+	if strings.Contains(body, `method="Status"`) {
+		t.Errorf("Requests for metrics must not be counted as requests of clients:\n%s", body)
+	}
+
+	status := c.status(&MrrFilter{})
+	pods := status.kindStatus(s, "pod")
+	if pods.Added != 2 || pods.Deleted != 1 || pods.EventLag != 2*time.Second {
+		t.Errorf("Expected changes and lag of pods in the status, got %+v", pods)
+	}
+	if status.Requests["Objects"] != 2 || status.FilterTime <= 0 {
+		t.Errorf("Expected requests of clients in the status, got %v, %s", status.Requests, status.FilterTime)
+	}
+}
+
+func TestMetricLabels(t *testing.T) {
+	actual := labels("server", `a"b\c`, "kind", "pod")
+	expected := `{server="a\"b\\c",kind="pod"}`
+	if actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+}
@@ -0,0 +1,282 @@
+package app
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"reflect"
+	"sync"
+	"time"
+)
+
+//watchTarget is a Kubernetes API server given to the watch command, either by url or by context name
+type watchTarget struct {
+	name    string
+	config  *Config
+	options KubeClientOptions
+	filters []FilterRule
+}
+
+//clusterWatcher runs the loops that put objects of one Kubernetes API server into the cache
+type clusterWatcher struct {
+	kc     KubeClient
+	target watchTarget
+	filter *objectFilter
+	stop   chan struct{}
+	wg     sync.WaitGroup
+
+	//backoffMin and backoffMax bound delays between retries of failed requests
+	backoffMin time.Duration
+	backoffMax time.Duration
+
+	mu               sync.Mutex
+	resourceVersions map[string]string
+}
+
+func newClusterWatcher(kc KubeClient, t watchTarget) *clusterWatcher {
+	//filters are validated when the configuration is read
+	filter, err := newObjectFilter(t.filters)
+	if err != nil {
+		log.WithField("cluster", t.name).WithField("error", err).Error("invalid filters, mirroring all objects")
+	}
+
+	return &clusterWatcher{
+		kc:     kc,
+		target: t,
+		filter: filter,
+		stop:   make(chan struct{}),
+
+		backoffMin: time.Second,
+		backoffMax: 2 * time.Minute,
+
+		resourceVersions: make(map[string]string),
+	}
+}
+
+//resourceVersion returns the last seen resource version of objects of the kind in the namespace
+func (w *clusterWatcher) resourceVersion(kind string, namespace string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.resourceVersions[kind+"/"+namespace]
+}
+
+func (w *clusterWatcher) setResourceVersion(kind string, namespace string, rv string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.resourceVersions[kind+"/"+namespace] = rv
+}
+
+func (w *clusterWatcher) isStopped() bool {
+	select {
+	case <-w.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+//sleep waits for the given duration and returns false if the watcher was stopped meanwhile
+func (w *clusterWatcher) sleep(d time.Duration) bool {
+	select {
+	case <-w.stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+//close stops the loops of the watcher and waits until they return
+func (w *clusterWatcher) close() {
+	close(w.stop)
+	w.kc.Close()
+	w.wg.Wait()
+}
+
+//mirror keeps a watcher for each of the watched Kubernetes API servers
+type mirror struct {
+	f          Factory
+	cache      *MrrCache
+	interval   time.Duration
+	only       string
+	namespaces []string
+
+	//objectTTL is how long objects stay mirrored without being confirmed by servers, 0 for ever
+	objectTTL time.Duration
+
+	mu       sync.Mutex
+	watchers map[string]*clusterWatcher
+}
+
+func newMirror(f Factory, c *MrrCache, interval time.Duration, only string, namespaces []string) *mirror {
+	return &mirror{
+		f:          f,
+		cache:      c,
+		interval:   interval,
+		only:       only,
+		namespaces: namespaces,
+		watchers:   make(map[string]*clusterWatcher),
+	}
+}
+
+//start begins to put objects of the target into the cache using the given client
+func (m *mirror) start(t watchTarget, kc KubeClient) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.startLocked(t, kc)
+}
+
+func (m *mirror) startLocked(t watchTarget, kc KubeClient) {
+	w := newClusterWatcher(kc, t)
+	m.watchers[t.name] = w
+	identifyCluster(m.cache, w)
+	discoverResources(m.cache, w)
+
+	for _, k := range []string{"pod"} {
+		if isWatching(k, m.only) {
+			for _, ns := range m.kindNamespaces(k) {
+				loopWatchObjects(m.cache, w, k, ns)
+				//watches send only changes, so unchanged objects are confirmed by lists
+				if m.objectTTL > 0 {
+					loopGetObjects(m.cache, w, k, ns, m.objectTTL/2)
+				}
+			}
+		}
+	}
+
+	for _, k := range []string{"service", "deployment", "configmap", "namespace", "node"} {
+		if isWatching(k, m.only) {
+			for _, ns := range m.kindNamespaces(k) {
+				loopGetObjects(m.cache, w, k, ns, m.interval)
+			}
+		}
+	}
+}
+
+//identifyCluster asks the server for the UID of its cluster, so that objects of contexts that
+//point to the same cluster by different URLs are merged. Without the UID servers are told apart by URLs
+func identifyCluster(c *MrrCache, w *clusterWatcher) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		server := w.kc.Server()
+		id, err := w.kc.ClusterID()
+		if w.isStopped() {
+			return
+		}
+		if err != nil {
+			log.WithField("server", server.URL).WithField("error", err).Info("could not identify the cluster, telling it apart by url")
+			return
+		}
+		c.setClusterID(server, id)
+	}()
+}
+
+//kindNamespaces returns namespaces in which objects of the kind are mirrored.
+//Objects that do not belong to namespaces are always mirrored cluster-wide
+func (m *mirror) kindNamespaces(kind string) []string {
+	if len(m.namespaces) == 0 || !isNamespaced(kind) {
+		return []string{""}
+	}
+	return m.namespaces
+}
+
+//stopLocked stops watching of the named target and removes its objects from the cache,
+//unless the same server is watched for another target
+func (m *mirror) stopLocked(name string) {
+	w, ok := m.watchers[name]
+	if !ok {
+		return
+	}
+	delete(m.watchers, name)
+	w.close()
+
+	server := w.kc.Server()
+	for _, other := range m.watchers {
+		if other.kc.Server() == server {
+			return
+		}
+	}
+	m.cache.deleteServer(server)
+}
+
+//stop stops all watchers and closes their connections to API servers. Objects stay in the cache
+func (m *mirror) stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, w := range m.watchers {
+		delete(m.watchers, name)
+		w.close()
+	}
+}
+
+//reload makes the mirror watch exactly the given targets. Watchers of targets with unchanged
+//configuration keep running, so objects of their servers stay in the cache
+func (m *mirror) reload(targets []watchTarget) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	given := map[string]bool{}
+	for _, t := range targets {
+		given[t.name] = true
+	}
+
+	for name := range m.watchers {
+		if !given[name] {
+			log.WithField("cluster", name).Info("stopping watcher")
+			m.stopLocked(name)
+		}
+	}
+
+	for _, t := range targets {
+		w, ok := m.watchers[t.name]
+		if ok && sameTarget(w.target, t) {
+			continue
+		}
+
+		kc := m.f.KubeClient(t.config, t.options)
+		if err := kc.Ping(); err != nil {
+			log.WithField("cluster", t.name).WithField("error", err).Error("failed to ping server, not watching it")
+			continue
+		}
+
+		if ok {
+			log.WithField("cluster", t.name).Info("configuration has changed, restarting watcher")
+			m.stopLocked(t.name)
+		} else {
+			log.WithField("cluster", t.name).Info("starting watcher")
+		}
+		m.startLocked(t, kc)
+	}
+}
+
+//sameTarget checks whether both targets talk to the same server with the same credentials and options
+func sameTarget(a watchTarget, b watchTarget) bool {
+	if !reflect.DeepEqual(a.options, b.options) || !reflect.DeepEqual(a.filters, b.filters) {
+		return false
+	}
+	ac, bc := a.config.getCurrentContext(), b.config.getCurrentContext()
+	return reflect.DeepEqual(ac, bc) &&
+		reflect.DeepEqual(a.config.getCluster(ac.Cluster), b.config.getCluster(bc.Cluster)) &&
+		reflect.DeepEqual(a.config.getUser(ac.User), b.config.getUser(bc.User))
+}
+
+//flush removes objects of servers that match the filter from the cache and restarts their
+//watchers, which list all objects again. Empty filter flushes all servers
+func (m *mirror) flush(filter MrrFilter) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f := &filter
+	targets := []watchTarget{}
+	for name, w := range m.watchers {
+		if matchesServer(f, w.kc.Server()) {
+			targets = append(targets, w.target)
+			m.stopLocked(name)
+		}
+	}
+	m.cache.deleteServers(f)
+
+	for _, t := range targets {
+		log.WithField("cluster", t.name).Info("restarting flushed watcher")
+		m.startLocked(t, m.f.KubeClient(t.config, t.options))
+	}
+	return len(targets)
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestMirrorReload(t *testing.T) {
+	f := NewTestFactory()
+	c := f.MrrCache()
+	m := newMirror(f, c, time.Hour, "", nil)
+
+	kubeconfig := Config{
+		Contexts: []ContextWrap{
+			{"dev", Context{Cluster: "cluster_1"}},
+			{"prod", Context{Cluster: "cluster_2"}},
+		},
+		Clusters: []ClusterWrap{
+			{"cluster_1", Cluster{Server: "https://foo.com"}},
+			{"cluster_2", Cluster{Server: "https://bar.com"}},
+		},
+	}
+	target := func(context string, kubeconfig Config) watchTarget {
+		kubeconfig.CurrentContext = context
+		return watchTarget{name: context, config: &kubeconfig}
+	}
+
+	m.reload([]watchTarget{target("dev", kubeconfig), target("prod", kubeconfig)})
+	time.Sleep(10 * time.Millisecond)
+	dev := f.kubeClients["https://foo.com"]
+	prod := f.kubeClients["https://bar.com"]
+	assert.Equal(t, 1, dev.pings)
+	assert.Equal(t, 1, prod.pings)
+
+	o := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a"}}
+	c.updateKubeObject(dev.Server(), o)
+	c.updateKubeObject(prod.Server(), o)
+
+	m.reload([]watchTarget{target("dev", kubeconfig)})
+	assert.False(t, dev.isClosed(), "must not stop watcher of unchanged server")
+	assert.True(t, prod.isClosed(), "must stop watcher of removed server")
+	assert.Equal(t, []KubeObject{o}, c.objects[dev.Server()])
+	_, ok := c.objects[prod.Server()]
+	assert.False(t, ok, "must remove objects of removed server")
+
+	kubeconfig.Clusters = []ClusterWrap{{"cluster_1", Cluster{Server: "https://foo.com", SkipVerify: true}}}
+	m.reload([]watchTarget{target("dev", kubeconfig)})
+	assert.True(t, dev.isClosed(), "must restart watcher of changed server")
+	assert.Equal(t, 1, len(m.watchers))
+	m.stopLocked("dev")
+}
+
+func TestMirrorNamespaces(t *testing.T) {
+	f := NewTestFactory()
+	m := newMirror(f, f.MrrCache(), time.Hour, "pod,node", []string{"red", "blue"})
+
+	cfg, _ := NewConfigFromURL("https://foo.com")
+	t1 := watchTarget{name: "foo", config: cfg}
+	kc := f.KubeClient(cfg, t1.options).(*TestKubeClient)
+	m.start(t1, kc)
+	time.Sleep(10 * time.Millisecond)
+
+	kc.watchObjectLock.Lock()
+	watched := []string{}
+	for _, opts := range kc.watchOptions {
+		watched = append(watched, opts.Namespace)
+	}
+	listed := kc.getObjectOptions
+	kc.watchObjectLock.Unlock()
+
+	sort.Strings(watched)
+	assert.Equal(t, []string{"blue", "red"}, watched)
+	assert.Equal(t, []ListOptions{{}}, listed, "nodes must be listed cluster-wide")
+	m.stopLocked("foo")
+}
+
+func TestMirrorFlush(t *testing.T) {
+	f := NewTestFactory()
+	c := f.MrrCache()
+	m := newMirror(f, c, time.Hour, "pod", nil)
+
+	foo, _ := NewConfigFromURL("https://foo.com")
+	bar, _ := NewConfigFromURL("https://bar.com")
+	fooClient := f.KubeClient(foo, KubeClientOptions{}).(*TestKubeClient)
+	barClient := f.KubeClient(bar, KubeClientOptions{}).(*TestKubeClient)
+	m.start(watchTarget{name: "foo", config: foo}, fooClient)
+	m.start(watchTarget{name: "bar", config: bar}, barClient)
+
+	o := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a"}}
+	c.updateKubeObject(fooClient.Server(), o)
+	c.updateKubeObject(barClient.Server(), o)
+	stale := KubeServer{URL: "https://foo.com:8443"}
+	c.updateKubeObject(stale, o)
+
+	c.flusher = m.flush
+	var restarted int
+	err := c.Flush(&MrrFilter{Server: "https://foo.com"}, &restarted)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, restarted)
+	assert.True(t, fooClient.isClosed(), "must stop watcher of flushed server")
+	assert.False(t, barClient.isClosed(), "must not stop watcher of other server")
+	assert.Equal(t, 2, len(m.watchers))
+	_, ok := c.objects[fooClient.Server()]
+	assert.False(t, ok, "must remove objects of flushed server")
+	_, ok = c.objects[stale]
+	assert.False(t, ok, "must remove objects of flushed server on any port")
+	assert.Equal(t, []KubeObject{o}, c.objects[barClient.Server()])
+
+	err = c.Flush(&MrrFilter{}, &restarted)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, restarted)
+	assert.Equal(t, 0, len(c.objects))
+
+	c.flusher = nil
+	assert.Equal(t, errAdminDisabled, c.Flush(&MrrFilter{}, &restarted))
+	m.stopLocked("foo")
+	m.stopLocked("bar")
+}
+
+func TestMirrorStop(t *testing.T) {
+	f := NewTestFactory()
+	c := f.MrrCache()
+	m := newMirror(f, c, time.Hour, "pod", nil)
+
+	foo, _ := NewConfigFromURL("https://foo.com")
+	fooClient := f.KubeClient(foo, KubeClientOptions{}).(*TestKubeClient)
+	m.start(watchTarget{name: "foo", config: foo}, fooClient)
+	o := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a"}}
+	c.updateKubeObject(fooClient.Server(), o)
+
+	m.stop()
+	assert.True(t, fooClient.isClosed(), "must close connections to API servers")
+	assert.Equal(t, 0, len(m.watchers))
+	assert.Equal(t, []KubeObject{o}, c.objects[fooClient.Server()], "must keep objects in the cache")
+}
@@ -0,0 +1,296 @@
+package app
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//MrrConfig represents configuration of kubemrr written in ~/.kubemrr.yaml file
+type MrrConfig struct {
+	Clusters []MrrClusterConfig `yaml:"clusters"`
+
+	//Filters decide which objects of all clusters are mirrored
+	Filters []FilterRule `yaml:"filters"`
+
+	//Aliases map names of resources given to get to resources or kinds, such as "vs: virtualservice".
+	//They take precedence over the built-in names
+	Aliases map[string]string `yaml:"aliases"`
+
+	//Address, Port and Bind locate the mirror: "watch" listens on it and other commands connect to it
+	Address string `yaml:"address"`
+	Port    int    `yaml:"port"`
+	Bind    string `yaml:"bind"`
+
+	//Kubeconfig is the path to the kubeconfig file
+	Kubeconfig string `yaml:"kubeconfig"`
+
+	//Contexts are contexts of the kubeconfig that "watch" mirrors when none are given on the command line
+	Contexts []string `yaml:"contexts"`
+
+	//Selector is the label selector of objects that "watch" mirrors from clusters without their own selector
+	Selector string `yaml:"selector"`
+
+	//Interval is the interval between requests of "watch" to the servers, such as 2m
+	Interval time.Duration `yaml:"interval"`
+
+	//Log sets the logs of "watch"
+	Log MrrLogConfig `yaml:"log"`
+}
+
+//MrrLogConfig sets where logs of "watch" go, which of them and in what format
+type MrrLogConfig struct {
+	File     string `yaml:"file"`
+	Level    string `yaml:"level"`
+	Format   string `yaml:"format"`
+	MaxSize  int    `yaml:"maxSize"`
+	MaxFiles int    `yaml:"maxFiles"`
+}
+
+//MrrClusterConfig holds settings of the watched clusters that match
+//either the name of the cluster in kubeconfig or the url of its server
+type MrrClusterConfig struct {
+	Cluster string `yaml:"cluster"`
+	Server  string `yaml:"server"`
+
+	//Paths overrides API paths of kinds. Paths are templates
+	//that can refer to the watched namespace as {{.Namespace}}
+	Paths map[string]string `yaml:"paths"`
+
+	//Selector is a label selector that limits mirrored objects, such as "app=web,tier!=db"
+	Selector string `yaml:"selector"`
+
+	//FieldSelectors limit mirrored objects of kinds, such as "status.phase!=Succeeded" for pods
+	FieldSelectors map[string]string `yaml:"fieldSelectors"`
+
+	//Filters decide which objects of the cluster are mirrored. They are checked before the common filters
+	Filters []FilterRule `yaml:"filters"`
+}
+
+//KubeClientOptions are settings of a KubeClient that are not part of kubeconfig
+type KubeClientOptions struct {
+	Paths             map[string]string
+	Protobuf          bool
+	DiscoveryCacheDir string
+	LabelSelector     string
+	FieldSelectors    map[string]string
+
+	//QPS and Burst limit the rate of requests to the server. Zero QPS means no limit
+	QPS   float64
+	Burst int
+
+	//Profile is the name of the kubeconfig that the server is watched for, empty for the default one
+	Profile string
+}
+
+func (c *MrrClusterConfig) matches(config *Config) bool {
+	context := config.getCurrentContext()
+	if c.Cluster != "" && c.Cluster != context.Cluster {
+		return false
+	}
+	if c.Server != "" && c.Server != config.getCluster(context.Cluster).Server {
+		return false
+	}
+	return c.Cluster != "" || c.Server != ""
+}
+
+//clientOptions merges settings of all clusters matching the current context of the given config
+func (c *MrrConfig) clientOptions(config *Config) KubeClientOptions {
+	opts := KubeClientOptions{}
+	if c == nil {
+		return opts
+	}
+
+	for i := range c.Clusters {
+		cc := &c.Clusters[i]
+		if !cc.matches(config) {
+			continue
+		}
+		for kind, path := range cc.Paths {
+			if opts.Paths == nil {
+				opts.Paths = make(map[string]string)
+			}
+			opts.Paths[kind] = path
+		}
+		if cc.Selector != "" {
+			opts.LabelSelector = cc.Selector
+		}
+		for kind, selector := range cc.FieldSelectors {
+			if opts.FieldSelectors == nil {
+				opts.FieldSelectors = make(map[string]string)
+			}
+			opts.FieldSelectors[kind] = selector
+		}
+	}
+
+	return opts
+}
+
+//flagValues returns values of flags of the command that the file sets. Flags of "watch" that
+//other commands have with another meaning, such as --selector of "get", are set only for "watch"
+func (c *MrrConfig) flagValues(command string) map[string]string {
+	values := map[string]string{}
+	set := func(flag string, value string) {
+		if value != "" {
+			values[flag] = value
+		}
+	}
+	set("address", c.Address)
+	set("bind", c.Bind)
+	set("kubeconfig", c.Kubeconfig)
+	if c.Port != 0 {
+		set("port", strconv.Itoa(c.Port))
+	}
+	if command != "watch" {
+		return values
+	}
+
+	set("selector", c.Selector)
+	if c.Interval != 0 {
+		set("interval", c.Interval.String())
+	}
+	set("log-file", c.Log.File)
+	set("log-level", c.Log.Level)
+	set("log-format", c.Log.Format)
+	if c.Log.MaxSize != 0 {
+		set("log-max-size", strconv.Itoa(c.Log.MaxSize))
+	}
+	if c.Log.MaxFiles != 0 {
+		set("log-max-files", strconv.Itoa(c.Log.MaxFiles))
+	}
+	return values
+}
+
+//filterRules returns filters of all clusters matching the current context of the given config,
+//followed by the common filters
+func (c *MrrConfig) filterRules(config *Config) []FilterRule {
+	if c == nil {
+		return nil
+	}
+
+	var rules []FilterRule
+	for i := range c.Clusters {
+		if c.Clusters[i].matches(config) {
+			rules = append(rules, c.Clusters[i].Filters...)
+		}
+	}
+	return append(rules, c.Filters...)
+}
+
+func (c *MrrConfig) validate() error {
+	for _, cc := range c.Clusters {
+		for kind, path := range cc.Paths {
+			if _, err := template.New(kind).Parse(path); err != nil {
+				return fmt.Errorf("invalid path of %s: %s", kind, err)
+			}
+		}
+		if _, err := newObjectFilter(cc.Filters); err != nil {
+			return fmt.Errorf("invalid filters: %s", err)
+		}
+	}
+	if _, err := newObjectFilter(c.Filters); err != nil {
+		return fmt.Errorf("invalid filters: %s", err)
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid port %d", c.Port)
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("invalid interval %s", c.Interval)
+	}
+	if _, ok := logLevels[c.Log.Level]; !ok && c.Log.Level != "" {
+		return fmt.Errorf("invalid log level %q", c.Log.Level)
+	}
+	if _, ok := logFormatters[c.Log.Format]; !ok && c.Log.Format != "" {
+		return fmt.Errorf("invalid log format %q", c.Log.Format)
+	}
+	for alias, resource := range c.Aliases {
+		if alias == "" || strings.ContainsAny(alias, ", ") || alias == "all" {
+			return fmt.Errorf("invalid alias %q", alias)
+		}
+		if resource == "" || strings.ContainsAny(resource, ", ") {
+			return fmt.Errorf("invalid resource %q of alias %s", resource, alias)
+		}
+	}
+	return nil
+}
+
+//parseMrrConfig reads kubemrr configuration file. If the file does not exist
+//and it is not required, an empty configuration is returned
+func parseMrrConfig(filename string, required bool) (MrrConfig, error) {
+	res := MrrConfig{}
+	fnResolved, err := substituteUserHome(filename)
+	if err != nil {
+		return res, fmt.Errorf("could not substitute ~ in file %s: %s", filename, err)
+	}
+	raw, err := ioutil.ReadFile(fnResolved)
+	if os.IsNotExist(err) && !required {
+		return res, nil
+	}
+	if err != nil {
+		return res, fmt.Errorf("could not read file %s: %s", filename, err)
+	}
+
+	err = yaml.Unmarshal(raw, &res)
+	if err != nil {
+		return res, fmt.Errorf("could not parse file %s: %s", filename, err)
+	}
+
+	err = res.validate()
+	if err != nil {
+		return res, fmt.Errorf("invalid file %s: %s", filename, err)
+	}
+
+	return res, nil
+}
+
+//dirConfigName is the name of the file that sets defaults of queries made in its directory and subdirectories
+const dirConfigName = ".kubemrr"
+
+//DirConfig represents defaults of queries written in a .kubemrr file, for example
+//in the directory of a service in a monorepo
+type DirConfig struct {
+	Context   string `yaml:"context"`
+	Namespace string `yaml:"namespace"`
+}
+
+//findDirConfig reads the .kubemrr file of the directory or of the closest parent directory
+//that has one. If there is no such file, an empty configuration is returned
+func findDirConfig(dir string) (DirConfig, error) {
+	res := DirConfig{}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return res, err
+	}
+
+	for {
+		filename := filepath.Join(dir, dirConfigName)
+		raw, err := ioutil.ReadFile(filename)
+		if err == nil {
+			if err := yaml.Unmarshal(raw, &res); err != nil {
+				return res, fmt.Errorf("could not parse file %s: %s", filename, err)
+			}
+			return res, nil
+		}
+		//a directory named .kubemrr, such as the one with the discovery cache in the home directory, is not a config
+		if !os.IsNotExist(err) && !isDir(filename) {
+			return res, fmt.Errorf("could not read file %s: %s", filename, err)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return res, nil
+		}
+		dir = parent
+	}
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
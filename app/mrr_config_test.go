@@ -0,0 +1,197 @@
+package app
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseMrrConfigFailures(t *testing.T) {
+	tests := []struct {
+		filename string
+		required bool
+		complain string
+	}{
+		{
+			filename: "test_data/kubemrr_config_missing",
+			required: true,
+			complain: "not read",
+		},
+		{
+			filename: "test_data/kubemrr_config_invalid",
+			complain: "invalid path of pod",
+		},
+		{
+			filename: "test_data/kubeconfig_invalid",
+			complain: "could not parse",
+		},
+		{
+			filename: "test_data/kubemrr_config_invalid_filters",
+			complain: "invalid filters",
+		},
+		{
+			filename: "test_data/kubemrr_config_invalid_aliases",
+			complain: "invalid alias",
+		},
+		{
+			filename: "test_data/kubemrr_config_invalid_port",
+			complain: "invalid port",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := parseMrrConfig(test.filename, test.required)
+		if err == nil {
+			t.Errorf("Expected an error for file %s", test.filename)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), test.complain) {
+			t.Errorf("Error [%s] does not contain [%s]", err, test.complain)
+		}
+	}
+}
+
+func TestParseMrrConfigMissing(t *testing.T) {
+	actual, err := parseMrrConfig("test_data/kubemrr_config_missing", false)
+	assert.NoError(t, err)
+	assert.Equal(t, MrrConfig{}, actual)
+}
+
+func TestApplyMrrConfig(t *testing.T) {
+	defer log.SetLevel(log.InfoLevel)
+
+	watch := NewWatchCommand(&TestFactory{})
+	watch.Flags().Set("config", "test_data/kubemrr_config_flags")
+	watch.Flags().Set("port", "4000")
+	watch.Flags().Set("log-format", "text")
+	config, err := applyMrrConfig(watch)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod", "dev"}, config.Contexts)
+
+	expected := map[string]string{
+		"address":       "0.0.0.0",
+		"port":          "4000",
+		"kubeconfig":    "test_data/kubeconfig_valid",
+		"selector":      "team=payments",
+		"interval":      "30s",
+		"log-file":      "/var/log/kubemrr.log",
+		"log-level":     "warn",
+		"log-format":    "text",
+		"log-max-size":  "50",
+		"log-max-files": "3",
+	}
+	for name, value := range expected {
+		assert.Equal(t, value, watch.Flags().Lookup(name).Value.String(), "flag %s of watch", name)
+	}
+
+	get := NewGetCommand(&TestFactory{})
+	get.Flags().Set("config", "test_data/kubemrr_config_flags")
+	_, err = applyMrrConfig(get)
+	assert.NoError(t, err)
+	assert.Equal(t, "33034", get.Flags().Lookup("port").Value.String())
+	assert.Equal(t, "", get.Flags().Lookup("selector").Value.String(), "selector of watch is not a query of get")
+	assert.Equal(t, "", get.Flags().Lookup("log-level").Value.String(), "logs of watch are not logs of get")
+
+	get = NewGetCommand(&TestFactory{})
+	get.Flags().Set("config", "test_data/kubemrr_config_invalid_port")
+	_, err = RunCommon(get)
+	assert.Error(t, err)
+}
+
+func TestMrrConfigClientOptions(t *testing.T) {
+	mrrConfig, err := parseMrrConfig("test_data/kubemrr_config_valid", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	kubeconfig, err := parseKubeConfig("test_data/kubeconfig_valid")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		context  string
+		expected KubeClientOptions
+	}{
+		{
+			context: "prod",
+			expected: KubeClientOptions{
+				Paths: map[string]string{
+					"pod": "/gateway/prod/api/v1/{{if .Namespace}}namespaces/{{.Namespace}}/{{end}}pods",
+				},
+				LabelSelector:  "tier=web",
+				FieldSelectors: map[string]string{"pod": "status.phase!=Succeeded"},
+			},
+		},
+		{
+			context: "dev",
+			expected: KubeClientOptions{Paths: map[string]string{
+				"deployment": "/gateway/dev/apis/apps/v1/deployments",
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		kubeconfig.CurrentContext = test.context
+		assert.Equal(t, test.expected, mrrConfig.clientOptions(&kubeconfig), "context %s", test.context)
+	}
+}
+
+func TestMrrConfigFilterRules(t *testing.T) {
+	mrrConfig, err := parseMrrConfig("test_data/kubemrr_config_valid", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	kubeconfig, err := parseKubeConfig("test_data/kubeconfig_valid")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	common := FilterRule{Exclude: &ObjectMatch{Namespaces: []string{"kube-system"}}}
+
+	kubeconfig.CurrentContext = "dev"
+	assert.Equal(t, []FilterRule{
+		{Exclude: &ObjectMatch{Kinds: []string{"configmap"}}},
+		common,
+	}, mrrConfig.filterRules(&kubeconfig), "rules of the cluster go first")
+
+	kubeconfig.CurrentContext = "prod"
+	assert.Equal(t, []FilterRule{common}, mrrConfig.filterRules(&kubeconfig))
+}
+
+func TestFindDirConfig(t *testing.T) {
+	root, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	service := filepath.Join(root, "services", "payments")
+	os.MkdirAll(filepath.Join(service, "src"), 0755)
+	os.MkdirAll(filepath.Join(root, "tools", ".kubemrr"), 0755)
+	ioutil.WriteFile(filepath.Join(root, ".kubemrr"), []byte("context: dev\n"), 0644)
+	ioutil.WriteFile(filepath.Join(service, ".kubemrr"), []byte("context: prod\nnamespace: payments\n"), 0644)
+
+	tests := []struct {
+		dir      string
+		expected DirConfig
+	}{
+		{service, DirConfig{Context: "prod", Namespace: "payments"}},
+		{filepath.Join(service, "src"), DirConfig{Context: "prod", Namespace: "payments"}},
+		{filepath.Join(root, "services"), DirConfig{Context: "dev"}},
+		{filepath.Join(root, "tools"), DirConfig{Context: "dev"}},
+	}
+	for _, test := range tests {
+		res, err := findDirConfig(test.dir)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, res, "dir %s", test.dir)
+	}
+
+	ioutil.WriteFile(filepath.Join(service, ".kubemrr"), []byte("context: [\n"), 0644)
+	_, err = findDirConfig(service)
+	assert.Error(t, err)
+}
@@ -0,0 +1,509 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const protobufContentType = "application/vnd.kubernetes.protobuf"
+
+//protobufMagic prefixes every object that Kubernetes encodes in protobuf
+var protobufMagic = []byte{0x6b, 0x38, 0x73, 0x00}
+
+//pbField is a field of a protobuf message. Only varint and length-delimited
+//values are kept, which is enough to read metadata of Kubernetes objects
+type pbField struct {
+	num    int
+	varint uint64
+	bytes  []byte
+}
+
+func isProtobuf(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == protobufContentType
+}
+
+//pbParse splits a protobuf message into fields
+func pbParse(b []byte) ([]pbField, error) {
+	fields := []pbField{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("invalid protobuf field key")
+		}
+		b = b[n:]
+
+		f := pbField{num: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			f.varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, errors.New("invalid protobuf varint")
+			}
+			b = b[n:]
+		case 1:
+			if len(b) < 8 {
+				return nil, errors.New("truncated protobuf fixed64")
+			}
+			b = b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return nil, errors.New("truncated protobuf bytes")
+			}
+			f.bytes = b[n : n+int(l)]
+			b = b[n+int(l):]
+		case 5:
+			if len(b) < 4 {
+				return nil, errors.New("truncated protobuf fixed32")
+			}
+			b = b[4:]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+//decodeProtobufUnknown strips the envelope Kubernetes puts around encoded objects,
+//returning the kind and the encoded object
+func decodeProtobufUnknown(b []byte) (string, []byte, error) {
+	if !bytes.HasPrefix(b, protobufMagic) {
+		return "", nil, errors.New("protobuf object does not start with the Kubernetes prefix")
+	}
+
+	fields, err := pbParse(b[len(protobufMagic):])
+	if err != nil {
+		return "", nil, err
+	}
+
+	var kind string
+	var raw []byte
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			typeMeta, err := pbParse(f.bytes)
+			if err != nil {
+				return "", nil, err
+			}
+			for _, tf := range typeMeta {
+				if tf.num == 2 {
+					kind = string(tf.bytes)
+				}
+			}
+		case 2:
+			raw = f.bytes
+		}
+	}
+	return kind, raw, nil
+}
+
+func decodeProtobufObjectMeta(b []byte, o *KubeObject) error {
+	fields, err := pbParse(b)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			o.Name = string(f.bytes)
+		case 3:
+			o.Namespace = string(f.bytes)
+		case 5:
+			o.UID = string(f.bytes)
+		case 6:
+			o.ResourceVersion = string(f.bytes)
+		case 8:
+			//creationTimestamp is a Time message with seconds and nanos
+			ts, err := pbParse(f.bytes)
+			if err != nil {
+				return err
+			}
+			var seconds, nanos int64
+			for _, tf := range ts {
+				switch tf.num {
+				case 1:
+					seconds = int64(tf.varint)
+				case 2:
+					nanos = int64(int32(tf.varint))
+				}
+			}
+			o.CreationTimestamp = time.Unix(seconds, nanos).UTC()
+		case 11:
+			entry, err := pbParse(f.bytes)
+			if err != nil {
+				return err
+			}
+			var key, value string
+			for _, ef := range entry {
+				switch ef.num {
+				case 1:
+					key = string(ef.bytes)
+				case 2:
+					value = string(ef.bytes)
+				}
+			}
+			if o.Labels == nil {
+				o.Labels = make(map[string]string)
+			}
+			o.Labels[key] = value
+		}
+	}
+	return nil
+}
+
+func decodeProtobufObject(b []byte, kind string) (KubeObject, error) {
+	o := KubeObject{TypeMeta: TypeMeta{Kind: kind}}
+	fields, err := pbParse(b)
+	if err != nil {
+		return o, err
+	}
+
+	for _, f := range fields {
+		switch {
+		case f.num == 1:
+			if err := decodeProtobufObjectMeta(f.bytes, &o); err != nil {
+				return o, err
+			}
+		case f.num == 2 && kind == "Service":
+			if err := decodeProtobufServiceSpec(f.bytes, &o.Spec); err != nil {
+				return o, err
+			}
+		case f.num == 2 && kind == "Pod":
+			if err := decodeProtobufPodSpec(f.bytes, &o.Spec); err != nil {
+				return o, err
+			}
+		case f.num == 2 && kind == "Deployment":
+			if err := decodeProtobufDeploymentSpec(f.bytes, &o.Spec); err != nil {
+				return o, err
+			}
+		case f.num == 3 && kind == "Pod":
+			if err := decodeProtobufPodStatus(f.bytes, &o.Status); err != nil {
+				return o, err
+			}
+		case f.num == 2 && kind == "Node":
+			if err := decodeProtobufNodeSpec(f.bytes, &o.Spec); err != nil {
+				return o, err
+			}
+		case f.num == 3 && kind == "Node":
+			if err := decodeProtobufNodeStatus(f.bytes, &o.Status); err != nil {
+				return o, err
+			}
+		}
+	}
+	return o, nil
+}
+
+//decodeProtobufPodSpec reads containers, field 2 of PodSpec, and init containers, field 20
+func decodeProtobufPodSpec(b []byte, spec *ObjectSpec) error {
+	fields, err := pbParse(b)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.num != 2 && f.num != 20 {
+			continue
+		}
+		c, err := decodeProtobufContainer(f.bytes)
+		if err != nil {
+			return err
+		}
+		if f.num == 2 {
+			spec.Containers = append(spec.Containers, c)
+		} else {
+			spec.InitContainers = append(spec.InitContainers, c)
+		}
+	}
+	return nil
+}
+
+//decodeProtobufPodStatus reads phase, field 1 of PodStatus, and podIP, field 6
+func decodeProtobufPodStatus(b []byte, status *ObjectStatus) error {
+	fields, err := pbParse(b)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			status.Phase = string(f.bytes)
+		case 6:
+			status.PodIP = string(f.bytes)
+		}
+	}
+	return nil
+}
+
+//decodeProtobufNodeSpec reads unschedulable, field 4 of NodeSpec
+func decodeProtobufNodeSpec(b []byte, spec *ObjectSpec) error {
+	fields, err := pbParse(b)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.num == 4 {
+			spec.Unschedulable = f.varint != 0
+		}
+	}
+	return nil
+}
+
+//decodeProtobufNodeStatus reads conditions, field 4 of NodeStatus.
+//Type is field 1 of NodeCondition, and status is field 2
+func decodeProtobufNodeStatus(b []byte, status *ObjectStatus) error {
+	fields, err := pbParse(b)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.num != 4 {
+			continue
+		}
+		conditionFields, err := pbParse(f.bytes)
+		if err != nil {
+			return err
+		}
+		c := Condition{}
+		for _, cf := range conditionFields {
+			switch cf.num {
+			case 1:
+				c.Type = string(cf.bytes)
+			case 2:
+				c.Status = string(cf.bytes)
+			}
+		}
+		status.Conditions = append(status.Conditions, c)
+	}
+	return nil
+}
+
+//decodeProtobufServiceSpec reads ports, field 1 of ServiceSpec, and clusterIP, field 3.
+//Name is field 1 of ServicePort, and port is field 3
+func decodeProtobufServiceSpec(b []byte, spec *ObjectSpec) error {
+	fields, err := pbParse(b)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			p, err := decodeProtobufPort(f.bytes)
+			if err != nil {
+				return err
+			}
+			spec.Ports = append(spec.Ports, Port{Name: p.Name, Port: p.ContainerPort})
+		case 3:
+			spec.ClusterIP = string(f.bytes)
+		}
+	}
+	return nil
+}
+
+//decodeProtobufPort reads name, field 1 of ContainerPort and of ServicePort, and the port, field 3 of both
+func decodeProtobufPort(b []byte) (Port, error) {
+	p := Port{}
+	fields, err := pbParse(b)
+	if err != nil {
+		return p, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			p.Name = string(f.bytes)
+		case 3:
+			p.ContainerPort = int(f.varint)
+		}
+	}
+	return p, nil
+}
+
+//decodeProtobufContainer reads name, field 1 of Container, image, field 2, and ports, field 6
+func decodeProtobufContainer(b []byte) (Container, error) {
+	c := Container{}
+	fields, err := pbParse(b)
+	if err != nil {
+		return c, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			c.Name = string(f.bytes)
+		case 2:
+			c.Image = string(f.bytes)
+		case 6:
+			p, err := decodeProtobufPort(f.bytes)
+			if err != nil {
+				return c, err
+			}
+			c.Ports = append(c.Ports, p)
+		}
+	}
+	return c, nil
+}
+
+//decodeProtobufDeploymentSpec reads the template of pods, field 3 of DeploymentSpec.
+//Spec of pods is field 2 of PodTemplateSpec
+func decodeProtobufDeploymentSpec(b []byte, spec *ObjectSpec) error {
+	fields, err := pbParse(b)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.num != 3 {
+			continue
+		}
+		template, err := pbParse(f.bytes)
+		if err != nil {
+			return err
+		}
+		spec.Template = &PodTemplate{}
+		for _, t := range template {
+			if t.num == 2 {
+				if err := decodeProtobufPodSpec(t.bytes, &spec.Template.Spec); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//decodeProtobufList reads items of a list of objects, such as PodList
+func decodeProtobufList(b []byte, list *ObjectList) error {
+	kind, raw, err := decodeProtobufUnknown(b)
+	if err != nil {
+		return err
+	}
+
+	fields, err := pbParse(raw)
+	if err != nil {
+		return err
+	}
+
+	list.Objects = []KubeObject{}
+	for _, f := range fields {
+		if f.num == 1 {
+			//ListMeta keeps the resource version of the list in its second field
+			meta, err := pbParse(f.bytes)
+			if err != nil {
+				return err
+			}
+			for _, mf := range meta {
+				if mf.num == 2 {
+					list.Metadata.ResourceVersion = string(mf.bytes)
+				}
+			}
+		}
+		if f.num == 2 {
+			o, err := decodeProtobufObject(f.bytes, strings.TrimSuffix(kind, "List"))
+			if err != nil {
+				return err
+			}
+			list.Objects = append(list.Objects, o)
+		}
+	}
+	return nil
+}
+
+//decodeProtobufEvent reads a watch event, which holds the changed object in its own envelope
+func decodeProtobufEvent(b []byte) (*ObjectEvent, error) {
+	_, raw, err := decodeProtobufUnknown(b)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := pbParse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &ObjectEvent{}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			event.Type = EventType(f.bytes)
+		case 2:
+			if event.Type == Error {
+				return nil, decodeProtobufStatus(f.bytes)
+			}
+			ext, err := pbParse(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+			for _, ef := range ext {
+				if ef.num != 1 {
+					continue
+				}
+				kind, objRaw, err := decodeProtobufUnknown(ef.bytes)
+				if err != nil {
+					return nil, err
+				}
+				o, err := decodeProtobufObject(objRaw, kind)
+				if err != nil {
+					return nil, err
+				}
+				event.Object = &o
+			}
+		}
+	}
+
+	if event.Object == nil {
+		return nil, errors.New("protobuf watch event does not have an object")
+	}
+	return event, nil
+}
+
+//decodeProtobufStatus turns the Status object of an error event into an error
+func decodeProtobufStatus(rawExtension []byte) error {
+	ext, err := pbParse(rawExtension)
+	if err != nil {
+		return err
+	}
+
+	we := &APIError{}
+	for _, ef := range ext {
+		if ef.num != 1 {
+			continue
+		}
+		_, raw, err := decodeProtobufUnknown(ef.bytes)
+		if err != nil {
+			return err
+		}
+		fields, err := pbParse(raw)
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			switch f.num {
+			case 3:
+				we.Message = string(f.bytes)
+			case 6:
+				we.Code = int(int32(f.varint))
+			}
+		}
+	}
+	return we
+}
+
+//readProtobufFrame reads one message of a watch stream, where each message is prefixed by its length
+func readProtobufFrame(r io.Reader) ([]byte, error) {
+	var l uint32
+	if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,198 @@
+package app
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func pbAppendUvarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, v)
+	return append(b, buf[:n]...)
+}
+
+func pbAppendBytes(b []byte, num int, value []byte) []byte {
+	b = pbAppendUvarint(b, uint64(num<<3|2))
+	b = pbAppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+func pbAppendVarint(b []byte, num int, value uint64) []byte {
+	b = pbAppendUvarint(b, uint64(num<<3))
+	return pbAppendUvarint(b, value)
+}
+
+//pbUnknown wraps the encoded object into the envelope used by Kubernetes
+func pbUnknown(kind string, raw []byte) []byte {
+	typeMeta := pbAppendBytes(nil, 1, []byte("v1"))
+	typeMeta = pbAppendBytes(typeMeta, 2, []byte(kind))
+	b := pbAppendBytes(append([]byte{}, protobufMagic...), 1, typeMeta)
+	return pbAppendBytes(b, 2, raw)
+}
+
+func pbObject(name string, namespace string) []byte {
+	meta := pbAppendBytes(nil, 1, []byte(name))
+	meta = pbAppendBytes(meta, 3, []byte(namespace))
+	meta = pbAppendBytes(meta, 6, []byte("42"))
+	meta = pbAppendVarint(meta, 7, 3)
+	o := pbAppendBytes(nil, 1, meta)
+	return pbAppendBytes(o, 2, []byte{0x0a, 0x00})
+}
+
+func pbList(kind string, objects ...[]byte) []byte {
+	list := pbAppendBytes(nil, 1, pbAppendBytes(nil, 2, []byte("100")))
+	for _, o := range objects {
+		list = pbAppendBytes(list, 2, o)
+	}
+	return pbUnknown(kind, list)
+}
+
+func pbEvent(eventType string, kind string, object []byte) []byte {
+	event := pbAppendBytes(nil, 1, []byte(eventType))
+	event = pbAppendBytes(event, 2, pbAppendBytes(nil, 1, pbUnknown(kind, object)))
+	return pbUnknown("WatchEvent", event)
+}
+
+func TestDecodeProtobufList(t *testing.T) {
+	var list ObjectList
+	err := decodeProtobufList(pbList("PodList", pbObject("a", "ns1"), pbObject("b", "ns2")), &list)
+	assert.NoError(t, err)
+
+	expected := []KubeObject{
+		{TypeMeta: TypeMeta{"Pod"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "ns1", ResourceVersion: "42"}},
+		{TypeMeta: TypeMeta{"Pod"}, ObjectMeta: ObjectMeta{Name: "b", Namespace: "ns2", ResourceVersion: "42"}},
+	}
+	assert.Equal(t, expected, list.Objects)
+	assert.Equal(t, "100", list.Metadata.ResourceVersion)
+}
+
+func TestDecodeProtobufIPs(t *testing.T) {
+	meta := pbAppendBytes(nil, 1, []byte("a"))
+	pod := pbAppendBytes(nil, 1, meta)
+	pod = pbAppendBytes(pod, 2, pbAppendBytes(nil, 3, []byte("Always")))
+	status := pbAppendBytes(pbAppendBytes(nil, 1, []byte("Running")), 5, []byte("10.0.0.1"))
+	pod = pbAppendBytes(pod, 3, pbAppendBytes(status, 6, []byte("10.1.2.3")))
+	o, err := decodeProtobufObject(pod, "Pod")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.2.3", o.IP())
+	assert.Equal(t, "Running", o.Status.Phase)
+
+	svc := pbAppendBytes(nil, 1, meta)
+	svc = pbAppendBytes(svc, 2, pbAppendBytes(nil, 3, []byte("10.96.0.10")))
+	o, err = decodeProtobufObject(svc, "Service")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.96.0.10", o.IP())
+}
+
+func TestDecodeProtobufContainers(t *testing.T) {
+	spec := pbAppendBytes(nil, 2, pbAppendBytes(nil, 1, []byte("web")))
+	spec = pbAppendBytes(spec, 20, pbAppendBytes(nil, 1, []byte("migrate")))
+	spec = pbAppendBytes(spec, 2, pbAppendBytes(pbAppendBytes(nil, 1, []byte("proxy")), 2, []byte("envoy:1.2")))
+	pod := pbAppendBytes(nil, 1, pbAppendBytes(nil, 1, []byte("a")))
+	pod = pbAppendBytes(pod, 2, spec)
+	o, err := decodeProtobufObject(pod, "Pod")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web", "proxy", "migrate"}, o.ContainerNames())
+	assert.Equal(t, []string{"envoy:1.2"}, o.Images())
+
+	template := pbAppendBytes(nil, 2, pbAppendBytes(nil, 2, pbAppendBytes(pbAppendBytes(nil, 1, []byte("web")), 2, []byte("web:3"))))
+	deployment := pbAppendBytes(nil, 1, pbAppendBytes(nil, 1, []byte("web")))
+	deployment = pbAppendBytes(deployment, 2, pbAppendVarint(pbAppendBytes(nil, 3, template), 1, 2))
+	o, err = decodeProtobufObject(deployment, "Deployment")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web:3"}, o.Images())
+}
+
+func TestDecodeProtobufPorts(t *testing.T) {
+	port := func(name string, number uint64) []byte {
+		return pbAppendVarint(pbAppendBytes(nil, 1, []byte(name)), 3, number)
+	}
+	container := pbAppendBytes(nil, 1, []byte("web"))
+	container = pbAppendBytes(container, 6, port("http", 8080))
+	container = pbAppendBytes(container, 6, port("metrics", 9090))
+	pod := pbAppendBytes(nil, 1, pbAppendBytes(nil, 1, []byte("a")))
+	pod = pbAppendBytes(pod, 2, pbAppendBytes(nil, 2, container))
+	o, err := decodeProtobufObject(pod, "Pod")
+	assert.NoError(t, err)
+	assert.Equal(t, []Port{{Name: "http", ContainerPort: 8080}, {Name: "metrics", ContainerPort: 9090}}, o.Spec.Containers[0].Ports)
+
+	spec := pbAppendBytes(nil, 1, port("https", 443))
+	spec = pbAppendBytes(spec, 3, []byte("10.96.0.10"))
+	spec = pbAppendBytes(spec, 1, port("http", 80))
+	svc := pbAppendBytes(nil, 1, pbAppendBytes(nil, 1, []byte("web")))
+	svc = pbAppendBytes(svc, 2, spec)
+	o, err = decodeProtobufObject(svc, "Service")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.96.0.10", o.IP())
+	assert.Equal(t, []int{80, 443}, o.PortNumbers())
+}
+
+func TestDecodeProtobufNode(t *testing.T) {
+	condition := func(conditionType string, status string) []byte {
+		return pbAppendBytes(pbAppendBytes(nil, 1, []byte(conditionType)), 2, []byte(status))
+	}
+	status := pbAppendBytes(nil, 4, condition("MemoryPressure", "False"))
+	status = pbAppendBytes(status, 4, condition("Ready", "True"))
+	node := pbAppendBytes(nil, 1, pbAppendBytes(nil, 1, []byte("n1")))
+	node = pbAppendBytes(node, 2, pbAppendVarint(pbAppendBytes(nil, 1, []byte("10.0.0.0/24")), 4, 1))
+	node = pbAppendBytes(node, 3, status)
+	o, err := decodeProtobufObject(node, "Node")
+	assert.NoError(t, err)
+	assert.True(t, o.Spec.Unschedulable)
+	assert.Equal(t, []Condition{{Type: "MemoryPressure", Status: "False"}, {Type: "Ready", Status: "True"}}, o.Status.Conditions)
+	assert.False(t, o.Ready())
+}
+
+func TestDecodeProtobufEvent(t *testing.T) {
+	event, err := decodeProtobufEvent(pbEvent("MODIFIED", "Pod", pbObject("a", "ns1")))
+	assert.NoError(t, err)
+
+	expected := &ObjectEvent{
+		Modified,
+		&KubeObject{TypeMeta: TypeMeta{"Pod"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "ns1", ResourceVersion: "42"}},
+	}
+	assert.Equal(t, expected, event)
+}
+
+func TestDecodeProtobufCreationTimestamp(t *testing.T) {
+	meta := pbAppendBytes(nil, 1, []byte("a"))
+	meta = pbAppendBytes(meta, 8, pbAppendVarint(pbAppendVarint(nil, 1, 1493640000), 2, 500))
+	o, err := decodeProtobufObject(pbAppendBytes(nil, 1, meta), "Pod")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2017, 5, 1, 12, 0, 0, 500, time.UTC), o.CreationTimestamp)
+}
+
+func TestDecodeProtobufLabels(t *testing.T) {
+	meta := pbAppendBytes(nil, 1, []byte("a"))
+	meta = pbAppendBytes(meta, 11, pbAppendBytes(pbAppendBytes(nil, 1, []byte("app")), 2, []byte("web")))
+	meta = pbAppendBytes(meta, 11, pbAppendBytes(pbAppendBytes(nil, 1, []byte("tier")), 2, []byte("")))
+	o, err := decodeProtobufObject(pbAppendBytes(nil, 1, meta), "Pod")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": "web", "tier": ""}, o.Labels)
+}
+
+func TestDecodeProtobufErrorEvent(t *testing.T) {
+	status := pbAppendBytes(nil, 3, []byte("too old resource version"))
+	status = pbAppendVarint(status, 6, 410)
+	_, err := decodeProtobufEvent(pbEvent("ERROR", "Status", status))
+	assert.Equal(t, &APIError{Code: 410, Message: "too old resource version"}, err)
+}
+
+func TestDecodeProtobufFailures(t *testing.T) {
+	tests := [][]byte{
+		{},
+		[]byte(`{"items": []}`),
+		append(append([]byte{}, protobufMagic...), 0x12, 0x05, 0x01),
+		append(append([]byte{}, protobufMagic...), 0x0b),
+	}
+
+	for _, test := range tests {
+		var list ObjectList
+		assert.Error(t, decodeProtobufList(test, &list), "input %v", test)
+	}
+
+	_, err := decodeProtobufEvent(pbUnknown("WatchEvent", pbAppendBytes(nil, 1, []byte("ADDED"))))
+	assert.Error(t, err, "event without object")
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//tokenBucket limits the rate of requests. It holds up to burst tokens
+//and gets qps tokens per second, each request takes one token
+type tokenBucket struct {
+	mu     sync.Mutex
+	qps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+//newTokenBucket returns a limiter with the given rate, or nil if the rate is not limited
+func newTokenBucket(qps float64, burst int) *tokenBucket {
+	if qps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		qps:    qps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+//wait takes a token, waiting until one is available or the context is done. Nil bucket never waits
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.qps
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.qps * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//backoff grows delays between retries of failing requests exponentially
+type backoff struct {
+	min   time.Duration
+	max   time.Duration
+	delay time.Duration
+}
+
+func newBackoff(min time.Duration, max time.Duration) *backoff {
+	return &backoff{min: min, max: max}
+}
+
+//next returns how long to wait before retrying after the error. The delay is never
+//shorter than the server asked for by Retry-After of 429 and 503 responses
+func (b *backoff) next(err error) time.Duration {
+	if b.delay == 0 {
+		b.delay = b.min
+	} else {
+		b.delay *= 2
+	}
+	if b.delay > b.max {
+		b.delay = b.max
+	}
+
+	//jitter keeps watchers of many clusters from retrying at the same moment
+	d := b.delay + time.Duration(rand.Int63n(int64(b.delay)/5+1))
+	if e, ok := err.(*APIError); ok && e.RetryAfter > d {
+		d = e.RetryAfter
+	}
+	return d
+}
+
+func (b *backoff) reset() {
+	b.delay = 0
+}
+
+//parseRetryAfter reads the Retry-After header given in seconds or as HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(100, 3)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, b.wait(context.Background()))
+	}
+	elapsed := time.Since(start)
+	if elapsed < 15*time.Millisecond || elapsed > 200*time.Millisecond {
+		t.Errorf("Two requests above burst must wait about 20ms, waited %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b = newTokenBucket(0.001, 1)
+	assert.NoError(t, b.wait(ctx))
+	assert.Error(t, b.wait(ctx), "must not wait after the context is done")
+
+	var unlimited *tokenBucket
+	assert.Nil(t, newTokenBucket(0, 10))
+	assert.NoError(t, unlimited.wait(ctx))
+}
+
+func TestBackoff(t *testing.T) {
+	b := newBackoff(time.Second, 4*time.Second)
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	for _, e := range expected {
+		d := b.next(nil)
+		if d < e || d > e+e/5 {
+			t.Errorf("Expected delay about %s, got %s", e, d)
+		}
+	}
+
+	b.reset()
+	d := b.next(&APIError{Code: 429, RetryAfter: time.Minute})
+	assert.Equal(t, time.Minute, d, "must respect Retry-After")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon"))
+
+	d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if d < 58*time.Second || d > time.Minute {
+		t.Errorf("Expected about a minute, got %s", d)
+	}
+}
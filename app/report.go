@@ -0,0 +1,266 @@
+package app
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+//redacted replaces secrets and paths to them in report bundles
+const redacted = "REDACTED"
+
+func NewReportBundleCommand(f Factory) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "report-bundle",
+		Short: "Package diagnostic information for attaching to an issue",
+		Long: `
+DESCRIPTION:
+  Write a tarball with information that helps to debug problems of kubemrr:
+  version of kubemrr, status and recent logs of the "kubemrr watch" process,
+  kubeconfig and kubemrr configuration files.
+
+  Credentials and paths to them are redacted, and addresses of Kubernetes API servers
+  are replaced with anonymous names. Review the content before attaching it to an issue.
+
+EXAMPLE:
+  kubemrr -a 0.0.0.0 -p 33033 report-bundle -o report.tar.gz
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := RunCommon(cmd); err != nil {
+				return err
+			}
+			return RunReportBundle(f, cmd, args)
+		},
+	}
+
+	AddCommonFlags(cmd)
+	AddMirrorTLSFlags(cmd)
+	AddTokenFlag(cmd)
+	cmd.Flags().StringP("output", "o", "", "Path to the written tarball, by default kubemrr-report-<time>.tar.gz")
+	return cmd
+}
+
+//reportFile is a file of the report bundle
+type reportFile struct {
+	name    string
+	content []byte
+}
+
+func RunReportBundle(f Factory, cmd *cobra.Command, args []string) error {
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return errors.New("could not parse value of --output")
+	}
+	if output == "" {
+		output = fmt.Sprintf("kubemrr-report-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+
+	bind, err := GetBind(cmd)
+	if err != nil {
+		return fmt.Errorf("unexpected error: %s", err)
+	}
+
+	opts, err := GetMrrClientOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	files := []reportFile{}
+	problems := []string{}
+	servers := []string{}
+	add := func(name string, v interface{}, marshal func(interface{}) ([]byte, error)) {
+		raw, err := marshal(v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("could not write %s: %s", name, err))
+			return
+		}
+		files = append(files, reportFile{name, raw})
+	}
+
+	files = append(files, reportFile{"version.txt", []byte(buildInfo())})
+
+	kubeconfig, err := GetKubeconfig(cmd)
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		for _, c := range kubeconfig.Clusters {
+			servers = append(servers, c.Cluster.Server)
+		}
+		add("kubeconfig.yaml", redactedKubeconfig(*kubeconfig), yaml.Marshal)
+	}
+
+	mrrConfig, err := GetMrrConfig(cmd)
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		for _, c := range mrrConfig.Clusters {
+			servers = append(servers, c.Server)
+		}
+		add("kubemrr.yaml", mrrConfig, yaml.Marshal)
+	}
+
+	client, err := f.MrrClient(bind, opts)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("could not connect to kubemrr at %s: %s", bind, err))
+	} else {
+		status, err := client.Status(MrrFilter{})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("could not get status of kubemrr: %s", err))
+		} else {
+			for _, s := range status.Servers {
+				servers = append(servers, s.Server)
+			}
+			add("status.json", status, func(v interface{}) ([]byte, error) { return json.MarshalIndent(v, "", "  ") })
+		}
+
+		lines, err := client.Logs(MrrFilter{})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("could not get logs of kubemrr: %s", err))
+		} else {
+			files = append(files, reportFile{"logs.txt", []byte(strings.Join(lines, ""))})
+		}
+	}
+
+	if len(problems) > 0 {
+		files = append(files, reportFile{"problems.txt", []byte(strings.Join(problems, "\n") + "\n")})
+	}
+
+	s := newReportSanitizer(servers)
+	for i := range files {
+		files[i].content = []byte(s.sanitize(string(files[i].content)))
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("could not create file %s: %s", output, err)
+	}
+	err = writeReportBundle(out, files)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write file %s: %s", output, err)
+	}
+
+	fmt.Fprintf(f.StdOut(), "Report bundle is written to %s\n", output)
+	return nil
+}
+
+//redactedKubeconfig returns a copy of the kubeconfig without credentials and paths to them
+func redactedKubeconfig(c Config) Config {
+	res := c
+	res.Clusters = append([]ClusterWrap{}, c.Clusters...)
+	for i := range res.Clusters {
+		if res.Clusters[i].Cluster.CertificateAuthority != "" {
+			res.Clusters[i].Cluster.CertificateAuthority = redacted
+		}
+		if res.Clusters[i].Cluster.CertificateAuthorityData != "" {
+			res.Clusters[i].Cluster.CertificateAuthorityData = redacted
+		}
+		if u, err := url.Parse(res.Clusters[i].Cluster.ProxyURL); err == nil && u.User != nil {
+			u.User = url.User(redacted)
+			res.Clusters[i].Cluster.ProxyURL = u.String()
+		}
+	}
+
+	res.Users = append([]UserWrap{}, c.Users...)
+	for i := range res.Users {
+		u := &res.Users[i].User
+		if u.ClientCertificate != "" {
+			u.ClientCertificate = redacted
+		}
+		if u.ClientKey != "" {
+			u.ClientKey = redacted
+		}
+		if u.ClientCertificateData != "" {
+			u.ClientCertificateData = redacted
+		}
+		if u.ClientKeyData != "" {
+			u.ClientKeyData = redacted
+		}
+		if u.Token != "" {
+			u.Token = redacted
+		}
+		if u.TokenFile != "" {
+			u.TokenFile = redacted
+		}
+		if u.Exec != nil {
+			exec := *u.Exec
+			exec.Env = append([]ExecEnvVar{}, u.Exec.Env...)
+			for j := range exec.Env {
+				exec.Env[j].Value = redacted
+			}
+			u.Exec = &exec
+		}
+	}
+	return res
+}
+
+//reportSanitizer replaces host names of Kubernetes API servers with anonymous names
+type reportSanitizer struct {
+	replacer *strings.Replacer
+}
+
+func newReportSanitizer(servers []string) *reportSanitizer {
+	hosts := []string{}
+	seen := map[string]bool{}
+	for _, s := range servers {
+		u, err := url.Parse(s)
+		if err != nil || u.Hostname() == "" || seen[u.Hostname()] {
+			continue
+		}
+		seen[u.Hostname()] = true
+		hosts = append(hosts, u.Hostname())
+	}
+
+	names := map[string]string{}
+	for i, h := range hosts {
+		names[h] = fmt.Sprintf("server-%d", i+1)
+	}
+
+	//longer names go first, so that a host is not replaced inside another one
+	sort.SliceStable(hosts, func(i, j int) bool { return len(hosts[i]) > len(hosts[j]) })
+	pairs := []string{}
+	for _, h := range hosts {
+		pairs = append(pairs, h, names[h])
+	}
+	return &reportSanitizer{strings.NewReplacer(pairs...)}
+}
+
+func (s *reportSanitizer) sanitize(text string) string {
+	return s.replacer.Replace(text)
+}
+
+func writeReportBundle(w io.Writer, files []reportFile) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, file := range files {
+		hdr := &tar.Header{
+			Name:    "kubemrr-report/" + file.name,
+			Mode:    0644,
+			Size:    int64(len(file.content)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
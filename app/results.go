@@ -0,0 +1,113 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+//maxCachedResults limits the number of filters whose results the mirror remembers
+const maxCachedResults = 64
+
+//resultKey identifies a request of a client by the fields of its filter that decide the answer
+type resultKey struct {
+	server    string
+	profile   string
+	kind      string
+	namespace string
+	selector  string
+	names     string
+	nameRegex string
+	phase     string
+	readyOnly bool
+}
+
+func resultKeyOf(f *MrrFilter) resultKey {
+	return resultKey{
+		server:    f.Server,
+		profile:   f.Profile,
+		kind:      strings.ToLower(f.Kind),
+		namespace: f.Namespace,
+		selector:  f.Selector,
+		names:     f.Names,
+		nameRegex: f.NameRegex,
+		phase:     f.Phase,
+		readyOnly: f.ReadyOnly,
+	}
+}
+
+//result keeps objects that match a filter, and names of the objects encoded for clients
+type result struct {
+	servers KubeServers
+	objects []KubeObject
+	used    time.Time
+
+	encodeOnce sync.Once
+	encoded    []byte
+}
+
+//names returns names of the objects encoded by encodeNames. They are encoded once per result
+func (r *result) names() []byte {
+	r.encodeOnce.Do(func() {
+		r.encoded = encodeNames(r.objects)
+	})
+	return r.encoded
+}
+
+//resultCache remembers results of recent filters. Clients ask for the same filter on every
+//press of tab, and the answer changes only when objects do. Results are stored while the cache
+//is read-locked and dropped while it is write-locked, so a stored result is never older than objects
+type resultCache struct {
+	mu      sync.Mutex
+	results map[resultKey]*result
+}
+
+func newResultCache() *resultCache {
+	return &resultCache{results: make(map[resultKey]*result)}
+}
+
+func (rc *resultCache) get(k resultKey) *result {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	r, ok := rc.results[k]
+	if !ok {
+		return nil
+	}
+	r.used = time.Now()
+	return r
+}
+
+//put remembers the result, forgetting the least recently used one when there are too many
+func (rc *resultCache) put(k resultKey, r *result) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if _, ok := rc.results[k]; !ok && len(rc.results) >= maxCachedResults {
+		var oldest resultKey
+		var oldestUsed time.Time
+		for k, r := range rc.results {
+			if oldestUsed.IsZero() || r.used.Before(oldestUsed) {
+				oldest, oldestUsed = k, r.used
+			}
+		}
+		delete(rc.results, oldest)
+	}
+	r.used = time.Now()
+	rc.results[k] = r
+}
+
+//invalidate drops results that objects of the kind of the server may change.
+//Empty kind drops all results of the server. Namespaces are also seen in other objects,
+//so their results are dropped on every change
+func (rc *resultCache) invalidate(server KubeServer, kind string) {
+	kind = strings.ToLower(kind)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for k := range rc.results {
+		if !matchesServer(&MrrFilter{Server: k.server, Profile: k.profile}, server) {
+			continue
+		}
+		if kind == "" || k.kind == kind || k.kind == "namespace" {
+			delete(rc.results, k)
+		}
+	}
+}
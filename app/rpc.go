@@ -1,90 +1,661 @@
 package app
 
 import (
+	"bufio"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/http"
 	"net/rpc"
+	"path"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 type MrrFilter struct {
 	Server    string
 	Namespace string
 	Kind      string
+
+	//Profile limits the query to servers watched for the named kubeconfig. Empty profile matches all
+	Profile string
+
+	//Selector is a label selector that the objects must match, such as "app=web,tier!=db". Empty selector matches all
+	Selector string
+
+	//Names is a glob pattern such as "web-*", and NameRegex a regular expression, that names of the objects
+	//must match. Empty patterns match all names
+	Names     string
+	NameRegex string
+
+	//Phase limits the query to pods in the phase, such as Running. Objects without phase, such as services,
+	//are not filtered. Empty phase matches all
+	Phase string
+
+	//ReadyOnly limits the query to objects that are Ready, such as nodes that are not cordoned
+	ReadyOnly bool
+
+	//Deadline is when the client stops waiting for the answer. Zero deadline means no deadline
+	Deadline time.Time
+
+	//RequestID identifies the request in logs of the client and of the mirror
+	RequestID string
+}
+
+//errDeadlineExceeded is returned when the deadline of the query has passed
+var errDeadlineExceeded = errors.New("deadline exceeded")
+
+//expired checks whether the client has already given up on the query
+func (f *MrrFilter) expired() bool {
+	return !f.Deadline.IsZero() && time.Now().After(f.Deadline)
+}
+
+//defaultQueryTimeout bounds how long the client waits for answers of the mirror
+const defaultQueryTimeout = 5 * time.Second
+
+//maxRecentEvents is the number of the latest changes of the cache that are kept for status
+const maxRecentEvents = 100
+
+//CacheEvent is a change of an object in the cache
+type CacheEvent struct {
+	Time      time.Time
+	Server    string
+	Profile   string
+	Type      EventType
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+type KindStatus struct {
+	Kind    string
+	Objects int
+	Updated time.Time
+
+	//Namespaces are numbers of objects in each namespace, under "" for objects that do not belong to namespaces
+	Namespaces map[string]int
+
+	//Bytes estimates memory that the objects take in the cache
+	Bytes int
+
+	//Hash identifies the cached objects of the kind. Caches with equal objects have equal hashes
+	Hash string
+
+	//LastEvent is when the watch of the kind received the last event, zero for listed kinds
+	LastEvent time.Time
+
+	//LastError is the last error of requests to the API server for the kind
+	LastError     string
+	LastErrorTime time.Time
+
+	//Reconnects is how many times the watch connection was opened again
+	Reconnects int
+
+	//Added, Modified and Deleted count changes of objects of the kind in the cache
+	Added    int
+	Modified int
+	Deleted  int
+
+	//EventLag is the mean time from creation of objects to their arrival in the cache,
+	//for objects created while the kind was watched
+	EventLag time.Duration
+}
+
+type ServerStatus struct {
+	Server  string
+	Profile string
+	Kinds   []KindStatus
+
+	//Hash identifies all cached objects of the server
+	Hash string
+}
+
+//kindStatus returns status of the kind of the server, adding it if there is none
+func (s *MrrStatus) kindStatus(server KubeServer, kind string) *KindStatus {
+	i := sort.Search(len(s.Servers), func(i int) bool {
+		return !(KubeServers{{URL: s.Servers[i].Server, Profile: s.Servers[i].Profile}, server}).Less(0, 1)
+	})
+	if i == len(s.Servers) || s.Servers[i].Server != server.URL || s.Servers[i].Profile != server.Profile {
+		s.Servers = append(s.Servers, ServerStatus{})
+		copy(s.Servers[i+1:], s.Servers[i:])
+		s.Servers[i] = ServerStatus{Server: server.URL, Profile: server.Profile, Kinds: []KindStatus{}}
+	}
+
+	ss := &s.Servers[i]
+	j := sort.Search(len(ss.Kinds), func(j int) bool { return ss.Kinds[j].Kind >= kind })
+	if j == len(ss.Kinds) || ss.Kinds[j].Kind != kind {
+		ss.Kinds = append(ss.Kinds, KindStatus{})
+		copy(ss.Kinds[j+1:], ss.Kinds[j:])
+		ss.Kinds[j] = KindStatus{Kind: kind}
+	}
+	return &ss.Kinds[j]
+}
+
+//MrrStatus describes content of the cache: objects of each server and the latest changes
+type MrrStatus struct {
+	Servers []ServerStatus
+	Events  []CacheEvent
+
+	//Features are the feature gates of the mirror
+	Features map[string]bool
+
+	//Requests are numbers of requests of clients by method, and FilterTime is the mean time
+	//it took to find objects that match their filters
+	Requests   map[string]int
+	FilterTime time.Duration
 }
 
 type MrrCache struct {
 	objects map[KubeServer][]KubeObject
 	mu      *sync.RWMutex
+
+	updated map[KubeServer]map[string]time.Time
+	events  []CacheEvent
+
+	metrics *mrrMetrics
+
+	//flusher restarts watchers of servers that match the filter and returns their number.
+	//It is nil when the admin API of the mirror is disabled
+	flusher func(f MrrFilter) int
+
+	//tokenRequired tells that clients must give the token of the mirror. Imports are refused otherwise
+	tokenRequired bool
+
+	subscribers map[*subscription]bool
+
+	//seen keeps when each object was last confirmed by its server, in events or lists
+	seen map[KubeServer]map[objectKey]time.Time
+
+	//accessed keeps when clients last asked for objects of a kind in a namespace, without names.
+	//It has its own lock, since requests of clients only read-lock the cache
+	accessMu sync.Mutex
+	accessed map[KubeServer]map[objectKey]time.Time
+
+	//index groups objects of each server by kind and namespace. Events update it in place,
+	//other changes drop it with reindexLocked. Readers build missing indexes under indexMu
+	indexMu sync.Mutex
+	index   map[KubeServer]objectIndex
+
+	//positions keeps where objects of each server are in objects, so that events do not scan them.
+	//It is dropped with the index and built again on the next event, under the write lock
+	positions map[KubeServer]map[objectKey]int
+
+	//strings interns repeated strings of objects put into the cache. It is used under the write lock
+	strings interner
+
+	//clusters keeps UIDs of clusters of the servers, when the servers told them
+	clusters map[KubeServer]string
+
+	//resources keeps resources discovered on the servers, to resolve their short names
+	resources map[KubeServer][]APIResource
+
+	//requests is read-locked while a request of a client is answered, so that drain can wait for them
+	requests sync.RWMutex
+
+	//results keeps answers to recent filters until objects they depend on change
+	results *resultCache
+
+	//features are the feature gates of the mirror, reported in its status. They are set before serving
+	features FeatureGates
 }
 
 func NewMrrCache() *MrrCache {
 	c := &MrrCache{}
 	c.mu = &sync.RWMutex{}
 	c.objects = make(map[KubeServer][]KubeObject)
+	c.updated = make(map[KubeServer]map[string]time.Time)
+	c.metrics = newMrrMetrics()
+	c.subscribers = make(map[*subscription]bool)
+	c.seen = make(map[KubeServer]map[objectKey]time.Time)
+	c.accessed = make(map[KubeServer]map[objectKey]time.Time)
+	c.index = make(map[KubeServer]objectIndex)
+	c.positions = make(map[KubeServer]map[objectKey]int)
+	c.strings = make(interner)
+	c.clusters = make(map[KubeServer]string)
+	c.resources = make(map[KubeServer][]APIResource)
+	c.results = newResultCache()
 	return c
 }
 
+//drain waits for requests of clients that are being answered. Requests that come later wait
+//forever, so it is called only when the mirror exits
+func (c *MrrCache) drain() {
+	c.requests.Lock()
+}
+
+func matchesServer(f *MrrFilter, s KubeServer) bool {
+	return (f.Server == "" || strings.EqualFold(trimPort(f.Server), trimPort(s.URL))) &&
+		(f.Profile == "" || f.Profile == s.Profile)
+}
+
+func matchesPhase(f *MrrFilter, o KubeObject) bool {
+	return f.Phase == "" || o.Status.Phase == "" || strings.EqualFold(f.Phase, o.Status.Phase)
+}
+
+func matchesReady(f *MrrFilter, o KubeObject) bool {
+	return !f.ReadyOnly || o.Ready()
+}
+
 func (c *MrrCache) Objects(f *MrrFilter, os *[]KubeObject) error {
+	defer c.metrics.observeRequest("Objects", time.Now())
+	res, err := c.matching(f)
+	if err != nil {
+		return err
+	}
+	*os = res.objects
+	return nil
+}
+
+//Names answers with names of the objects that Objects answers with, encoded by encodeNames.
+//Clients that complete names need nothing else, and the encoded names are much cheaper to send
+func (c *MrrCache) Names(f *MrrFilter, names *[]byte) error {
+	defer c.metrics.observeRequest("Names", time.Now())
+	res, err := c.matching(f)
+	if err != nil {
+		return err
+	}
+	*names = res.names()
+	return nil
+}
+
+//matching returns objects that match the filter, with their servers set.
+//Results of recent filters are reused until objects they depend on change
+func (c *MrrCache) matching(f *MrrFilter) (*result, error) {
+	c.requests.RLock()
+	defer c.requests.RUnlock()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	log.WithField("filter", f).Debug("Received request for objects")
 
 	if f == nil {
-		return errors.New("Cannot find pods with nil filter")
+		return nil, errors.New("Cannot find pods with nil filter")
+	}
+
+	if f.expired() {
+		return nil, errDeadlineExceeded
+	}
+
+	key := resultKeyOf(f)
+	if cached := c.results.get(key); cached != nil {
+		c.access(cached.servers, f.Kind, f.Namespace)
+		log.WithFields(log.Fields{
+			"id":      f.RequestID,
+			"objects": len(cached.objects),
+		}).Debug("answered with cached objects")
+		return cached, nil
+	}
+
+	started := time.Now()
+	selector, err := parseSelector(f.Selector)
+	if err != nil {
+		return nil, err
 	}
 
+	matchesName, err := nameMatcher(f)
+	if err != nil {
+		return nil, err
+	}
+	parsed := time.Now()
+
 	keys := KubeServers{}
 	for k, _ := range c.objects {
-		if f.Server == "" || strings.EqualFold(trimPort(f.Server), trimPort(k.URL)) {
+		if matchesServer(f, k) {
 			keys = append(keys, k)
 		}
 	}
 	if len(keys) == 0 {
 		log.WithField("server", f.Server).Error("unknown server")
+		return nil, fmt.Errorf("Unknown server %s", f.Server)
+	}
+
+	c.access(keys, f.Kind, f.Namespace)
+
+	sort.Sort(keys)
+	var seen map[dedupKey]bool
+	if c.sharedClusters(keys) {
+		seen = map[dedupKey]bool{}
+	}
+	candidates := []serverObjects{}
+	for _, k := range keys {
+		if f.expired() {
+			log.WithField("filter", f).Debug("deadline exceeded while collecting objects")
+			return nil, errDeadlineExceeded
+		}
+		objects := c.indexOf(k).find(f.Kind, f.Namespace)
+		if seen != nil {
+			objects = c.dedupObjects(k, objects, seen)
+		}
+		candidates = append(candidates, serverObjects{server: k.URL, objects: objects})
+	}
+
+	res, err := matchObjects(f, candidates, func(o *KubeObject) bool {
+		return selector.matches(o.Labels) && matchesName(o.Name) && matchesPhase(f, *o) && matchesReady(f, *o)
+	})
+	if err != nil {
+		log.WithField("filter", f).Debug("deadline exceeded while matching objects")
+		return nil, err
+	}
+	c.metrics.filtered(time.Since(started))
+	log.WithFields(log.Fields{
+		"id":      f.RequestID,
+		"parse":   parsed.Sub(started).String(),
+		"filter":  time.Since(parsed).String(),
+		"objects": len(res),
+	}).Debug("filtered objects")
+	r := &result{servers: keys, objects: res}
+	c.results.put(key, r)
+	return r, nil
+}
+
+//Status describes objects of the servers that match the filter
+func (c *MrrCache) Status(f *MrrFilter, s *MrrStatus) error {
+	defer c.metrics.observeRequest("Status", time.Now())
+	c.requests.RLock()
+	defer c.requests.RUnlock()
+	if f == nil {
+		return errors.New("Cannot make status with nil filter")
+	}
+
+	*s = c.status(f)
+	return nil
+}
+
+//Synced returns when objects of the kind of the filter were last updated from the servers that match it.
+//When several servers match, the oldest time is returned. Zero time means that some of them never updated the kind
+func (c *MrrCache) Synced(f *MrrFilter, synced *time.Time) error {
+	defer c.metrics.observeRequest("Synced", time.Now())
+	c.requests.RLock()
+	defer c.requests.RUnlock()
+	if f == nil {
+		return errors.New("Cannot find sync time with nil filter")
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	res := time.Time{}
+	found := false
+	for k := range c.objects {
+		if !matchesServer(f, k) {
+			continue
+		}
+		updated := c.updated[k][strings.ToLower(f.Kind)]
+		if !found || updated.Before(res) {
+			res = updated
+		}
+		found = true
+	}
+	if !found {
 		return fmt.Errorf("Unknown server %s", f.Server)
 	}
+	*synced = res
+	return nil
+}
 
-	res := []KubeObject{}
+//status describes content of the cache of servers that match the filter
+func (c *MrrCache) status(f *MrrFilter) MrrStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := KubeServers{}
+	for k := range c.objects {
+		if matchesServer(f, k) {
+			keys = append(keys, k)
+		}
+	}
 	sort.Sort(keys)
+
+	res := MrrStatus{Servers: []ServerStatus{}, Events: []CacheEvent{}, Features: c.features.State()}
 	for _, k := range keys {
+		byKind := map[string][]KubeObject{}
+		for kind := range c.updated[k] {
+			byKind[kind] = nil
+		}
 		for _, o := range c.objects[k] {
-			if strings.EqualFold(o.Kind, f.Kind) &&
-				(f.Namespace == "" || o.Kind == "namespace" || strings.EqualFold(o.Namespace, f.Namespace)) {
-				res = append(res, o)
+			kind := strings.ToLower(o.Kind)
+			byKind[kind] = append(byKind[kind], o)
+		}
+
+		kinds := []string{}
+		for kind := range byKind {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		ss := ServerStatus{Server: k.URL, Profile: k.Profile, Kinds: []KindStatus{}}
+		serverHash := fnv.New64a()
+		for _, kind := range kinds {
+			hash := objectsHash(byKind[kind])
+			ks := KindStatus{
+				Kind:       kind,
+				Objects:    len(byKind[kind]),
+				Updated:    c.updated[k][kind],
+				Hash:       hash,
+				Namespaces: map[string]int{},
 			}
+			for _, o := range byKind[kind] {
+				ks.Namespaces[o.Namespace]++
+				ks.Bytes += objectSize(o)
+			}
+			ss.Kinds = append(ss.Kinds, ks)
+			fmt.Fprintf(serverHash, "%s\x00%s\x00", kind, hash)
 		}
+		ss.Hash = fmt.Sprintf("%016x", serverHash.Sum64())
+		res.Servers = append(res.Servers, ss)
 	}
-	log.WithField("filter", f).WithField("objects", res).Debug("Returning result for objects")
-	*os = res
+
+	c.metrics.addWatcherStatus(&res, f)
+
+	for i := len(c.events) - 1; i >= 0; i-- {
+		if matchesServer(f, KubeServer{URL: c.events[i].Server, Profile: c.events[i].Profile}) {
+			res.Events = append(res.Events, c.events[i])
+		}
+	}
+	return res
+}
+
+//objectsHash returns a hash of the objects that does not depend on their order
+func objectsHash(objects []KubeObject) string {
+	sorted := append([]KubeObject{}, objects...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	h := fnv.New64a()
+	for _, o := range sorted {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", o.Namespace, o.Name, o.ResourceVersion)
+		labels := []string{}
+		for k, v := range o.Labels {
+			labels = append(labels, k+"="+v)
+		}
+		sort.Strings(labels)
+		fmt.Fprintf(h, "%s\x00", strings.Join(labels, ","))
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+//errAdminDisabled is returned by admin requests when the mirror does not allow them
+var errAdminDisabled = errors.New("admin API is disabled, the mirror must be given --token and --feature-gates=AdminAPI=true")
+
+//Flush removes objects of servers that match the filter from the cache and lists them again.
+//Empty server of the filter flushes all servers. The number of restarted watchers is returned
+func (c *MrrCache) Flush(f *MrrFilter, restarted *int) error {
+	c.requests.RLock()
+	defer c.requests.RUnlock()
+	if f == nil {
+		return errors.New("Cannot flush with nil filter")
+	}
+	if c.flusher == nil {
+		return errAdminDisabled
+	}
+
+	log.WithField("server", f.Server).WithField("profile", f.Profile).Warn("flushing the cache")
+	*restarted = c.flusher(*f)
 	return nil
 }
 
+//deleteServers removes objects of servers that match the filter from the cache
+func (c *MrrCache) deleteServers(f *MrrFilter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for s := range c.objects {
+		if matchesServer(f, s) {
+			c.deleteServerLocked(s)
+		}
+	}
+}
+
+//Logs returns the latest log lines of the mirror
+func (c *MrrCache) Logs(f *MrrFilter, lines *[]string) error {
+	c.requests.RLock()
+	defer c.requests.RUnlock()
+	if f == nil {
+		return errors.New("Cannot find logs with nil filter")
+	}
+
+	*lines = recentLogs.get()
+	return nil
+}
+
+//recordLocked remembers the change of the object. Caller must hold the write lock
+func (c *MrrCache) recordLocked(server KubeServer, t EventType, o KubeObject) {
+	c.touchLocked(server, o.Kind)
+	c.results.invalidate(server, o.Kind)
+	c.publishLocked(server, t, o)
+	c.metrics.changed(server, o.Kind, t)
+	c.events = append(c.events, CacheEvent{
+		Time:      time.Now(),
+		Server:    server.URL,
+		Profile:   server.Profile,
+		Type:      t,
+		Kind:      strings.ToLower(o.Kind),
+		Namespace: o.Namespace,
+		Name:      o.Name,
+	})
+	if len(c.events) > maxRecentEvents {
+		c.events = c.events[len(c.events)-maxRecentEvents:]
+	}
+}
+
+//touchLocked marks the kind as just updated from the server. Caller must hold the write lock
+func (c *MrrCache) touchLocked(server KubeServer, kind string) {
+	if c.updated[server] == nil {
+		c.updated[server] = make(map[string]time.Time)
+	}
+	c.updated[server][strings.ToLower(kind)] = time.Now()
+}
+
 func (c *MrrCache) updateKubeObject(server KubeServer, o KubeObject) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	o = c.strings.object(o)
 	os, ok := c.objects[server]
 	if !ok {
 		os = make([]KubeObject, 0)
 	}
 
-	found := false
-	for i := range os {
-		if os[i].Name == o.Name && os[i].Namespace == o.Namespace && os[i].Kind == o.Kind {
-			os[i] = o
-			found = true
-			break
+	positions := c.positionsLocked(server)
+	if i, found := positions[keyOf(o)]; found {
+		os[i] = o
+		c.recordLocked(server, Modified, o)
+	} else {
+		positions[keyOf(o)] = len(os)
+		os = append(os, o)
+		c.recordLocked(server, Added, o)
+	}
+	c.seenLocked(server, o)
+	c.objects[server] = os
+	if ix, ok := c.index[server]; ok {
+		ix.put(o)
+	}
+}
+
+//positionsLocked returns positions of objects of the server, building them after the objects
+//were changed at once. Caller must hold the write lock
+func (c *MrrCache) positionsLocked(server KubeServer) map[objectKey]int {
+	positions, ok := c.positions[server]
+	if !ok {
+		positions = make(map[objectKey]int, len(c.objects[server]))
+		for i, o := range c.objects[server] {
+			positions[keyOf(o)] = i
 		}
+		c.positions[server] = positions
 	}
+	return positions
+}
 
-	if !found {
-		os = append(os, o)
+type objectKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+//inScope checks whether the object is of the kind and belongs to the namespace.
+//Empty namespace matches objects of all namespaces
+func inScope(o KubeObject, kind string, namespace string) bool {
+	return strings.EqualFold(o.Kind, kind) && (namespace == "" || o.Namespace == namespace)
+}
+
+//replaceKubeObjects puts the given objects into the cache instead of the cached objects of the kind
+//in the namespace. Only objects that were actually added, modified or deleted are recorded as changes
+func (c *MrrCache) replaceKubeObjects(server KubeServer, kind string, namespace string, objects []KubeObject) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old := map[objectKey]KubeObject{}
+	newObjects := []KubeObject{}
+	for _, o := range c.objects[server] {
+		if inScope(o, kind, namespace) {
+			old[objectKey{strings.ToLower(o.Kind), o.Namespace, o.Name}] = o
+		} else {
+			newObjects = append(newObjects, o)
+		}
 	}
-	c.objects[server] = os
+
+	for _, o := range objects {
+		o = c.strings.object(o)
+		newObjects = append(newObjects, o)
+		c.seenLocked(server, o)
+		k := objectKey{strings.ToLower(o.Kind), o.Namespace, o.Name}
+		prev, ok := old[k]
+		if !ok {
+			c.recordLocked(server, Added, o)
+		} else if prev.ResourceVersion != o.ResourceVersion {
+			c.recordLocked(server, Modified, o)
+		}
+		delete(old, k)
+	}
+
+	for _, o := range old {
+		c.recordLocked(server, Deleted, o)
+	}
+
+	c.objects[server] = newObjects
+	c.reindexLocked(server)
+	c.touchLocked(server, kind)
+}
+
+//hasObjects tells whether the cache keeps objects of the kind in the namespace
+func (c *MrrCache) hasObjects(server KubeServer, kind string, namespace string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for i := range c.objects[server] {
+		if inScope(c.objects[server][i], kind, namespace) {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *MrrCache) deleteKubeObject(server KubeServer, o KubeObject) {
@@ -96,21 +667,26 @@ func (c *MrrCache) deleteKubeObject(server KubeServer, o KubeObject) {
 		return
 	}
 
-	idx := -1
-	for i := range os {
-		if os[i].Name == o.Name && os[i].Namespace == o.Namespace {
-			idx = i
-			break
+	positions := c.positionsLocked(server)
+	if idx, ok := positions[keyOf(o)]; ok {
+		//the last object takes the place of the deleted one, so that only its position changes
+		last := len(os) - 1
+		moved := os[last]
+		os[idx] = moved
+		os[last] = KubeObject{}
+		c.objects[server] = os[:last]
+		delete(positions, keyOf(o))
+		if idx != last {
+			positions[keyOf(moved)] = idx
+		}
+		c.recordLocked(server, Deleted, o)
+		if ix, ok := c.index[server]; ok {
+			ix.remove(o)
 		}
-	}
-
-	if idx >= 0 {
-		os = append(os[:idx], os[idx+1:]...)
-		c.objects[server] = os
 	}
 }
 
-func (c *MrrCache) deleteKubeObjects(s KubeServer, kind string) {
+func (c *MrrCache) deleteKubeObjects(s KubeServer, kind string, namespace string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -121,12 +697,56 @@ func (c *MrrCache) deleteKubeObjects(s KubeServer, kind string) {
 
 	newObjects := []KubeObject{}
 	for i := range os {
-		if os[i].Kind != kind {
+		if !inScope(os[i], kind, namespace) {
 			newObjects = append(newObjects, os[i])
+		} else {
+			c.publishLocked(s, Deleted, os[i])
 		}
 	}
 
 	c.objects[s] = newObjects
+	c.reindexLocked(s)
+}
+
+func (c *MrrCache) deleteServer(s KubeServer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleteServerLocked(s)
+}
+
+//deleteServerLocked removes objects of the server, telling subscribers. Caller must hold the write lock
+func (c *MrrCache) deleteServerLocked(s KubeServer) {
+	for _, o := range c.objects[s] {
+		c.publishLocked(s, Deleted, o)
+	}
+	delete(c.objects, s)
+	delete(c.updated, s)
+	delete(c.clusters, s)
+	delete(c.resources, s)
+	c.reindexLocked(s)
+}
+
+//nameMatcher returns a function that checks names of objects against the patterns of the filter
+func nameMatcher(f *MrrFilter) (func(name string) bool, error) {
+	if _, err := path.Match(f.Names, ""); err != nil {
+		return nil, fmt.Errorf("invalid name pattern %q: %s", f.Names, err)
+	}
+	var re *regexp.Regexp
+	if f.NameRegex != "" {
+		var err error
+		if re, err = regexp.Compile(f.NameRegex); err != nil {
+			return nil, fmt.Errorf("invalid name regex %q: %s", f.NameRegex, err)
+		}
+	}
+
+	return func(name string) bool {
+		if f.Names != "" {
+			if ok, _ := path.Match(f.Names, name); !ok {
+				return false
+			}
+		}
+		return re == nil || re.MatchString(name)
+	}, nil
 }
 
 func trimPort(url string) string {
@@ -140,34 +760,446 @@ func trimPort(url string) string {
 
 type MrrClient interface {
 	Objects(f MrrFilter) ([]KubeObject, error)
+
+	//Names returns names of the objects that Objects returns
+	Names(f MrrFilter) ([]string, error)
+
+	Status(f MrrFilter) (MrrStatus, error)
+	Logs(f MrrFilter) ([]string, error)
+
+	//Flush empties the cache of servers that match the filter and lists their objects again
+	Flush(f MrrFilter) (int, error)
+
+	//Export returns objects of servers that match the filter, Import puts them into the cache
+	Export(f MrrFilter) (CacheSnapshot, error)
+	Import(s CacheSnapshot) (int, error)
+
+	//Resources returns resources discovered on servers that match the filter
+	Resources(f MrrFilter) ([]APIResource, error)
+
+	//Synced returns when objects of the kind were last updated from servers that match the filter
+	Synced(f MrrFilter) (time.Time, error)
 }
 
 type MrrClientDefault struct {
-	conn *rpc.Client
+	conn    *rpc.Client
+	timeout time.Duration
+}
+
+//MrrClientOptions configures connections of clients to mirrors
+type MrrClientOptions struct {
+	//TLS is the configuration of connections to mirrors that serve TLS, nil for plain connections
+	TLS *tls.Config
+
+	//Token is the secret shared with the mirror, empty when the mirror has no secret
+	Token string
+
+	//Timeout bounds connecting to the mirror and waiting for its answers, 0 for the default
+	Timeout time.Duration
+}
+
+func (opts MrrClientOptions) timeout() time.Duration {
+	if opts.Timeout > 0 {
+		return opts.Timeout
+	}
+	return defaultQueryTimeout
 }
 
-func NewMrrClient(address string) (*MrrClientDefault, error) {
-	connection, err := rpc.DialHTTP("tcp", address)
+//MrrServerOptions configures how the mirror serves clients
+type MrrServerOptions struct {
+	//Token is the secret that clients must give, empty to serve everybody
+	Token string
+
+	//Shutdown is closed to stop serving. The mirror stops accepting connections, ends subscriptions
+	//and waits for requests that are being answered
+	Shutdown <-chan struct{}
+	//DrainTimeout limits how long requests are waited for on shutdown, 0 for no limit
+	DrainTimeout time.Duration
+
+	//Features are the feature gates of the mirror
+	Features FeatureGates
+}
+
+func NewMrrClient(address string, opts MrrClientOptions) (*MrrClientDefault, error) {
+	return dialMrrClient(address, opts.timeout(), opts)
+}
+
+//dialMrrClient connects to the mirror, giving up when the connection is not made within the timeout
+func dialMrrClient(address string, timeout time.Duration, opts MrrClientOptions) (*MrrClientDefault, error) {
+	network, addr := mirrorNetwork(address)
+	conn, err := net.DialTimeout(network, addr, timeout)
 	if err != nil {
 		return nil, err
 	}
 
-	return &MrrClientDefault{conn: connection}, nil
+	conn.SetDeadline(time.Now().Add(timeout))
+	if opts.TLS != nil {
+		config := opts.TLS.Clone()
+		if config.ServerName == "" && network == "tcp" {
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("could not connect to %s: %s", address, err)
+		}
+		conn = tlsConn
+	}
+
+	//the same handshake as rpc.DialHTTP does, which has no timeout
+	io.WriteString(conn, "CONNECT "+rpc.DefaultRPCPath+" HTTP/1.0\n")
+	if opts.Token != "" {
+		io.WriteString(conn, "Authorization: Bearer "+opts.Token+"\n")
+	}
+	io.WriteString(conn, "\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		err = errors.New("the mirror rejected the token, check --token or $" + tokenEnv)
+	} else if err == nil && resp.Status != "200 Connected to Go RPC" {
+		err = errors.New("unexpected HTTP response: " + resp.Status)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not connect to %s: %s", address, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return &MrrClientDefault{conn: rpc.NewClient(conn), timeout: timeout}, nil
 }
 
+//Objects asks the mirror for objects. The mirror stops looking for them when the deadline
+//of the filter passes, and so does the client. Without deadline the timeout of the client is used
 func (mc *MrrClientDefault) Objects(f MrrFilter) ([]KubeObject, error) {
 	var os []KubeObject
-	err := mc.conn.Call("MrrCache.Objects", f, &os)
-	return os, err
+	if err := mc.callDeadline("MrrCache.Objects", f, &os); err != nil {
+		return nil, err
+	}
+	return os, nil
+}
+
+//callDeadline calls the method of the mirror, but stops waiting for the answer when the deadline of the filter,
+//or the timeout of the client, passes. A hung mirror must not hang the shell that completes a word
+func (mc *MrrClientDefault) callDeadline(method string, f MrrFilter, reply interface{}) error {
+	if f.Deadline.IsZero() && mc.timeout > 0 {
+		f.Deadline = time.Now().Add(mc.timeout)
+	}
+	if f.RequestID == "" {
+		f.RequestID = newRequestID()
+	}
+	l := log.WithField("id", f.RequestID).WithField("method", method)
+	l.Debug("asking the mirror")
+	started := time.Now()
+
+	call := mc.conn.Go(method, f, reply, make(chan *rpc.Call, 1))
+	if f.Deadline.IsZero() {
+		<-call.Done
+		l.WithField("duration", time.Since(started).String()).Debug("the mirror answered")
+		return call.Error
+	}
+
+	timer := time.NewTimer(time.Until(f.Deadline))
+	defer timer.Stop()
+	select {
+	case <-call.Done:
+		l.WithField("duration", time.Since(started).String()).Debug("the mirror answered")
+		return call.Error
+	case <-timer.C:
+		l.WithField("duration", time.Since(started).String()).Debug("the mirror did not answer in time")
+		return errDeadlineExceeded
+	}
+}
+
+//Names asks the mirror for names of objects in the compact encoding of encodeNames.
+//Mirrors that do not know the encoding are asked for objects
+func (mc *MrrClientDefault) Names(f MrrFilter) ([]string, error) {
+	var encoded []byte
+	err := mc.callDeadline("MrrCache.Names", f, &encoded)
+	if _, ok := err.(rpc.ServerError); ok && strings.Contains(err.Error(), "can't find method") {
+		objects, err := mc.Objects(f)
+		if err != nil {
+			return nil, err
+		}
+		return objectNames(objects), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeNames(encoded)
+}
+
+func (mc *MrrClientDefault) Status(f MrrFilter) (MrrStatus, error) {
+	var s MrrStatus
+	err := mc.conn.Call("MrrCache.Status", f, &s)
+	return s, err
+}
+
+func (mc *MrrClientDefault) Logs(f MrrFilter) ([]string, error) {
+	var lines []string
+	err := mc.conn.Call("MrrCache.Logs", f, &lines)
+	return lines, err
+}
+
+func (mc *MrrClientDefault) Flush(f MrrFilter) (int, error) {
+	var restarted int
+	err := mc.conn.Call("MrrCache.Flush", f, &restarted)
+	return restarted, err
+}
+
+func (mc *MrrClientDefault) Export(f MrrFilter) (CacheSnapshot, error) {
+	var s CacheSnapshot
+	err := mc.conn.Call("MrrCache.Export", f, &s)
+	return s, err
+}
+
+func (mc *MrrClientDefault) Import(s CacheSnapshot) (int, error) {
+	var imported int
+	err := mc.conn.Call("MrrCache.Import", s, &imported)
+	return imported, err
+}
+
+func (mc *MrrClientDefault) Resources(f MrrFilter) ([]APIResource, error) {
+	var rs []APIResource
+	err := mc.callDeadline("MrrCache.Resources", f, &rs)
+	return rs, err
+}
+
+func (mc *MrrClientDefault) Synced(f MrrFilter) (time.Time, error) {
+	var synced time.Time
+	err := mc.callDeadline("MrrCache.Synced", f, &synced)
+	return synced, err
+}
+
+//MrrClientFailover asks mirrors in the given order, for example a local one first and a shared
+//one second. It moves on to the next mirror when one cannot be reached or fails to answer,
+//as long as the timeout of the query allows
+type MrrClientFailover struct {
+	addresses []string
+	timeout   time.Duration
+	dial      func(address string, timeout time.Duration) (MrrClient, error)
+
+	mu      sync.Mutex
+	clients map[string]MrrClient
+}
+
+func NewMrrClientFailover(addresses []string, opts MrrClientOptions) *MrrClientFailover {
+	return &MrrClientFailover{
+		addresses: addresses,
+		timeout:   opts.timeout(),
+		dial: func(address string, timeout time.Duration) (MrrClient, error) {
+			return dialMrrClient(address, timeout, opts)
+		},
+		clients: map[string]MrrClient{},
+	}
+}
+
+//client returns the connected client of the mirror, connecting if there is no one yet
+func (mc *MrrClientFailover) client(address string, timeout time.Duration) (MrrClient, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if c, ok := mc.clients[address]; ok {
+		return c, nil
+	}
+	c, err := mc.dial(address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	mc.clients[address] = c
+	return c, nil
+}
+
+//forget drops the client of the mirror that has failed, so that the next query connects again
+func (mc *MrrClientFailover) forget(address string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	delete(mc.clients, address)
+}
+
+//try calls the query with clients of the mirrors in order until one succeeds. Each mirror but the last
+//gets an equal share of the time left before the deadline, the last one gets all of it
+func (mc *MrrClientFailover) try(deadline time.Time, query func(c MrrClient, deadline time.Time) error) error {
+	var err error
+	for i, address := range mc.addresses {
+		left := time.Until(deadline)
+		if left <= 0 {
+			break
+		}
+		attemptDeadline := time.Now().Add(left / time.Duration(len(mc.addresses)-i))
+
+		c, dialErr := mc.client(address, time.Until(attemptDeadline))
+		if dialErr != nil {
+			err = dialErr
+		} else if err = query(c, attemptDeadline); err == nil {
+			return nil
+		} else {
+			mc.forget(address)
+		}
+		log.WithField("address", address).WithField("error", err).Debug("mirror failed to answer")
+	}
+	if err == nil {
+		err = errDeadlineExceeded
+	}
+	return err
+}
+
+func (mc *MrrClientFailover) deadline(f MrrFilter) time.Time {
+	if f.Deadline.IsZero() {
+		return time.Now().Add(mc.timeout)
+	}
+	return f.Deadline
+}
+
+func (mc *MrrClientFailover) Objects(f MrrFilter) ([]KubeObject, error) {
+	var res []KubeObject
+	err := mc.try(mc.deadline(f), func(c MrrClient, deadline time.Time) error {
+		f.Deadline = deadline
+		var err error
+		res, err = c.Objects(f)
+		return err
+	})
+	return res, err
+}
+
+func (mc *MrrClientFailover) Names(f MrrFilter) ([]string, error) {
+	var res []string
+	err := mc.try(mc.deadline(f), func(c MrrClient, deadline time.Time) error {
+		f.Deadline = deadline
+		var err error
+		res, err = c.Names(f)
+		return err
+	})
+	return res, err
+}
+
+func (mc *MrrClientFailover) Status(f MrrFilter) (MrrStatus, error) {
+	var res MrrStatus
+	err := mc.try(mc.deadline(f), func(c MrrClient, deadline time.Time) error {
+		var err error
+		res, err = c.Status(f)
+		return err
+	})
+	return res, err
+}
+
+func (mc *MrrClientFailover) Logs(f MrrFilter) ([]string, error) {
+	var res []string
+	err := mc.try(mc.deadline(f), func(c MrrClient, deadline time.Time) error {
+		var err error
+		res, err = c.Logs(f)
+		return err
+	})
+	return res, err
+}
+
+func (mc *MrrClientFailover) Flush(f MrrFilter) (int, error) {
+	var res int
+	err := mc.try(mc.deadline(f), func(c MrrClient, deadline time.Time) error {
+		var err error
+		res, err = c.Flush(f)
+		return err
+	})
+	return res, err
+}
+
+func (mc *MrrClientFailover) Export(f MrrFilter) (CacheSnapshot, error) {
+	var res CacheSnapshot
+	err := mc.try(mc.deadline(f), func(c MrrClient, deadline time.Time) error {
+		var err error
+		res, err = c.Export(f)
+		return err
+	})
+	return res, err
+}
+
+func (mc *MrrClientFailover) Import(s CacheSnapshot) (int, error) {
+	var res int
+	err := mc.try(mc.deadline(MrrFilter{}), func(c MrrClient, deadline time.Time) error {
+		var err error
+		res, err = c.Import(s)
+		return err
+	})
+	return res, err
+}
+
+func (mc *MrrClientFailover) Resources(f MrrFilter) ([]APIResource, error) {
+	var res []APIResource
+	err := mc.try(mc.deadline(f), func(c MrrClient, deadline time.Time) error {
+		f.Deadline = deadline
+		var err error
+		res, err = c.Resources(f)
+		return err
+	})
+	return res, err
+}
+
+func (mc *MrrClientFailover) Synced(f MrrFilter) (time.Time, error) {
+	var res time.Time
+	err := mc.try(mc.deadline(f), func(c MrrClient, deadline time.Time) error {
+		f.Deadline = deadline
+		var err error
+		res, err = c.Synced(f)
+		return err
+	})
+	return res, err
 }
 
 type TestMirrorClient struct {
 	err        error
 	lastFilter MrrFilter
 	objects    []KubeObject
+	status     MrrStatus
+	logs       []string
+	flushed    int
+	snapshot   CacheSnapshot
+	imported   *CacheSnapshot
+	resources  []APIResource
+	synced     time.Time
 }
 
 func (mc *TestMirrorClient) Objects(f MrrFilter) ([]KubeObject, error) {
 	mc.lastFilter = f
 	return mc.objects, mc.err
 }
+
+func (mc *TestMirrorClient) Names(f MrrFilter) ([]string, error) {
+	mc.lastFilter = f
+	return objectNames(mc.objects), mc.err
+}
+
+func (mc *TestMirrorClient) Status(f MrrFilter) (MrrStatus, error) {
+	mc.lastFilter = f
+	return mc.status, mc.err
+}
+
+func (mc *TestMirrorClient) Logs(f MrrFilter) ([]string, error) {
+	mc.lastFilter = f
+	return mc.logs, mc.err
+}
+
+func (mc *TestMirrorClient) Flush(f MrrFilter) (int, error) {
+	mc.lastFilter = f
+	return mc.flushed, mc.err
+}
+
+func (mc *TestMirrorClient) Export(f MrrFilter) (CacheSnapshot, error) {
+	mc.lastFilter = f
+	return mc.snapshot, mc.err
+}
+
+func (mc *TestMirrorClient) Resources(f MrrFilter) ([]APIResource, error) {
+	mc.lastFilter = f
+	return mc.resources, mc.err
+}
+
+func (mc *TestMirrorClient) Synced(f MrrFilter) (time.Time, error) {
+	mc.lastFilter = f
+	return mc.synced, mc.err
+}
+
+func (mc *TestMirrorClient) Import(s CacheSnapshot) (int, error) {
+	mc.imported = &s
+	n := 0
+	for _, ss := range s.Servers {
+		n += len(ss.Objects)
+	}
+	return n, mc.err
+}
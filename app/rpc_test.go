@@ -1,19 +1,36 @@
 package app
 
 import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"math/big"
 	"net"
 	"net/http"
 	"net/rpc"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 var (
-	cache     *MrrCache
-	mrrClient MrrClient
-	once      sync.Once
+	cache      *MrrCache
+	mrrClient  MrrClient
+	mrrAddress string
+	once       sync.Once
 )
 
 func setupRPC() {
@@ -25,10 +42,11 @@ func setupRPC() {
 	cache = f.MrrCache()
 	fillCache(cache)
 	rpc.Register(cache)
-	rpc.HandleHTTP()
+	http.Handle(rpc.DefaultRPCPath, rpcHandler(rpc.DefaultServer))
 	go http.Serve(l, nil)
 
-	mrrClient, err = f.MrrClient(l.Addr().String())
+	mrrAddress = l.Addr().String()
+	mrrClient, err = f.MrrClient(mrrAddress, MrrClientOptions{})
 	if err != nil {
 		log.Fatalf("Failed to create client: %v", err)
 	}
@@ -36,7 +54,7 @@ func setupRPC() {
 
 func fillCache(c *MrrCache) {
 	for _, s := range []string{"server1", "server2", "server3"} {
-		ks := KubeServer{s}
+		ks := KubeServer{URL: s}
 		for _, ns := range []string{"ns1", "ns2", "ns3"} {
 			for _, kind := range []string{"pod", "service", "deployment"} {
 				for _, name := range []string{"a", "b", "c"} {
@@ -44,7 +62,7 @@ func fillCache(c *MrrCache) {
 						c.objects[ks] = make([]KubeObject, 0)
 					}
 
-					o := KubeObject{TypeMeta{kind}, ObjectMeta{Name: s + "-" + name, Namespace: ns}}
+					o := KubeObject{TypeMeta: TypeMeta{kind}, ObjectMeta: ObjectMeta{Name: s + "-" + name, Namespace: ns}}
 					c.objects[ks] = append(c.objects[ks], o)
 				}
 			}
@@ -52,9 +70,9 @@ func fillCache(c *MrrCache) {
 	}
 
 	for _, s := range []string{"server1", "server2"} {
-		ks := KubeServer{s}
+		ks := KubeServer{URL: s}
 		for _, name := range []string{"ns1", "ns2"} {
-			o := KubeObject{TypeMeta{"namespace"}, ObjectMeta{Name: s + "-" + name}}
+			o := KubeObject{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: s + "-" + name}}
 			c.objects[ks] = append(c.objects[ks], o)
 		}
 	}
@@ -72,97 +90,109 @@ func TestClientObjects(t *testing.T) {
 			filter: MrrFilter{},
 		},
 		{
-			filter:  MrrFilter{"server_other", "ns1", "pod"},
+			filter:  MrrFilter{Server: "server_other", Namespace: "ns1", Kind: "pod"},
 			isError: true,
 		},
 		{
-			filter: MrrFilter{"server1", "ns_other", "pod"},
+			filter: MrrFilter{Server: "server1", Namespace: "ns_other", Kind: "pod"},
 		},
 		{
-			filter: MrrFilter{"server1", "ns1", "pod_other"},
+			filter: MrrFilter{Server: "server1", Namespace: "ns1", Kind: "pod_other"},
 		},
 		{
-			filter: MrrFilter{"SERVER1", "ns1", "pod"},
+			filter: MrrFilter{Server: "SERVER1", Namespace: "ns1", Kind: "pod"},
 			expected: []KubeObject{
-				{TypeMeta{"pod"}, ObjectMeta{"server1-a", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-b", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-c", "ns1", ""}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-a", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-b", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-c", Namespace: "ns1"}},
 			},
 		},
 		{
-			filter: MrrFilter{"server2:8443", "NS1", "pod"},
+			filter: MrrFilter{Server: "server2:8443", Namespace: "NS1", Kind: "pod"},
 			expected: []KubeObject{
-				{TypeMeta{"pod"}, ObjectMeta{"server2-a", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server2-b", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server2-c", "ns1", ""}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server2-a", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server2-b", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server2-c", Namespace: "ns1"}},
 			},
 		},
 		{
-			filter: MrrFilter{"server1", "ns2", "POD"},
+			filter: MrrFilter{Server: "server1", Namespace: "ns2", Kind: "POD"},
 			expected: []KubeObject{
-				{TypeMeta{"pod"}, ObjectMeta{"server1-a", "ns2", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-b", "ns2", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-c", "ns2", ""}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-a", Namespace: "ns2"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-b", Namespace: "ns2"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-c", Namespace: "ns2"}},
 			},
 		},
 		{
-			filter: MrrFilter{"server1", "ns1", "service"},
+			filter: MrrFilter{Server: "server1", Namespace: "ns1", Kind: "service"},
 			expected: []KubeObject{
-				{TypeMeta{"service"}, ObjectMeta{"server1-a", "ns1", ""}},
-				{TypeMeta{"service"}, ObjectMeta{"server1-b", "ns1", ""}},
-				{TypeMeta{"service"}, ObjectMeta{"server1-c", "ns1", ""}},
+				{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "server1-a", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "server1-b", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "server1-c", Namespace: "ns1"}},
 			},
 		},
 		{
-			filter: MrrFilter{"server1", "ns1", "deployment"},
+			filter: MrrFilter{Server: "server1", Namespace: "ns1", Kind: "deployment"},
 			expected: []KubeObject{
-				{TypeMeta{"deployment"}, ObjectMeta{"server1-a", "ns1", ""}},
-				{TypeMeta{"deployment"}, ObjectMeta{"server1-b", "ns1", ""}},
-				{TypeMeta{"deployment"}, ObjectMeta{"server1-c", "ns1", ""}},
+				{TypeMeta: TypeMeta{"deployment"}, ObjectMeta: ObjectMeta{Name: "server1-a", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"deployment"}, ObjectMeta: ObjectMeta{Name: "server1-b", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"deployment"}, ObjectMeta: ObjectMeta{Name: "server1-c", Namespace: "ns1"}},
 			},
 		},
 		{
-			filter: MrrFilter{"", "ns1", "pod"},
+			filter: MrrFilter{Server: "", Namespace: "ns1", Kind: "pod"},
 			expected: []KubeObject{
-				{TypeMeta{"pod"}, ObjectMeta{"server1-a", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-b", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-c", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server2-a", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server2-b", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server2-c", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server3-a", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server3-b", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server3-c", "ns1", ""}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-a", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-b", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-c", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server2-a", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server2-b", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server2-c", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server3-a", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server3-b", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server3-c", Namespace: "ns1"}},
 			},
 		},
 		{
-			filter: MrrFilter{"server1", "", "pod"},
+			filter: MrrFilter{Server: "server1", Namespace: "", Kind: "pod"},
 			expected: []KubeObject{
-				{TypeMeta{"pod"}, ObjectMeta{"server1-a", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-b", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-c", "ns1", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-a", "ns2", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-b", "ns2", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-c", "ns2", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-a", "ns3", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-b", "ns3", ""}},
-				{TypeMeta{"pod"}, ObjectMeta{"server1-c", "ns3", ""}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-a", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-b", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-c", Namespace: "ns1"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-a", Namespace: "ns2"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-b", Namespace: "ns2"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-c", Namespace: "ns2"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-a", Namespace: "ns3"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-b", Namespace: "ns3"}},
+				{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "server1-c", Namespace: "ns3"}},
 			},
 		},
 		{
-			filter: MrrFilter{"server1", "should be ignored", "namespace"},
+			filter: MrrFilter{Server: "server1", Namespace: "should be ignored", Kind: "namespace"},
 			expected: []KubeObject{
-				{TypeMeta{"namespace"}, ObjectMeta{"server1-ns1", "", ""}},
-				{TypeMeta{"namespace"}, ObjectMeta{"server1-ns2", "", ""}},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "server1-ns1"}},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "server1-ns2"}},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns1"}, Server: "server1"},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns2"}, Server: "server1"},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns3"}, Server: "server1"},
 			},
 		},
 		{
-			filter: MrrFilter{"", "should be ignored", "namespace"},
+			filter: MrrFilter{Server: "", Namespace: "should be ignored", Kind: "namespace"},
 			expected: []KubeObject{
-				{TypeMeta{"namespace"}, ObjectMeta{"server1-ns1", "", ""}},
-				{TypeMeta{"namespace"}, ObjectMeta{"server1-ns2", "", ""}},
-				{TypeMeta{"namespace"}, ObjectMeta{"server2-ns1", "", ""}},
-				{TypeMeta{"namespace"}, ObjectMeta{"server2-ns2", "", ""}},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "server1-ns1"}},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "server1-ns2"}},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns1"}, Server: "server1"},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns2"}, Server: "server1"},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns3"}, Server: "server1"},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "server2-ns1"}},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "server2-ns2"}},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns1"}, Server: "server2"},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns2"}, Server: "server2"},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns3"}, Server: "server2"},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns1"}, Server: "server3"},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns2"}, Server: "server3"},
+				{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "ns3"}, Server: "server3"},
 			},
 		},
 	}
@@ -173,6 +203,13 @@ func TestClientObjects(t *testing.T) {
 			t.Errorf("Unexpected error %v", err)
 		}
 
+		//names of objects start with their servers, unless the server is given
+		for j := range test.expected {
+			if test.expected[j].Server == "" {
+				test.expected[j].Server = strings.SplitN(test.expected[j].Name, "-", 2)[0]
+			}
+		}
+
 		if !reflect.DeepEqual(actual, test.expected) {
 			t.Errorf("Test %d: \n Expected \n %+v\n Found %+v", i, test.expected, actual)
 		}
@@ -181,21 +218,47 @@ func TestClientObjects(t *testing.T) {
 
 func TestDeleteKubeObjects(t *testing.T) {
 	c := NewMrrCache()
-	s := KubeServer{"s"}
+	s := KubeServer{URL: "s"}
 	o1 := KubeObject{TypeMeta: TypeMeta{"x"}, ObjectMeta: ObjectMeta{Name: "x1"}}
 	o2 := KubeObject{TypeMeta: TypeMeta{"y"}, ObjectMeta: ObjectMeta{Name: "y1"}}
 	c.updateKubeObject(s, o1)
 	c.updateKubeObject(s, o2)
 
-	c.deleteKubeObjects(s, "y")
+	c.deleteKubeObjects(s, "y", "")
 	if !reflect.DeepEqual(c.objects[s], []KubeObject{o1}) {
 		t.Errorf("Cache should contain only %+v, but it contains %+v", o1, c.objects[s])
 	}
 }
 
+func TestDeleteKubeObject(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	x := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "x"}}
+	y := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "y"}}
+	z := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "z"}}
+	for _, o := range []KubeObject{x, y, z} {
+		c.updateKubeObject(s, o)
+	}
+
+	c.deleteKubeObject(s, x)
+	if !reflect.DeepEqual(c.objects[s], []KubeObject{z, y}) {
+		t.Errorf("Expected the last object to take the place of the deleted one, got %+v", c.objects[s])
+	}
+	if positions := map[objectKey]int{keyOf(z): 0, keyOf(y): 1}; !reflect.DeepEqual(c.positions[s], positions) {
+		t.Errorf("Expected positions %v, got %v", positions, c.positions[s])
+	}
+
+	z.ResourceVersion = "2"
+	c.updateKubeObject(s, z)
+	c.deleteKubeObject(s, y)
+	if !reflect.DeepEqual(c.objects[s], []KubeObject{z}) {
+		t.Errorf("Cache should contain only %+v, but it contains %+v", z, c.objects[s])
+	}
+}
+
 func TestUpdateKubeObject(t *testing.T) {
 	c := NewMrrCache()
-	s := KubeServer{"s"}
+	s := KubeServer{URL: "s"}
 
 	expected := []KubeObject{
 		{TypeMeta: TypeMeta{"x"}, ObjectMeta: ObjectMeta{Name: "x1"}},
@@ -211,3 +274,829 @@ func TestUpdateKubeObject(t *testing.T) {
 		t.Errorf("Cache should all %d obejcts, but it contains %+v", len(expected), c.objects[s])
 	}
 }
+
+func TestObjectsIndex(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	a := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "prod"}}
+	b := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "b", Namespace: "dev"}}
+	svc := KubeObject{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "prod"}}
+	c.updateKubeObject(s, a)
+	c.updateKubeObject(s, svc)
+
+	names := func(f MrrFilter) []string {
+		var os []KubeObject
+		if err := c.Objects(&f, &os); err != nil {
+			t.Fatal(err)
+		}
+		res := []string{}
+		for _, o := range os {
+			res = append(res, o.Namespace+"/"+o.Name)
+		}
+		return res
+	}
+
+	tests := []struct {
+		change   func()
+		filter   MrrFilter
+		expected []string
+	}{
+		{func() {}, MrrFilter{Kind: "pod"}, []string{"prod/a"}},
+		{func() { c.updateKubeObject(s, b) }, MrrFilter{Kind: "pod"}, []string{"prod/a", "dev/b"}},
+		{func() {}, MrrFilter{Kind: "Pod", Namespace: "DEV"}, []string{"dev/b"}},
+		{func() { c.deleteKubeObject(s, a) }, MrrFilter{Kind: "pod"}, []string{"dev/b"}},
+		{func() {}, MrrFilter{Kind: "pod", Namespace: "prod"}, []string{}},
+		{func() { c.replaceKubeObjects(s, "pod", "", []KubeObject{a}) }, MrrFilter{Kind: "pod"}, []string{"prod/a"}},
+		{func() {}, MrrFilter{Kind: "service", Namespace: "prod"}, []string{"prod/a"}},
+	}
+
+	for i, test := range tests {
+		test.change()
+		actual := names(test.filter)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, test.expected, actual)
+		}
+	}
+}
+
+func TestObjectsCachedResults(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "prod"}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "prod"}})
+
+	pods := MrrFilter{Kind: "pod"}
+	namespaces := MrrFilter{Kind: "namespace"}
+	first, _ := c.matching(&pods)
+	c.matching(&namespaces)
+	if again, _ := c.matching(&MrrFilter{Kind: "Pod", Deadline: time.Now().Add(time.Minute)}); again != first {
+		t.Errorf("Expected cached result for the same filter")
+	}
+
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "b", Namespace: "dev"}})
+	if again, _ := c.matching(&pods); again != first {
+		t.Errorf("Expected cached result after change of another kind")
+	}
+	if _, ok := c.results.results[resultKeyOf(&namespaces)]; ok {
+		t.Errorf("Expected namespaces to be dropped after change of any kind")
+	}
+
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "b", Namespace: "dev"}})
+	res, _ := c.matching(&pods)
+	if res == first || len(res.objects) != 2 {
+		t.Errorf("Expected result with the new pod, got %+v", res.objects)
+	}
+
+	c.deleteServer(s)
+	if _, err := c.matching(&pods); err == nil {
+		t.Errorf("Expected error after the server was deleted")
+	}
+}
+
+func TestResultCacheLimit(t *testing.T) {
+	rc := newResultCache()
+	for i := 0; i <= maxCachedResults; i++ {
+		rc.put(resultKey{kind: fmt.Sprintf("kind%d", i)}, &result{})
+		if i == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if len(rc.results) != maxCachedResults {
+		t.Errorf("Expected %d results, got %d", maxCachedResults, len(rc.results))
+	}
+	if rc.get(resultKey{kind: "kind0"}) != nil {
+		t.Errorf("Expected the least recently used result to be dropped")
+	}
+}
+
+func TestObjectsSelector(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "web", Labels: map[string]string{"app": "checkout", "tier": "web"}}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "db", Labels: map[string]string{"app": "checkout", "tier": "db"}}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "other"}})
+
+	var os []KubeObject
+	err := c.Objects(&MrrFilter{Kind: "pod", Selector: "app=checkout,tier!=db"}, &os)
+	if err != nil || len(os) != 1 || os[0].Name != "web" {
+		t.Errorf("Expected only the web pod, got %v and error %v", os, err)
+	}
+
+	err = c.Objects(&MrrFilter{Kind: "pod", Selector: "app="}, &os)
+	if err != nil || len(os) != 0 {
+		t.Errorf("Expected no pods, got %v and error %v", os, err)
+	}
+
+	err = c.Objects(&MrrFilter{Kind: "pod", Selector: "app in checkout"}, &os)
+	if err == nil {
+		t.Errorf("Expected error for invalid selector")
+	}
+}
+
+func TestObjectsObservedNamespaces(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "prod"}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "b", Namespace: "dev"}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "c", Namespace: "dev"}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"node"}, ObjectMeta: ObjectMeta{Name: "n"}})
+
+	names := func() []string {
+		var os []KubeObject
+		if err := c.Objects(&MrrFilter{Kind: "namespace", Namespace: "prod"}, &os); err != nil {
+			t.Fatal(err)
+		}
+		res := []string{}
+		for _, o := range os {
+			res = append(res, o.Name)
+		}
+		return res
+	}
+
+	if actual := names(); !reflect.DeepEqual(actual, []string{"dev", "prod"}) {
+		t.Errorf("Expected namespaces seen in objects, got %v", actual)
+	}
+
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "prod"}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"namespace"}, ObjectMeta: ObjectMeta{Name: "empty"}})
+	if actual := names(); !reflect.DeepEqual(actual, []string{"prod", "empty", "dev"}) {
+		t.Errorf("Expected mirrored namespaces followed by seen ones, got %v", actual)
+	}
+}
+
+func TestObjectsNames(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	for _, name := range []string{"web-1", "web-2", "api-1", "worker"} {
+		c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: name}})
+	}
+
+	tests := []struct {
+		filter   MrrFilter
+		expected []string
+	}{
+		{filter: MrrFilter{Kind: "pod"}, expected: []string{"web-1", "web-2", "api-1", "worker"}},
+		{filter: MrrFilter{Kind: "pod", Names: "web-*"}, expected: []string{"web-1", "web-2"}},
+		{filter: MrrFilter{Kind: "pod", Names: "w*"}, expected: []string{"web-1", "web-2", "worker"}},
+		{filter: MrrFilter{Kind: "pod", NameRegex: "-1$"}, expected: []string{"web-1", "api-1"}},
+		{filter: MrrFilter{Kind: "pod", Names: "w*", NameRegex: "-2$"}, expected: []string{"web-2"}},
+		{filter: MrrFilter{Kind: "pod", Names: "db-*"}, expected: []string{}},
+	}
+
+	for i, test := range tests {
+		var os []KubeObject
+		if err := c.Objects(&test.filter, &os); err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		names := []string{}
+		for _, o := range os {
+			names = append(names, o.Name)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, test.expected, names)
+		}
+	}
+
+	var os []KubeObject
+	if err := c.Objects(&MrrFilter{Kind: "pod", Names: "["}, &os); err == nil {
+		t.Errorf("Expected error for invalid pattern")
+	}
+	if err := c.Objects(&MrrFilter{Kind: "pod", NameRegex: "("}, &os); err == nil {
+		t.Errorf("Expected error for invalid regex")
+	}
+}
+
+func TestObjectsPhase(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "web"}, Status: ObjectStatus{Phase: "Running"}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "job"}, Status: ObjectStatus{Phase: "Succeeded"}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "evicted"}, Status: ObjectStatus{Phase: "Failed"}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "web"}})
+
+	var os []KubeObject
+	if err := c.Objects(&MrrFilter{Kind: "pod", Phase: "running"}, &os); err != nil || len(os) != 1 || os[0].Name != "web" {
+		t.Errorf("Expected only the running pod, got %v, %v", os, err)
+	}
+	if err := c.Objects(&MrrFilter{Kind: "pod"}, &os); err != nil || len(os) != 3 {
+		t.Errorf("Expected pods of all phases, got %v, %v", os, err)
+	}
+	if err := c.Objects(&MrrFilter{Kind: "service", Phase: "Running"}, &os); err != nil || len(os) != 1 {
+		t.Errorf("Expected objects without phase not to be filtered, got %v, %v", os, err)
+	}
+}
+
+func TestObjectsReadyOnly(t *testing.T) {
+	ready := []Condition{{Type: "MemoryPressure", Status: "False"}, {Type: "Ready", Status: "True"}}
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"node"}, ObjectMeta: ObjectMeta{Name: "n1"}, Status: ObjectStatus{Conditions: ready}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"node"}, ObjectMeta: ObjectMeta{Name: "n2"}, Status: ObjectStatus{Conditions: []Condition{{Type: "Ready", Status: "Unknown"}}}})
+	c.updateKubeObject(s, KubeObject{TypeMeta: TypeMeta{"node"}, ObjectMeta: ObjectMeta{Name: "n3"}, Spec: ObjectSpec{Unschedulable: true}, Status: ObjectStatus{Conditions: ready}})
+
+	var os []KubeObject
+	if err := c.Objects(&MrrFilter{Kind: "node", ReadyOnly: true}, &os); err != nil || len(os) != 1 || os[0].Name != "n1" {
+		t.Errorf("Expected only the ready node, got %v, %v", os, err)
+	}
+	if err := c.Objects(&MrrFilter{Kind: "node"}, &os); err != nil || len(os) != 3 {
+		t.Errorf("Expected all nodes, got %v, %v", os, err)
+	}
+}
+
+func TestObjectsDeadline(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	for i := 0; i < 10; i++ {
+		c.objects[s] = append(c.objects[s], KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: fmt.Sprintf("pod%d", i)}})
+	}
+
+	var os []KubeObject
+	err := c.Objects(&MrrFilter{Kind: "pod", Deadline: time.Now().Add(-time.Second)}, &os)
+	if err != errDeadlineExceeded {
+		t.Errorf("Expected deadline error, got %v", err)
+	}
+
+	err = c.Objects(&MrrFilter{Kind: "pod", Deadline: time.Now().Add(time.Minute)}, &os)
+	if err != nil || len(os) != 10 {
+		t.Errorf("Expected all objects before deadline, got %d objects and error %v", len(os), err)
+	}
+}
+
+func TestClientExport(t *testing.T) {
+	once.Do(setupRPC)
+
+	snapshot, err := mrrClient.Export(MrrFilter{Server: "server2"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(snapshot.Servers) != 1 || snapshot.Servers[0].Server.URL != "server2" || len(snapshot.Servers[0].Objects) == 0 {
+		t.Errorf("Expected objects of server2, got %+v", snapshot)
+	}
+
+	_, err = mrrClient.Import(snapshot)
+	if err == nil || !strings.Contains(err.Error(), "admin API is disabled") {
+		t.Errorf("Expected disabled admin API, got %v", err)
+	}
+}
+
+func TestClientObjectsDeadline(t *testing.T) {
+	once.Do(setupRPC)
+
+	_, err := mrrClient.Objects(MrrFilter{Kind: "pod", Deadline: time.Now().Add(-time.Second)})
+	if err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Errorf("Expected deadline error, got %v", err)
+	}
+
+	_, err = mrrClient.Objects(MrrFilter{Kind: "pod", Deadline: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestClientNames(t *testing.T) {
+	once.Do(setupRPC)
+
+	names, err := mrrClient.Names(MrrFilter{Server: "server1", Namespace: "ns1", Kind: "pod"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"server1-a", "server1-b", "server1-c"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected names %v, got %v", expected, names)
+	}
+}
+
+//oldMrrCache is a mirror that does not know the compact encoding of names
+type oldMrrCache struct {
+	cache *MrrCache
+}
+
+func (c *oldMrrCache) Objects(f *MrrFilter, os *[]KubeObject) error {
+	return c.cache.Objects(f, os)
+}
+
+func TestClientNamesOldMirror(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	c := NewMrrCache()
+	fillCache(c)
+	server := rpc.NewServer()
+	server.RegisterName("MrrCache", &oldMrrCache{c})
+	mux := http.NewServeMux()
+	mux.Handle(rpc.DefaultRPCPath, server)
+	go http.Serve(l, mux)
+
+	client, err := NewMrrClient(l.Addr().String(), MrrClientOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	names, err := client.Names(MrrFilter{Server: "server2", Namespace: "ns3", Kind: "service"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"server2-a", "server2-b", "server2-c"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected names %v, got %v", expected, names)
+	}
+}
+
+func TestClientHungMirror(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		http.ReadRequest(bufio.NewReader(conn))
+		io.WriteString(conn, "HTTP/1.0 200 Connected to Go RPC\n\n")
+		time.Sleep(time.Minute)
+	}()
+
+	c, err := NewMrrClient(l.Addr().String(), MrrClientOptions{Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	start := time.Now()
+	if _, err := c.Resources(MrrFilter{}); err != errDeadlineExceeded {
+		t.Errorf("Expected deadline error, got %v", err)
+	}
+	if _, err := c.Synced(MrrFilter{Kind: "pod"}); err != errDeadlineExceeded {
+		t.Errorf("Expected deadline error, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Expected client to give up after its timeout, waited %s", d)
+	}
+}
+
+func TestReplaceKubeObjects(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	other := KubeObject{TypeMeta: TypeMeta{"y"}, ObjectMeta: ObjectMeta{Name: "y1"}}
+	c.updateKubeObject(s, other)
+	c.replaceKubeObjects(s, "x", "", []KubeObject{
+		{TypeMeta: TypeMeta{"x"}, ObjectMeta: ObjectMeta{Name: "x1", ResourceVersion: "1"}},
+		{TypeMeta: TypeMeta{"x"}, ObjectMeta: ObjectMeta{Name: "x2", ResourceVersion: "1"}},
+	})
+
+	objects := []KubeObject{
+		{TypeMeta: TypeMeta{"x"}, ObjectMeta: ObjectMeta{Name: "x2", ResourceVersion: "2"}},
+		{TypeMeta: TypeMeta{"x"}, ObjectMeta: ObjectMeta{Name: "x3", ResourceVersion: "1"}},
+	}
+	c.events = nil
+	c.replaceKubeObjects(s, "x", "", objects)
+
+	if !reflect.DeepEqual(c.objects[s], append([]KubeObject{other}, objects...)) {
+		t.Errorf("Cache has unexpected objects %+v", c.objects[s])
+	}
+
+	types := map[string]EventType{}
+	for _, e := range c.events {
+		types[e.Name] = e.Type
+	}
+	expected := map[string]EventType{"x1": Deleted, "x2": Modified, "x3": Added}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected changes %v, recorded %v", expected, types)
+	}
+}
+
+func TestReplaceKubeObjectsNamespace(t *testing.T) {
+	c := NewMrrCache()
+	s := KubeServer{URL: "s"}
+	red := KubeObject{TypeMeta: TypeMeta{"x"}, ObjectMeta: ObjectMeta{Name: "x1", Namespace: "red"}}
+	blue := KubeObject{TypeMeta: TypeMeta{"x"}, ObjectMeta: ObjectMeta{Name: "x1", Namespace: "blue"}}
+	c.replaceKubeObjects(s, "x", "red", []KubeObject{red})
+	c.replaceKubeObjects(s, "x", "blue", []KubeObject{blue})
+	if !reflect.DeepEqual(c.objects[s], []KubeObject{red, blue}) {
+		t.Errorf("Cache has unexpected objects %+v", c.objects[s])
+	}
+
+	c.replaceKubeObjects(s, "x", "red", []KubeObject{})
+	if !reflect.DeepEqual(c.objects[s], []KubeObject{blue}) {
+		t.Errorf("Cache must keep objects of other namespaces, but has %+v", c.objects[s])
+	}
+
+	c.deleteKubeObjects(s, "x", "blue")
+	if len(c.objects[s]) != 0 {
+		t.Errorf("Cache must be empty, but has %+v", c.objects[s])
+	}
+}
+
+func TestStatus(t *testing.T) {
+	c := NewMrrCache()
+	c.features = FeatureGates{NameCompression: false}
+	c.updateKubeObject(KubeServer{URL: "https://s1:443"}, KubeObject{TypeMeta: TypeMeta{"Pod"}, ObjectMeta: ObjectMeta{Name: "a"}})
+	c.updateKubeObject(KubeServer{URL: "https://s1:443"}, KubeObject{TypeMeta: TypeMeta{"Pod"}, ObjectMeta: ObjectMeta{Name: "b"}})
+	c.replaceKubeObjects(KubeServer{URL: "https://s1:443"}, "node", "", []KubeObject{})
+	c.updateKubeObject(KubeServer{URL: "https://s2"}, KubeObject{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "c"}})
+
+	var s MrrStatus
+	err := c.Status(&MrrFilter{Server: "https://s1"}, &s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(s.Servers) != 1 || s.Servers[0].Server != "https://s1:443" {
+		t.Fatalf("Expected status of one server, got %+v", s.Servers)
+	}
+	kinds := s.Servers[0].Kinds
+	if len(kinds) != 2 || kinds[0].Kind != "node" || kinds[0].Objects != 0 || kinds[1].Kind != "pod" || kinds[1].Objects != 2 {
+		t.Errorf("Unexpected status of kinds %+v", kinds)
+	}
+	if kinds[1].Updated.IsZero() {
+		t.Errorf("Update time of pods is not set")
+	}
+	if !reflect.DeepEqual(kinds[1].Namespaces, map[string]int{"": 2}) || kinds[1].Bytes == 0 || kinds[0].Bytes != 0 {
+		t.Errorf("Unexpected namespaces and sizes of kinds %+v", kinds)
+	}
+
+	if len(s.Events) != 2 || s.Events[0].Name != "b" || s.Events[1].Name != "a" {
+		t.Errorf("Expected latest events first, got %+v", s.Events)
+	}
+	if kinds[0].Hash == "" || kinds[0].Hash == kinds[1].Hash || s.Servers[0].Hash == "" {
+		t.Errorf("Expected different hashes of kinds, got %+v", kinds)
+	}
+	if expected := map[string]bool{HTTPObjects: true, NameCompression: false, SnapshotPersistence: false, Subscriptions: false, AdminAPI: false}; !reflect.DeepEqual(s.Features, expected) {
+		t.Errorf("Expected feature gates %v, got %v", expected, s.Features)
+	}
+}
+
+func TestSynced(t *testing.T) {
+	c := NewMrrCache()
+	s1, s2 := KubeServer{URL: "https://s1"}, KubeServer{URL: "https://s2"}
+	c.replaceKubeObjects(s1, "pod", "", []KubeObject{})
+	c.replaceKubeObjects(s2, "service", "", []KubeObject{})
+
+	var synced time.Time
+	if err := c.Synced(&MrrFilter{Server: "https://s1", Kind: "Pod"}, &synced); err != nil || synced.IsZero() {
+		t.Errorf("Expected sync time of pods, got %v, %v", synced, err)
+	}
+	if err := c.Synced(&MrrFilter{Kind: "pod"}, &synced); err != nil || !synced.IsZero() {
+		t.Errorf("Expected zero time when a server never updated pods, got %v, %v", synced, err)
+	}
+	if err := c.Synced(&MrrFilter{Server: "https://s3", Kind: "pod"}, &synced); err == nil {
+		t.Errorf("Expected error for unknown server")
+	}
+}
+
+func TestObjectsProfile(t *testing.T) {
+	c := NewMrrCache()
+	alice := KubeServer{URL: "https://foo.com", Profile: "alice"}
+	bob := KubeServer{URL: "https://foo.com", Profile: "bob"}
+	c.updateKubeObject(alice, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a"}})
+	c.updateKubeObject(bob, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "b"}})
+
+	tests := []struct {
+		filter   MrrFilter
+		expected []string
+	}{
+		{MrrFilter{Kind: "pod", Profile: "alice"}, []string{"a"}},
+		{MrrFilter{Kind: "pod", Server: "https://foo.com", Profile: "bob"}, []string{"b"}},
+		{MrrFilter{Kind: "pod"}, []string{"a", "b"}},
+	}
+	for _, test := range tests {
+		objects := []KubeObject{}
+		if err := c.Objects(&test.filter, &objects); err != nil {
+			t.Errorf("Unexpected error for filter %+v: %v", test.filter, err)
+		}
+		names := []string{}
+		for _, o := range objects {
+			names = append(names, o.Name)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("Filter %+v: expected %v, got %v", test.filter, test.expected, names)
+		}
+	}
+
+	if err := c.Objects(&MrrFilter{Kind: "pod", Profile: "carol"}, &[]KubeObject{}); err == nil {
+		t.Errorf("Expected error for servers of other profiles")
+	}
+
+	status := c.status(&MrrFilter{Profile: "bob"})
+	if len(status.Servers) != 1 || status.Servers[0].Profile != "bob" {
+		t.Errorf("Expected only the server of bob, got %+v", status.Servers)
+	}
+	if len(status.Events) != 1 {
+		t.Errorf("Expected only events of bob, got %+v", status.Events)
+	}
+}
+
+func TestStatusHash(t *testing.T) {
+	a := KubeObject{TypeMeta: TypeMeta{"Pod"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "ns", ResourceVersion: "1"}}
+	b := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "b", Namespace: "ns", ResourceVersion: "1", Labels: map[string]string{"x": "1", "y": "2"}}}
+	s1, s2 := KubeServer{URL: "https://s1"}, KubeServer{URL: "https://s2"}
+
+	c := NewMrrCache()
+	c.updateKubeObject(s1, a)
+	c.updateKubeObject(s1, b)
+	c.updateKubeObject(s2, b)
+	c.updateKubeObject(s2, a)
+
+	var s MrrStatus
+	if err := c.Status(&MrrFilter{}, &s); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s.Servers[0].Kinds[0].Hash != s.Servers[1].Kinds[0].Hash || s.Servers[0].Hash != s.Servers[1].Hash {
+		t.Errorf("Hash must not depend on order of objects, got %+v", s.Servers)
+	}
+
+	b.ResourceVersion = "2"
+	c.updateKubeObject(s2, b)
+	if err := c.Status(&MrrFilter{}, &s); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s.Servers[0].Kinds[0].Hash == s.Servers[1].Kinds[0].Hash || s.Servers[0].Hash == s.Servers[1].Hash {
+		t.Errorf("Hash must change with version of an object, got %+v", s.Servers)
+	}
+}
+
+func TestClientFailover(t *testing.T) {
+	once.Do(setupRPC)
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := l.Addr().String()
+	l.Close()
+
+	f := &DefaultFactory{}
+	c, err := f.MrrClient(down+","+mrrAddress, MrrClientOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	res, err := c.Objects(MrrFilter{Server: "server1", Namespace: "ns1", Kind: "pod"})
+	if err != nil {
+		t.Errorf("Expected fallback mirror to answer, got %v", err)
+	}
+	if len(res) != 3 {
+		t.Errorf("Expected 3 objects from fallback mirror, got %v", res)
+	}
+}
+
+func TestClientFailoverOrder(t *testing.T) {
+	primary := &TestMirrorClient{err: fmt.Errorf("connection is shut down")}
+	fallback := &TestMirrorClient{objects: []KubeObject{{ObjectMeta: ObjectMeta{Name: "a"}}}}
+	dials := []string{}
+	deadlines := map[string]time.Duration{}
+
+	c := NewMrrClientFailover([]string{"primary", "fallback"}, MrrClientOptions{})
+	c.timeout = time.Second
+	c.dial = func(address string, timeout time.Duration) (MrrClient, error) {
+		dials = append(dials, address)
+		deadlines[address] = timeout
+		if address == "primary" {
+			return primary, nil
+		}
+		return fallback, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		res, err := c.Objects(MrrFilter{Kind: "pod"})
+		if err != nil || len(res) != 1 {
+			t.Errorf("Query %d: expected object of fallback mirror, got %v, %v", i, res, err)
+		}
+	}
+
+	expected := []string{"primary", "fallback", "primary"}
+	if !reflect.DeepEqual(dials, expected) {
+		t.Errorf("Expected failed mirror to be dialed again and working one to be reused, got dials %v", dials)
+	}
+	if deadlines["primary"] > 600*time.Millisecond || deadlines["fallback"] < 900*time.Millisecond {
+		t.Errorf("Expected primary mirror to get half of the time and fallback the rest, got %v", deadlines)
+	}
+
+	fallback.err = fmt.Errorf("down")
+	_, err := c.Objects(MrrFilter{Kind: "pod"})
+	if err == nil || err.Error() != "down" {
+		t.Errorf("Expected error of the last mirror, got %v", err)
+	}
+}
+
+func TestClientUnixSocket(t *testing.T) {
+	once.Do(setupRPC)
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "kubemrr.sock")
+	l, err := listenMirror(unixScheme + socket)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+	go http.Serve(l, nil)
+
+	fi, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected socket only for the owner, got %v", fi.Mode().Perm())
+	}
+
+	c, err := NewMrrClient(unixScheme+socket, MrrClientOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	objects, err := c.Objects(MrrFilter{Server: "server1", Namespace: "ns1", Kind: "pod"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(objects) != 3 {
+		t.Errorf("Expected 3 pods, got %v", objects)
+	}
+
+	_, err = listenMirror(unixScheme + socket)
+	if err == nil || !strings.Contains(err.Error(), "another process") {
+		t.Errorf("Expected socket of the running mirror not to be taken over, got %v", err)
+	}
+}
+
+func TestListenMirrorStaleSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "kubemrr.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	l, err = listenMirror(unixScheme + socket)
+	if err != nil {
+		t.Fatalf("Expected stale socket to be replaced, got %v", err)
+	}
+	l.Close()
+
+	file := filepath.Join(dir, "file")
+	ioutil.WriteFile(file, []byte{}, 0600)
+	_, err = listenMirror(unixScheme + file)
+	if err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("Expected error about not a socket, got %v", err)
+	}
+}
+
+//writeTestCertificate writes a self-signed certificate of localhost and its key to the directory
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kubemrr"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := filepath.Join(dir, "cert.pem")
+	ioutil.WriteFile(cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}), 0600)
+	keyFile := filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawKey}), 0600)
+	return cert, keyFile
+}
+
+func TestClientTLS(t *testing.T) {
+	once.Do(setupRPC)
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cert, key := writeTestCertificate(t, dir)
+
+	watchCmd := NewWatchCommand(&TestFactory{})
+	watchCmd.Flags().Set("tls-cert", cert)
+	watchCmd.Flags().Set("tls-key", key)
+	config, err := mirrorTLSConfig(watchCmd)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go http.Serve(tls.NewListener(l, config), nil)
+
+	getCmd := NewGetCommand(&TestFactory{})
+	getCmd.Flags().Set("tls-ca", cert)
+	opts, err := GetMrrClientOptions(getCmd)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c, err := NewMrrClient(l.Addr().String(), opts)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	objects, err := c.Objects(MrrFilter{Server: "server1", Namespace: "ns1", Kind: "pod"})
+	if err != nil || len(objects) != 3 {
+		t.Errorf("Expected 3 pods, got %v, %v", objects, err)
+	}
+
+	_, err = NewMrrClient(l.Addr().String(), MrrClientOptions{TLS: &tls.Config{}})
+	if err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("Expected error of unknown certificate authority, got %v", err)
+	}
+
+	_, err = NewMrrClient(l.Addr().String(), MrrClientOptions{})
+	if err == nil {
+		t.Errorf("Expected error of plain client of TLS mirror")
+	}
+
+	watchCmd.Flags().Set("tls-key", "")
+	_, err = mirrorTLSConfig(watchCmd)
+	if err == nil || !strings.Contains(err.Error(), "must be given together") {
+		t.Errorf("Expected error of missing key, got %v", err)
+	}
+}
+
+func TestClientToken(t *testing.T) {
+	once.Do(setupRPC)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go http.Serve(l, requireToken("secret", http.DefaultServeMux))
+
+	c, err := NewMrrClient(l.Addr().String(), MrrClientOptions{Token: "secret"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	objects, err := c.Objects(MrrFilter{Server: "server1", Namespace: "ns1", Kind: "pod"})
+	if err != nil || len(objects) != 3 {
+		t.Errorf("Expected 3 pods, got %v, %v", objects, err)
+	}
+
+	for _, token := range []string{"", "other"} {
+		_, err = NewMrrClient(l.Addr().String(), MrrClientOptions{Token: token})
+		if err == nil || !strings.Contains(err.Error(), "rejected the token") {
+			t.Errorf("Token %q: expected rejected token, got %v", token, err)
+		}
+	}
+}
+
+func TestStatusWatchers(t *testing.T) {
+	c := NewMrrCache()
+	c.updateKubeObject(KubeServer{URL: "https://s2"}, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a"}})
+	c.metrics.watchEvent(KubeServer{URL: "https://s2"}, "pod")
+	c.metrics.watchReconnected(KubeServer{URL: "https://s2"}, "pod")
+	c.metrics.apiError(KubeServer{URL: "https://s1"}, "service", fmt.Errorf("forbidden"))
+	c.metrics.apiError(KubeServer{URL: "https://s2"}, "node", fmt.Errorf("timeout"))
+
+	var s MrrStatus
+	err := c.Status(&MrrFilter{}, &s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(s.Servers) != 2 || s.Servers[0].Server != "https://s1" || s.Servers[1].Server != "https://s2" {
+		t.Fatalf("Expected sorted status of servers with errors, got %+v", s.Servers)
+	}
+	s1 := s.Servers[0].Kinds
+	if len(s1) != 1 || s1[0].Kind != "service" || s1[0].LastError != "forbidden" || s1[0].LastErrorTime.IsZero() {
+		t.Errorf("Unexpected status of kinds of s1 %+v", s1)
+	}
+	s2 := s.Servers[1].Kinds
+	if len(s2) != 2 || s2[0].Kind != "node" || s2[0].LastError != "timeout" || s2[1].Kind != "pod" {
+		t.Fatalf("Unexpected status of kinds of s2 %+v", s2)
+	}
+	if s2[1].Objects != 1 || s2[1].Reconnects != 1 || s2[1].LastEvent.IsZero() || s2[1].LastError != "" {
+		t.Errorf("Unexpected status of pods of s2 %+v", s2[1])
+	}
+
+	err = c.Status(&MrrFilter{Server: "https://s1"}, &s)
+	if err != nil || len(s.Servers) != 1 {
+		t.Errorf("Expected status of one server, got %+v, %v", s.Servers, err)
+	}
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//selectorOp is the operator of a requirement of a label selector
+type selectorOp string
+
+const (
+	opEquals    selectorOp = "="
+	opNotEquals selectorOp = "!="
+	opIn        selectorOp = "in"
+	opNotIn     selectorOp = "notin"
+	opExists    selectorOp = "exists"
+	opNotExists selectorOp = "!"
+)
+
+//labelRequirement is a condition on one label, such as "app=web" or "tier in (db,cache)"
+type labelRequirement struct {
+	key    string
+	op     selectorOp
+	values []string
+}
+
+//labelSelector matches labels that satisfy all of its requirements. Empty selector matches all labels
+type labelSelector []labelRequirement
+
+var (
+	labelKeyRegex   = regexp.MustCompile(`^([a-zA-Z0-9]([-a-zA-Z0-9.]*[a-zA-Z0-9])?/)?[a-zA-Z0-9]([-a-zA-Z0-9_.]*[a-zA-Z0-9])?$`)
+	labelValueRegex = regexp.MustCompile(`^([a-zA-Z0-9]([-a-zA-Z0-9_.]*[a-zA-Z0-9])?)?$`)
+	setRequirement  = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+//parseSelector parses a label selector in the syntax of kubectl, such as "app=web,tier!=db,env in (prod,qa),!canary"
+func parseSelector(s string) (labelSelector, error) {
+	res := labelSelector{}
+	if strings.TrimSpace(s) == "" {
+		return res, nil
+	}
+
+	for _, raw := range splitRequirements(s) {
+		r, err := parseRequirement(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %s", s, err)
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+//splitRequirements splits the selector at commas that are not inside parentheses of sets
+func splitRequirements(s string) []string {
+	res := []string{}
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				res = append(res, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(res, s[start:])
+}
+
+func parseRequirement(s string) (labelRequirement, error) {
+	r := labelRequirement{}
+	switch {
+	case s == "":
+		return r, fmt.Errorf("empty requirement")
+	case setRequirement.MatchString(s):
+		m := setRequirement.FindStringSubmatch(s)
+		r.key, r.op = m[1], selectorOp(m[2])
+		for _, v := range strings.Split(m[3], ",") {
+			r.values = append(r.values, strings.TrimSpace(v))
+		}
+	case strings.HasPrefix(s, "!"):
+		r.key, r.op = strings.TrimSpace(s[1:]), opNotExists
+	case strings.Contains(s, "!="):
+		parts := strings.SplitN(s, "!=", 2)
+		r.key, r.op, r.values = strings.TrimSpace(parts[0]), opNotEquals, []string{strings.TrimSpace(parts[1])}
+	case strings.Contains(s, "="):
+		parts := strings.SplitN(strings.Replace(s, "==", "=", 1), "=", 2)
+		r.key, r.op, r.values = strings.TrimSpace(parts[0]), opEquals, []string{strings.TrimSpace(parts[1])}
+	default:
+		r.key, r.op = s, opExists
+	}
+
+	if !labelKeyRegex.MatchString(r.key) {
+		return r, fmt.Errorf("invalid label key %q", r.key)
+	}
+	for _, v := range r.values {
+		if !labelValueRegex.MatchString(v) {
+			return r, fmt.Errorf("invalid label value %q", v)
+		}
+	}
+	return r, nil
+}
+
+func (r *labelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.key]
+	switch r.op {
+	case opExists:
+		return ok
+	case opNotExists:
+		return !ok
+	case opEquals, opIn:
+		return ok && containsString(r.values, value)
+	case opNotEquals, opNotIn:
+		return !ok || !containsString(r.values, value)
+	}
+	return false
+}
+
+//matches checks whether the labels satisfy all requirements of the selector
+func (s labelSelector) matches(labels map[string]string) bool {
+	for i := range s {
+		if !s[i].matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	labels := map[string]string{"app": "checkout", "tier": "web", "example.com/team": "payments"}
+	tests := []struct {
+		selector string
+		matches  bool
+	}{
+		{"", true},
+		{"app=checkout", true},
+		{"app==checkout", true},
+		{"app = checkout", true},
+		{"app=api", false},
+		{"app!=api", true},
+		{"app!=checkout", false},
+		{"owner!=x", true},
+		{"app", true},
+		{"owner", false},
+		{"!owner", true},
+		{"!app", false},
+		{"tier in (web, api)", true},
+		{"tier in (db)", false},
+		{"tier notin (db,cache)", true},
+		{"owner notin (x)", true},
+		{"example.com/team=payments", true},
+		{"app=checkout,tier in (web,api),!canary", true},
+		{"app=checkout,tier=db", false},
+	}
+
+	for _, test := range tests {
+		s, err := parseSelector(test.selector)
+		if err != nil {
+			t.Errorf("Selector %q: unexpected error %v", test.selector, err)
+			continue
+		}
+		if s.matches(labels) != test.matches {
+			t.Errorf("Selector %q: expected match %v", test.selector, test.matches)
+		}
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, selector := range []string{"app=checkout,", "=web", "app in web", "!", "app=a b", "app in (a,b c)"} {
+		if _, err := parseSelector(selector); err == nil {
+			t.Errorf("Selector %q: expected error", selector)
+		}
+	}
+}
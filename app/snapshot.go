@@ -0,0 +1,189 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+//snapshotVersion is increased when the format of snapshots changes. Snapshots of other versions are ignored
+const snapshotVersion = 1
+
+//CacheSnapshot is the content of the cache kept on disk, so that a restarted mirror serves
+//objects right away while its watchers list them again. It is also exported and imported by clients
+type CacheSnapshot struct {
+	Version int              `json:"version"`
+	Servers []ServerSnapshot `json:"servers"`
+}
+
+type ServerSnapshot struct {
+	Server  KubeServer           `json:"server"`
+	Objects []KubeObject         `json:"objects"`
+	Updated map[string]time.Time `json:"updated"`
+}
+
+//saveSnapshot writes objects of all servers to the file
+func (c *MrrCache) saveSnapshot(path string) error {
+	path, err := substituteUserHome(path)
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	raw, err := json.Marshal(c.snapshotLocked(&MrrFilter{}))
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	//write to a temporary file first, so that a mirror stopped while writing keeps the previous snapshot
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "snapshot")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+//loadSnapshot puts objects of the given servers from the file into the cache and returns their number.
+//Objects of other servers are skipped, since nobody would ever update them. Missing file is not an error
+func (c *MrrCache) loadSnapshot(path string, servers []KubeServer) (int, error) {
+	path, err := substituteUserHome(path)
+	if err != nil {
+		return 0, err
+	}
+	raw, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	s, err := parseSnapshot(raw)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse snapshot %s: %s", path, err)
+	}
+
+	watched := map[KubeServer]bool{}
+	for _, server := range servers {
+		watched[server] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.restoreLocked(s, func(server KubeServer) bool { return watched[server] }), nil
+}
+
+//parseSnapshot reads a snapshot written by saveSnapshot or "kubemrr export"
+func parseSnapshot(raw []byte) (CacheSnapshot, error) {
+	s := CacheSnapshot{}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return s, err
+	}
+	if s.Version != snapshotVersion {
+		return s, fmt.Errorf("snapshot has version %d, expected %d", s.Version, snapshotVersion)
+	}
+	return s, nil
+}
+
+//snapshotLocked returns objects of the servers that match the filter. Caller must hold at least the read lock
+func (c *MrrCache) snapshotLocked(f *MrrFilter) CacheSnapshot {
+	s := CacheSnapshot{Version: snapshotVersion, Servers: []ServerSnapshot{}}
+	for server, objects := range c.objects {
+		if matchesServer(f, server) {
+			s.Servers = append(s.Servers, ServerSnapshot{Server: server, Objects: objects, Updated: c.updated[server]})
+		}
+	}
+	sort.Slice(s.Servers, func(i, j int) bool { return KubeServers{s.Servers[i].Server, s.Servers[j].Server}.Less(0, 1) })
+	return s
+}
+
+//restoreLocked replaces objects of the servers in the snapshot that the function accepts, and
+//returns the number of restored objects. Caller must hold the write lock
+func (c *MrrCache) restoreLocked(s CacheSnapshot, accept func(server KubeServer) bool) int {
+	restored := 0
+	for _, ss := range s.Servers {
+		if !accept(ss.Server) {
+			continue
+		}
+		for i := range ss.Objects {
+			ss.Objects[i] = c.strings.object(ss.Objects[i])
+		}
+		c.objects[ss.Server] = ss.Objects
+		c.updated[ss.Server] = ss.Updated
+		c.reindexLocked(ss.Server)
+		restored += len(ss.Objects)
+	}
+	return restored
+}
+
+//Export returns objects of the servers that match the filter, in the format of snapshots
+func (c *MrrCache) Export(f *MrrFilter, s *CacheSnapshot) error {
+	defer c.metrics.observeRequest("Export", time.Now())
+	c.requests.RLock()
+	defer c.requests.RUnlock()
+	if f == nil {
+		return errors.New("Cannot export with nil filter")
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	*s = c.snapshotLocked(f)
+	return nil
+}
+
+//Import replaces objects of the servers in the snapshot and returns the number of imported objects.
+//Servers that are not watched keep the imported objects until they are flushed. It is an admin request
+func (c *MrrCache) Import(s *CacheSnapshot, imported *int) error {
+	defer c.metrics.observeRequest("Import", time.Now())
+	c.requests.RLock()
+	defer c.requests.RUnlock()
+	if s == nil {
+		return errors.New("Cannot import nil snapshot")
+	}
+	if !c.tokenRequired || !c.features.Enabled(AdminAPI) {
+		return errAdminDisabled
+	}
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("snapshot has version %d, expected %d", s.Version, snapshotVersion)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*imported = c.restoreLocked(*s, func(KubeServer) bool { return true })
+	log.WithField("objects", *imported).Warn("imported objects into the cache")
+	return nil
+}
+
+//saveSnapshots writes the snapshot every interval until stop is closed
+func (c *MrrCache) saveSnapshots(path string, interval time.Duration, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+		if err := c.saveSnapshot(path); err != nil {
+			log.WithField("error", err).Warn("could not save snapshot of the cache")
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache", "snapshot.json")
+
+	foo := KubeServer{URL: "https://foo.com"}
+	bar := KubeServer{URL: "https://bar.com", Profile: "alice"}
+	c := NewMrrCache()
+	pod := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "ns", ResourceVersion: "7"}}
+	svc := KubeObject{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "b"}, Spec: ObjectSpec{ClusterIP: "10.0.0.1"}}
+	c.updateKubeObject(foo, pod)
+	c.updateKubeObject(bar, svc)
+	assert.NoError(t, c.saveSnapshot(path))
+
+	loaded := NewMrrCache()
+	n, err := loaded.loadSnapshot(path, []KubeServer{foo})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []KubeObject{pod}, loaded.objects[foo])
+	assert.True(t, loaded.updated[foo]["pod"].Equal(c.updated[foo]["pod"]))
+	_, ok := loaded.objects[bar]
+	assert.False(t, ok, "must skip servers that are not watched")
+	assert.Empty(t, loaded.events, "loaded objects are not changes")
+
+	n, err = NewMrrCache().loadSnapshot(filepath.Join(dir, "missing"), []KubeServer{foo})
+	assert.NoError(t, err, "missing snapshot is not an error")
+	assert.Equal(t, 0, n)
+
+	ioutil.WriteFile(path, []byte(`{"version":0}`), 0600)
+	_, err = NewMrrCache().loadSnapshot(path, []KubeServer{foo})
+	assert.Error(t, err, "must not load snapshots of other versions")
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unsafe"
+)
+
+func NewStatsCommand(f Factory) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "stats",
+		Short: "Print what is mirrored",
+		Long: `
+DESCRIPTION:
+  Print numbers of objects in the "kubemrr watch" process for each server, kind and
+  namespace, when they were last updated, and how much memory they take.
+
+  Memory is estimated from the sizes of the mirrored fields, so the mirror process
+  takes somewhat more.
+
+  It also prints how many objects of each kind were added, modified and deleted, how long
+  after their creation new objects reached the mirror, how many requests clients made and
+  how long it took on average to find the objects they asked for. The same is served on
+  /metrics of the mirror.
+
+EXAMPLE:
+  kubemrr stats
+  kubemrr stats --server https://prod.example.com
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := RunCommon(cmd); err != nil {
+				return err
+			}
+			return RunStats(f, cmd, args)
+		},
+	}
+
+	AddCommonFlags(cmd)
+	AddFallbackFlag(cmd)
+	AddMirrorTLSFlags(cmd)
+	AddTokenFlag(cmd)
+	AddProfileFlag(cmd)
+	cmd.Flags().String("server", "", "URL of the Kubernetes API server, empty for all servers")
+	return cmd
+}
+
+func RunStats(f Factory, cmd *cobra.Command, args []string) error {
+	server, err := cmd.Flags().GetString("server")
+	if err != nil {
+		return errors.New("could not parse value of --server")
+	}
+
+	profile, err := GetProfile(cmd)
+	if err != nil {
+		return errors.New("could not parse value of --profile")
+	}
+
+	bind, err := GetMirrorAddress(cmd)
+	if err != nil {
+		return fmt.Errorf("unexpected error: %s", err)
+	}
+
+	opts, err := GetMrrClientOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	client, err := f.MrrClient(bind, opts)
+	if err != nil {
+		return fmt.Errorf("could not create client to kubemrr: %s", err)
+	}
+
+	status, err := client.Status(MrrFilter{Server: server, Profile: profile})
+	if err != nil {
+		return fmt.Errorf("could not get status of kubemrr: %s", err)
+	}
+
+	return outputStats(status, time.Now(), f.StdOut())
+}
+
+//outputStats prints a table of numbers of objects of each server, kind and namespace,
+//followed by the totals
+func outputStats(status MrrStatus, now time.Time, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVER\tKIND\tNAMESPACE\tOBJECTS\tUPDATED")
+
+	objects, bytes := 0, 0
+	var updated time.Time
+	for _, ss := range status.Servers {
+		server := ss.Server
+		if ss.Profile != "" {
+			server = ss.Profile + ": " + server
+		}
+		for _, ks := range ss.Kinds {
+			objects += ks.Objects
+			bytes += ks.Bytes
+			if ks.Updated.After(updated) {
+				updated = ks.Updated
+			}
+
+			namespaces := []string{}
+			for ns := range ks.Namespaces {
+				namespaces = append(namespaces, ns)
+			}
+			sort.Strings(namespaces)
+			if len(namespaces) == 0 {
+				//listed kinds without objects are printed too, so that it is clear they are mirrored
+				namespaces = []string{""}
+			}
+			for _, ns := range namespaces {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", server, ks.Kind, valueOrNone(ns), ks.Namespaces[ns], since(ks.Updated, now))
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(out, "\n%d servers, %d objects, about %s in memory, last updated %s\n",
+		len(status.Servers), objects, formatBytes(bytes), since(updated, now)); err != nil {
+		return err
+	}
+	return outputActivity(status, out)
+}
+
+//outputActivity prints a table of changes of objects of each server and kind, followed by requests
+//of clients. Nothing is printed by mirrors that do not count them
+func outputActivity(status MrrStatus, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	changed := false
+	for _, ss := range status.Servers {
+		server := ss.Server
+		if ss.Profile != "" {
+			server = ss.Profile + ": " + server
+		}
+		for _, ks := range ss.Kinds {
+			if ks.Added+ks.Modified+ks.Deleted == 0 {
+				continue
+			}
+			if !changed {
+				fmt.Fprintln(w, "\nSERVER\tKIND\tADDED\tMODIFIED\tDELETED\tEVENT LAG")
+				changed = true
+			}
+			lag := "<none>"
+			if ks.EventLag > 0 {
+				lag = ks.EventLag.String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\n", server, ks.Kind, ks.Added, ks.Modified, ks.Deleted, lag)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(status.Requests) == 0 {
+		return nil
+	}
+	methods := []string{}
+	for method := range status.Requests {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	requests := []string{}
+	for _, method := range methods {
+		requests = append(requests, fmt.Sprintf("%s %d", method, status.Requests[method]))
+	}
+	_, err := fmt.Fprintf(out, "\nRequests of clients: %s, objects are found in %s on average\n",
+		strings.Join(requests, ", "), status.FilterTime)
+	return err
+}
+
+//since formats how long ago the time was, "never" for zero time
+func since(t time.Time, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return age(t, now) + " ago"
+}
+
+//formatBytes formats the size with a binary unit, such as "512 B" or "1.5 MiB"
+func formatBytes(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+//mapEntrySize roughly estimates memory that a map takes for each entry besides the key and the value
+const mapEntrySize = 48
+
+//objectSize estimates memory that the object takes in the cache: the struct and its strings
+func objectSize(o KubeObject) int {
+	n := int(unsafe.Sizeof(o))
+	n += len(o.Kind) + len(o.Name) + len(o.Namespace) + len(o.UID) + len(o.ResourceVersion)
+	n += len(o.Spec.ClusterIP) + len(o.Status.PodIP) + len(o.Status.Phase)
+	for _, c := range o.Spec.allContainers() {
+		n += int(unsafe.Sizeof(c)) + len(c.Name) + len(c.Image)
+		for _, p := range c.Ports {
+			n += int(unsafe.Sizeof(p)) + len(p.Name)
+		}
+	}
+	for _, p := range o.Spec.Ports {
+		n += int(unsafe.Sizeof(p)) + len(p.Name)
+	}
+	for _, c := range o.Status.Conditions {
+		n += int(unsafe.Sizeof(c)) + len(c.Type) + len(c.Status)
+	}
+	for k, v := range o.Labels {
+		n += mapEntrySize + len(k) + len(v)
+	}
+	return n
+}
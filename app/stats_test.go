@@ -0,0 +1,97 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunStats(t *testing.T) {
+	now := time.Now()
+	tc := &TestMirrorClient{status: MrrStatus{Servers: []ServerStatus{
+		{Server: "https://s1", Kinds: []KindStatus{
+			{Kind: "pod", Objects: 3, Updated: now.Add(-5 * time.Second), Namespaces: map[string]int{"prod": 2, "dev": 1}, Bytes: 1536},
+			{Kind: "service", Updated: now.Add(-time.Minute)},
+		}},
+		{Server: "https://s2", Profile: "alice", Kinds: []KindStatus{
+			{Kind: "node", Objects: 1, Namespaces: map[string]int{"": 1}, Bytes: 512},
+		}},
+	}}}
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewStatsCommand(&TestFactory{mrrClient: tc, stdOut: buf})
+	cmd.Flags().Set("server", "https://s1")
+
+	if err := cmd.RunE(cmd, []string{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tc.lastFilter.Server != "https://s1" {
+		t.Errorf("Expected stats of https://s1, got %v", tc.lastFilter)
+	}
+
+	expected := []string{
+		"SERVER             KIND     NAMESPACE  OBJECTS  UPDATED",
+		"https://s1         pod      dev        1        5s ago",
+		"https://s1         pod      prod       2        5s ago",
+		"https://s1         service  <none>     0        1m ago",
+		"alice: https://s2  node     <none>     1        never",
+		"2 servers, 4 objects, about 2.0 KiB in memory, last updated 5s ago",
+	}
+	for _, line := range expected {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Output [%s] does not contain [%s]", buf, line)
+		}
+	}
+}
+
+func TestOutputActivity(t *testing.T) {
+	status := MrrStatus{
+		Servers: []ServerStatus{
+			{Server: "https://s1", Kinds: []KindStatus{
+				{Kind: "pod", Added: 5, Modified: 12, Deleted: 2, EventLag: 350 * time.Millisecond},
+				{Kind: "service"},
+				{Kind: "node", Modified: 3},
+			}},
+		},
+		Requests:   map[string]int{"Status": 1, "Objects": 42},
+		FilterTime: 250 * time.Microsecond,
+	}
+	buf := bytes.NewBuffer([]byte{})
+	if err := outputActivity(status, buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"SERVER      KIND  ADDED  MODIFIED  DELETED  EVENT LAG",
+		"https://s1  pod   5      12        2        350ms",
+		"https://s1  node  0      3         0        <none>",
+		"Requests of clients: Objects 42, Status 1, objects are found in 250µs on average",
+	}
+	for _, line := range expected {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Output [%s] does not contain [%s]", buf, line)
+		}
+	}
+	if strings.Contains(buf.String(), "service") {
+		t.Errorf("Expected kinds without changes to be left out, got [%s]", buf)
+	}
+
+	buf.Reset()
+	if err := outputActivity(MrrStatus{}, buf); err != nil || buf.Len() != 0 {
+		t.Errorf("Expected nothing for mirrors that do not count activity, got %q, %v", buf, err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+	}
+	for n, expected := range tests {
+		if actual := formatBytes(n); actual != expected {
+			t.Errorf("Bytes %d: expected %s, got %s", n, expected, actual)
+		}
+	}
+}
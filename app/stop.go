@@ -0,0 +1,84 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"syscall"
+	"time"
+)
+
+func NewStopCommand(f Factory) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the mirror running in background",
+		Long: `
+DESCRIPTION:
+  Stop the "kubemrr watch" process whose id is written to --pidfile,
+  as started by "kubemrr watch --daemon". The mirror answers requests of
+  connected clients before it exits.
+
+EXAMPLE:
+  kubemrr stop
+  kubemrr stop --pidfile ~/.kubemrr/prod.pid
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := RunCommon(cmd); err != nil {
+				return err
+			}
+			return RunStop(f, cmd, args)
+		},
+	}
+
+	AddCommonFlags(cmd)
+	cmd.Flags().String("pidfile", defaultPidfile, "File with the process id of the mirror")
+	cmd.Flags().Duration("timeout", 15*time.Second, "How long to wait for the mirror to exit")
+	return cmd
+}
+
+func RunStop(f Factory, cmd *cobra.Command, args []string) error {
+	pidfile, err := cmd.Flags().GetString("pidfile")
+	if err != nil {
+		return errors.New("could not parse value of --pidfile")
+	}
+
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return errors.New("could not parse value of --timeout")
+	}
+
+	//the id may belong to another process after a crash or a reboot, so only the mirror
+	//that still locks the pidfile is signalled
+	pid, err := lockedPidfile(pidfile)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("kubemrr is not running, %s does not exist", pidfile)
+	}
+	if err == errStalePidfile {
+		if path, err := substituteUserHome(pidfile); err == nil {
+			os.Remove(path)
+		}
+		return fmt.Errorf("kubemrr is not running, removed stale %s", pidfile)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("could not stop kubemrr with pid %d: %s", pid, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := lockedPidfile(pidfile); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("kubemrr with pid %d did not stop in %s", pid, timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	fmt.Fprintf(f.StdOut(), "Stopped kubemrr with pid %d\n", pid)
+	return nil
+}
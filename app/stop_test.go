@@ -0,0 +1,90 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pidfile := filepath.Join(dir, "pid")
+
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewStopCommand(&TestFactory{stdOut: buf})
+	cmd.Flags().Set("pidfile", pidfile)
+	if err := cmd.RunE(cmd, []string{}); err == nil {
+		t.Errorf("Expected error without pidfile")
+	}
+
+	//a process that took the id of a crashed mirror is left alone
+	sleep := exec.Command("sleep", "10")
+	if err := sleep.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		sleep.Wait()
+		close(exited)
+	}()
+	defer sleep.Process.Kill()
+	ioutil.WriteFile(pidfile, []byte(strconv.Itoa(sleep.Process.Pid)), 0644)
+
+	err = cmd.RunE(cmd, []string{})
+	if err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Errorf("Expected error about stale pidfile, got %v", err)
+	}
+	if _, err := os.Stat(pidfile); !os.IsNotExist(err) {
+		t.Errorf("Expected stale pidfile to be removed")
+	}
+	select {
+	case <-exited:
+		t.Errorf("Expected process without the lock of the pidfile to keep running")
+	default:
+	}
+
+	mirror := exec.Command(os.Args[0], "-test.run=TestPidfileHelperProcess")
+	mirror.Env = append(os.Environ(), "KUBEMRR_TEST_PIDFILE="+pidfile)
+	if err := mirror.Start(); err != nil {
+		t.Fatal(err)
+	}
+	go mirror.Wait()
+	defer mirror.Process.Kill()
+	for i := 0; i < 100; i++ {
+		if pid, err := lockedPidfile(pidfile); err == nil && pid == mirror.Process.Pid {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if err := cmd.RunE(cmd, []string{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Stopped kubemrr") {
+		t.Errorf("Unexpected output [%s]", buf)
+	}
+}
+
+//TestPidfileHelperProcess is the mirror that TestRunStop stops. It locks the pidfile and waits for SIGTERM
+func TestPidfileHelperProcess(t *testing.T) {
+	pidfile := os.Getenv("KUBEMRR_TEST_PIDFILE")
+	if pidfile == "" {
+		return
+	}
+	remove, err := writePidfile(pidfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	time.Sleep(time.Minute)
+}
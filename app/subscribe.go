@@ -0,0 +1,149 @@
+package app
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+//subscribePath is the path of the HTTP endpoint that streams changes of cached objects
+const subscribePath = "/subscribe"
+
+//subscriptionBuffer is the number of changes kept for a subscriber that does not read them yet.
+//Subscribers that fall further behind are dropped and have to subscribe again
+const subscriptionBuffer = 256
+
+//CacheChange is a change of a cached object sent to subscribers
+type CacheChange struct {
+	Type    EventType  `json:"type"`
+	Server  string     `json:"server"`
+	Profile string     `json:"profile,omitempty"`
+	Object  KubeObject `json:"object"`
+}
+
+type subscription struct {
+	filter  MrrFilter
+	changes chan CacheChange
+}
+
+//matches checks whether the change of the object of the server is sent to the subscriber.
+//Empty kind of the filter matches objects of all kinds
+func (s *subscription) matches(server KubeServer, o KubeObject) bool {
+	f := &s.filter
+	return matchesServer(f, server) &&
+		(f.Kind == "" || strings.EqualFold(o.Kind, f.Kind)) &&
+		(f.Namespace == "" || strings.EqualFold(o.Kind, "namespace") || strings.EqualFold(o.Namespace, f.Namespace))
+}
+
+//subscribe returns a subscription to changes of objects that match the filter and the objects
+//that match it now, so that no change is missed between listing and subscribing
+func (c *MrrCache) subscribe(f MrrFilter) (*subscription, []CacheChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := &subscription{filter: f, changes: make(chan CacheChange, subscriptionBuffer)}
+	current := []CacheChange{}
+	for server, objects := range c.objects {
+		for _, o := range objects {
+			if s.matches(server, o) {
+				current = append(current, CacheChange{Type: Added, Server: server.URL, Profile: server.Profile, Object: o})
+			}
+		}
+	}
+	c.subscribers[s] = true
+	return s, current
+}
+
+//unsubscribe stops sending changes to the subscriber
+func (c *MrrCache) unsubscribe(s *subscription) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.subscribers[s] {
+		delete(c.subscribers, s)
+		close(s.changes)
+	}
+}
+
+//closeSubscribers ends streams of all subscribers
+func (c *MrrCache) closeSubscribers() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for s := range c.subscribers {
+		delete(c.subscribers, s)
+		close(s.changes)
+	}
+}
+
+//publishLocked sends the change to matching subscribers. Caller must hold the write lock
+func (c *MrrCache) publishLocked(server KubeServer, t EventType, o KubeObject) {
+	for s := range c.subscribers {
+		if !s.matches(server, o) {
+			continue
+		}
+		select {
+		case s.changes <- CacheChange{Type: t, Server: server.URL, Profile: server.Profile, Object: o}:
+		default:
+			log.WithField("filter", s.filter).Warn("subscriber is too slow, dropping it")
+			delete(c.subscribers, s)
+			close(s.changes)
+		}
+	}
+}
+
+//subscribeHandler streams changes of objects that match GET /subscribe?kind=pod&namespace=ns&server=url&profile=name
+//as JSON objects, one per line. The stream starts with ADDED changes of the current objects.
+//Kind is optional and accepts the same names as "kubemrr get"
+func subscribeHandler(c *MrrCache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		q := r.URL.Query()
+		f := MrrFilter{Server: q.Get("server"), Profile: q.Get("profile"), Namespace: q.Get("namespace")}
+		if q.Get("kind") != "" {
+			kind, err := resourceKind(q.Get("kind"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.Kind = kind
+		}
+
+		s, current := c.subscribe(f)
+		defer c.unsubscribe(s)
+		log.WithField("filter", f).Info("client subscribed to changes")
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		for _, change := range current {
+			if err := enc.Encode(change); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case change, ok := <-s.changes:
+				if !ok {
+					return
+				}
+				if err := enc.Encode(change); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
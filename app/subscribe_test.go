@@ -0,0 +1,90 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	c := NewMrrCache()
+	s1 := KubeServer{URL: "https://s1"}
+	a := KubeObject{TypeMeta: TypeMeta{Kind: "pod"}, ObjectMeta: ObjectMeta{Name: "a", Namespace: "prod"}}
+	c.updateKubeObject(s1, a)
+
+	server := httptest.NewServer(subscribeHandler(c))
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/subscribe?kind=po&namespace=prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	lines := make(chan CacheChange)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			change := CacheChange{}
+			json.Unmarshal(scanner.Bytes(), &change)
+			lines <- change
+		}
+		close(lines)
+	}()
+	next := func() CacheChange {
+		select {
+		case change := <-lines:
+			return change
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for change")
+			return CacheChange{}
+		}
+	}
+
+	if change := next(); change.Type != Added || change.Object.Name != "a" || change.Server != "https://s1" {
+		t.Errorf("Expected current object first, got %+v", change)
+	}
+
+	c.updateKubeObject(s1, KubeObject{TypeMeta: TypeMeta{Kind: "pod"}, ObjectMeta: ObjectMeta{Name: "dev", Namespace: "dev"}})
+	c.updateKubeObject(s1, KubeObject{TypeMeta: TypeMeta{Kind: "service"}, ObjectMeta: ObjectMeta{Name: "svc", Namespace: "prod"}})
+	c.updateKubeObject(s1, KubeObject{TypeMeta: TypeMeta{Kind: "pod"}, ObjectMeta: ObjectMeta{Name: "b", Namespace: "prod"}})
+	if change := next(); change.Type != Added || change.Object.Name != "b" {
+		t.Errorf("Expected only matching changes, got %+v", change)
+	}
+
+	c.deleteKubeObject(s1, a)
+	if change := next(); change.Type != Deleted || change.Object.Name != "a" {
+		t.Errorf("Expected deletion of a, got %+v", change)
+	}
+
+	c.deleteServer(s1)
+	if change := next(); change.Type != Deleted || change.Object.Name != "b" {
+		t.Errorf("Expected deletion of objects of removed server, got %+v", change)
+	}
+}
+
+func TestSubscribeSlowSubscriber(t *testing.T) {
+	c := NewMrrCache()
+	s, _ := c.subscribe(MrrFilter{})
+	for i := 0; i <= subscriptionBuffer; i++ {
+		c.updateKubeObject(KubeServer{URL: "https://s1"}, KubeObject{TypeMeta: TypeMeta{Kind: "pod"}, ObjectMeta: ObjectMeta{Name: fmt.Sprintf("pod-%d", i)}})
+	}
+
+	received := 0
+	for range s.changes {
+		received++
+	}
+	if received != subscriptionBuffer {
+		t.Errorf("Expected %d changes before the subscriber is dropped, got %d", subscriptionBuffer, received)
+	}
+	if len(c.subscribers) != 0 {
+		t.Errorf("Expected slow subscriber to be dropped")
+	}
+	c.unsubscribe(s)
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+//listenFDsStart is the first file descriptor passed by systemd, SD_LISTEN_FDS_START of sd_listen_fds(3)
+var listenFDsStart = 3
+
+//systemdListener returns the socket passed by systemd when the mirror is started by socket activation,
+//and nil when it is started otherwise. The mirror then starts on the first request of a client
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LISTEN_FDS: %s", os.Getenv("LISTEN_FDS"))
+	}
+	if fds != 1 {
+		return nil, fmt.Errorf("expected one socket from systemd, got %d", fds)
+	}
+
+	//children of the mirror must not take the socket for their own
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	syscall.CloseOnExec(listenFDsStart)
+	f := os.NewFile(uintptr(listenFDsStart), "systemd-socket")
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+//listenerBind returns the address of the listener in the form of --bind
+func listenerBind(l net.Listener) string {
+	if l.Addr().Network() == "unix" {
+		return unixScheme + l.Addr().String()
+	}
+	return l.Addr().String()
+}
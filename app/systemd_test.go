@@ -0,0 +1,62 @@
+package app
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestSystemdListener(t *testing.T) {
+	l, err := systemdListener()
+	if l != nil || err != nil {
+		t.Fatalf("Expected no socket without socket activation, got %v, %v", l, err)
+	}
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+	f, err := tcp.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//systemdListener closes the descriptor it is given, so it gets its own copy
+	fd, err := syscall.Dup(int(f.Fd()))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(start int) { listenFDsStart = start }(listenFDsStart)
+	listenFDsStart = fd
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	os.Setenv("LISTEN_FDS", "1")
+	if l, _ := systemdListener(); l != nil {
+		t.Errorf("Expected sockets passed to other process to be ignored")
+	}
+
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	os.Setenv("LISTEN_FDS", "2")
+	if _, err := systemdListener(); err == nil {
+		t.Errorf("Expected error for several sockets")
+	}
+
+	os.Setenv("LISTEN_FDS", "1")
+	l, err = systemdListener()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer l.Close()
+	if listenerBind(l) != tcp.Addr().String() {
+		t.Errorf("Expected socket on %s, got %s", tcp.Addr(), listenerBind(l))
+	}
+	if os.Getenv("LISTEN_PID") != "" || os.Getenv("LISTEN_FDS") != "" {
+		t.Errorf("Expected environment of socket activation to be cleared")
+	}
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	log "github.com/Sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+//newRequestID returns a random ID of a request to the mirror, logged by both the client and the mirror
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+//rpcHandler serves net/rpc over HTTP the way rpc.HandleHTTP does, but with tracingServerCodec,
+//so that the mirror logs how long requests of clients take
+func rpcHandler(server *rpc.Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "CONNECT" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			io.WriteString(w, "405 must CONNECT\n")
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			log.WithField("remote", r.RemoteAddr).WithField("error", err).Warn("could not hijack connection of the client")
+			return
+		}
+		io.WriteString(conn, "HTTP/1.0 200 Connected to Go RPC\n\n")
+		server.ServeCodec(newTracingServerCodec(conn))
+	})
+}
+
+//requestTrace is what is known about a request of a client until it is answered
+type requestTrace struct {
+	id      string
+	method  string
+	started time.Time
+	decoded time.Time
+}
+
+//tracingServerCodec is the gob codec of net/rpc that logs at debug level, for each request, the ID given
+//by the client and how long it took to decode the request, to answer it and to encode the answer
+type tracingServerCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	closed bool
+
+	//read is the request whose body is read next. Requests are read one by one,
+	//but answered concurrently
+	read   *requestTrace
+	mu     sync.Mutex
+	traces map[uint64]*requestTrace
+}
+
+func newTracingServerCodec(conn io.ReadWriteCloser) *tracingServerCodec {
+	buf := bufio.NewWriter(conn)
+	return &tracingServerCodec{
+		rwc:    conn,
+		dec:    gob.NewDecoder(conn),
+		enc:    gob.NewEncoder(buf),
+		encBuf: buf,
+		traces: make(map[uint64]*requestTrace),
+	}
+}
+
+func (c *tracingServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	if err := c.dec.Decode(r); err != nil {
+		return err
+	}
+	c.read = &requestTrace{method: r.ServiceMethod, started: time.Now()}
+	c.mu.Lock()
+	c.traces[r.Seq] = c.read
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *tracingServerCodec) ReadRequestBody(body interface{}) error {
+	if err := c.dec.Decode(body); err != nil {
+		return err
+	}
+	if f, ok := body.(*MrrFilter); ok {
+		c.read.id = f.RequestID
+	}
+	c.read.decoded = time.Now()
+	return nil
+}
+
+func (c *tracingServerCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	answered := time.Now()
+	err := c.writeResponse(r, body)
+
+	c.mu.Lock()
+	t := c.traces[r.Seq]
+	delete(c.traces, r.Seq)
+	c.mu.Unlock()
+	if t != nil && !t.decoded.IsZero() {
+		log.WithFields(log.Fields{
+			"id":        t.id,
+			"method":    t.method,
+			"decode":    t.decoded.Sub(t.started).String(),
+			"answer":    answered.Sub(t.decoded).String(),
+			"serialize": time.Since(answered).String(),
+			"total":     time.Since(t.started).String(),
+		}).Debug("answered request of a client")
+	}
+	return err
+}
+
+func (c *tracingServerCodec) writeResponse(r *rpc.Response, body interface{}) error {
+	if err := c.enc.Encode(r); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *tracingServerCodec) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.rwc.Close()
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"net"
+	"net/rpc"
+	"sync"
+	"testing"
+	"time"
+)
+
+//entriesHook remembers logged entries
+type entriesHook struct {
+	mu      sync.Mutex
+	entries []*log.Entry
+}
+
+func (h *entriesHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *entriesHook) Fire(e *log.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, e)
+	return nil
+}
+
+//withID returns messages of the entries logged with the request ID
+func (h *entriesHook) withID(id string) map[string]log.Fields {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	res := map[string]log.Fields{}
+	for _, e := range h.entries {
+		if e.Data["id"] == id {
+			res[e.Message] = e.Data
+		}
+	}
+	return res
+}
+
+func TestNewRequestID(t *testing.T) {
+	a, b := newRequestID(), newRequestID()
+	if len(a) != 16 || a == b {
+		t.Errorf("Expected distinct IDs of 16 hex digits, got %q and %q", a, b)
+	}
+}
+
+func TestTracingServerCodec(t *testing.T) {
+	hook := &entriesHook{}
+	hooks, level := log.StandardLogger().Hooks, log.GetLevel()
+	log.StandardLogger().Hooks = make(log.LevelHooks)
+	log.AddHook(hook)
+	log.SetLevel(log.DebugLevel)
+	defer func() {
+		log.StandardLogger().Hooks = hooks
+		log.SetLevel(level)
+	}()
+
+	c := NewMrrCache()
+	c.updateKubeObject(KubeServer{URL: "s"}, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "web"}})
+	server := rpc.NewServer()
+	server.Register(c)
+	serverConn, clientConn := net.Pipe()
+	go server.ServeCodec(newTracingServerCodec(serverConn))
+	client := &MrrClientDefault{conn: rpc.NewClient(clientConn)}
+	defer client.conn.Close()
+
+	objects, err := client.Objects(MrrFilter{Kind: "pod", RequestID: "42"})
+	if err != nil || len(objects) != 1 {
+		t.Fatalf("Expected the pod, got %v, %v", objects, err)
+	}
+
+	//the mirror logs the request once the answer is sent, which may be after the client reads it
+	entries := hook.withID("42")
+	for start := time.Now(); entries["answered request of a client"] == nil && time.Since(start) < time.Second; {
+		time.Sleep(10 * time.Millisecond)
+		entries = hook.withID("42")
+	}
+	for _, msg := range []string{"asking the mirror", "the mirror answered", "filtered objects", "answered request of a client"} {
+		if _, ok := entries[msg]; !ok {
+			t.Errorf("Expected %q to be logged with the request ID, got %v", msg, entries)
+		}
+	}
+	answered := entries["answered request of a client"]
+	for _, field := range []string{"decode", "answer", "serialize", "total"} {
+		if answered[field] == nil {
+			t.Errorf("Expected %s time to be logged, got %v", field, answered)
+		}
+	}
+	if answered["method"] != "MrrCache.Objects" {
+		t.Errorf("Expected the method to be logged, got %v", answered)
+	}
+}
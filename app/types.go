@@ -3,28 +3,181 @@ package app
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
 )
 
 type ObjectMeta struct {
-	Name            string `json:"name,omitempty"`
-	Namespace       string `json:"namespace,omitempty"`
-	ResourceVersion string `json:"resourceVersion,omitempty"`
+	Name            string            `json:"name,omitempty"`
+	Namespace       string            `json:"namespace,omitempty"`
+	UID             string            `json:"uid,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+
+	//CreationTimestamp is when the object was created, zero when the API server did not tell
+	CreationTimestamp time.Time `json:"creationTimestamp,omitempty"`
 }
 
 type TypeMeta struct {
 	Kind string `json:"kind,omitempty"`
 }
 
+//ObjectSpec holds the mirrored fields of the spec of an object
+type ObjectSpec struct {
+	ClusterIP string `json:"clusterIP,omitempty"`
+
+	//Containers and InitContainers are containers of a pod, to complete "kubectl logs -c"
+	Containers     []Container `json:"containers,omitempty"`
+	InitContainers []Container `json:"initContainers,omitempty"`
+
+	//Template is the template of pods of a deployment
+	Template *PodTemplate `json:"template,omitempty"`
+
+	//Ports are ports of a service, to complete "kubectl port-forward svc/NAME"
+	Ports []Port `json:"ports,omitempty"`
+
+	//Unschedulable is set on cordoned nodes
+	Unschedulable bool `json:"unschedulable,omitempty"`
+}
+
+//PodTemplate holds the mirrored fields of the template of pods of a deployment
+type PodTemplate struct {
+	Spec ObjectSpec `json:"spec,omitempty"`
+}
+
+//Container holds the mirrored fields of a container of a pod
+type Container struct {
+	Name  string `json:"name"`
+	Image string `json:"image,omitempty"`
+	Ports []Port `json:"ports,omitempty"`
+}
+
+//Port is a port of a service, given in Port, or of a container, given in ContainerPort
+type Port struct {
+	Name          string `json:"name,omitempty"`
+	Port          int    `json:"port,omitempty"`
+	ContainerPort int    `json:"containerPort,omitempty"`
+}
+
+//allContainers returns containers and init containers of a pod or of the template of pods
+func (s *ObjectSpec) allContainers() []Container {
+	res := append(append([]Container{}, s.Containers...), s.InitContainers...)
+	if s.Template != nil {
+		res = append(res, s.Template.Spec.allContainers()...)
+	}
+	return res
+}
+
+//Images returns distinct images of containers of a pod or of pods of a deployment
+func (o *KubeObject) Images() []string {
+	res := []string{}
+	for _, c := range o.Spec.allContainers() {
+		if c.Image != "" && !containsString(res, c.Image) {
+			res = append(res, c.Image)
+		}
+	}
+	return res
+}
+
+//PortNumbers returns distinct ports of a service, or of containers of a pod or of pods of a deployment, sorted
+func (o *KubeObject) PortNumbers() []int {
+	res := []int{}
+	add := func(port int) {
+		for _, p := range res {
+			if p == port {
+				return
+			}
+		}
+		res = append(res, port)
+	}
+	for _, p := range o.Spec.Ports {
+		add(p.Port)
+	}
+	for _, c := range o.Spec.allContainers() {
+		for _, p := range c.Ports {
+			add(p.ContainerPort)
+		}
+	}
+	sort.Ints(res)
+	return res
+}
+
+//ContainerNames returns names of containers of a pod, followed by names of its init containers
+func (o *KubeObject) ContainerNames() []string {
+	res := []string{}
+	for _, c := range o.Spec.Containers {
+		res = append(res, c.Name)
+	}
+	for _, c := range o.Spec.InitContainers {
+		res = append(res, c.Name)
+	}
+	return res
+}
+
+//ObjectStatus holds the mirrored fields of the status of an object
+type ObjectStatus struct {
+	PodIP string `json:"podIP,omitempty"`
+	Phase string `json:"phase,omitempty"`
+
+	//Conditions are conditions of a node, such as Ready, to complete "kubectl drain" with ready nodes only
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+//Condition is a condition of an object, such as Ready with status True
+type Condition struct {
+	Type   string `json:"type,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+//Ready tells whether new pods can be scheduled to the node: it is not cordoned, and its Ready
+//condition is True. Objects without the Ready condition are ready unless they are cordoned
+func (o *KubeObject) Ready() bool {
+	if o.Spec.Unschedulable {
+		return false
+	}
+	for _, c := range o.Status.Conditions {
+		if c.Type == "Ready" {
+			return c.Status == "True"
+		}
+	}
+	return true
+}
+
 type KubeObject struct {
 	TypeMeta   `json:",inline"`
 	ObjectMeta `json:"metadata,omitempty"`
+	Spec       ObjectSpec   `json:"spec,omitempty"`
+	Status     ObjectStatus `json:"status,omitempty"`
+
+	//Server is the URL of the API server the object is mirrored from. It is set only in answers of the mirror
+	Server string `json:"server,omitempty"`
+}
+
+//IP returns IP of a pod or cluster IP of a service, empty for other objects
+func (o *KubeObject) IP() string {
+	if o.Status.PodIP != "" {
+		return o.Status.PodIP
+	}
+	if o.Spec.ClusterIP != "None" {
+		return o.Spec.ClusterIP
+	}
+	return ""
 }
 
 //KubeServer represents a Kubernetes API server which we ask for information
 type KubeServer struct {
 	URL string
+
+	//Profile is the name of the kubeconfig that the server is watched for, empty for the default one.
+	//Servers watched for several profiles are mirrored separately
+	Profile string
 }
 
 type KubeServers []KubeServer
@@ -34,7 +187,10 @@ func (s KubeServers) Len() int {
 }
 
 func (s KubeServers) Less(i, j int) bool {
-	return s[i].URL < s[j].URL
+	if s[i].URL != s[j].URL {
+		return s[i].URL < s[j].URL
+	}
+	return s[i].Profile < s[j].Profile
 }
 
 func (s KubeServers) Swap(i, j int) {
@@ -55,6 +211,10 @@ type Cluster struct {
 	Server               string `yaml:"server"`
 	SkipVerify           bool   `yaml:"insecure-skip-tls-verify"`
 	CertificateAuthority string `yaml:"certificate-authority"`
+	ProxyURL             string `yaml:"proxy-url,omitempty"`
+
+	//CertificateAuthorityData is base64 encoded PEM of CA certificates. It takes precedence over the file
+	CertificateAuthorityData string `yaml:"certificate-authority-data,omitempty"`
 }
 
 type ClusterWrap struct {
@@ -76,6 +236,14 @@ type ContextWrap struct {
 type User struct {
 	ClientCertificate string `yaml:"client-certificate"`
 	ClientKey         string `yaml:"client-key"`
+	Token             string `yaml:"token,omitempty"`
+	TokenFile         string `yaml:"tokenFile,omitempty"`
+
+	//ClientCertificateData and ClientKeyData are base64 encoded PEM. They take precedence over the files
+	ClientCertificateData string `yaml:"client-certificate-data,omitempty"`
+	ClientKeyData         string `yaml:"client-key-data,omitempty"`
+
+	Exec *ExecConfig `yaml:"exec,omitempty"`
 }
 
 type UserWrap struct {
@@ -93,6 +261,78 @@ func NewConfigFromURL(url string) (*Config, error) {
 	return &config, nil
 }
 
+//serviceAccountDir is where Kubernetes mounts the token and CA certificate of the service account of pods
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+//inClusterName names the cluster, user and context of the in-cluster configuration
+const inClusterName = "in-cluster"
+
+//NewInClusterConfig makes configuration of the cluster that the process runs in,
+//from the environment and the service account that Kubernetes gives to pods
+func NewInClusterConfig() (*Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set, kubemrr is not running in a pod")
+	}
+
+	token := filepath.Join(serviceAccountDir, "token")
+	if _, err := os.Stat(token); err != nil {
+		return nil, fmt.Errorf("cannot use token of the service account: %s", err)
+	}
+
+	config := Config{
+		Clusters: []ClusterWrap{{inClusterName, Cluster{
+			Server:               "https://" + net.JoinHostPort(host, port),
+			CertificateAuthority: filepath.Join(serviceAccountDir, "ca.crt"),
+		}}},
+		Users:          []UserWrap{{inClusterName, User{TokenFile: token}}},
+		Contexts:       []ContextWrap{{inClusterName, Context{Cluster: inClusterName, User: inClusterName}}},
+		CurrentContext: inClusterName,
+	}
+	return &config, nil
+}
+
+//merge adds clusters, contexts and users of the other config that are not defined yet,
+//and its current context when it is not set. Earlier configs win, as in kubectl
+func (c *Config) merge(other Config) {
+	if c.CurrentContext == "" {
+		c.CurrentContext = other.CurrentContext
+	}
+	for _, cluster := range other.Clusters {
+		if !c.hasCluster(cluster.Name) {
+			c.Clusters = append(c.Clusters, cluster)
+		}
+	}
+	for _, context := range other.Contexts {
+		if c.getContext(context.Name) == nil {
+			c.Contexts = append(c.Contexts, context)
+		}
+	}
+	for _, user := range other.Users {
+		if !c.hasUser(user.Name) {
+			c.Users = append(c.Users, user)
+		}
+	}
+}
+
+func (c *Config) hasCluster(name string) bool {
+	for i := range c.Clusters {
+		if c.Clusters[i].Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) hasUser(name string) bool {
+	for i := range c.Users {
+		if c.Users[i].Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Config) makeFilter() MrrFilter {
 	context := c.getCurrentContext()
 	cluster := c.getCluster(context.Cluster)
@@ -125,6 +365,40 @@ func (c *Config) getContext(name string) *Context {
 	return context
 }
 
+//userContext returns the name of the context of the user, and of the cluster when it is not empty.
+//The current context is preferred, so that contexts that differ only by users are told apart.
+//Empty name is returned when no context has the user
+func (c *Config) userContext(user string, cluster string) string {
+	matches := func(ctx Context) bool {
+		return ctx.User == user && (cluster == "" || ctx.Cluster == cluster)
+	}
+	if ctx := c.getContext(c.CurrentContext); ctx != nil && matches(*ctx) {
+		return c.CurrentContext
+	}
+	for i := range c.Contexts {
+		if matches(c.Contexts[i].Context) {
+			return c.Contexts[i].Name
+		}
+	}
+	return ""
+}
+
+//clusterContexts returns one context name for each cluster referenced by contexts,
+//preserving the order in which contexts are defined
+func (c *Config) clusterContexts() []string {
+	res := []string{}
+	seen := map[string]bool{}
+	for i := range c.Contexts {
+		cluster := c.Contexts[i].Context.Cluster
+		if seen[cluster] {
+			continue
+		}
+		seen[cluster] = true
+		res = append(res, c.Contexts[i].Name)
+	}
+	return res
+}
+
 func (c *Config) getCurrentCluster() Cluster {
 	return c.getCluster(c.getCurrentContext().Cluster)
 }
@@ -140,6 +414,46 @@ func (c *Config) getCluster(name string) Cluster {
 	return cluster
 }
 
+//clusterNamesByServer maps normalized URLs of API servers to names of their clusters.
+//When several clusters have the same server, the first one names it
+func (c *Config) clusterNamesByServer() map[string]string {
+	res := map[string]string{}
+	for i := range c.Clusters {
+		u := normalizeServerURL(c.Clusters[i].Cluster.Server)
+		if _, ok := res[u]; !ok {
+			res[u] = c.Clusters[i].Name
+		}
+	}
+	return res
+}
+
+//contextNames returns names of the contexts in the order of the file
+func (c *Config) contextNames() []string {
+	res := []string{}
+	for i := range c.Contexts {
+		res = append(res, c.Contexts[i].Name)
+	}
+	return res
+}
+
+//clusterNames returns names of the clusters in the order of the file
+func (c *Config) clusterNames() []string {
+	res := []string{}
+	for i := range c.Clusters {
+		res = append(res, c.Clusters[i].Name)
+	}
+	return res
+}
+
+//userNames returns names of the users in the order of the file
+func (c *Config) userNames() []string {
+	res := []string{}
+	for i := range c.Users {
+		res = append(res, c.Users[i].Name)
+	}
+	return res
+}
+
 func (c *Config) getUser(name string) User {
 	var user User
 	for i := range c.Users {
@@ -160,7 +474,17 @@ func (cfg *Config) GenerateTLSConfig() (*tls.Config, error) {
 		InsecureSkipVerify: c.SkipVerify,
 	}
 
-	if len(c.CertificateAuthority) > 0 {
+	if len(c.CertificateAuthorityData) > 0 {
+		caCert, err := base64.StdEncoding.DecodeString(c.CertificateAuthorityData)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode certificate-authority-data: %s", err)
+		}
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("unable to parse CA cert of certificate-authority-data")
+		}
+		tlsConfig.RootCAs = caCertPool
+	} else if len(c.CertificateAuthority) > 0 {
 		caCertPool := x509.NewCertPool()
 		caCert, err := ioutil.ReadFile(c.CertificateAuthority)
 		if err != nil {
@@ -173,7 +497,21 @@ func (cfg *Config) GenerateTLSConfig() (*tls.Config, error) {
 		tlsConfig.RootCAs = caCertPool
 	}
 
-	if len(u.ClientCertificate) > 0 && len(u.ClientKey) == 0 {
+	if len(u.ClientCertificateData) > 0 || len(u.ClientKeyData) > 0 {
+		certPEM, err := base64.StdEncoding.DecodeString(u.ClientCertificateData)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode client-certificate-data: %s", err)
+		}
+		keyPEM, err := base64.StdEncoding.DecodeString(u.ClientKeyData)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode client-key-data: %s", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("unable to use client-certificate-data & client-key-data: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else if len(u.ClientCertificate) > 0 && len(u.ClientKey) == 0 {
 		return nil, fmt.Errorf("client cert file %q specified without client key file", u.ClientCertificate)
 	} else if len(u.ClientKey) > 0 && len(u.ClientCertificate) == 0 {
 		return nil, fmt.Errorf("client key file %q specified without client cert file", u.ClientKey)
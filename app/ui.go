@@ -0,0 +1,346 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+func NewUICommand(f Factory) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "ui",
+		Short: "Show content of the mirror in an interactive terminal dashboard",
+		Long: `
+DESCRIPTION:
+  Show content of the "kubemrr watch" process in the terminal.
+
+  The dashboard shows how many objects of each kind are mirrored from each server,
+  when they were last updated, the latest changes and a browser of mirrored objects.
+
+  Keys:
+    Tab, Shift-Tab   switch kind of the browsed objects
+    Up, Down         scroll the browsed objects
+    any text         search the browsed objects by namespace and name
+    Backspace, Esc   edit or clear the search
+    Ctrl-C, Ctrl-D   quit
+
+EXAMPLE:
+  kubemrr -a 0.0.0.0 -p 33033 ui
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := RunCommon(cmd); err != nil {
+				return err
+			}
+			return RunUI(f, cmd, args)
+		},
+	}
+
+	AddCommonFlags(cmd)
+	AddMirrorTLSFlags(cmd)
+	AddTokenFlag(cmd)
+	AddProfileFlag(cmd)
+	AddFallbackFlag(cmd)
+	cmd.Flags().Duration("refresh", time.Second, "Interval between requests to the mirror")
+	return cmd
+}
+
+func RunUI(f Factory, cmd *cobra.Command, args []string) error {
+	refresh, err := cmd.Flags().GetDuration("refresh")
+	if err != nil {
+		return errors.New("could not parse value of --refresh")
+	}
+
+	bind, err := GetMirrorAddress(cmd)
+	if err != nil {
+		return fmt.Errorf("unexpected error: %s", err)
+	}
+
+	profile, err := GetProfile(cmd)
+	if err != nil {
+		return errors.New("could not parse value of --profile")
+	}
+
+	opts, err := GetMrrClientOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	client, err := f.MrrClient(bind, opts)
+	if err != nil {
+		return fmt.Errorf("could not create client to kubemrr: %s", err)
+	}
+
+	restore, err := makeTerminalRaw()
+	if err != nil {
+		return fmt.Errorf("kubemrr ui must be run in a terminal: %s", err)
+	}
+	defer restore()
+
+	out := f.StdOut()
+	fmt.Fprint(out, "\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[H\x1b[2J")
+
+	input := make(chan []byte)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(input)
+				return
+			}
+			input <- append([]byte{}, buf[:n]...)
+		}
+	}()
+
+	s := &uiState{bind: bind, profile: profile}
+	s.update(client)
+	drawUI(out, s)
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case b, ok := <-input:
+			if !ok || s.handleInput(b) {
+				return nil
+			}
+			s.update(client)
+		case <-ticker.C:
+			s.update(client)
+		}
+		drawUI(out, s)
+	}
+}
+
+//uiState is what the dashboard shows
+type uiState struct {
+	bind    string
+	profile string
+	now     time.Time
+	status  MrrStatus
+	kinds   []string
+	kind    int
+	objects []KubeObject
+	search  string
+	offset  int
+	err     error
+}
+
+//update asks the mirror for its status and for objects of the browsed kind
+func (s *uiState) update(client MrrClient) {
+	s.now = time.Now()
+	status, err := client.Status(MrrFilter{Profile: s.profile})
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.status = status
+
+	current := ""
+	if s.kind < len(s.kinds) {
+		current = s.kinds[s.kind]
+	}
+	seen := map[string]bool{}
+	s.kinds = []string{}
+	for _, ss := range status.Servers {
+		for _, ks := range ss.Kinds {
+			if !seen[ks.Kind] {
+				seen[ks.Kind] = true
+				s.kinds = append(s.kinds, ks.Kind)
+			}
+		}
+	}
+	sort.Strings(s.kinds)
+	s.kind = 0
+	for i, k := range s.kinds {
+		if k == current {
+			s.kind = i
+		}
+	}
+
+	s.objects = nil
+	if len(s.kinds) > 0 {
+		s.objects, err = client.Objects(MrrFilter{Profile: s.profile, Kind: s.kinds[s.kind]})
+	}
+	s.err = err
+}
+
+//handleInput changes the state according to the pressed keys and reports whether to quit
+func (s *uiState) handleInput(b []byte) bool {
+	switch {
+	case len(b) == 1 && (b[0] == 3 || b[0] == 4):
+		return true
+	case len(b) == 1 && b[0] == 27:
+		s.search = ""
+		s.offset = 0
+	case bytes.Equal(b, []byte("\x1b[A")):
+		if s.offset > 0 {
+			s.offset--
+		}
+	case bytes.Equal(b, []byte("\x1b[B")):
+		s.offset++
+	case bytes.Equal(b, []byte("\t")):
+		if len(s.kinds) > 0 {
+			s.kind = (s.kind + 1) % len(s.kinds)
+		}
+		s.offset = 0
+	case bytes.Equal(b, []byte("\x1b[Z")):
+		if len(s.kinds) > 0 {
+			s.kind = (s.kind + len(s.kinds) - 1) % len(s.kinds)
+		}
+		s.offset = 0
+	case len(b) == 1 && (b[0] == 127 || b[0] == 8):
+		if len(s.search) > 0 {
+			s.search = s.search[:len(s.search)-1]
+		}
+		s.offset = 0
+	default:
+		for _, c := range b {
+			if c >= 32 && c < 127 {
+				s.search += string(c)
+				s.offset = 0
+			}
+		}
+	}
+	return false
+}
+
+//browsed returns objects matching the search as "namespace/name" lines
+func (s *uiState) browsed() []string {
+	res := []string{}
+	search := strings.ToLower(s.search)
+	for _, o := range s.objects {
+		name := o.Name
+		if o.Namespace != "" {
+			name = o.Namespace + "/" + o.Name
+		}
+		if strings.Contains(strings.ToLower(name), search) {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+//renderUI returns lines of the dashboard that fit into the given size of the terminal
+func renderUI(s *uiState, width int, height int) []string {
+	lines := []string{
+		fmt.Sprintf("kubemrr %s at %s", VERSION, s.bind),
+		"",
+	}
+
+	if s.err != nil {
+		lines = append(lines, fmt.Sprintf("ERROR: %s", s.err), "")
+	}
+
+	lines = append(lines, fmt.Sprintf("%-40s %-14s %8s %8s", "SERVER", "KIND", "OBJECTS", "UPDATED"))
+	for _, ss := range s.status.Servers {
+		for _, ks := range ss.Kinds {
+			updated := "never"
+			if !ks.Updated.IsZero() {
+				updated = formatAge(s.now.Sub(ks.Updated))
+			}
+			lines = append(lines, fmt.Sprintf("%-40s %-14s %8d %8s", ss.Server, ks.Kind, ks.Objects, updated))
+		}
+	}
+
+	lines = append(lines, "", "LATEST CHANGES")
+	for i, e := range s.status.Events {
+		if i == 5 {
+			break
+		}
+		name := e.Name
+		if e.Namespace != "" {
+			name = e.Namespace + "/" + e.Name
+		}
+		lines = append(lines, fmt.Sprintf("%8s %-9s %-14s %s (%s)", formatAge(s.now.Sub(e.Time)), e.Type, e.Kind, name, e.Server))
+	}
+
+	kind := ""
+	if s.kind < len(s.kinds) {
+		kind = s.kinds[s.kind]
+	}
+	browsed := s.browsed()
+	lines = append(lines, "", fmt.Sprintf("OBJECTS [%s] %d found, search: %s_", kind, len(browsed), s.search))
+
+	rows := height - len(lines)
+	if s.offset > len(browsed)-rows {
+		s.offset = len(browsed) - rows
+	}
+	if s.offset < 0 {
+		s.offset = 0
+	}
+	for i := s.offset; i < len(browsed) && i < s.offset+rows; i++ {
+		lines = append(lines, browsed[i])
+	}
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for i := range lines {
+		if len(lines[i]) > width {
+			lines[i] = lines[i][:width]
+		}
+	}
+	return lines
+}
+
+func drawUI(out io.Writer, s *uiState) {
+	width, height := terminalSize()
+	lines := renderUI(s, width, height)
+	fmt.Fprint(out, "\x1b[H\x1b[2J"+strings.Join(lines, "\r\n"))
+}
+
+//formatAge prints duration the way kubectl prints age of objects
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+//makeTerminalRaw switches the terminal to raw mode and returns the function that restores it
+func makeTerminalRaw() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+func terminalSize() (int, int) {
+	var width, height int
+	size, err := stty("size")
+	if err == nil {
+		_, err = fmt.Sscan(size, &height, &width)
+	}
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
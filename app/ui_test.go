@@ -0,0 +1,93 @@
+package app
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestUIStateUpdate(t *testing.T) {
+	tc := &TestMirrorClient{
+		objects: []KubeObject{
+			{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "web", Namespace: "ns1"}},
+			{TypeMeta: TypeMeta{"service"}, ObjectMeta: ObjectMeta{Name: "db", Namespace: "ns2"}},
+		},
+		status: MrrStatus{
+			Servers: []ServerStatus{
+				{Server: "https://foo.com", Kinds: []KindStatus{{Kind: "pod"}, {Kind: "service"}}},
+				{Server: "https://bar.com", Kinds: []KindStatus{{Kind: "node"}}},
+			},
+		},
+	}
+
+	s := &uiState{}
+	s.update(tc)
+	assert.Equal(t, []string{"node", "pod", "service"}, s.kinds)
+	assert.Equal(t, MrrFilter{Kind: "node"}, tc.lastFilter)
+
+	s.handleInput([]byte("\t"))
+	s.handleInput([]byte("\t"))
+	s.update(tc)
+	assert.Equal(t, MrrFilter{Kind: "service"}, tc.lastFilter, "must keep the browsed kind")
+
+	s.handleInput([]byte("w"))
+	s.handleInput([]byte("E"))
+	assert.Equal(t, []string{"ns1/web"}, s.browsed())
+	s.handleInput([]byte{127})
+	assert.Equal(t, "w", s.search)
+	s.handleInput([]byte{27})
+	assert.Equal(t, []string{"ns1/web", "ns2/db"}, s.browsed())
+
+	assert.True(t, s.handleInput([]byte{3}), "must quit on Ctrl-C")
+}
+
+func TestRenderUI(t *testing.T) {
+	now := time.Now()
+	s := &uiState{
+		bind: "127.0.0.1:33033",
+		now:  now,
+		status: MrrStatus{
+			Servers: []ServerStatus{
+				{Server: "https://foo.com", Kinds: []KindStatus{{Kind: "pod", Objects: 2, Updated: now.Add(-5 * time.Second)}, {Kind: "node", Objects: 0, Updated: time.Time{}}}},
+			},
+			Events: []CacheEvent{
+				{Time: now.Add(-3 * time.Minute), Server: "https://foo.com", Type: Added, Kind: "pod", Namespace: "ns", Name: "web"},
+			},
+		},
+		kinds: []string{"pod"},
+	}
+	for i := 0; i < 20; i++ {
+		s.objects = append(s.objects, KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: fmt.Sprintf("pod-%02d", i), Namespace: "ns"}})
+	}
+
+	lines := renderUI(s, 80, 16)
+	assert.Equal(t, 16, len(lines))
+	assert.Equal(t, "https://foo.com                          pod                   2       5s", lines[3])
+	assert.Equal(t, "https://foo.com                          node                  0    never", lines[4])
+	assert.Equal(t, "      3m ADDED     pod            ns/web (https://foo.com)", lines[7])
+	assert.Equal(t, "OBJECTS [pod] 20 found, search: _", lines[9])
+	assert.Equal(t, "ns/pod-00", lines[10])
+
+	s.offset = 100
+	lines = renderUI(s, 80, 16)
+	assert.Equal(t, "ns/pod-19", lines[15], "must not scroll past the last object")
+	assert.Equal(t, 14, s.offset)
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{3 * time.Second, "3s"},
+		{90 * time.Second, "1m"},
+		{3 * time.Hour, "3h"},
+		{47 * time.Hour, "47h"},
+		{72 * time.Hour, "3d"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, formatAge(test.d))
+	}
+}
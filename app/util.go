@@ -1,8 +1,11 @@
 package app
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"gopkg.in/yaml.v2"
 	"io"
 	"io/ioutil"
@@ -11,28 +14,64 @@ import (
 	"net/rpc"
 	"net/url"
 	"os"
+	"os/exec"
 	"os/user"
 	"path"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 func AddCommonFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("address", "a", "127.0.0.1", "The IP address where mirror is accessible")
 	cmd.Flags().String("kubeconfig", "~/.kube/config", "Path to the kubeconfig file")
+	cmd.Flags().String("config", "~/.kubemrr.yaml", "Path to the kubemrr configuration file, which sets flags that are not given")
 	cmd.Flags().IntP("port", "p", 33033, "The port on which mirror is accessible")
+	cmd.Flags().String("bind", "", "Address of the mirror as host:port or unix:///path/to/socket, overrides --address and --port")
 	cmd.Flags().BoolP("verbose", "v", false, "Enables verbose output")
+	addFeatureGatesFlag(cmd)
 }
 
-func RunCommon(cmd *cobra.Command) error {
+//RunCommon applies flags common to all commands. Flags that are not given on the command line
+//are set from the environment first, then from the --config file, which is returned
+func RunCommon(cmd *cobra.Command) (*MrrConfig, error) {
+	if err := applyEnv(cmd); err != nil {
+		return nil, err
+	}
+	config, err := applyMrrConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
 	isVerbose, err := cmd.Flags().GetBool("verbose")
 	if err != nil {
-		return err
+		return nil, err
 	} else if isVerbose {
 		enableDebug()
 	}
-	return nil
+	if cmd.Flags().Lookup("log-level") != nil {
+		level, err := cmd.Flags().GetString("log-level")
+		if err != nil {
+			return nil, err
+		}
+		if err := setLogLevel(level); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := GetFeatureGates(cmd); err != nil {
+		return nil, err
+	}
+	return config, nil
 }
 
 func GetBind(cmd *cobra.Command) (string, error) {
+	bind, err := cmd.Flags().GetString("bind")
+	if err != nil {
+		return "", err
+	}
+	if bind != "" {
+		return bind, nil
+	}
+
 	address, err := cmd.Flags().GetString("address")
 	if err != nil {
 		return "", err
@@ -46,6 +85,125 @@ func GetBind(cmd *cobra.Command) (string, error) {
 	return fmt.Sprintf("%s:%d", address, port), nil
 }
 
+//unixScheme prefixes addresses of mirrors that listen on unix domain sockets
+const unixScheme = "unix://"
+
+//mirrorNetwork returns the network and the address to listen on or to dial for the address of a mirror
+func mirrorNetwork(address string) (string, string) {
+	if strings.HasPrefix(address, unixScheme) {
+		return "unix", strings.TrimPrefix(address, unixScheme)
+	}
+	return "tcp", address
+}
+
+//AddFallbackFlag adds the flag of mirrors to ask when the mirror at --address and --port does not answer
+func AddFallbackFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("fallback", nil, "Coma-separated addresses of mirrors as host:port, asked in order when the mirror at --address does not answer")
+}
+
+//GetMirrorAddress returns the address of the mirror followed by addresses of fallback mirrors, separated by commas
+func GetMirrorAddress(cmd *cobra.Command) (string, error) {
+	bind, err := GetBind(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	fallback, err := cmd.Flags().GetStringSlice("fallback")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(append([]string{bind}, fallback...), ","), nil
+}
+
+//AddMirrorTLSFlags adds flags of clients that connect to mirrors serving TLS
+func AddMirrorTLSFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("tls", false, "Connect to the mirror with TLS, verifying its certificate with the system certificate authorities")
+	cmd.Flags().String("tls-ca", "", "Path to the certificate authority that signed the certificate of the mirror, implies --tls")
+}
+
+//tokenEnv is the environment variable with the token of the mirror, used when --token is not given
+const tokenEnv = "KUBEMRR_TOKEN"
+
+func AddLogLevelFlag(cmd *cobra.Command) {
+	cmd.Flags().String("log-level", "", "Level of logs, one of: debug, info, warn, error. By default info, or debug with --verbose")
+}
+
+//AddTokenFlag adds the flag of the secret shared by the mirror and its clients
+func AddTokenFlag(cmd *cobra.Command) {
+	cmd.Flags().String("token", "", "Secret that clients must give to the mirror, by default taken from $"+tokenEnv)
+}
+
+//GetToken returns the secret shared by the mirror and its clients, empty if there is no secret
+func GetToken(cmd *cobra.Command) (string, error) {
+	token, err := cmd.Flags().GetString("token")
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		token = os.Getenv(tokenEnv)
+	}
+	return token, nil
+}
+
+const profileEnv = "KUBEMRR_PROFILE"
+
+//AddProfileFlag adds the flag of the kubeconfig profile that queries of clients are scoped to
+func AddProfileFlag(cmd *cobra.Command) {
+	cmd.Flags().String("profile", "", "Name of the kubeconfig profile of the mirror to query, by default taken from $"+profileEnv+". Empty queries all profiles")
+}
+
+//GetProfile returns the kubeconfig profile that queries are scoped to, empty for all profiles
+func GetProfile(cmd *cobra.Command) (string, error) {
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return "", err
+	}
+	if profile == "" {
+		profile = os.Getenv(profileEnv)
+	}
+	return profile, nil
+}
+
+//GetMrrClientOptions returns options of clients of mirrors given by flags
+func GetMrrClientOptions(cmd *cobra.Command) (MrrClientOptions, error) {
+	opts := MrrClientOptions{}
+	token, err := GetToken(cmd)
+	if err != nil {
+		return opts, err
+	}
+	opts.Token = token
+
+	useTLS, err := cmd.Flags().GetBool("tls")
+	if err != nil {
+		return opts, err
+	}
+	ca, err := cmd.Flags().GetString("tls-ca")
+	if err != nil {
+		return opts, err
+	}
+	if !useTLS && ca == "" {
+		return opts, nil
+	}
+
+	opts.TLS = &tls.Config{}
+	if ca != "" {
+		file, err := substituteUserHome(ca)
+		if err != nil {
+			return opts, err
+		}
+		pem, err := ioutil.ReadFile(file)
+		if err != nil {
+			return opts, fmt.Errorf("could not read certificate authority of the mirror: %s", err)
+		}
+		opts.TLS.RootCAs = x509.NewCertPool()
+		if !opts.TLS.RootCAs.AppendCertsFromPEM(pem) {
+			return opts, fmt.Errorf("no certificates found in %s", ca)
+		}
+	}
+	return opts, nil
+}
+
 func GetKubeconfig(cmd *cobra.Command) (*Config, error) {
 	file, err := cmd.Flags().GetString("kubeconfig")
 	if err != nil {
@@ -60,13 +218,93 @@ func GetKubeconfig(cmd *cobra.Command) (*Config, error) {
 	return &config, nil
 }
 
+func GetMrrConfig(cmd *cobra.Command) (*MrrConfig, error) {
+	file, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := parseMrrConfig(file, cmd.Flags().Changed("config"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+//envPrefix prefixes environment variables that set flags, such as KUBEMRR_PORT for --port
+const envPrefix = "KUBEMRR_"
+
+//flagEnv returns the environment variable that sets the flag, such as KUBEMRR_LOG_LEVEL for --log-level
+func flagEnv(name string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+//envExceptions are flags of commands that are not set from the environment,
+//because their variables mean something else or describe a single query
+var envExceptions = map[string]map[string]bool{
+	//$KUBEMRR_PROFILE is the profile that clients query, while watch takes profiles as name=path
+	"watch": {"profile": true},
+	//a selector of the mirror, such as $KUBEMRR_SELECTOR of watch, must not filter what get prints.
+	//$KUBEMRR_PROFILE is read by GetProfile itself
+	"get": {
+		"selector":      true,
+		"output":        true,
+		"prefix":        true,
+		"kubectl-flags": true,
+		"comp-line":     true,
+		"profile":       true,
+	},
+}
+
+//applyEnv sets flags that are not given on the command line to values of their environment variables
+func applyEnv(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "help" || envExceptions[cmd.Name()][flag.Name] {
+			return
+		}
+		value, ok := os.LookupEnv(flagEnv(flag.Name))
+		if !ok {
+			return
+		}
+		if e := cmd.Flags().Set(flag.Name, value); e != nil {
+			err = fmt.Errorf("invalid $%s: %s", flagEnv(flag.Name), e)
+		}
+	})
+	return err
+}
+
+//applyMrrConfig sets flags that are not given on the command line to values of the --config file,
+//and returns the file. Commands without --config have no file
+func applyMrrConfig(cmd *cobra.Command) (*MrrConfig, error) {
+	if cmd.Flags().Lookup("config") == nil {
+		return nil, nil
+	}
+	config, err := GetMrrConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range config.flagValues(cmd.Name()) {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return nil, fmt.Errorf("invalid %s in the config file: %s", name, err)
+		}
+	}
+	return config, nil
+}
+
 type Factory interface {
-	KubeClient(config *Config) KubeClient
-	MrrClient(bind string) (MrrClient, error)
+	KubeClient(config *Config, opts KubeClientOptions) KubeClient
+	MrrClient(bind string, opts MrrClientOptions) (MrrClient, error)
 	MrrCache() *MrrCache
-	Serve(l net.Listener, c *MrrCache) error
+	Serve(l net.Listener, c *MrrCache, opts MrrServerOptions) error
 	HomeKubeconfig() (Config, error)
 	StdOut() io.Writer
+	StartMirror(args []string) error
 }
 
 type DefaultFactory struct {
@@ -81,8 +319,13 @@ func NewFactory(stdOut io.Writer, kubeconfig *Config) Factory {
 	}
 }
 
-func (f *DefaultFactory) MrrClient(address string) (MrrClient, error) {
-	return NewMrrClient(address)
+//MrrClient returns client of the mirror at the address. Several addresses separated by commas
+//are asked in order, until one of the mirrors answers
+func (f *DefaultFactory) MrrClient(address string, opts MrrClientOptions) (MrrClient, error) {
+	if addresses := strings.Split(address, ","); len(addresses) > 1 {
+		return NewMrrClientFailover(addresses, opts), nil
+	}
+	return NewMrrClient(address, opts)
 }
 
 func (f *DefaultFactory) StdOut() io.Writer {
@@ -97,20 +340,59 @@ func (f *DefaultFactory) MrrCache() *MrrCache {
 	return NewMrrCache()
 }
 
-func (f *DefaultFactory) KubeClient(config *Config) KubeClient {
-	return NewKubeClient(config)
+func (f *DefaultFactory) KubeClient(config *Config, opts KubeClientOptions) KubeClient {
+	return NewKubeClient(config, opts)
 }
 
-func (f *DefaultFactory) Serve(l net.Listener, cache *MrrCache) error {
+func (f *DefaultFactory) Serve(l net.Listener, cache *MrrCache, opts MrrServerOptions) error {
 	rpc.Register(cache)
-	rpc.HandleHTTP()
-	return http.Serve(l, nil)
+	http.Handle(rpc.DefaultRPCPath, rpcHandler(rpc.DefaultServer))
+	http.Handle(metricsPath, metricsHandler(cache))
+	http.Handle(watchersPath, watchersHandler(cache))
+	if opts.Features.Enabled(AdminAPI) {
+		http.Handle(flushPath, flushHandler(cache))
+	}
+	if opts.Features.Enabled(Subscriptions) {
+		http.Handle(subscribePath, subscribeHandler(cache))
+	}
+	if opts.Features.Enabled(HTTPObjects) {
+		http.Handle(objectsPath, objectsHandler(cache))
+	}
+	return serveUntilShutdown(l, cache, requireToken(opts.Token, http.DefaultServeMux), opts)
 }
 
+//StartMirror runs "kubemrr watch --daemon" with the given arguments and waits until the mirror runs
+func (f *DefaultFactory) StartMirror(args []string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(self, append([]string{"watch", "--daemon"}, args...)...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(autoStartTimeout):
+		cmd.Process.Kill()
+		return fmt.Errorf("mirror did not start in %s", autoStartTimeout)
+	}
+}
+
+//kubeconfigEnv lists kubeconfig files to merge, separated as in $PATH, like kubectl reads them
+const kubeconfigEnv = "KUBECONFIG"
+
 func (f *DefaultFactory) HomeKubeconfig() (Config, error) {
 	if f.kubeconfig != nil {
 		return *f.kubeconfig, nil
 	}
+	if paths := os.Getenv(kubeconfigEnv); paths != "" {
+		return parseKubeConfigs(filepath.SplitList(paths))
+	}
 
 	usr, err := user.Current()
 	if err != nil {
@@ -138,12 +420,46 @@ func parseKubeConfig(filename string) (Config, error) {
 	return res, nil
 }
 
+//parseKubeConfigs merges kubeconfig files the way kubectl does. The first file that defines a cluster,
+//a context, a user or the current context wins. Missing files are skipped, but at least one must exist
+func parseKubeConfigs(filenames []string) (Config, error) {
+	res := Config{}
+	found := false
+	for _, filename := range filenames {
+		if filename == "" {
+			continue
+		}
+		fnResolved, err := substituteUserHome(filename)
+		if err != nil {
+			return res, fmt.Errorf("could not substitute ~ in file %s: %s", filename, err)
+		}
+		if _, err := os.Stat(fnResolved); os.IsNotExist(err) {
+			continue
+		}
+
+		config, err := parseKubeConfig(filename)
+		if err != nil {
+			return res, err
+		}
+		res.merge(config)
+		found = true
+	}
+	if !found {
+		return res, fmt.Errorf("none of kubeconfig files %s exists", strings.Join(filenames, ", "))
+	}
+	return res, nil
+}
+
 type TestFactory struct {
 	mrrClient   MrrClient
 	mrrCache    *MrrCache
 	kubeClients map[string]*TestKubeClient
 	kubeconfig  Config
 	stdOut      io.Writer
+
+	//mrrClientErr is returned by MrrClient until the mirror is started by StartMirror
+	mrrClientErr  error
+	mirrorStarted []string
 }
 
 func NewTestFactory() *TestFactory {
@@ -153,10 +469,19 @@ func NewTestFactory() *TestFactory {
 	}
 }
 
-func (f *TestFactory) MrrClient(address string) (MrrClient, error) {
+func (f *TestFactory) MrrClient(address string, opts MrrClientOptions) (MrrClient, error) {
+	if f.mrrClientErr != nil {
+		return nil, f.mrrClientErr
+	}
 	return f.mrrClient, nil
 }
 
+func (f *TestFactory) StartMirror(args []string) error {
+	f.mirrorStarted = args
+	f.mrrClientErr = nil
+	return nil
+}
+
 func (f *TestFactory) StdOut() io.Writer {
 	if f.stdOut == nil {
 		return os.Stdout
@@ -169,7 +494,7 @@ func (f *TestFactory) MrrCache() *MrrCache {
 	return f.mrrCache
 }
 
-func (f *TestFactory) Serve(l net.Listener, cache *MrrCache) error {
+func (f *TestFactory) Serve(l net.Listener, cache *MrrCache, opts MrrServerOptions) error {
 	return nil
 }
 
@@ -177,13 +502,18 @@ func (f *TestFactory) HomeKubeconfig() (Config, error) {
 	return f.kubeconfig, nil
 }
 
-func (f *TestFactory) KubeClient(config *Config) KubeClient {
+func (f *TestFactory) KubeClient(config *Config, opts KubeClientOptions) KubeClient {
 	url, _ := url.Parse(config.getCurrentCluster().Server)
-	kc, ok := f.kubeClients[url.String()]
-	if !ok {
+	key := url.String()
+	if opts.Profile != "" {
+		key = opts.Profile + "/" + key
+	}
+	kc, ok := f.kubeClients[key]
+	if !ok || kc.isClosed() {
 		kc = NewTestKubeClient()
 		kc.baseURL = url
-		f.kubeClients[url.String()] = kc
+		kc.profile = opts.Profile
+		f.kubeClients[key] = kc
 	}
 	return kc
 }
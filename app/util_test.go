@@ -1,7 +1,10 @@
 package app
 
 import (
+	"encoding/base64"
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"os"
 	"os/user"
 	"path"
@@ -55,8 +58,8 @@ func TestParseKubeConfig(t *testing.T) {
 			{"cluster_2", Cluster{Server: "https://bar.com", CertificateAuthority: "ca2", SkipVerify: true}},
 		},
 		Users: []UserWrap{
-			{"user_1", User{"cert1", "key1"}},
-			{"user_2", User{"cert2", "key2"}},
+			{"user_1", User{ClientCertificate: "cert1", ClientKey: "key1"}},
+			{"user_2", User{ClientCertificate: "cert2", ClientKey: "key2"}},
 		},
 	}
 
@@ -81,12 +84,74 @@ func TestConfigMakeFilter(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestConfigClusterContexts(t *testing.T) {
+	conf := Config{
+		Contexts: []ContextWrap{
+			{"dev", Context{Cluster: "cluster_2"}},
+			{"prod", Context{Cluster: "cluster_1"}},
+			{"dev-admin", Context{Cluster: "cluster_2", User: "admin"}},
+		},
+	}
+
+	assert.Equal(t, []string{"dev", "prod"}, conf.clusterContexts())
+}
+
+func TestParseKubeConfigs(t *testing.T) {
+	conf, err := parseKubeConfigs([]string{"test_data/kubeconfig_missing", "test_data/kubeconfig_valid", "", "test_data/kubeconfig_other"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "prod", conf.CurrentContext, "the first current context wins")
+	assert.Equal(t, []string{"dev", "prod", "staging"}, conf.contextNames())
+	assert.Equal(t, []string{"cluster_1", "cluster_2", "cluster_3"}, conf.clusterNames())
+	assert.Equal(t, []string{"user_1", "user_2", "user_3"}, conf.userNames())
+	assert.Equal(t, MrrFilter{Server: "https://foo.com", Namespace: "blue"}, conf.makeFilter(), "the first definitions win")
+
+	conf.CurrentContext = "staging"
+	assert.Equal(t, MrrFilter{Server: "https://baz.com", Namespace: "green"}, conf.makeFilter())
+
+	_, err = parseKubeConfigs([]string{"test_data/kubeconfig_missing"})
+	assert.Error(t, err, "at least one file must exist")
+	_, err = parseKubeConfigs([]string{"test_data/kubeconfig_valid", "test_data/kubeconfig_invalid"})
+	assert.Error(t, err)
+}
+
+func TestHomeKubeconfigEnv(t *testing.T) {
+	defer os.Setenv(kubeconfigEnv, os.Getenv(kubeconfigEnv))
+	os.Setenv(kubeconfigEnv, "test_data/kubeconfig_other"+string(os.PathListSeparator)+"test_data/kubeconfig_valid")
+
+	conf, err := (&DefaultFactory{}).HomeKubeconfig()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "staging", conf.CurrentContext)
+		assert.Equal(t, []string{"prod", "staging", "dev"}, conf.contextNames())
+	}
+}
+
+func TestConfigUserContext(t *testing.T) {
+	conf := Config{
+		CurrentContext: "team",
+		Contexts: []ContextWrap{
+			{"admin", Context{Cluster: "prod", User: "admin", Namespace: "kube-system"}},
+			{"team", Context{Cluster: "prod", User: "dev", Namespace: "checkout"}},
+			{"staging-admin", Context{Cluster: "staging", User: "admin"}},
+			{"staging-team", Context{Cluster: "staging", User: "dev"}},
+		},
+	}
+
+	assert.Equal(t, "admin", conf.userContext("admin", ""), "the first context of the user")
+	assert.Equal(t, "staging-admin", conf.userContext("admin", "staging"))
+	assert.Equal(t, "team", conf.userContext("dev", ""), "the current context is preferred")
+	assert.Equal(t, "staging-team", conf.userContext("dev", "staging"))
+	assert.Equal(t, "", conf.userContext("nobody", ""))
+}
+
 func TestConfigMakeTLSConfig(t *testing.T) {
 	cfg := Config{
 		CurrentContext: "x",
 		Contexts:       []ContextWrap{{"x", Context{Cluster: "cluster", User: "user"}}},
 		Clusters:       []ClusterWrap{{"cluster", Cluster{CertificateAuthority: "test_data/ca.pem", SkipVerify: true}}},
-		Users:          []UserWrap{{"user", User{"test_data/cert.pem", "test_data/key.pem"}}},
+		Users:          []UserWrap{{"user", User{ClientCertificate: "test_data/cert.pem", ClientKey: "test_data/key.pem"}}},
 	}
 
 	tls, err := cfg.GenerateTLSConfig()
@@ -97,6 +162,33 @@ func TestConfigMakeTLSConfig(t *testing.T) {
 	assert.Equal(t, true, tls.InsecureSkipVerify)
 }
 
+func TestConfigMakeTLSConfigData(t *testing.T) {
+	encode := func(file string) string {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(raw)
+	}
+
+	cfg := Config{
+		CurrentContext: "x",
+		Contexts:       []ContextWrap{{"x", Context{Cluster: "cluster", User: "user"}}},
+		Clusters:       []ClusterWrap{{"cluster", Cluster{CertificateAuthority: "missing.pem", CertificateAuthorityData: encode("test_data/ca.pem")}}},
+		Users:          []UserWrap{{"user", User{ClientCertificateData: encode("test_data/cert.pem"), ClientKeyData: encode("test_data/key.pem")}}},
+	}
+
+	tls, err := cfg.GenerateTLSConfig()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, len(tls.RootCAs.Subjects()), "data must take precedence over the file")
+		assert.Equal(t, 1, len(tls.Certificates))
+	}
+
+	cfg.Clusters[0].Cluster.CertificateAuthorityData = "not base64"
+	_, err = cfg.GenerateTLSConfig()
+	assert.Error(t, err)
+}
+
 //Copyright 2014 The Kubernetes Authors.
 func TestSubstituteUserHome(t *testing.T) {
 	usr, err := user.Current()
@@ -124,3 +216,130 @@ func TestSubstituteUserHome(t *testing.T) {
 		assert.Equal(t, test.expected, output)
 	}
 }
+
+func TestGetBind(t *testing.T) {
+	tests := []struct {
+		flags    map[string]string
+		expected string
+	}{
+		{flags: map[string]string{}, expected: "127.0.0.1:33033"},
+		{flags: map[string]string{"address": "10.0.0.1", "port": "1234"}, expected: "10.0.0.1:1234"},
+		{flags: map[string]string{"address": "10.0.0.1", "bind": "unix:///run/kubemrr.sock"}, expected: "unix:///run/kubemrr.sock"},
+	}
+
+	for _, test := range tests {
+		cmd := &cobra.Command{}
+		AddCommonFlags(cmd)
+		for k, v := range test.flags {
+			cmd.Flags().Set(k, v)
+		}
+		actual, err := GetBind(cmd)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, actual)
+	}
+
+	network, address := mirrorNetwork("unix:///run/kubemrr.sock")
+	assert.Equal(t, "unix", network)
+	assert.Equal(t, "/run/kubemrr.sock", address)
+	network, address = mirrorNetwork("localhost:33033")
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "localhost:33033", address)
+}
+
+func TestGetToken(t *testing.T) {
+	defer os.Unsetenv(tokenEnv)
+	os.Setenv(tokenEnv, "from-env")
+
+	cmd := &cobra.Command{}
+	AddTokenFlag(cmd)
+	token, err := GetToken(cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, "from-env", token)
+
+	cmd.Flags().Set("token", "from-flag")
+	token, err = GetToken(cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, "from-flag", token)
+}
+
+func TestGetProfile(t *testing.T) {
+	defer os.Unsetenv(profileEnv)
+	os.Setenv(profileEnv, "alice")
+
+	cmd := &cobra.Command{}
+	AddProfileFlag(cmd)
+	profile, err := GetProfile(cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", profile)
+
+	cmd.Flags().Set("profile", "bob")
+	profile, err = GetProfile(cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", profile)
+}
+
+func TestRunCommonEnv(t *testing.T) {
+	env := map[string]string{
+		"KUBEMRR_CONFIG":    "test_data/kubemrr_config_flags",
+		"KUBEMRR_PORT":      "4000",
+		"KUBEMRR_ADDRESS":   "10.0.0.1",
+		"KUBEMRR_LOG_LEVEL": "error",
+		"KUBEMRR_PROFILE":   "alice",
+		"KUBEMRR_SELECTOR":  "app=web",
+	}
+	for name, value := range env {
+		defer os.Unsetenv(name)
+		os.Setenv(name, value)
+	}
+	defer enableDebug()
+
+	watch := NewWatchCommand(&TestFactory{})
+	watch.Flags().Set("address", "127.0.0.2")
+	config, err := RunCommon(watch)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"prod", "dev"}, config.Contexts)
+		expected := map[string]string{
+			"address":    "127.0.0.2",
+			"port":       "4000",
+			"kubeconfig": "test_data/kubeconfig_valid",
+			"selector":   "app=web",
+			"interval":   "30s",
+			"log-level":  "error",
+			"profile":    "[]",
+		}
+		for name, value := range expected {
+			assert.Equal(t, value, watch.Flags().Lookup(name).Value.String(), "flag %s of watch", name)
+		}
+	}
+
+	os.Setenv("KUBEMRR_OUTPUT", "json")
+	os.Setenv("KUBEMRR_PREFIX", "web")
+	os.Setenv("KUBEMRR_KUBECTL_FLAGS", "get pods")
+	os.Setenv("KUBEMRR_COMP_LINE", "true")
+	defer os.Unsetenv("KUBEMRR_OUTPUT")
+	defer os.Unsetenv("KUBEMRR_PREFIX")
+	defer os.Unsetenv("KUBEMRR_KUBECTL_FLAGS")
+	defer os.Unsetenv("KUBEMRR_COMP_LINE")
+	get := NewGetCommand(&TestFactory{})
+	if _, err := RunCommon(get); assert.NoError(t, err) {
+		assert.Equal(t, "4000", get.Flags().Lookup("port").Value.String())
+		for _, name := range []string{"selector", "output", "prefix", "kubectl-flags", "comp-line", "profile"} {
+			flag := get.Flags().Lookup(name)
+			assert.Equal(t, flag.DefValue, flag.Value.String(), "flag %s of get", name)
+			assert.False(t, flag.Changed, "flag %s of get", name)
+		}
+		profile, _ := GetProfile(get)
+		assert.Equal(t, "alice", profile)
+	}
+
+	os.Setenv("KUBEMRR_PORT", "http")
+	_, err = RunCommon(NewGetCommand(&TestFactory{}))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "$KUBEMRR_PORT")
+	}
+}
+
+func TestFlagEnv(t *testing.T) {
+	assert.Equal(t, "KUBEMRR_PORT", flagEnv("port"))
+	assert.Equal(t, "KUBEMRR_LOG_MAX_SIZE", flagEnv("log-max-size"))
+}
@@ -3,20 +3,52 @@ package app
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"runtime"
 )
 
 const (
 	VERSION = "1.3.0"
 )
 
+//gitCommit and buildDate are set at link time by the Makefile, for example
+//-ldflags "-X github.com/mkokho/kubemrr/app.gitCommit=abc1234"
+var (
+	gitCommit = "unknown"
+	buildDate = "unknown"
+)
+
+//buildInfo describes the build for bug reports
+func buildInfo() string {
+	return fmt.Sprintf("kubemrr-%s\ncommit: %s\nbuilt: %s\ngo: %s\nos: %s/%s\n",
+		VERSION, gitCommit, buildDate, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
 func NewVersionCommand(f Factory) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "version",
 		Short: "Print version",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Fprintf(f.StdOut(), "kubemrr-%s\n", VERSION)
+		Long: `
+DESCRIPTION:
+  Prints the version of kubemrr, the git commit and the date it was built from, the version
+  of Go and the state of feature gates. Please include it in bug reports.
+  With --short only the version is printed, such as 1.3.0.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gates, err := GetFeatureGates(cmd)
+			if err != nil {
+				return err
+			}
+			if short, _ := cmd.Flags().GetBool("short"); short {
+				fmt.Fprintln(f.StdOut(), VERSION)
+				return nil
+			}
+			fmt.Fprint(f.StdOut(), buildInfo())
+			fmt.Fprintf(f.StdOut(), "feature gates: %s\n", gates)
+			return nil
 		},
 	}
 
+	addFeatureGatesFlag(cmd)
+	cmd.Flags().Bool("short", false, "Print only the version")
 	return cmd
 }
@@ -2,6 +2,7 @@ package app
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -9,10 +10,50 @@ func TestRunVersion(t *testing.T) {
 	buf := bytes.NewBuffer([]byte{})
 	f := &TestFactory{stdOut: buf}
 	cmd := NewVersionCommand(f)
-	cmd.Run(cmd, []string{})
+	err := cmd.RunE(cmd, []string{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 
-	expected := "kubemrr-" + VERSION + "\n"
+	expected := buildInfo() + "feature gates: AdminAPI=false (Alpha), HTTPObjects=true (Beta), NameCompression=true (Beta), SnapshotPersistence=false (Alpha), Subscriptions=false (Alpha)\n"
 	if buf.String() != expected {
 		t.Errorf("Expected verion %s, got %s", expected, buf.String())
 	}
 }
+
+func TestRunVersionFeatureGates(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	f := &TestFactory{stdOut: buf}
+	cmd := NewVersionCommand(f)
+	cmd.Flags().Set("feature-gates", "NameCompression=false")
+	err := cmd.RunE(cmd, []string{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := buildInfo() + "feature gates: AdminAPI=false (Alpha), HTTPObjects=true (Beta), NameCompression=false (Beta), SnapshotPersistence=false (Alpha), Subscriptions=false (Alpha)\n"
+	if buf.String() != expected {
+		t.Errorf("Expected verion %s, got %s", expected, buf.String())
+	}
+}
+
+func TestRunVersionShort(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	cmd := NewVersionCommand(&TestFactory{stdOut: buf})
+	cmd.Flags().Set("short", "true")
+	if err := cmd.RunE(cmd, []string{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if buf.String() != VERSION+"\n" {
+		t.Errorf("Expected version %s, got %s", VERSION, buf.String())
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	defer func(commit, date string) { gitCommit, buildDate = commit, date }(gitCommit, buildDate)
+	gitCommit, buildDate = "abc1234", "2017-05-01T12:00:00Z"
+	info := buildInfo()
+	if !strings.Contains(info, "commit: abc1234\n") || !strings.Contains(info, "built: 2017-05-01T12:00:00Z\n") {
+		t.Errorf("Expected commit and build date, got %s", info)
+	}
+}
@@ -1,13 +1,17 @@
 package app
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/asaskevich/govalidator"
 	"github.com/spf13/cobra"
 	"net"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -29,38 +33,162 @@ DESCRIPTION:
   By default, "get pod" returns pods from all servers and all namespaces.
   See help for "get" command to know how to filter.
 
+  Selectors, filters and API paths of each cluster are set in the --config file, and flags
+  by KUBEMRR_* environment variables. See the README for both.
+
 EXAMPLE:
   kubemrr -a 0.0.0.0 -p 33033 watch dev-context prod-context
+  kubemrr -a 0.0.0.0 -p 33033 watch --all-contexts
+  kubemrr watch --daemon --all-contexts
   kubemrr -a 0.0.0.0 -p 33033 get pod
 
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := RunCommon(cmd); err != nil {
+			mrrConfig, err := RunCommon(cmd)
+			if err != nil {
+				return err
+			}
+			if format, err := cmd.Flags().GetString("log-format"); err != nil {
+				return errors.New("could not parse value of --log-format")
+			} else if err := setLogFormat(format); err != nil {
 				return err
 			}
-			return RunWatch(f, cmd, args)
+			return RunWatch(f, cmd, mrrConfig, args)
 		},
 	}
 
 	AddCommonFlags(watchCmd)
+	AddLogLevelFlag(watchCmd)
+	AddTokenFlag(watchCmd)
 	watchCmd.Flags().Duration("interval", 2*time.Minute, "Interval between requests to the server")
 	watchCmd.Flags().String("only", "", "Coma-separated names of resources to watch, empty to watch all supported")
+	watchCmd.Flags().StringSlice("namespace", nil, "Coma-separated namespaces to watch, empty to watch all namespaces")
+	watchCmd.Flags().StringP("selector", "l", "", "Label selector of mirrored objects, unless the cluster has its own selector in the --config file")
+	watchCmd.Flags().StringArray("field-selector", nil, "Field selector of mirrored objects of a kind as kind:selector, unless the cluster has its own in the --config file")
+	watchCmd.Flags().Float64("qps", 5, "Maximum number of requests per second to each server, 0 for no limit")
+	watchCmd.Flags().Int("burst", 10, "Maximum number of requests to each server above --qps in short bursts")
+	watchCmd.Flags().Bool("reload", true, "Reload watched servers when the kubeconfig or kubemrr config file changes. SIGHUP reloads them too")
+	watchCmd.Flags().Bool("all-contexts", false, "Watch every cluster referenced by contexts of the kubeconfig file")
+	watchCmd.Flags().String("tls-cert", "", "Path to the certificate of the mirror, to serve clients with TLS")
+	watchCmd.Flags().String("tls-key", "", "Path to the private key of the certificate given by --tls-cert")
+	watchCmd.Flags().StringArray("profile", nil, "Kubeconfig file of a profile as name=path, instead of --kubeconfig. Can be given several times")
+	watchCmd.Flags().Bool("in-cluster", false, "Watch the cluster that kubemrr runs in, with the service account of its pod")
+	watchCmd.Flags().Bool("protobuf", true, "Ask API servers to send objects in protobuf instead of JSON where supported")
+	watchCmd.Flags().Bool("daemon", false, "Run in background, detached from the terminal. Stop it with \"kubemrr stop\"")
+	watchCmd.Flags().String("pidfile", "", "File to write the process id to, "+defaultPidfile+" with --daemon")
+	watchCmd.Flags().String("log-file", "~/.kubemrr/log", "File that logs go to when given, and output of --daemon by default")
+	watchCmd.Flags().Int("log-max-size", 10, "Size in megabytes that --log-file grows to before it is rotated, 0 to never rotate it")
+	watchCmd.Flags().Int("log-max-files", 3, "Number of rotated files of --log-file to keep")
+	watchCmd.Flags().String("log-format", "", "Format of logs, text or json. By default JSON with the time key, or text with --verbose")
+	watchCmd.Flags().Duration("object-ttl", 0, "Evict objects that servers have not confirmed for this long, 0 to keep them until deleted. Watched pods are listed every half of it")
+	watchCmd.Flags().Int("max-objects", 0, "Maximum number of mirrored objects, 0 for no limit. Least recently used objects are evicted")
+	watchCmd.Flags().String("snapshot", "", "File to keep mirrored objects in between restarts, such as ~/.kubemrr/snapshot.json. It is written every --snapshot-interval and on shutdown. Empty starts with an empty mirror. Needs --feature-gates=SnapshotPersistence=true")
+	watchCmd.Flags().Duration("snapshot-interval", time.Minute, "Interval between writes of --snapshot")
+	watchCmd.Flags().Duration("shutdown-timeout", 10*time.Second, "How long to wait for requests of clients on SIGTERM, 0 for no limit")
+	watchCmd.Flags().String("discovery-cache-dir", "~/.kubemrr/discovery", "Directory to keep discovered API resources in, empty to always wait for discovery")
 	return watchCmd
 }
 
-func RunWatch(f Factory, cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
+func RunWatch(f Factory, cmd *cobra.Command, mrrConfig *MrrConfig, args []string) error {
+	allContexts, err := cmd.Flags().GetBool("all-contexts")
+	if err != nil {
+		return errors.New("could not parse value of --all-contexts")
+	}
+
+	inCluster, err := cmd.Flags().GetBool("in-cluster")
+	if err != nil {
+		return errors.New("could not parse value of --in-cluster")
+	}
+
+	if !allContexts && !inCluster && !cmd.Flags().Changed("profile") && len(args) == 0 && mrrConfig != nil {
+		args = mrrConfig.Contexts
+	}
+
+	if allContexts && len(args) > 0 {
+		return errors.New("--all-contexts cannot be combined with urls or context names")
+	}
+
+	if inCluster && (allContexts || len(args) > 0) {
+		return errors.New("--in-cluster cannot be combined with --all-contexts, urls or context names")
+	}
+
+	profiles, err := getWatchProfiles(cmd)
+	if err != nil {
+		return err
+	}
+
+	if inCluster && cmd.Flags().Changed("profile") {
+		return errors.New("--in-cluster cannot be combined with --profile")
+	}
+
+	if !allContexts && !inCluster && !cmd.Flags().Changed("profile") && len(args) < 1 {
 		return errors.New("at least one argument is required, either url or context name")
 	}
 
+	daemon, err := cmd.Flags().GetBool("daemon")
+	if err != nil {
+		return errors.New("could not parse value of --daemon")
+	}
+
+	pidfile, err := cmd.Flags().GetString("pidfile")
+	if err != nil {
+		return errors.New("could not parse value of --pidfile")
+	}
+
+	if daemon {
+		logFile, err := cmd.Flags().GetString("log-file")
+		if err != nil {
+			return errors.New("could not parse value of --log-file")
+		}
+		if pidfile == "" {
+			pidfile = defaultPidfile
+		}
+		return daemonize(pidfile, logFile, f.StdOut())
+	}
+
+	//an empty --log-file keeps logs on stdout, even when the config file sets a file
+	if logFile, _ := cmd.Flags().GetString("log-file"); cmd.Flags().Changed("log-file") && logFile != "" {
+		closeLog, err := logToFile(cmd)
+		if err != nil {
+			return err
+		}
+		defer closeLog()
+	}
+
 	bind, err := GetBind(cmd)
 	if err != nil {
 		return fmt.Errorf("unexpected error: %s", err)
 	}
 
-	l, err := net.Listen("tcp", bind)
+	tlsConfig, err := mirrorTLSConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	l, err := systemdListener()
 	if err != nil {
-		return fmt.Errorf("failed to bind on %s: %v", bind, err)
+		return fmt.Errorf("could not use the socket passed by systemd: %v", err)
+	}
+	if l != nil {
+		bind = listenerBind(l)
+		log.WithField("bind", bind).Info("using the socket passed by systemd")
+	} else {
+		l, err = listenMirror(bind)
+		if err != nil {
+			return fmt.Errorf("failed to bind on %s: %v", bind, err)
+		}
+	}
+
+	token, err := GetToken(cmd)
+	if err != nil {
+		return errors.New("could not parse value of --token")
+	}
+	if token == "" && !isLoopback(bind) {
+		log.WithField("bind", bind).Warn("mirror is reachable from the network without --token, anyone can list mirrored objects")
+	}
+
+	if tlsConfig != nil {
+		l = tls.NewListener(l, tlsConfig)
 	}
 
 	interval, err := cmd.Flags().GetDuration("interval")
@@ -73,29 +201,60 @@ func RunWatch(f Factory, cmd *cobra.Command, args []string) error {
 		return errors.New("could not parse value of --only")
 	}
 
-	clients := make([]KubeClient, len(args))
-	c := f.MrrCache()
+	namespaces, err := cmd.Flags().GetStringSlice("namespace")
+	if err != nil {
+		return errors.New("could not parse value of --namespace")
+	}
 
-	for i, arg := range args {
-		var config *Config
-		if govalidator.IsURL(arg) {
-			config, err = NewConfigFromURL(arg)
-			if err != nil {
-				return fmt.Errorf("url %s is not valid: %s", arg, err)
-			}
-		} else {
-			config, err = GetKubeconfig(cmd)
-			if err != nil {
-				return fmt.Errorf("cannot parse kubeconfig file %s: %s", arg, err)
-			}
-			context := config.getContext(arg)
-			if context == nil {
-				return fmt.Errorf("cannot find context %s in kubeconfig", arg)
-			}
-			config.CurrentContext = arg
-		}
+	reload, err := cmd.Flags().GetBool("reload")
+	if err != nil {
+		return errors.New("could not parse value of --reload")
+	}
 
-		kc := f.KubeClient(config)
+	shutdownTimeout, err := cmd.Flags().GetDuration("shutdown-timeout")
+	if err != nil {
+		return errors.New("could not parse value of --shutdown-timeout")
+	}
+
+	snapshot, err := cmd.Flags().GetString("snapshot")
+	if err != nil {
+		return errors.New("could not parse value of --snapshot")
+	}
+
+	snapshotInterval, err := cmd.Flags().GetDuration("snapshot-interval")
+	if err != nil {
+		return errors.New("could not parse value of --snapshot-interval")
+	}
+
+	objectTTL, err := cmd.Flags().GetDuration("object-ttl")
+	if err != nil {
+		return errors.New("could not parse value of --object-ttl")
+	}
+	maxObjects, err := cmd.Flags().GetInt("max-objects")
+	if err != nil {
+		return errors.New("could not parse value of --max-objects")
+	}
+
+	gates, err := GetFeatureGates(cmd)
+	if err != nil {
+		return err
+	}
+	if snapshot != "" && !gates.Enabled(SnapshotPersistence) {
+		return fmt.Errorf("--snapshot needs --feature-gates=%s=true", SnapshotPersistence)
+	}
+
+	if objectTTL > 0 && objectTTL < 2*interval {
+		return errors.New("--object-ttl must be at least twice --interval, otherwise listed objects are evicted")
+	}
+
+	targets, err := resolveWatchTargets(cmd, args, allContexts)
+	if err != nil {
+		return err
+	}
+
+	clients := make([]KubeClient, len(targets))
+	for i, t := range targets {
+		kc := f.KubeClient(t.config, t.options)
 		log.WithField("server", kc.Server().URL).Info("created client")
 		clients[i] = kc
 	}
@@ -106,22 +265,116 @@ func RunWatch(f Factory, cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	for _, kc := range clients {
-		for _, k := range []string{"pod"} {
-			if isWatching(k, enabledResources) {
-				loopWatchObjects(c, kc, k)
-			}
+	if pidfile != "" {
+		removePidfile, err := writePidfile(pidfile)
+		if err != nil {
+			return err
 		}
+		defer removePidfile()
+	}
 
-		for _, k := range []string{"service", "deployment", "configmap", "namespace", "node"} {
-			if isWatching(k, enabledResources) {
-				loopGetObjects(c, kc, k, interval)
-			}
+	recentLogs.install()
+	c := f.MrrCache()
+	c.features = gates
+	m := newMirror(f, c, interval, enabledResources, namespaces)
+	m.objectTTL = objectTTL
+	//any local process and any web page can reach loopback, so admin requests need the token too
+	c.tokenRequired = token != ""
+	if !gates.Enabled(AdminAPI) {
+		log.Debug("admin API is disabled by its feature gate")
+	} else if token != "" {
+		c.flusher = m.flush
+	} else {
+		log.Warn("admin API is disabled, give --token to enable it")
+	}
+
+	shutdown := make(chan struct{})
+	if snapshot != "" {
+		servers := []KubeServer{}
+		for _, kc := range clients {
+			servers = append(servers, kc.Server())
+		}
+		loaded, err := c.loadSnapshot(snapshot, servers)
+		if err != nil {
+			log.WithField("error", err).Warn("could not load snapshot of the cache")
+		} else if loaded > 0 {
+			log.WithField("objects", loaded).Info("loaded snapshot of the cache, watchers are listing objects again")
+		}
+		go c.saveSnapshots(snapshot, snapshotInterval, shutdown)
+	}
+	if objectTTL > 0 {
+		go c.evictStaleObjects(objectTTL, shutdown)
+	}
+	if maxObjects > 0 {
+		go c.limitObjects(maxObjects, shutdown)
+	}
+
+	for i, t := range targets {
+		m.start(t, clients[i])
+	}
+
+	reloadTargets := func() {
+		targets, err := resolveWatchTargets(cmd, args, allContexts)
+		if err != nil {
+			log.WithField("error", err).Error("could not reload watched servers")
+			return
 		}
+		m.reload(targets)
 	}
 
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	changed := make(chan struct{}, 1)
+	if reload {
+		config, _ := cmd.Flags().GetString("config")
+		files := []string{config}
+		for _, p := range profiles {
+			files = append(files, p.kubeconfig)
+		}
+		w, err := watchFiles(files, changed)
+		if err != nil {
+			log.WithField("error", err).Warn("could not watch config files, send SIGHUP to reload them")
+		} else {
+			defer w.Close()
+		}
+	}
+	go func() {
+		for {
+			select {
+			case <-hup:
+				log.Info("received SIGHUP, reloading watched servers")
+			case <-changed:
+				log.Info("config files have changed, reloading watched servers")
+			}
+			reloadTargets()
+		}
+	}()
+
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		sig := <-term
+		log.WithField("signal", sig).Info("shutting down, answering requests of connected clients")
+		close(shutdown)
+	}()
+
 	log.WithField("bind", bind).Info("started to listen")
-	err = f.Serve(l, c)
+	err = f.Serve(l, c, MrrServerOptions{Token: token, Shutdown: shutdown, DrainTimeout: shutdownTimeout, Features: gates})
+	select {
+	case <-shutdown:
+		if err != nil {
+			log.WithField("error", err).Warn("could not answer requests of all clients")
+		}
+		m.stop()
+		if snapshot != "" {
+			if err := c.saveSnapshot(snapshot); err != nil {
+				log.WithField("error", err).Warn("could not save snapshot of the cache")
+			}
+		}
+		log.Info("kubemrr has stopped")
+		return nil
+	default:
+	}
 	if err != nil {
 		return fmt.Errorf("unexpected error: %v", err)
 	}
@@ -129,31 +382,300 @@ func RunWatch(f Factory, cmd *cobra.Command, args []string) error {
 	return errors.New("kubemrr has stopped")
 }
 
+//watchProfile is a kubeconfig file whose clusters are watched with its credentials
+type watchProfile struct {
+	name       string
+	kubeconfig string
+}
+
+//getWatchProfiles returns profiles given by --profile, or the default profile with --kubeconfig
+func getWatchProfiles(cmd *cobra.Command) ([]watchProfile, error) {
+	values, err := cmd.Flags().GetStringArray("profile")
+	if err != nil {
+		return nil, errors.New("could not parse value of --profile")
+	}
+
+	if len(values) == 0 {
+		kubeconfig, err := cmd.Flags().GetString("kubeconfig")
+		if err != nil {
+			return nil, errors.New("could not parse value of --kubeconfig")
+		}
+		return []watchProfile{{kubeconfig: kubeconfig}}, nil
+	}
+
+	profiles := []watchProfile{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.Contains(parts[0], "/") {
+			return nil, fmt.Errorf("profile must be given as name=path, got %q", v)
+		}
+		if seen[parts[0]] {
+			return nil, fmt.Errorf("profile %s is given more than once", parts[0])
+		}
+		seen[parts[0]] = true
+		profiles = append(profiles, watchProfile{name: parts[0], kubeconfig: parts[1]})
+	}
+	return profiles, nil
+}
+
+//resolveWatchTargets makes configuration for each of the given urls and context names in each profile.
+//When allContexts is true, the contexts are taken from the kubeconfig file, one per cluster.
+//Profiles without urls and context names watch their current context
+func resolveWatchTargets(cmd *cobra.Command, args []string, allContexts bool) ([]watchTarget, error) {
+	mrrConfig, err := GetMrrConfig(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse kubemrr config file: %s", err)
+	}
+
+	protobuf, err := cmd.Flags().GetBool("protobuf")
+	if err != nil {
+		return nil, errors.New("could not parse value of --protobuf")
+	}
+
+	selector, err := cmd.Flags().GetString("selector")
+	if err != nil {
+		return nil, errors.New("could not parse value of --selector")
+	}
+
+	fieldSelectors, err := getFieldSelectors(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	qps, err := cmd.Flags().GetFloat64("qps")
+	if err != nil {
+		return nil, errors.New("could not parse value of --qps")
+	}
+
+	burst, err := cmd.Flags().GetInt("burst")
+	if err != nil {
+		return nil, errors.New("could not parse value of --burst")
+	}
+
+	discoveryCacheDir, err := cmd.Flags().GetString("discovery-cache-dir")
+	if err != nil {
+		return nil, errors.New("could not parse value of --discovery-cache-dir")
+	}
+	discoveryCacheDir, err = substituteUserHome(discoveryCacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("could not substitute ~ in %s: %s", discoveryCacheDir, err)
+	}
+
+	profiles, err := getWatchProfiles(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var inClusterConfig *Config
+	if inCluster, _ := cmd.Flags().GetBool("in-cluster"); inCluster {
+		inClusterConfig, err = NewInClusterConfig()
+		if err != nil {
+			return nil, err
+		}
+		args = []string{inClusterName}
+	}
+
+	targets := []watchTarget{}
+	for _, p := range profiles {
+		var kubeconfig *Config
+		names := args
+		if allContexts || (p.name != "" && len(args) == 0) {
+			kubeconfig, err = p.config()
+			if err != nil {
+				return nil, err
+			}
+			if allContexts {
+				names = kubeconfig.clusterContexts()
+			} else if kubeconfig.CurrentContext != "" {
+				names = []string{kubeconfig.CurrentContext}
+			}
+			if len(names) == 0 {
+				return nil, fmt.Errorf("kubeconfig %s does not have any context", p.kubeconfig)
+			}
+		}
+
+		for _, arg := range names {
+			var config *Config
+			if inClusterConfig != nil {
+				config = inClusterConfig
+			} else if govalidator.IsURL(arg) {
+				config, err = NewConfigFromURL(arg)
+				if err != nil {
+					return nil, fmt.Errorf("url %s is not valid: %s", arg, err)
+				}
+			} else {
+				if kubeconfig == nil {
+					kubeconfig, err = p.config()
+					if err != nil {
+						return nil, err
+					}
+				}
+				context := kubeconfig.getContext(arg)
+				if context == nil {
+					return nil, fmt.Errorf("cannot find context %s in kubeconfig %s", arg, p.kubeconfig)
+				}
+				c := *kubeconfig
+				c.CurrentContext = arg
+				config = &c
+			}
+			t := watchTarget{
+				name:    arg,
+				config:  config,
+				options: mrrConfig.clientOptions(config),
+				filters: mrrConfig.filterRules(config),
+			}
+			if p.name != "" {
+				t.name = p.name + "/" + arg
+			}
+			t.options.Profile = p.name
+			t.options.Protobuf = protobuf
+			t.options.DiscoveryCacheDir = discoveryCacheDir
+			t.options.QPS = qps
+			t.options.Burst = burst
+			if t.options.LabelSelector == "" {
+				t.options.LabelSelector = selector
+			}
+			for kind, fields := range fieldSelectors {
+				if _, ok := t.options.FieldSelectors[kind]; ok {
+					continue
+				}
+				if t.options.FieldSelectors == nil {
+					t.options.FieldSelectors = make(map[string]string)
+				}
+				t.options.FieldSelectors[kind] = fields
+			}
+			targets = append(targets, t)
+		}
+	}
+
+	return targets, nil
+}
+
+//config reads the kubeconfig file of the profile
+func (p watchProfile) config() (*Config, error) {
+	config, err := parseKubeConfig(p.kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse kubeconfig file: %s", err)
+	}
+	return &config, nil
+}
+
+//getFieldSelectors parses values of --field-selector, which are given as kind:selector
+func getFieldSelectors(cmd *cobra.Command) (map[string]string, error) {
+	values, err := cmd.Flags().GetStringArray("field-selector")
+	if err != nil {
+		return nil, errors.New("could not parse value of --field-selector")
+	}
+
+	res := map[string]string{}
+	for _, v := range values {
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid value of --field-selector %s, must be kind:selector", v)
+		}
+		res[parts[0]] = parts[1]
+	}
+	return res, nil
+}
+
 func isWatching(r string, rs string) bool {
 	return len(rs) == 0 || strings.Contains(rs, r)
 }
 
-func loopWatchObjects(c *MrrCache, kc KubeClient, kind string) {
+//loopWatchObjects keeps objects of the kind in the namespace up to date by watching them.
+//Empty namespace means all namespaces
+func loopWatchObjects(c *MrrCache, w *clusterWatcher, kind string, namespace string) {
 	events := make(chan *ObjectEvent)
-	l := log.WithField("kind", kind).WithField("server", kc.Server().URL)
+	kc := w.kc
+	l := log.WithField("cluster", w.target.name).WithField("kind", kind).WithField("server", kc.Server().URL)
+	if namespace != "" {
+		l = l.WithField("namespace", namespace)
+	}
+	//lags of objects created before the watch started are how long the mirror did not run
+	watching := time.Now()
 
 	watch := func() {
+		defer w.wg.Done()
+		b := newBackoff(w.backoffMin, w.backoffMax)
 		for {
-			l.Info("started to watch")
-			err := kc.WatchObjects(kind, events)
+			rv := w.resourceVersion(kind, namespace)
+			//a watch from scratch sends only objects that exist, so objects kept before it, such as those
+			//of the snapshot, are listed once instead. The watch continues from the version of the list
+			if rv == "" && c.hasObjects(kc.Server(), kind, namespace) {
+				objects, version, err := kc.ListObjects(kind, ListOptions{Namespace: namespace})
+				if w.isStopped() {
+					return
+				}
+				if err != nil {
+					c.metrics.apiError(kc.Server(), kind, err)
+					d := b.next(err)
+					l.WithField("error", err).WithField("delay", d.String()).Error("could not list objects before watching")
+					if !w.sleep(d) {
+						return
+					}
+					continue
+				}
+				objects = w.filter.filterObjects(objects)
+				c.replaceKubeObjects(kc.Server(), kind, namespace, objects)
+				w.setResourceVersion(kind, namespace, version)
+				rv = version
+				l.Infof("put %d listed objects into cache", len(objects))
+			}
+			l.WithField("resourceVersion", rv).Info("started to watch")
+			started := time.Now()
+			err := kc.WatchObjects(kind, ListOptions{Namespace: namespace, ResourceVersion: rv}, events)
+			if w.isStopped() {
+				l.Info("stopped to watch")
+				return
+			}
 			fields := log.Fields{}
 			if err != nil {
 				fields["error"] = err.Error()
 			}
 			l.WithFields(fields).Info("watch connection was closed, retrying")
-			c.deleteKubeObjects(kc.Server(), kind)
+			c.metrics.watchReconnected(kc.Server(), kind)
+
+			//the watch resumes from the last seen version, so the cache stays valid.
+			//Without a version the server sends all objects again
+			if isExpired(err) {
+				l.Info("resource version is too old, watching from scratch")
+				w.setResourceVersion(kind, namespace, "")
+			} else if err != nil {
+				c.metrics.apiError(kc.Server(), kind, err)
+				//a watch that worked for a while does not make the server look unhealthy
+				if time.Since(started) > w.backoffMax {
+					b.reset()
+				}
+				d := b.next(err)
+				l.WithField("delay", d.String()).Info("waiting before watching again")
+				if !w.sleep(d) {
+					return
+				}
+			} else {
+				b.reset()
+			}
+
+			if w.resourceVersion(kind, namespace) == "" {
+				c.deleteKubeObjects(kc.Server(), kind, namespace)
+			}
 		}
 	}
 
 	update := func() {
+		defer w.wg.Done()
 		for {
 			select {
+			case <-w.stop:
+				return
 			case e := <-events:
+				if e.Object.ResourceVersion != "" {
+					w.setResourceVersion(kind, namespace, e.Object.ResourceVersion)
+				}
+				c.metrics.watchEvent(kc.Server(), kind)
+				if e.Type == Bookmark {
+					continue
+				}
 				l.
 					WithField("name", e.Object.Name).
 					WithField("type", e.Type).
@@ -162,39 +684,172 @@ func loopWatchObjects(c *MrrCache, kc KubeClient, kind string) {
 				case Deleted:
 					c.deleteKubeObject(kc.Server(), *e.Object)
 				case Added, Modified:
-					c.updateKubeObject(kc.Server(), *e.Object)
+					//an object that no longer passes the filter may have been mirrored before
+					if w.filter.allows(*e.Object) {
+						c.updateKubeObject(kc.Server(), *e.Object)
+						if created := e.Object.CreationTimestamp; e.Type == Added && created.After(watching) {
+							c.metrics.eventLag(kc.Server(), kind, time.Since(created))
+						}
+					} else {
+						c.deleteKubeObject(kc.Server(), *e.Object)
+					}
 				}
 				l.WithField("cache", c.objects).Debugf("objects in cache")
 			}
 		}
 	}
 
+	w.wg.Add(2)
 	go watch()
 	go update()
 }
 
-func loopGetObjects(c *MrrCache, kc KubeClient, kind string, interval time.Duration) {
-	l := log.WithField("kind", kind).WithField("server", kc.Server().URL)
+//loopGetObjects keeps objects of the kind in the namespace up to date by listing them periodically.
+//Empty namespace means all namespaces
+func loopGetObjects(c *MrrCache, w *clusterWatcher, kind string, namespace string, interval time.Duration) {
+	kc := w.kc
+	l := log.WithField("cluster", w.target.name).WithField("kind", kind).WithField("server", kc.Server().URL)
+	if namespace != "" {
+		l = l.WithField("namespace", namespace)
+	}
 	update := func() {
+		defer w.wg.Done()
+		b := newBackoff(w.backoffMin, w.backoffMax)
 		for {
 			l.Info("updating objects")
-			objects, err := kc.GetObjects(kind)
+			objects, err := kc.GetObjects(kind, ListOptions{Namespace: namespace})
+			if w.isStopped() {
+				return
+			}
 			if err != nil {
-				l.WithField("error", err).Error("unexpected error while updating objects")
-				time.Sleep(10 * time.Second)
+				c.metrics.apiError(kc.Server(), kind, err)
+				d := b.next(err)
+				l.WithField("error", err).WithField("delay", d.String()).Error("unexpected error while updating objects")
+				if !w.sleep(d) {
+					return
+				}
 				continue
 			}
+			b.reset()
 
 			l.WithField("objects", objects).Debug("received objects")
-			c.deleteKubeObjects(kc.Server(), kind)
-			for i := range objects {
-				c.updateKubeObject(kc.Server(), objects[i])
-			}
+			objects = w.filter.filterObjects(objects)
+			c.replaceKubeObjects(kc.Server(), kind, namespace, objects)
 			l.Infof("put %d objects into cache", len(objects))
 
-			time.Sleep(interval)
+			if !w.sleep(interval) {
+				return
+			}
 		}
 	}
 
+	w.wg.Add(1)
 	go update()
 }
+
+//listenMirror listens on the address of the mirror, a TCP address or a unix socket. A socket file
+//left by a mirror that did not stop cleanly is removed, but a socket of a running mirror is not taken over
+func listenMirror(bind string) (net.Listener, error) {
+	network, addr := mirrorNetwork(bind)
+	if network != "unix" {
+		return net.Listen(network, addr)
+	}
+
+	if fi, err := os.Stat(addr); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", addr)
+		}
+		if conn, err := net.Dial(network, addr); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another process listens on %s", addr)
+		}
+		if err := os.Remove(addr); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(addr, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+//mirrorTLSConfig returns configuration of the listener of the mirror given by --tls-cert and --tls-key,
+//or nil when the mirror serves without TLS
+func mirrorTLSConfig(cmd *cobra.Command) (*tls.Config, error) {
+	cert, err := cmd.Flags().GetString("tls-cert")
+	if err != nil {
+		return nil, errors.New("could not parse value of --tls-cert")
+	}
+	key, err := cmd.Flags().GetString("tls-key")
+	if err != nil {
+		return nil, errors.New("could not parse value of --tls-key")
+	}
+	if cert == "" && key == "" {
+		return nil, nil
+	}
+	if cert == "" || key == "" {
+		return nil, errors.New("--tls-cert and --tls-key must be given together")
+	}
+
+	if cert, err = substituteUserHome(cert); err != nil {
+		return nil, err
+	}
+	if key, err = substituteUserHome(key); err != nil {
+		return nil, err
+	}
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("could not load certificate of the mirror: %s", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{pair}}, nil
+}
+
+//isLoopback tells if only local processes can connect to the address of the mirror
+func isLoopback(bind string) bool {
+	network, addr := mirrorNetwork(bind)
+	if network == "unix" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+//logToFile sends logs to --log-file, rotated by --log-max-size and --log-max-files.
+//The returned function sends logs back to stdout and closes the file
+func logToFile(cmd *cobra.Command) (func(), error) {
+	logFile, err := cmd.Flags().GetString("log-file")
+	if err != nil {
+		return nil, errors.New("could not parse value of --log-file")
+	}
+	maxSize, err := cmd.Flags().GetInt("log-max-size")
+	if err != nil || maxSize < 0 {
+		return nil, errors.New("--log-max-size must be a non-negative number of megabytes")
+	}
+	maxFiles, err := cmd.Flags().GetInt("log-max-files")
+	if err != nil || maxFiles < 0 {
+		return nil, errors.New("--log-max-files must be a non-negative number")
+	}
+
+	out, err := openRotatingFile(logFile, int64(maxSize)<<20, maxFiles)
+	if err != nil {
+		return nil, err
+	}
+	log.SetOutput(out)
+	return func() {
+		log.SetOutput(os.Stdout)
+		out.Close()
+	}, nil
+}
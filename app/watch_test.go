@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"math/rand"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 )
@@ -98,11 +102,35 @@ func TestRunWatchContextMode(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 
 	//copied from kubeconfig_valid file
-	expectedURLs := []string{"https://foo.com", "https://bar.com"}
+	expectedURLs := []string{"https://bar.com", "https://foo.com"}
 	actualURLs := []string{}
 	for _, kc := range f.kubeClients {
 		actualURLs = append(actualURLs, kc.baseURL.String())
 	}
+	sort.Strings(actualURLs)
+
+	assert.Equal(t, expectedURLs, actualURLs)
+}
+
+func TestRunWatchConfigContexts(t *testing.T) {
+	f := NewTestFactory()
+	cmd := NewWatchCommand(f)
+	cmd.Flags().Set("config", "test_data/kubemrr_config_flags")
+	cmd.Flags().Set("port", "0")
+	cmd.Flags().Set("log-file", "")
+	cmd.Flags().Set("log-level", "info")
+	cmd.Flags().Set("log-format", "text")
+	defer enableDebug()
+
+	go cmd.RunE(cmd, []string{})
+	time.Sleep(50 * time.Millisecond)
+
+	expectedURLs := []string{"https://bar.com", "https://foo.com"}
+	actualURLs := []string{}
+	for _, kc := range f.kubeClients {
+		actualURLs = append(actualURLs, kc.baseURL.String())
+	}
+	sort.Strings(actualURLs)
 
 	assert.Equal(t, expectedURLs, actualURLs)
 }
@@ -148,7 +176,9 @@ func TestLoopWatchObjectsFailure(t *testing.T) {
 		}
 	}
 
-	loopWatchObjects(c, kc, kind)
+	w := newClusterWatcher(kc, watchTarget{})
+	w.backoffMin = time.Millisecond
+	loopWatchObjects(c, w, kind, "")
 
 	time.Sleep(50 * time.Millisecond)
 	if kc.watchObjectHits[kind] < 2 {
@@ -175,7 +205,7 @@ func TestLoopWatchObjects(t *testing.T) {
 		{Added, &KubeObject{TypeMeta: TypeMeta{"other"}, ObjectMeta: ObjectMeta{Name: "pod0"}}},
 	}
 
-	loopWatchObjects(c, kc, "does not matter")
+	loopWatchObjects(c, newClusterWatcher(kc, watchTarget{}), "does not matter", "")
 	time.Sleep(50 * time.Millisecond)
 
 	//order matters in slice
@@ -186,6 +216,99 @@ func TestLoopWatchObjects(t *testing.T) {
 	}
 }
 
+func TestLoopWatchObjectsResume(t *testing.T) {
+	c := NewMrrCache()
+	kc := NewTestKubeClient()
+	kc.watchObjectError = errors.New("Test Error")
+	kc.objectEvents = []*ObjectEvent{
+		{Added, &KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a", ResourceVersion: "1"}}},
+		{Bookmark, &KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{ResourceVersion: "2"}}},
+	}
+
+	w := newClusterWatcher(kc, watchTarget{})
+	w.backoffMin = time.Millisecond
+	loopWatchObjects(c, w, "pod", "")
+	time.Sleep(50 * time.Millisecond)
+
+	kc.watchObjectLock.Lock()
+	defer kc.watchObjectLock.Unlock()
+	assert.Equal(t, ListOptions{}, kc.watchOptions[0])
+	assert.Equal(t, ListOptions{ResourceVersion: "2"}, kc.watchOptions[len(kc.watchOptions)-1], "must resume from the bookmark")
+	assert.Equal(t, []KubeObject{*kc.objectEvents[0].Object}, c.objects[kc.Server()])
+}
+
+func TestLoopWatchObjectsRestored(t *testing.T) {
+	c := NewMrrCache()
+	kc := NewTestKubeClient()
+	kc.listVersion = "7"
+	kept := KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "kept", ResourceVersion: "1"}}
+	kc.objects = []KubeObject{kept}
+	c.updateKubeObject(kc.Server(), kept)
+	c.updateKubeObject(kc.Server(), KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "deleted", ResourceVersion: "1"}})
+
+	w := newClusterWatcher(kc, watchTarget{})
+	loopWatchObjects(c, w, "pod", "")
+	time.Sleep(50 * time.Millisecond)
+	defer w.close()
+
+	kc.watchObjectLock.Lock()
+	defer kc.watchObjectLock.Unlock()
+	assert.Equal(t, 1, kc.getObjectHits["pod"], "kept objects must be listed once")
+	assert.Equal(t, ListOptions{ResourceVersion: "7"}, kc.watchOptions[0], "must watch from the list")
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	assert.Equal(t, []KubeObject{kept}, c.objects[kc.Server()])
+}
+
+func TestLoopWatchObjectsBackoff(t *testing.T) {
+	c := NewMrrCache()
+	kc := NewTestKubeClient()
+	kc.watchObjectError = &APIError{Code: 503}
+
+	w := newClusterWatcher(kc, watchTarget{})
+	loopWatchObjects(c, w, "pod", "")
+	time.Sleep(50 * time.Millisecond)
+
+	kc.watchObjectLock.Lock()
+	defer kc.watchObjectLock.Unlock()
+	assert.Equal(t, 1, kc.watchObjectHits["pod"], "must wait before watching again")
+	w.close()
+}
+
+func TestLoopWatchObjectsExpired(t *testing.T) {
+	c := NewMrrCache()
+	kc := NewTestKubeClient()
+	kc.watchObjectError = &APIError{Code: 410}
+	c.updateKubeObject(kc.Server(), KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "stale", ResourceVersion: "1"}})
+
+	w := newClusterWatcher(kc, watchTarget{})
+	w.setResourceVersion("pod", "", "5")
+	loopWatchObjects(c, w, "pod", "")
+	time.Sleep(50 * time.Millisecond)
+
+	kc.watchObjectLock.Lock()
+	defer kc.watchObjectLock.Unlock()
+	assert.Equal(t, []ListOptions{{ResourceVersion: "5"}, {}, {}, {}, {}}, kc.watchOptions)
+	assert.Empty(t, c.objects[kc.Server()], "objects must be listed again after expiry")
+}
+
+func TestLoopWatchObjectsFilter(t *testing.T) {
+	c := NewMrrCache()
+	kc := NewTestKubeClient()
+	kc.objectEvents = []*ObjectEvent{
+		{Added, &KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a"}}},
+		{Added, &KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "b"}}},
+		{Modified, &KubeObject{TypeMeta: TypeMeta{"pod"}, ObjectMeta: ObjectMeta{Name: "a", Labels: map[string]string{"skip": "true"}}}},
+	}
+	target := watchTarget{filters: []FilterRule{{Exclude: &ObjectMatch{Labels: map[string]string{"skip": "true"}}}}}
+
+	loopWatchObjects(c, newClusterWatcher(kc, target), "pod", "")
+	time.Sleep(50 * time.Millisecond)
+
+	expected := []KubeObject{*kc.objectEvents[1].Object}
+	assert.Equal(t, expected, c.objects[kc.Server()], "object must be removed when it stops passing the filter")
+}
+
 func TestLoopGetObjects(t *testing.T) {
 	c := NewMrrCache()
 	kc := NewTestKubeClient()
@@ -205,7 +328,7 @@ func TestLoopGetObjects(t *testing.T) {
 		}
 	}
 
-	loopGetObjects(c, kc, kind, 3*time.Millisecond)
+	loopGetObjects(c, newClusterWatcher(kc, watchTarget{}), kind, "", 3*time.Millisecond)
 	time.Sleep(50 * time.Millisecond)
 
 	actual := c.objects[kc.Server()]
@@ -214,3 +337,195 @@ func TestLoopGetObjects(t *testing.T) {
 		t.Errorf("Expected \n%+v \n Got \n%+v", expected, actual)
 	}
 }
+
+func TestRunWatchAllContexts(t *testing.T) {
+	f := NewTestFactory()
+	cmd := NewWatchCommand(f)
+	cmd.Flags().Set("port", "0")
+	cmd.Flags().Set("kubeconfig", "test_data/kubeconfig_valid")
+	cmd.Flags().Set("all-contexts", "true")
+
+	go cmd.RunE(cmd, []string{})
+	time.Sleep(50 * time.Millisecond)
+
+	expectedURLs := []string{"https://bar.com", "https://foo.com"}
+	actualURLs := []string{}
+	for url := range f.kubeClients {
+		actualURLs = append(actualURLs, url)
+	}
+	sort.Strings(actualURLs)
+
+	assert.Equal(t, expectedURLs, actualURLs)
+}
+
+func TestRunWatchAllContextsWithArgs(t *testing.T) {
+	f := NewTestFactory()
+	cmd := NewWatchCommand(f)
+	cmd.Flags().Set("port", "0")
+	cmd.Flags().Set("kubeconfig", "test_data/kubeconfig_valid")
+	cmd.Flags().Set("all-contexts", "true")
+
+	err := cmd.RunE(cmd, []string{"dev"})
+	assert.Error(t, err)
+}
+
+func TestRunWatchSnapshotGate(t *testing.T) {
+	f := NewTestFactory()
+	cmd := NewWatchCommand(f)
+	cmd.Flags().Set("port", "0")
+	cmd.Flags().Set("snapshot", "snapshot.json")
+
+	err := cmd.RunE(cmd, []string{"http://k8s.example.com"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), SnapshotPersistence)
+	}
+}
+
+func TestResolveWatchTargetsSelector(t *testing.T) {
+	cmd := NewWatchCommand(NewTestFactory())
+	cmd.Flags().Set("kubeconfig", "test_data/kubeconfig_valid")
+	cmd.Flags().Set("config", "test_data/kubemrr_config_valid")
+	cmd.Flags().Set("selector", "app=web")
+
+	targets, err := resolveWatchTargets(cmd, []string{"prod", "dev"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "tier=web", targets[0].options.LabelSelector, "selector of the cluster must take precedence")
+	assert.Equal(t, "app=web", targets[1].options.LabelSelector)
+}
+
+func TestResolveWatchTargetsFieldSelector(t *testing.T) {
+	cmd := NewWatchCommand(NewTestFactory())
+	cmd.Flags().Set("kubeconfig", "test_data/kubeconfig_valid")
+	cmd.Flags().Set("config", "test_data/kubemrr_config_valid")
+	cmd.Flags().Set("field-selector", "pod:status.phase=Running")
+	cmd.Flags().Set("field-selector", "node:spec.unschedulable=false,metadata.name!=a")
+
+	targets, err := resolveWatchTargets(cmd, []string{"prod", "dev"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"pod":  "status.phase!=Succeeded",
+		"node": "spec.unschedulable=false,metadata.name!=a",
+	}, targets[0].options.FieldSelectors, "selector of the cluster must take precedence")
+	assert.Equal(t, map[string]string{
+		"pod":  "status.phase=Running",
+		"node": "spec.unschedulable=false,metadata.name!=a",
+	}, targets[1].options.FieldSelectors)
+
+	cmd.Flags().Set("field-selector", "status.phase=Running")
+	_, err = resolveWatchTargets(cmd, []string{"prod"}, false)
+	assert.Error(t, err)
+}
+
+func TestResolveWatchTargetsProfiles(t *testing.T) {
+	cmd := NewWatchCommand(NewTestFactory())
+	cmd.Flags().Set("kubeconfig", "test_data/kubeconfig_missing")
+	cmd.Flags().Set("config", "test_data/kubemrr_config_valid")
+	cmd.Flags().Set("profile", "alice=test_data/kubeconfig_valid")
+	cmd.Flags().Set("profile", "bob=test_data/kubeconfig_valid")
+
+	targets, err := resolveWatchTargets(cmd, nil, false)
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(targets)) {
+		assert.Equal(t, "alice/prod", targets[0].name, "must watch the current context of the profile")
+		assert.Equal(t, "alice", targets[0].options.Profile)
+		assert.Equal(t, "bob/prod", targets[1].name)
+		assert.Equal(t, "bob", targets[1].options.Profile)
+	}
+
+	targets, err = resolveWatchTargets(cmd, []string{"dev"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(targets))
+
+	targets, err = resolveWatchTargets(cmd, nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(targets))
+
+	_, err = resolveWatchTargets(cmd, []string{"missing"}, false)
+	assert.Error(t, err)
+
+	for _, invalid := range []string{"alice", "=path", "alice=", "a/b=path", "alice=other"} {
+		cmd := NewWatchCommand(NewTestFactory())
+		cmd.Flags().Set("profile", "alice=test_data/kubeconfig_valid")
+		cmd.Flags().Set("profile", invalid)
+		_, err = getWatchProfiles(cmd)
+		assert.Error(t, err, "profile %q must be rejected", invalid)
+	}
+}
+
+func TestResolveWatchTargetsInCluster(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubemrr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "token"), []byte("secret\n"), 0600)
+
+	defer func(d string) { serviceAccountDir = d }(serviceAccountDir)
+	serviceAccountDir = dir
+	defer os.Unsetenv("KUBERNETES_SERVICE_HOST")
+	defer os.Unsetenv("KUBERNETES_SERVICE_PORT")
+
+	cmd := NewWatchCommand(NewTestFactory())
+	cmd.Flags().Set("kubeconfig", "test_data/kubeconfig_missing")
+	cmd.Flags().Set("in-cluster", "true")
+
+	_, err = resolveWatchTargets(cmd, nil, false)
+	assert.Error(t, err, "must fail outside of a pod")
+
+	os.Setenv("KUBERNETES_SERVICE_HOST", "fd00::1")
+	os.Setenv("KUBERNETES_SERVICE_PORT", "443")
+	targets, err := resolveWatchTargets(cmd, nil, false)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(targets)) {
+		assert.Equal(t, "https://[fd00::1]:443", targets[0].config.getCurrentCluster().Server)
+		assert.Equal(t, filepath.Join(dir, "ca.crt"), targets[0].config.getCurrentCluster().CertificateAuthority)
+
+		token, err := newTokenSource(targets[0].config.getUser(inClusterName)).token()
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", token)
+	}
+
+	err = cmd.RunE(cmd, []string{"dev"})
+	assert.Error(t, err, "must not be combined with context names")
+}
+
+func TestLoopWatchObjectsStop(t *testing.T) {
+	c := NewMrrCache()
+	kc := NewTestKubeClient()
+	w := newClusterWatcher(kc, watchTarget{})
+
+	loopWatchObjects(c, w, "pod", "")
+	loopGetObjects(c, w, "node", "", 3*time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		w.close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("loops did not return after watcher was closed")
+	}
+	assert.True(t, kc.isClosed(), "must have closed the client")
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:33033":      true,
+		"localhost:33033":      true,
+		"[::1]:33033":          true,
+		"unix:///run/mrr.sock": true,
+		"0.0.0.0:33033":        false,
+		"10.5.1.6:33033":       false,
+		"mirror.example:33033": false,
+	}
+
+	for bind, expected := range tests {
+		if isLoopback(bind) != expected {
+			t.Errorf("isLoopback(%s) is %v, expected %v", bind, !expected, expected)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeNames(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"web"},
+		{"", "web-5d8f7-x2x9k", strings.Repeat("n", 300)},
+	}
+
+	for _, names := range tests {
+		objects := []KubeObject{}
+		for _, n := range names {
+			objects = append(objects, KubeObject{ObjectMeta: ObjectMeta{Name: n, Namespace: "ns"}})
+		}
+		decoded, err := decodeNames(encodeNames(objects))
+		if err != nil {
+			t.Errorf("Unexpected error decoding %v: %v", names, err)
+		}
+		if !reflect.DeepEqual(decoded, names) {
+			t.Errorf("Expected names %v, got %v", names, decoded)
+		}
+	}
+}
+
+func TestDecodeNamesMalformed(t *testing.T) {
+	for _, b := range [][]byte{{5, 'a', 'b'}, {0x80}} {
+		if _, err := decodeNames(b); err == nil {
+			t.Errorf("Expected error decoding %v", b)
+		}
+	}
+}
+
+func TestDecodeNamesAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	objects := make([]KubeObject, 10000)
+	for i := range objects {
+		objects[i].Name = fmt.Sprintf("web-%d", i)
+	}
+	encoded := encodeNames(objects)
+	if allocs := testing.AllocsPerRun(10, func() { decodeNames(encoded) }); allocs > 2 {
+		t.Errorf("Expected names to share a string, allocated %v times", allocs)
+	}
+	if allocs := testing.AllocsPerRun(10, func() { encodeNames(objects) }); allocs > 1 {
+		t.Errorf("Expected names to be encoded at once, allocated %v times", allocs)
+	}
+}
@@ -32,6 +32,15 @@ func init() {
 	RootCmd.AddCommand(app.NewWatchCommand(f))
 	RootCmd.AddCommand(app.NewVersionCommand(f))
 	RootCmd.AddCommand(app.NewCompletionCommand(f))
+	RootCmd.AddCommand(app.NewUICommand(f))
+	RootCmd.AddCommand(app.NewReportBundleCommand(f))
+	RootCmd.AddCommand(app.NewBenchCommand(f))
+	RootCmd.AddCommand(app.NewFlushCommand(f))
+	RootCmd.AddCommand(app.NewStopCommand(f))
+	RootCmd.AddCommand(app.NewExportCommand(f))
+	RootCmd.AddCommand(app.NewImportCommand(f))
+	RootCmd.AddCommand(app.NewStatsCommand(f))
+	RootCmd.AddCommand(app.NewCompleteCommand(f))
 }
 
 func main() {
@@ -101,7 +101,11 @@ func TestCommands(t *testing.T) {
 	watchCmd.Flags().Set("port", "39000")
 	go watchCmd.RunE(watchCmd, []string{k8sAddress})
 
-	time.Sleep(1 * time.Millisecond)
+	//wait until the mirror has the first objects
+	for i := 0; i < 100 && buf.Len() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		getCmd.RunE(getCmd, []string{"pod"})
+	}
 
 	tests := []struct {
 		arg    string